@@ -0,0 +1,31 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Package gkepolicies embeds the default set of GKE policies, used when
+// no other policy source is configured.
+package gkepolicies
+
+import "embed"
+
+// FS holds the default policies along with the rules they use. Policy tests
+// and policies used for testing the tool are not included.
+//
+//go:embed policy/cluster_version.rego
+//go:embed policy/control_plane_access.rego
+//go:embed policy/control_plane_endpoint.rego
+//go:embed policy/control_plane_redundancy.rego
+//go:embed policy/node_pool_redundancy.rego
+//go:embed policy/private_cluster.rego
+//go:embed rule
+var FS embed.FS
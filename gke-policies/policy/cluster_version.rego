@@ -0,0 +1,48 @@
+#Copyright 2022 Google LLC
+#
+#Licensed under the Apache License, Version 2.0 (the "License");
+#you may not use this file except in compliance with the License.
+#You may obtain a copy of the License at
+#
+#    https://www.apache.org/licenses/LICENSE-2.0
+#
+#Unless required by applicable law or agreed to in writing, software
+#distributed under the License is distributed on an "AS IS" BASIS,
+#WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+#See the License for the specific language governing permissions and
+#limitations under the License.
+
+# METADATA
+# title: Cluster version currency
+# description: GKE cluster should run a version that is available and node versions should not lag behind the control plane
+# custom:
+#   group: Currency
+package gke.policy.cluster_version
+
+default valid = false
+
+valid {
+  count(violation) == 0
+}
+
+violation[msg] {
+  versions := input.versions
+  count(versions.available_versions) > 0
+  not available(versions.master, versions.available_versions)
+  msg := sprintf("GKE cluster version %q is not available in %q release channel", [versions.master, versions.release_channel])
+}
+
+violation[msg] {
+  versions := input.versions
+  minor(versions.master) - minor(versions.node) > 2
+  msg := sprintf("GKE cluster node version %q is more than two minor versions behind control plane version %q", [versions.node, versions.master])
+}
+
+available(version, versions) {
+  versions[_] == version
+}
+
+minor(version) = num {
+  parts := split(version, ".")
+  num := to_number(parts[1])
+}
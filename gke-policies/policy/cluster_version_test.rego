@@ -0,0 +1,31 @@
+#Copyright 2022 Google LLC
+#
+#Licensed under the Apache License, Version 2.0 (the "License");
+#you may not use this file except in compliance with the License.
+#You may obtain a copy of the License at
+#
+#    https://www.apache.org/licenses/LICENSE-2.0
+#
+#Unless required by applicable law or agreed to in writing, software
+#distributed under the License is distributed on an "AS IS" BASIS,
+#WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+#See the License for the specific language governing permissions and
+#limitations under the License.
+
+package gke.policy.cluster_version
+
+test_version_available {
+    valid with input as {"versions": {"master": "1.21.6-gke.1500", "node": "1.21.6-gke.1500", "release_channel": "REGULAR", "available_versions": ["1.22.6-gke.300", "1.21.6-gke.1500"]}}
+}
+
+test_version_not_available {
+    not valid with input as {"versions": {"master": "1.20.9-gke.700", "node": "1.20.9-gke.700", "release_channel": "REGULAR", "available_versions": ["1.22.6-gke.300", "1.21.6-gke.1500"]}}
+}
+
+test_node_version_skew {
+    not valid with input as {"versions": {"master": "1.22.6-gke.300", "node": "1.19.16-gke.1500", "release_channel": "REGULAR", "available_versions": ["1.22.6-gke.300"]}}
+}
+
+test_versions_not_included {
+    valid with input as {"name": "cluster"}
+}
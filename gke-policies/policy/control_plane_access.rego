@@ -17,6 +17,7 @@
 # description: Control Plane endpoint access should be limited to authorized networks only
 # custom:
 #   group: Security
+#   cis: "5.6.3"
 package gke.policy.control_plane_access
 
 default valid = false
@@ -17,6 +17,7 @@
 # description: Control Plane endpoint should be locked from external access
 # custom:
 #   group: Security
+#   cis: "5.6.4"
 package gke.policy.control_plane_endpoint
 
 default valid = false
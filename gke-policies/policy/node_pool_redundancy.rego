@@ -17,6 +17,7 @@
 # description: GKE node pools should be regional for maximum availability of a node pool during zonal outages
 # custom:
 #   group: Availability
+#   applies_to: standard
 package gke.policy.node_pool_redundancy
 
 default valid = false
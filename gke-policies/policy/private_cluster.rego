@@ -17,6 +17,7 @@
 # description: GKE cluster should be private to ensure network isolation
 # custom:
 #   group: Security
+#   cis: "5.6.5"
 package gke.policy.private_cluster
 
 default valid = false
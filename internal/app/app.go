@@ -16,119 +16,1443 @@ package app
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	gkepolicies "github.com/mikouaj/gke-review/gke-policies"
 	"github.com/mikouaj/gke-review/internal/gke"
 	"github.com/mikouaj/gke-review/internal/log"
+	"github.com/mikouaj/gke-review/internal/outputs"
 	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
 )
 
+var ErrEnforcedViolations = errors.New("enforced policies are violated")
+var ErrInvalidPolicies = errors.New("policies failed validation")
+var ErrEvaluationErrors = errors.New("policies could not be evaluated")
+var ErrInterrupted = errors.New("review was interrupted")
+var ErrFailedTests = errors.New("policy tests failed")
+
+const (
+	ExitCodeClean       = 0
+	ExitCodeViolations  = 2
+	ExitCodeErrors      = 3
+	ExitCodeInterrupted = 130
+)
+
+// ExitCode maps error returned by the app to the process exit code. Violations of
+// enforced policies and failed policy tests result in ExitCodeViolations, interruption
+// in ExitCodeInterrupted and any other error in ExitCodeErrors.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeClean
+	}
+	if errors.Is(err, ErrEnforcedViolations) || errors.Is(err, ErrFailedTests) {
+		return ExitCodeViolations
+	}
+	if errors.Is(err, ErrInterrupted) {
+		return ExitCodeInterrupted
+	}
+	return ExitCodeErrors
+}
+
 type PolicyAutomation interface {
 	LoadCliConfig(cliConfig *CliConfig) error
 	Close() error
 	ClusterReview() error
+	PolicyCheck() error
+	PolicyList() error
+	PolicyWatch() error
+	PolicyExplain() error
+	PolicyTrend() error
+	PolicyTest() error
+	PolicyServe() error
 }
 
 type PolicyAutomationApp struct {
-	ctx    context.Context
-	config *ConfigNg
-	out    *Output
-	gke    *gke.GKEClient
+	ctx           context.Context
+	cancel        context.CancelFunc
+	stopSignals   context.CancelFunc
+	timeout       time.Duration
+	config        *ConfigNg
+	out           *Output
+	resultWriters []outputs.ResultWriter
+	waivers       []*policy.PolicyWaiver
+	suppressions  []*policy.ViolationSuppression
+	baseline      *Baseline
+	includeRe     *regexp.Regexp
+	excludeRe     *regexp.Regexp
+	stdin         io.Reader
+	gke           *gke.GKEClient
+	// quiet holds the standard output of the review in the quiet on success mode
+	quiet *quietWriter
+	// policySources are names of sources policy files were last read from
+	policySources []string
+	// defaultConfigFile is read, if it exists, when no configuration file is given
+	defaultConfigFile string
 }
 
+// StdinInputFile is the cluster input file path that reads the cluster details from
+// the standard input.
+const StdinInputFile = "-"
+
+// NewPolicyAutomationApp creates the app with a context that is cancelled on SIGINT or SIGTERM,
+// so the review stops promptly when interrupted.
 func NewPolicyAutomationApp() PolicyAutomation {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	return &PolicyAutomationApp{
-		ctx:    context.Background(),
-		config: &ConfigNg{},
-		out:    NewSilentOutput(),
+		ctx:               ctx,
+		stopSignals:       stop,
+		config:            &ConfigNg{},
+		out:               NewSilentOutput(),
+		stdin:             os.Stdin,
+		defaultConfigFile: DefaultConfigFile(),
+	}
+}
+
+// LoadCliConfig loads configuration from command line flags. Settings of the configuration file,
+// or of the default configuration file if it exists, are overridden by flags set explicitly.
+func (p *PolicyAutomationApp) LoadCliConfig(cliConfig *CliConfig) error {
+	if cliConfig.OutputFile != "" && strings.Contains(cliConfig.OutputFormat, ",") {
+		return fmt.Errorf("output file can be set for a single output format only, use format:path to write multiple outputs to files")
+	}
+	configFile := cliConfig.ConfigFile
+	if configFile == "" && p.defaultConfigFile != "" {
+		if _, err := os.Stat(p.defaultConfigFile); err == nil {
+			configFile = p.defaultConfigFile
+		}
+	}
+	if configFile == "" {
+		return p.LoadConfig(newConfigFromCli(cliConfig))
+	}
+	config, err := newConfigFromFile(configFile)
+	if err != nil {
+		return fmt.Errorf("could not read configuration file %s: %s", configFile, err)
+	}
+	overrideConfig(config, newConfigFromCli(cliConfig), cliConfig.isSet)
+	if err := overrideGitPolicies(config, cliConfig); err != nil {
+		return err
+	}
+	return p.LoadConfig(config)
+}
+
+// overrideGitPolicies applies the GIT branch and directory flags to GIT policy sources of the
+// configuration file, when they are set without the GIT repository flag, that replaces policy
+// sources of the file. The flags are rejected when the file has no GIT policy source.
+func overrideGitPolicies(config *ConfigNg, cliConfig *CliConfig) error {
+	if cliConfig.isSet("git-policy-repo") || (!cliConfig.isSet("git-policy-branch") && !cliConfig.isSet("git-policy-dir")) {
+		return nil
+	}
+	found := false
+	for i := range config.Policies {
+		if config.Policies[i].GitRepository == "" {
+			continue
+		}
+		found = true
+		if cliConfig.isSet("git-policy-branch") {
+			config.Policies[i].GitBranch = cliConfig.GitBranch
+		}
+		if cliConfig.isSet("git-policy-dir") {
+			config.Policies[i].GitDirectory = cliConfig.GitDirectory
+		}
+	}
+	if !found {
+		return fmt.Errorf("GIT policy branch and directory can be set only with a GIT repository, in flags or in the configuration file")
+	}
+	return nil
+}
+
+func (p *PolicyAutomationApp) LoadConfig(config *ConfigNg) (err error) {
+	p.config = config
+	if p.config.SilentMode {
+		log.Disable()
+	} else if p.config.LogLevel != "" {
+		if err := log.SetLevel(p.config.LogLevel); err != nil {
+			return err
+		}
+	}
+	p.config.MinSeverity = strings.ToUpper(p.config.MinSeverity)
+	if p.config.MinSeverity != "" && !policy.IsValidSeverity(p.config.MinSeverity) {
+		return fmt.Errorf("invalid minimum severity %q", p.config.MinSeverity)
+	}
+	p.config.FailOn = strings.ToUpper(p.config.FailOn)
+	if p.config.FailOn != "" && !policy.IsValidSeverity(p.config.FailOn) {
+		return fmt.Errorf("invalid fail on severity %q", p.config.FailOn)
+	}
+	for name, severity := range p.config.SeverityOverrides {
+		p.config.SeverityOverrides[name] = strings.ToUpper(severity)
+		if !policy.IsValidSeverity(p.config.SeverityOverrides[name]) {
+			return fmt.Errorf("invalid severity %q of policy %s override", severity, name)
+		}
+	}
+	if p.config.Timeout != "" {
+		if p.timeout, err = time.ParseDuration(p.config.Timeout); err != nil || p.timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q", p.config.Timeout)
+		}
+		p.ctx, p.cancel = context.WithTimeout(p.ctx, p.timeout)
+	}
+	if p.config.Include != "" {
+		if p.includeRe, err = regexp.Compile(p.config.Include); err != nil {
+			return fmt.Errorf("invalid include expression: %s", err)
+		}
+	}
+	if p.config.Exclude != "" {
+		if p.excludeRe, err = regexp.Compile(p.config.Exclude); err != nil {
+			return fmt.Errorf("invalid exclude expression: %s", err)
+		}
+	}
+	if p.config.Watch && len(p.watchedDirectories()) == 0 {
+		return fmt.Errorf("watch mode requires local policy directory")
+	}
+	if p.config.QuietOnSuccess && p.config.Watch {
+		return fmt.Errorf("quiet on success mode can not be used with watch mode")
+	}
+	if p.config.Explain && p.config.ExplainPolicy == "" {
+		return fmt.Errorf("explain mode requires policy name, set with explain-policy")
+	}
+	p.config.Trend.Format = strings.ToLower(p.config.Trend.Format)
+	if p.config.Trend.Format != "" && p.config.Trend.Format != TrendFormatJSON && p.config.Trend.Format != TrendFormatCSV {
+		return fmt.Errorf("invalid trend format %q, must be %s or %s", p.config.Trend.Format, TrendFormatJSON, TrendFormatCSV)
+	}
+	p.config.TagMatch = strings.ToLower(p.config.TagMatch)
+	if p.config.TagMatch != "" && p.config.TagMatch != TagMatchAll && p.config.TagMatch != TagMatchAny {
+		return fmt.Errorf("invalid tag match %q, must be %s or %s", p.config.TagMatch, TagMatchAll, TagMatchAny)
+	}
+	p.config.GroupBy = strings.ToLower(p.config.GroupBy)
+	if p.config.GroupBy != "" && p.config.GroupBy != GroupByGroup && p.config.GroupBy != GroupBySeverity {
+		return fmt.Errorf("invalid group by %q, must be %s or %s", p.config.GroupBy, GroupByGroup, GroupBySeverity)
+	}
+	for i := range p.config.Tags {
+		p.config.Tags[i] = strings.ToLower(strings.TrimSpace(p.config.Tags[i]))
+	}
+	for i := range p.config.FailOnGroups {
+		p.config.FailOnGroups[i] = strings.TrimSpace(p.config.FailOnGroups[i])
+	}
+	for _, discovery := range p.config.Discovery {
+		if discovery.Project == "" {
+			return fmt.Errorf("project is required for cluster discovery")
+		}
+	}
+	for _, fleet := range p.config.Fleets {
+		if fleet.Project == "" {
+			return fmt.Errorf("project is required for fleet review")
+		}
+	}
+	for i, cluster := range p.config.Clusters {
+		if missing := missingClusterParameters(cluster); len(missing) > 0 {
+			return fmt.Errorf("cluster [%d]: missing %s (project, location and name are required to review a single cluster)",
+				i, strings.Join(missing, ", "))
+		}
+	}
+	if p.config.Query != "" {
+		if _, err := policy.ParseQuery(p.config.Query); err != nil {
+			return err
+		}
+	}
+	if p.config.ValidRule != "" && p.config.ViolationRule == "" {
+		return fmt.Errorf("valid rule requires violation rule")
+	}
+	stdinClusters := 0
+	for _, cluster := range p.config.Clusters {
+		if cluster.File == StdinInputFile {
+			stdinClusters++
+		}
+	}
+	if stdinClusters > 1 {
+		return fmt.Errorf("standard input can be used as input file for one cluster only")
+	}
+	p.config.Color = strings.ToLower(p.config.Color)
+	if p.config.Color != "" && p.config.Color != ColorAuto && p.config.Color != ColorAlways && p.config.Color != ColorNever {
+		return fmt.Errorf("invalid color %q, must be %s, %s or %s", p.config.Color, ColorAuto, ColorAlways, ColorNever)
+	}
+	p.config.Count = strings.ToLower(p.config.Count)
+	if p.config.Count != "" && p.config.Count != outputs.CountViolated && p.config.Count != outputs.CountErrored {
+		return fmt.Errorf("invalid count %q, must be %s or %s", p.config.Count, outputs.CountViolated, outputs.CountErrored)
+	}
+	stdoutOutputs := 0
+	for _, output := range p.config.Outputs {
+		if output.File == "" {
+			stdoutOutputs++
+		}
+	}
+	if stdoutOutputs > 1 {
+		return fmt.Errorf("only one output can be written to the standard output, set files of the others")
+	}
+	var stdout io.Writer = os.Stdout
+	p.quiet = nil
+	if p.config.QuietOnSuccess {
+		p.quiet = &quietWriter{w: os.Stdout}
+		stdout = p.quiet
+	}
+	p.resultWriters = make([]outputs.ResultWriter, 0)
+	stdoutWriters := 0
+	for _, output := range p.config.Outputs {
+		if p.config.Count != "" && output.File == "" {
+			continue
+		}
+		if output.File != "" && output.Format == "" {
+			if output.Format = outputFormatFromFile(output.File); output.Format == "" {
+				return fmt.Errorf("output format is not set and can not be derived from file %q", output.File)
+			}
+		}
+		newWriter := newResultWriter
+		if p.config.Summary {
+			newWriter = newSummaryResultWriter
+		}
+		writer, err := newWriter(output, stdout)
+		if err != nil {
+			return err
+		}
+		if output.File != "" {
+			if writer == nil {
+				return fmt.Errorf("%q output format can not be written to a file", OutputFormatText)
+			}
+			if err := checkOutputFileDirectory(output.File); err != nil {
+				return err
+			}
+			if _, ok := writer.(outputs.StreamResultWriter); ok {
+				writer = newFileStreamResultWriter(output, newWriter)
+			} else {
+				writer = newFileResultWriter(output, newWriter)
+			}
+		} else if writer != nil {
+			stdoutWriters++
+		}
+		if writer != nil {
+			p.resultWriters = append(p.resultWriters, writer)
+		}
+	}
+	if p.config.Count != "" {
+		p.resultWriters = append(p.resultWriters, outputs.NewCountResultWriter(stdout, p.config.Count))
+	} else if !p.config.SilentMode && stdoutWriters == 0 {
+		p.out = NewStdOutOutput(p.config.Color)
+		if p.quiet != nil {
+			p.out.w = p.quiet
+		}
+	}
+	if p.config.WaiversFile != "" {
+		if p.waivers, err = ReadWaivers(p.config.WaiversFile, os.ReadFile); err != nil {
+			return fmt.Errorf("could not read waivers: %s", err)
+		}
+		p.warnExpiredWaivers(time.Now())
+	}
+	if p.suppressions, err = newViolationSuppressions(p.config.Suppressions); err != nil {
+		return fmt.Errorf("invalid suppressions: %s", err)
+	}
+	if p.config.Baseline != "" {
+		if p.baseline, err = ReadBaseline(p.config.Baseline, os.ReadFile); err != nil {
+			return fmt.Errorf("could not read baseline: %s", err)
+		}
+	}
+	if p.config.ListPolicies || !p.needsGKEClient() {
+		return
+	}
+	if p.config.CredentialsFile != "" {
+		if err := checkCredentialsFile(p.config.CredentialsFile); err != nil {
+			return err
+		}
+	}
+	p.gke, err = gke.NewClientWithConfig(p.ctx, gke.ClientConfig{
+		CredentialsFile:           p.config.CredentialsFile,
+		ImpersonateServiceAccount: p.config.ImpersonateServiceAccount,
+		CACertFile:                p.config.CACertFile,
+	})
+	return
+}
+
+// checkCredentialsFile checks that the credentials file can be read, so a wrong path is reported
+// clearly instead of as a failure of GKE client creation.
+func checkCredentialsFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not read credentials file: %s", err)
+	}
+	return f.Close()
+}
+
+// needsGKEClient returns true if any of the configured clusters has to be fetched from GKE API.
+// Clusters read from files, including the standard input and Terraform plans, do not need the client.
+// Cluster discovery and fleet review always need it.
+func (p *PolicyAutomationApp) needsGKEClient() bool {
+	if len(p.config.Discovery) > 0 || len(p.config.Fleets) > 0 {
+		return true
+	}
+	for _, cluster := range p.config.Clusters {
+		if cluster.File == "" && cluster.Terraform == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PolicyAutomationApp) warnExpiredWaivers(now time.Time) {
+	for _, waiver := range p.waivers {
+		if waiver.IsExpired(now) {
+			p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]waiver for policy %s is expired and ignored\n", waiver)
+			log.Warnf("waiver for policy %s is expired and ignored", waiver)
+		}
+	}
+}
+
+func (p *PolicyAutomationApp) Close() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	if p.stopSignals != nil {
+		p.stopSignals()
+	}
+	if p.gke != nil {
+		return p.gke.Close()
+	}
+	return nil
+}
+
+func (p *PolicyAutomationApp) ClusterReview() (err error) {
+	if p.quiet != nil {
+		defer func() { p.endQuietReview(err) }()
+	}
+	if err := p.prepareClusters(); err != nil {
+		return err
+	}
+	return p.reviewClusters(p.streamClusterInputs)
+}
+
+// endQuietReview writes the held output of the review in the quiet on success mode, unless
+// the review succeeded without violated or errored policies.
+func (p *PolicyAutomationApp) endQuietReview(err error) {
+	if err == nil && !p.quiet.findings {
+		log.Debugf("review has no violated or errored policies, output is dropped")
+		p.quiet.Discard()
+		return
+	}
+	if flushErr := p.quiet.Flush(); flushErr != nil {
+		log.Errorf("could not write output: %s", flushErr)
+	}
+}
+
+// hasFindings returns true if any of the results has violated or errored policies, or a cluster error.
+func hasFindings(results []*policy.PolicyEvaluationResult) bool {
+	for _, result := range results {
+		if result.ClusterError != nil || result.ViolatedCount() > 0 || result.ErroredCount() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareClusters discovers clusters and checks that there is anything to review.
+func (p *PolicyAutomationApp) prepareClusters() error {
+	if err := p.discoverClusters(); err != nil {
+		err = p.timeoutError(err)
+		p.out.ErrorPrint("could not discover clusters", err)
+		log.Errorf("could not discover clusters: %s", err)
+		return err
+	}
+	if err := p.expandTerraformClusters(); err != nil {
+		p.out.ErrorPrint("could not read Terraform clusters", err)
+		log.Errorf("could not read Terraform clusters: %s", err)
+		return err
+	}
+	if len(p.config.Clusters) == 0 {
+		err := errors.New("no clusters configured")
+		p.out.ErrorPrint("could not review clusters", err)
+		return err
+	}
+	return nil
+}
+
+// reviewClusters compiles policies and evaluates them against cluster inputs. Inputs are
+// requested only once policies compile, so broken policies do not cost API calls. Each cluster
+// is evaluated as soon as its input arrives and its result is written to outputs that stream
+// results, other outputs are written once all clusters are reviewed.
+func (p *PolicyAutomationApp) reviewClusters(inputsFn func() (<-chan *clusterInput, int)) (err error) {
+	files, err := p.loadPolicyFiles()
+	if err != nil {
+		return err
+	}
+	pa, err := p.newPolicyAgent()
+	if err != nil {
+		p.out.ErrorPrint("could not set policy query", err)
+		return err
+	}
+	if err := p.loadDataFiles(pa); err != nil {
+		p.out.ErrorPrint("could not load data files", err)
+		log.Errorf("could not load data files: %s", err)
+		return err
+	}
+	p.out.ColorPrintf("[white][bold]Parsing REGO policies...\n")
+	log.Info("Parsing rego policies")
+	if err := p.withPolicyFiles(pa, files); err != nil {
+		return err
+	}
+	for _, warning := range pa.MetadataWarnings() {
+		p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]%s\n", warning)
+		log.Warnf("%s", warning)
+	}
+	p.overrideSeverities(pa)
+	if p.config.Manifest != "" {
+		p.out.ColorPrintf("[white][bold]Writing policy manifest... [%s]\n", p.config.Manifest)
+		log.Infof("Writing policy manifest to %s", p.config.Manifest)
+		if err := writePolicyManifest(p.config.Manifest, pa.Manifest(files)); err != nil {
+			p.out.ErrorPrint("could not write policy manifest", err)
+			log.Errorf("could not write policy manifest: %s", err)
+			return err
+		}
+	}
+	if p.config.Coverage {
+		pa.WithCoverage()
+	}
+	if p.includeRe != nil || p.excludeRe != nil {
+		if cnt := pa.FilterPolicies(p.includeRe, p.excludeRe); cnt == 0 {
+			err := errors.New("no policies match include and exclude expressions")
+			p.out.ErrorPrint("could not select policies", err)
+			log.Errorf("could not select policies: %s", err)
+			return err
+		}
+	}
+	if len(p.config.Tags) > 0 {
+		if cnt := pa.FilterPoliciesByTags(p.config.Tags, p.matchAllTags()); cnt == 0 {
+			err := fmt.Errorf("no policies match tags %s", strings.Join(p.config.Tags, ", "))
+			p.out.ErrorPrint("could not select policies", err)
+			log.Errorf("could not select policies: %s", err)
+			return err
+		}
+	}
+	if p.config.NoDeprecated {
+		if cnt := pa.FilterDeprecated(); cnt == 0 {
+			err := errors.New("all selected policies are deprecated")
+			p.out.ErrorPrint("could not select policies", err)
+			log.Errorf("could not select policies: %s", err)
+			return err
+		}
+	} else {
+		p.warnDeprecated(pa.DeprecatedPolicies())
+	}
+
+	clusters, total := inputsFn()
+	retain := p.retainsResults()
+	var results []*policy.PolicyEvaluationResult
+	if retain {
+		results = make([]*policy.PolicyEvaluationResult, total)
+	}
+	reviewed, failedClusters := 0, 0
+	findings := false
+	var reviewErr error
+	defer func() {
+		if closeErr := p.closeResultStreams(); closeErr != nil && err == nil {
+			p.out.ErrorPrint("could not write evaluation results", closeErr)
+			log.Errorf("could not write evaluation results: %s", closeErr)
+			err = closeErr
+		}
+	}()
+	// record streams the result of a reviewed cluster and keeps it for other outputs, if any
+	record := func(index int, evalResult *policy.PolicyEvaluationResult) error {
+		reviewed++
+		findings = findings || hasFindings([]*policy.PolicyEvaluationResult{evalResult})
+		if retain {
+			results[index] = evalResult
+		}
+		return p.streamResult(evalResult)
+	}
+	// recordClusterError records a cluster that could not be reviewed, so other clusters are still reviewed
+	recordClusterError := func(cluster *clusterInput, clusterErr error) error {
+		failedClusters++
+		evalResult := policy.NewPolicyEvaluationResult()
+		evalResult.ClusterName = cluster.name
+		evalResult.ClusterError = clusterErr
+		return record(cluster.index, evalResult)
+	}
+	interrupted := false
+	for cluster := range clusters {
+		if p.interrupted() {
+			interrupted = true
+			break
+		}
+		if cluster.err != nil {
+			if err := recordClusterError(cluster, cluster.err); err != nil {
+				return err
+			}
+			if p.config.FailFast {
+				break
+			}
+			continue
+		}
+		if p.config.DumpInput != "" {
+			p.dumpInput(cluster, total)
+		}
+		p.out.ColorPrintf("[white][bold]Evaluating policies against GKE cluster... [%s]\n",
+			cluster.name)
+		if p.config.FailFast {
+			pa.WithFailFast(p.failsClusterReview(cluster.name))
+		}
+		evalResult, err := pa.Evaluate(cluster.input)
+		if err != nil && p.interrupted() {
+			interrupted = true
+			break
+		}
+		if err != nil {
+			err = p.timeoutError(err)
+			p.out.ErrorPrint("failed to evalute policies", err)
+			log.Errorf("could not evaluate rego policies on cluster %s: %s", cluster.name, err)
+			if err := recordClusterError(cluster, err); err != nil {
+				return err
+			}
+			if p.config.FailFast {
+				break
+			}
+			continue
+		}
+		evalResult.ClusterName = cluster.name
+		evalResult.ClusterLabels = cluster.input.Labels()
+		evalResult = p.applyReviewSettings(evalResult)
+		if err := record(cluster.index, evalResult); err != nil {
+			return err
+		}
+		if clusterErr := p.reviewError([]*policy.PolicyEvaluationResult{evalResult}); clusterErr != nil {
+			reviewErr = firstReviewError(reviewErr, clusterErr)
+			if p.config.FailFast {
+				break
+			}
+		}
+	}
+	if p.config.FailFast && !interrupted && reviewed < total {
+		p.out.ColorPrintf("[bold][yellow]Review stopped on the first failure, %d of %d clusters are reviewed.\n",
+			reviewed, total)
+		log.Infof("review stopped on the first failure after %d of %d clusters", reviewed, total)
+	}
+	evalResults := make([]*policy.PolicyEvaluationResult, 0, reviewed)
+	for _, result := range results {
+		if result != nil {
+			evalResults = append(evalResults, result)
+		}
+	}
+	if p.quiet != nil {
+		p.quiet.findings = findings
+	}
+	p.printEvaluationResults(evalResults)
+	for _, writer := range p.resultWriters {
+		if _, ok := writer.(outputs.StreamResultWriter); ok {
+			continue
+		}
+		if err := writer.Write(evalResults); err != nil {
+			p.out.ErrorPrint("could not write evaluation results", err)
+			log.Errorf("could not write evaluation results: %s", err)
+			return err
+		}
+	}
+	if p.config.ExceptionsReport {
+		p.printExceptionsReport(evalResults)
+	}
+	if p.config.Timings {
+		p.printTimings(evalResults)
+	}
+	if p.config.Coverage {
+		p.printCoverage(evalResults)
+	}
+	if p.baseline != nil {
+		p.printBaselineDiffs(evalResults)
+	}
+	if interrupted {
+		p.out.ColorPrintf("\n[bold][yellow]Review was interrupted, results of %d of %d clusters are reported.\n",
+			reviewed, total)
+		log.Warnf("review was interrupted after %d of %d clusters", reviewed, total)
+		return ErrInterrupted
+	}
+	if p.config.WriteBaseline != "" {
+		if err := WriteBaseline(p.config.WriteBaseline, evalResults); err != nil {
+			p.out.ErrorPrint("could not write baseline", err)
+			log.Errorf("could not write baseline: %s", err)
+			return err
+		}
+	}
+	if failedClusters > 0 {
+		return fmt.Errorf("%w: could not review %d of %d clusters", ErrEvaluationErrors, failedClusters, reviewed)
+	}
+	return reviewErr
+}
+
+// retainsResults tells whether results of all clusters are needed once the review ends, by
+// outputs that are not streamed, the text report or other reports. Otherwise results are
+// dropped as soon as they are streamed, so memory does not grow with the number of clusters.
+func (p *PolicyAutomationApp) retainsResults() bool {
+	for _, writer := range p.resultWriters {
+		if _, ok := writer.(outputs.StreamResultWriter); !ok {
+			return true
+		}
+	}
+	return p.out.w != io.Discard || p.config.ExceptionsReport || p.config.Timings || p.config.Coverage ||
+		p.baseline != nil || p.config.WriteBaseline != ""
+}
+
+// firstReviewError keeps the error of the first cluster with errored policies, that takes
+// precedence over enforced violations of any cluster.
+func firstReviewError(current error, err error) error {
+	if current == nil || (errors.Is(err, ErrEvaluationErrors) && !errors.Is(current, ErrEvaluationErrors)) {
+		return err
+	}
+	return current
+}
+
+// failsClusterReview returns the fail fast function of a given cluster. A policy fails when its
+// result alone fails the review once review settings are applied, so waived, suppressed and
+// filtered violations and violations below fail on thresholds do not stop the review.
+func (p *PolicyAutomationApp) failsClusterReview(clusterName string) func(*policy.Policy) bool {
+	return func(evaluated *policy.Policy) bool {
+		copied := *evaluated
+		copied.Violations = append([]string(nil), evaluated.Violations...)
+		copied.ViolationDetails = append([]*policy.Violation(nil), evaluated.ViolationDetails...)
+		copied.ProcessingErrors = append([]error(nil), evaluated.ProcessingErrors...)
+		evalResult := policy.NewPolicyEvaluationResult()
+		evalResult.ClusterName = clusterName
+		evalResult.AddPolicy(&copied)
+		evalResult = p.applyReviewSettings(evalResult)
+		return p.reviewError([]*policy.PolicyEvaluationResult{evalResult}) != nil
+	}
+}
+
+// applyReviewSettings applies errors as violations, suppressions, waivers, the minimum severity
+// and the grouping from the configuration to the evaluation result of a cluster.
+func (p *PolicyAutomationApp) applyReviewSettings(evalResult *policy.PolicyEvaluationResult) *policy.PolicyEvaluationResult {
+	if p.config.ErrorsAsViolations {
+		evalResult.ErrorsAsViolations()
+	}
+	evalResult.ApplySuppressions(p.suppressions)
+	evalResult.ApplyWaivers(p.waivers, time.Now())
+	if p.config.MinSeverity != "" {
+		evalResult.FilterBySeverity(p.config.MinSeverity)
+	}
+	evalResult.TruncateViolations(p.maxViolations())
+	if p.config.GroupBy == GroupBySeverity {
+		evalResult = evalResult.GroupBySeverity()
+	}
+	return evalResult
+}
+
+// maxViolations returns the limit of reported violations of each policy, negative for no limit.
+// The limit of 0 stands for the default, as it is the value of maxViolations not set in the configuration file.
+func (p *PolicyAutomationApp) maxViolations() int {
+	if p.config.MaxViolations == 0 {
+		return DefaultMaxViolations
+	}
+	return p.config.MaxViolations
+}
+
+// streamResult writes the result of a reviewed cluster to outputs that stream results.
+func (p *PolicyAutomationApp) streamResult(result *policy.PolicyEvaluationResult) error {
+	for _, writer := range p.resultWriters {
+		stream, ok := writer.(outputs.StreamResultWriter)
+		if !ok {
+			continue
+		}
+		if err := stream.WriteResult(result); err != nil {
+			p.out.ErrorPrint("could not write evaluation results", err)
+			log.Errorf("could not write evaluation results: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// closeResultStreams closes outputs that stream results and have to be closed, i.e. files.
+func (p *PolicyAutomationApp) closeResultStreams() error {
+	var result error
+	for _, writer := range p.resultWriters {
+		if closer, ok := writer.(io.Closer); ok {
+			if err := closer.Close(); err != nil && result == nil {
+				result = err
+			}
+		}
+	}
+	return result
+}
+
+// reviewError returns error reflecting evaluation results. Processing errors take precedence
+// over violations, as results are incomplete. Only violations that fail the review are taken
+// into account, see failsReview. With a baseline, only new violations are taken into account.
+func (p *PolicyAutomationApp) reviewError(evalResults []*policy.PolicyEvaluationResult) error {
+	violated := 0
+	for _, evalResult := range evalResults {
+		if evalResult.ErroredCount() > 0 {
+			return fmt.Errorf("%w: %d policies errored on cluster %s", ErrEvaluationErrors, evalResult.ErroredCount(), evalResult.ClusterName)
+		}
+		if p.baseline != nil {
+			for _, newPolicy := range p.baseline.Diff(evalResult).New {
+				if p.failsReview(newPolicy) {
+					violated++
+				}
+			}
+			continue
+		}
+		for _, policies := range evalResult.Violated {
+			for _, violatedPolicy := range policies {
+				if p.failsReview(violatedPolicy) {
+					violated++
+				}
+			}
+		}
+	}
+	if violated > 0 {
+		return ErrEnforcedViolations
+	}
+	return nil
+}
+
+// failsReview returns true if violation of a given policy fails the review. When fail on severity
+// is set, the policy needs at least that severity. When fail on groups are set, the policy needs
+// to belong to one of them. With both set, both conditions have to be met.
+func (p *PolicyAutomationApp) failsReview(violated *policy.Policy) bool {
+	if p.config.FailOn != "" && policy.SeverityLevel(violated.Severity) < policy.SeverityLevel(p.config.FailOn) {
+		return false
+	}
+	if len(p.config.FailOnGroups) == 0 {
+		return true
+	}
+	for _, group := range p.config.FailOnGroups {
+		if strings.EqualFold(group, violated.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+type clusterInput struct {
+	// index is the position of the cluster in the configured clusters
+	index int
+	name  string
+	input gke.ClusterInput
+	err   error
+}
+
+// discoverClusters adds clusters found in the configured projects, and clusters registered in the
+// configured fleets, to the reviewed clusters. Clusters that are already configured are not added again.
+func (p *PolicyAutomationApp) discoverClusters() error {
+	if len(p.config.Discovery) == 0 && len(p.config.Fleets) == 0 {
+		return nil
+	}
+	known := make(map[string]bool)
+	for _, cluster := range p.config.Clusters {
+		if name, err := getClusterName(cluster); err == nil {
+			known[name] = true
+		}
+	}
+	addClusters := func(names []string) {
+		for _, name := range names {
+			if !known[name] {
+				known[name] = true
+				p.config.Clusters = append(p.config.Clusters, ConfigCluster{ID: name})
+			}
+		}
+	}
+	for _, discovery := range p.config.Discovery {
+		location := discovery.Location
+		if location == "" {
+			location = "all locations"
+		}
+		p.out.ColorPrintf("[white][bold]Discovering GKE clusters... [project: %s, location: %s]\n", discovery.Project, location)
+		names, err := p.gke.ListClusters(discovery.Project, discovery.Location)
+		if err != nil {
+			return fmt.Errorf("project %s: %w", discovery.Project, err)
+		}
+		log.Infof("Discovered %d clusters in project %s, location %s", len(names), discovery.Project, location)
+		addClusters(names)
+	}
+	for _, fleet := range p.config.Fleets {
+		p.out.ColorPrintf("[white][bold]Listing fleet memberships... [fleet project: %s]\n", fleet.Project)
+		names, err := p.gke.ListFleetClusters(fleet.Project)
+		if err != nil {
+			return fmt.Errorf("fleet project %s: %w", fleet.Project, err)
+		}
+		log.Infof("Found %d GKE clusters in fleet of project %s", len(names), fleet.Project)
+		addClusters(names)
+	}
+	return nil
+}
+
+// expandTerraformClusters replaces clusters configured with Terraform plan or state without
+// an address with the clusters defined in it, one per resource address.
+func (p *PolicyAutomationApp) expandTerraformClusters() error {
+	clusters := make([]ConfigCluster, 0, len(p.config.Clusters))
+	for _, cluster := range p.config.Clusters {
+		if cluster.Terraform == "" || cluster.Address != "" {
+			clusters = append(clusters, cluster)
+			continue
+		}
+		tfClusters, err := readTerraformFile(cluster.Terraform)
+		if err != nil {
+			return err
+		}
+		log.Infof("Found %d clusters in Terraform file %s", len(tfClusters), cluster.Terraform)
+		for _, tfCluster := range tfClusters {
+			clusters = append(clusters, ConfigCluster{Terraform: cluster.Terraform, Address: tfCluster.Address})
+		}
+	}
+	p.config.Clusters = clusters
+	return nil
+}
+
+// streamClusterInputs fetches evaluation inputs of all configured clusters and sends each of
+// them as soon as it is fetched, so clusters are reviewed while others are still fetched. Clusters
+// are fetched concurrently, with the number of concurrent fetches bounded. A failure for one
+// cluster does not stop the others, the error is sent as part of its input instead. The channel
+// has room for all clusters, so fetches complete even when the review stops early.
+func (p *PolicyAutomationApp) streamClusterInputs() (<-chan *clusterInput, int) {
+	concurrency := p.config.ClusterFetchConcurrency
+	if concurrency < 1 {
+		concurrency = DefaultClusterFetchConcurrency
+	}
+	clusters := p.config.Clusters
+	inputs := make(chan *clusterInput, len(clusters))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range clusters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			var input *clusterInput
+			if err := p.ctx.Err(); err != nil {
+				input = &clusterInput{name: clusterDisplayName(clusters[i]), err: err}
+			} else {
+				input = p.getClusterInput(clusters[i])
+			}
+			input.index = i
+			inputs <- input
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(inputs)
+	}()
+	return inputs, len(clusters)
+}
+
+// getClusterInputs returns evaluation inputs of all configured clusters in the configured order.
+func (p *PolicyAutomationApp) getClusterInputs() []*clusterInput {
+	stream, total := p.streamClusterInputs()
+	inputs := make([]*clusterInput, total)
+	for input := range stream {
+		inputs[input.index] = input
+	}
+	return inputs
+}
+
+// sendClusterInputs sends inputs fetched earlier on a channel, as inputs of a review.
+func sendClusterInputs(inputs []*clusterInput) (<-chan *clusterInput, int) {
+	stream := make(chan *clusterInput, len(inputs))
+	for i, input := range inputs {
+		input.index = i
+		stream <- input
+	}
+	close(stream)
+	return stream, len(inputs)
+}
+
+func (p *PolicyAutomationApp) getClusterInput(cluster ConfigCluster) *clusterInput {
+	if cluster.Terraform != "" {
+		input, err := p.getClusterInputFromTerraform(cluster.Terraform, cluster.Address)
+		return &clusterInput{name: terraformClusterName(cluster), input: input, err: err}
+	}
+	if cluster.File == StdinInputFile {
+		input, err := p.getClusterInputFromStdin()
+		return &clusterInput{name: "stdin", input: input, err: err}
+	}
+	if cluster.File != "" {
+		input, err := p.getClusterInputFromFile(cluster.File)
+		return &clusterInput{name: cluster.File, input: input, err: err}
+	}
+	clusterName, err := getClusterName(cluster)
+	if err != nil {
+		p.out.ErrorPrint("could not get cluster name", err)
+		log.Errorf("could not get cluster name: %s", err)
+		return &clusterInput{name: cluster.Name, err: err}
+	}
+	input, err := p.getClusterInputFromAPI(clusterName, cluster)
+	return &clusterInput{name: clusterName, input: input, err: err}
+}
+
+// dumpInput writes evaluation input of a cluster as JSON file. With more than one cluster,
+// the cluster number is added to the file name, i.e. input-1.json. Errors are only reported,
+// as the dump is a debugging aid that should not stop the review.
+func (p *PolicyAutomationApp) dumpInput(cluster *clusterInput, total int) {
+	path := p.config.DumpInput
+	if total > 1 {
+		ext := filepath.Ext(path)
+		path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), cluster.index+1, ext)
+	}
+	p.out.ColorPrintf("[white][bold]Writing evaluation input... [%s]\n", path)
+	log.Infof("Writing evaluation input of cluster %s to %s", cluster.name, path)
+	if err := writeClusterInput(path, cluster.input); err != nil {
+		p.out.ErrorPrint("could not write evaluation input", err)
+		log.Errorf("could not write evaluation input: %s", err)
+	}
+}
+
+func writeClusterInput(path string, input gke.ClusterInput) error {
+	data, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func writePolicyManifest(path string, manifest *policy.PolicyManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// loadDataFiles reads configured JSON data files and passes them to the policy agent
+// under the configured data path.
+func (p *PolicyAutomationApp) loadDataFiles(pa *policy.PolicyAgent) error {
+	if len(p.config.DataFiles) == 0 {
+		return nil
+	}
+	files := make([]*policy.DataFile, len(p.config.DataFiles))
+	for i, path := range p.config.DataFiles {
+		p.out.ColorPrintf("[white][bold]Reading data file... [%s]\n", path)
+		log.Infof("Reading data file %s", path)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[i] = &policy.DataFile{Name: path, Content: content}
+	}
+	dataPath := p.config.DataPath
+	if dataPath == "" {
+		dataPath = DefaultDataPath
+	}
+	return pa.WithDataFiles(files, dataPath)
+}
+
+// warnDeprecated prints a warning for each of the given deprecated policies.
+func (p *PolicyAutomationApp) warnDeprecated(policies []*policy.Policy) {
+	for _, pol := range policies {
+		msg := fmt.Sprintf("policy %s is deprecated", pol.Name)
+		if pol.DeprecatedMessage != "" {
+			msg += ": " + pol.DeprecatedMessage
+		}
+		p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]%s\n", msg)
+		log.Warnf("%s", msg)
+	}
+}
+
+// matchAllTags returns true unless policies are selected with any of the configured tags.
+func (p *PolicyAutomationApp) matchAllTags() bool {
+	return p.config.TagMatch != TagMatchAny
+}
+
+// withPolicyFiles compiles policy files with the agent. Loading no policies is an error, unless
+// empty policy sets are allowed, so a misconfigured policy source does not result in a clean review.
+func (p *PolicyAutomationApp) withPolicyFiles(pa *policy.PolicyAgent, files []*policy.PolicyFile) error {
+	err := pa.WithFiles(files)
+	if errors.Is(err, policy.ErrNoPolicies) {
+		err = fmt.Errorf("%w from %s", err, strings.Join(p.policySources, ", "))
+	}
+	if err != nil {
+		p.out.ErrorPrint("could not parse policy files", err)
+		log.Errorf("could not parse policy files: %s", err)
+		return err
+	}
+	return nil
+}
+
+// overrideSeverities applies severities from the configuration to policies loaded with WithFiles,
+// warning about overrides of policies that are not loaded.
+func (p *PolicyAutomationApp) overrideSeverities(pa *policy.PolicyAgent) {
+	if len(p.config.SeverityOverrides) == 0 {
+		return
+	}
+	for _, name := range pa.OverrideSeverities(p.config.SeverityOverrides) {
+		p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]severity override of unknown policy %s is ignored\n", name)
+		log.Warnf("severity override of unknown policy %s is ignored", name)
+	}
+}
+
+// newPolicyAgent creates policy agent with the configured query and rule names.
+func (p *PolicyAutomationApp) newPolicyAgent() (*policy.PolicyAgent, error) {
+	pa := policy.NewPolicyAgent(p.ctx)
+	if p.config.StrictMetadata {
+		pa.WithStrictMetadata()
+	}
+	if p.config.StrictCompile {
+		pa.WithStrictCompile()
+	}
+	if p.config.AllowEmpty {
+		pa.WithAllowEmpty()
+	}
+	if p.config.Query != "" {
+		if err := pa.WithQuery(p.config.Query); err != nil {
+			return nil, err
+		}
+	}
+	if p.config.ViolationRule != "" {
+		ruleNames := policy.RuleNames{Valid: p.config.ValidRule, Violation: p.config.ViolationRule}
+		if err := pa.WithRuleNames(ruleNames); err != nil {
+			return nil, err
+		}
 	}
+	return pa, nil
 }
 
-func (p *PolicyAutomationApp) LoadCliConfig(cliConfig *CliConfig) error {
-	var config *ConfigNg
-	var err error
-	if cliConfig.ConfigFile != "" {
-		if config, err = newConfigFromFile(cliConfig.ConfigFile); err != nil {
-			return err
+// PolicyCheck compiles policies and validates their metadata, reporting every problem found.
+func (p *PolicyAutomationApp) PolicyCheck() error {
+	files, err := p.loadPolicyFiles()
+	if err != nil {
+		return err
+	}
+	pa, err := p.newPolicyAgent()
+	if err != nil {
+		p.out.ErrorPrint("could not set policy query", err)
+		return err
+	}
+	p.out.ColorPrintf("[white][bold]Parsing REGO policies...\n")
+	log.Info("Parsing rego policies")
+	if err := pa.Compile(files); err != nil {
+		p.out.ColorPrintf("[bold][red][x] Compilation failed:\n")
+		for _, diagnostic := range policy.CompileDiagnostics(err) {
+			p.out.ColorPrintf("[bold][red][x] [reset][red]%s\n", diagnostic)
+			log.Errorf("could not compile policy files: %s", diagnostic)
 		}
-	} else {
-		config = newConfigFromCli(cliConfig)
+		return ErrInvalidPolicies
 	}
-	return p.LoadConfig(config)
-}
-
-func (p *PolicyAutomationApp) LoadConfig(config *ConfigNg) (err error) {
-	p.config = config
-	if !p.config.SilentMode {
-		p.out = NewStdOutOutput()
+	policies, errs := pa.ParseCompiled()
+	for _, err := range errs {
+		p.out.ColorPrintf("[bold][red][x] [reset][red]%s\n", err)
+		log.Errorf("policy validation error: %s", err)
 	}
-	if p.config.CredentialsFile != "" {
-		p.gke, err = gke.NewClientWithCredentialsFile(p.ctx, p.config.CredentialsFile)
-	} else {
-		p.gke, err = gke.NewClient(p.ctx)
+	p.out.ColorPrintf("\n[bold][green]Policies: %d valid, %d invalid.\n", len(policies), len(errs))
+	if len(errs) > 0 {
+		return ErrInvalidPolicies
 	}
-	return
-}
-
-func (p *PolicyAutomationApp) Close() error {
-	if p.gke != nil {
-		return p.gke.Close()
+	if len(policies) == 0 && !p.config.AllowEmpty {
+		err := fmt.Errorf("%w from %s", policy.ErrNoPolicies, strings.Join(p.policySources, ", "))
+		p.out.ErrorPrint("could not validate policies", err)
+		log.Errorf("could not validate policies: %s", err)
+		return ErrInvalidPolicies
 	}
 	return nil
 }
 
-func (p *PolicyAutomationApp) ClusterReview() error {
+// PolicyList prints policies selected with include and exclude expressions, without evaluating them.
+// Policies with metadata errors are counted, as they would fail the review.
+func (p *PolicyAutomationApp) PolicyList() error {
 	files, err := p.loadPolicyFiles()
 	if err != nil {
 		return err
 	}
-	pa := policy.NewPolicyAgent(p.ctx)
+	pa, err := p.newPolicyAgent()
+	if err != nil {
+		p.out.ErrorPrint("could not set policy query", err)
+		return err
+	}
 	p.out.ColorPrintf("[white][bold]Parsing REGO policies...\n")
 	log.Info("Parsing rego policies")
-	if err := pa.WithFiles(files); err != nil {
-		p.out.ErrorPrint("could not parse policy files", err)
-		log.Errorf("could not parse policy files: %s", err)
-		return err
+	if err := pa.Compile(files); err != nil {
+		p.out.ErrorPrint("could not compile policy files", err)
+		log.Errorf("could not compile policy files: %s", err)
+		return ErrInvalidPolicies
+	}
+	policies, errs := pa.ParseCompiled()
+	selected := make([]*policy.Policy, 0, len(policies))
+	for _, parsed := range policies {
+		if policy.MatchesNameFilters(parsed.Name, p.includeRe, p.excludeRe) &&
+			policy.MatchesTags(parsed.Tags, p.config.Tags, p.matchAllTags()) {
+			selected = append(selected, parsed)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Name < selected[j].Name
+	})
+	p.out.Printf("\n")
+	w := tabwriter.NewWriter(p.out.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tTITLE\tGROUP\tSEVERITY\tTAGS\n")
+	for _, listed := range selected {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", listed.Name, listed.Title, listed.Group, listed.Severity,
+			strings.Join(listed.Tags, ","))
 	}
+	w.Flush()
+	p.out.ColorPrintf("\n[bold][green]Policies: %d listed, %d with metadata errors.\n", len(selected), len(errs))
+	return nil
+}
 
-	evalResults := make([]*policy.PolicyEvaluationResult, 0)
-	for _, cluster := range p.config.Clusters {
-		clusterName, err := getClusterName(cluster)
-		if err != nil {
-			p.out.ErrorPrint("could not get cluster name", err)
-			log.Errorf("could not get cluster name: %s", clusterName)
-			return err
+func (p *PolicyAutomationApp) getClusterInputFromAPI(clusterName string, cluster ConfigCluster) (gke.ClusterInput, error) {
+	p.out.ColorPrintf("[white][bold]Fetching GKE cluster details... [%s]\n", clusterName)
+	gkeCluster, err := p.gke.GetCluster(clusterName)
+	if err != nil {
+		err = p.timeoutError(err)
+		p.out.ErrorPrint("could not fetch the cluster details", err)
+		log.Errorf("could not fetch cluster details: %s", err)
+		return nil, err
+	}
+	input, err := gke.NewClusterInput(gkeCluster)
+	if err != nil {
+		p.out.ErrorPrint("could not prepare evaluation input", err)
+		log.Errorf("could not prepare evaluation input: %s", err)
+		return nil, err
+	}
+	if !input.IsAutopilot() {
+		if err := p.setNodePools(clusterName, input); err != nil {
+			return nil, err
 		}
-		p.out.ColorPrintf("[white][bold]Fetching GKE cluster details... [projects/%s/locations/%s/clusters/%s]\n",
-			cluster.Project,
-			cluster.Location,
-			cluster.Name)
-		cluster, err := p.gke.GetCluster(clusterName)
+	}
+	if p.config.IncludeVersions {
+		p.out.ColorPrintf("[white][bold]Fetching GKE versions... [%s]\n", gkeCluster.Location)
+		versions, err := p.gke.GetClusterVersions(clusterName, gkeCluster)
 		if err != nil {
-			p.out.ErrorPrint("could not fetch the cluster details", err)
-			log.Errorf("could not fetch cluster details: %s", err)
-			return err
+			err = p.timeoutError(err)
+			p.out.ErrorPrint("could not fetch GKE versions", err)
+			log.Errorf("could not fetch GKE versions: %s", err)
+			return nil, err
 		}
-		p.out.ColorPrintf("[white][bold]Evaluating policies against GKE cluster... [%s]\n",
-			cluster.Id)
-		evalResult, err := pa.Evaluate(cluster)
-		if err != nil {
-			p.out.ErrorPrint("failed to evalute policies", err)
-			log.Errorf("could not evaluate rego policies on cluster %s: %s", cluster.Id, err)
-			return err
+		input.SetVersions(versions)
+	}
+	if p.config.IncludeIAM {
+		if err := p.setIAMPolicy(clusterName, cluster, input); err != nil {
+			return nil, err
 		}
-		evalResult.ClusterName = clusterName
-		evalResults = append(evalResults, evalResult)
 	}
-	p.printEvaluationResults(evalResults)
+	if p.config.IncludeSecurityPosture {
+		if err := p.setSecurityPosture(clusterName, input); err != nil {
+			return nil, err
+		}
+	}
+	return input, nil
+}
+
+// setSecurityPosture fetches security posture findings of the cluster and sets them in the input.
+// When the Container Security API is not enabled or not permitted, it is reported as a warning
+// and results in no findings.
+func (p *PolicyAutomationApp) setSecurityPosture(clusterName string, input gke.ClusterInput) error {
+	p.out.ColorPrintf("[white][bold]Fetching security posture findings... [%s]\n", clusterName)
+	posture, err := p.gke.GetSecurityPosture(clusterName)
+	if gke.IsSecurityPostureUnavailable(err) {
+		p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]security posture findings of cluster %s are not available, they are not evaluated\n", clusterName)
+		log.Warnf("could not get security posture findings of cluster %s: %s", clusterName, err)
+		input.SetSecurityPosture(&gke.SecurityPosture{Findings: []*gke.SecurityPostureFinding{}})
+		return nil
+	}
+	if err != nil {
+		err = p.timeoutError(err)
+		p.out.ErrorPrint("could not fetch security posture findings", err)
+		log.Errorf("could not fetch security posture findings: %s", err)
+		return err
+	}
+	input.SetSecurityPosture(posture)
+	return nil
+}
+
+// setIAMPolicy fetches IAM policy of the cluster project and sets it in the input.
+// Lack of permissions is reported as a warning and results in an empty policy.
+func (p *PolicyAutomationApp) setIAMPolicy(clusterName string, cluster ConfigCluster, input gke.ClusterInput) error {
+	p.out.ColorPrintf("[white][bold]Fetching project IAM policy... [%s]\n", cluster.Project)
+	policy, err := p.gke.GetProjectIAMPolicy(clusterName)
+	if gke.IsPermissionDenied(err) {
+		p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]no permission to get IAM policy of project %s, IAM bindings are not evaluated\n", cluster.Project)
+		log.Warnf("no permission to get IAM policy of project %s: %s", cluster.Project, err)
+		input.SetIAMPolicy(&gke.IAMPolicy{Bindings: []*gke.IAMBinding{}})
+		return nil
+	}
+	if err != nil {
+		err = p.timeoutError(err)
+		p.out.ErrorPrint("could not fetch project IAM policy", err)
+		log.Errorf("could not fetch project IAM policy: %s", err)
+		return err
+	}
+	input.SetIAMPolicy(policy)
+	return nil
+}
+
+// setNodePools lists node pools of the cluster and sets them in the input.
+func (p *PolicyAutomationApp) setNodePools(clusterName string, input gke.ClusterInput) error {
+	nodePools, err := p.gke.ListNodePools(clusterName)
+	if err != nil {
+		err = p.timeoutError(err)
+		p.out.ErrorPrint("could not fetch node pools", err)
+		log.Errorf("could not fetch node pools: %s", err)
+		return err
+	}
+	if err := input.SetNodePools(nodePools); err != nil {
+		p.out.ErrorPrint("could not prepare evaluation input", err)
+		log.Errorf("could not prepare evaluation input: %s", err)
+		return err
+	}
 	return nil
 }
 
+// timeoutError replaces a given error with a timeout error when the configured
+// timeout is exceeded, or with ErrInterrupted when the review was interrupted, as errors
+// caused by the context are not always clear.
+func (p *PolicyAutomationApp) timeoutError(err error) error {
+	if p.timeout > 0 && errors.Is(p.ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("cluster review timed out after %s", p.timeout)
+	}
+	if p.interrupted() {
+		return ErrInterrupted
+	}
+	return err
+}
+
+// interrupted tells whether the app context was cancelled, i.e. on SIGINT.
+func (p *PolicyAutomationApp) interrupted() bool {
+	return errors.Is(p.ctx.Err(), context.Canceled)
+}
+
+func (p *PolicyAutomationApp) getClusterInputFromFile(path string) (gke.ClusterInput, error) {
+	p.out.ColorPrintf("[white][bold]Reading GKE cluster details from file... [%s]\n", path)
+	log.Infof("Reading cluster details from file %s", path)
+	input, err := readClusterInputFile(path, os.ReadFile)
+	if err != nil {
+		p.out.ErrorPrint("could not read the cluster details", err)
+		log.Errorf("could not read cluster details from file %s: %s", path, err)
+		return nil, err
+	}
+	if p.config.IncludeVersions {
+		log.Warnf("GKE versions are not fetched for cluster read from file %s", path)
+	}
+	if p.config.IncludeIAM {
+		log.Warnf("project IAM policy is not fetched for cluster read from file %s", path)
+	}
+	if p.config.IncludeSecurityPosture {
+		log.Warnf("security posture findings are not fetched for cluster read from file %s", path)
+	}
+	return input, nil
+}
+
+func (p *PolicyAutomationApp) getClusterInputFromTerraform(path string, address string) (gke.ClusterInput, error) {
+	p.out.ColorPrintf("[white][bold]Reading GKE cluster details from Terraform file... [%s: %s]\n", path, address)
+	log.Infof("Reading cluster %s details from Terraform file %s", address, path)
+	input, err := readTerraformCluster(path, address)
+	if err != nil {
+		p.out.ErrorPrint("could not read the cluster details", err)
+		log.Errorf("could not read cluster details from Terraform file %s: %s", path, err)
+		return nil, err
+	}
+	if p.config.IncludeVersions {
+		log.Warnf("GKE versions are not fetched for cluster read from Terraform file %s", path)
+	}
+	if p.config.IncludeIAM {
+		log.Warnf("project IAM policy is not fetched for cluster read from Terraform file %s", path)
+	}
+	if p.config.IncludeSecurityPosture {
+		log.Warnf("security posture findings are not fetched for cluster read from Terraform file %s", path)
+	}
+	return input, nil
+}
+
+func (p *PolicyAutomationApp) getClusterInputFromStdin() (gke.ClusterInput, error) {
+	p.out.ColorPrintf("[white][bold]Reading GKE cluster details from standard input...\n")
+	log.Info("Reading cluster details from standard input")
+	stdin := p.stdin
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		p.out.ErrorPrint("could not read the cluster details", err)
+		log.Errorf("could not read cluster details from standard input: %s", err)
+		return nil, err
+	}
+	input, err := gke.NewClusterInputFromJSON(data)
+	if err != nil {
+		err = fmt.Errorf("standard input: %s", err)
+		p.out.ErrorPrint("could not read the cluster details", err)
+		log.Errorf("could not read cluster details: %s", err)
+		return nil, err
+	}
+	if p.config.IncludeVersions {
+		log.Warnf("GKE versions are not fetched for cluster read from standard input")
+	}
+	if p.config.IncludeIAM {
+		log.Warnf("project IAM policy is not fetched for cluster read from standard input")
+	}
+	if p.config.IncludeSecurityPosture {
+		log.Warnf("security posture findings are not fetched for cluster read from standard input")
+	}
+	return input, nil
+}
+
+func readClusterInputFile(path string, readFn ReadFileFn) (gke.ClusterInput, error) {
+	data, err := readFn(path)
+	if err != nil {
+		return nil, err
+	}
+	input, err := gke.NewClusterInputFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("file %s: %s", path, err)
+	}
+	return input, nil
+}
+
+func readTerraformFile(path string) ([]*gke.TerraformCluster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := gke.NewClusterInputsFromTerraformJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("file %s: %s", path, err)
+	}
+	return clusters, nil
+}
+
+func readTerraformCluster(path string, address string) (gke.ClusterInput, error) {
+	clusters, err := readTerraformFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range clusters {
+		if cluster.Address == address {
+			return cluster.Input, nil
+		}
+	}
+	return nil, fmt.Errorf("file %s: cluster %s not found", path, address)
+}
+
+// terraformClusterName returns name of the cluster defined in Terraform plan or state.
+func terraformClusterName(c ConfigCluster) string {
+	if c.Address == "" {
+		return c.Terraform
+	}
+	return c.Terraform + ":" + c.Address
+}
+
+// loadPolicyFiles reads policy files of all configured sources and merges them, so that
+// a later source overrides packages of the earlier ones.
 func (p *PolicyAutomationApp) loadPolicyFiles() ([]*policy.PolicyFile, error) {
-	policyFiles := make([]*policy.PolicyFile, 0)
-	for _, policyConfig := range p.config.Policies {
+	policies := p.config.Policies
+	if len(policies) == 0 {
+		if p.config.NoDefaultPolicies {
+			err := errors.New("no policy source given and default policies are disabled")
+			p.out.ErrorPrint("could not read policy files", err)
+			log.Errorf("could not read policy files: %s", err)
+			return nil, err
+		}
+		policies = []ConfigPolicy{{Embedded: true}}
+	}
+	sources := make([][]*policy.PolicyFile, 0, len(policies))
+	p.policySources = make([]string, 0, len(policies))
+	for _, policyConfig := range policies {
 		var policySrc policy.PolicySource
+		var embeddedSrc *policy.EmbeddedPolicySource
+		if policyConfig.Embedded {
+			embeddedSrc = policy.NewEmbeddedPolicySource(gkepolicies.FS)
+			policySrc = embeddedSrc
+		}
 		if policyConfig.LocalDirectory != "" {
 			policySrc = policy.NewLocalPolicySource(policyConfig.LocalDirectory)
 		}
@@ -137,6 +1461,14 @@ func (p *PolicyAutomationApp) loadPolicyFiles() ([]*policy.PolicyFile, error) {
 				policyConfig.GitBranch,
 				policyConfig.GitDirectory)
 		}
+		var bundleSrc *policy.BundlePolicySource
+		if policyConfig.Bundle != "" {
+			bundleSrc = policy.NewBundlePolicySource(p.ctx, policyConfig.Bundle)
+			policySrc = bundleSrc
+		}
+		if policyConfig.URL != "" {
+			policySrc = policy.NewURLPolicySource(p.ctx, policyConfig.URL)
+		}
 		p.out.ColorPrintf("[white][bold]Reading policy files... [%s]\n", policySrc)
 		log.Infof("Reading policy files from %s", policySrc)
 		files, err := policySrc.GetPolicyFiles()
@@ -145,7 +1477,22 @@ func (p *PolicyAutomationApp) loadPolicyFiles() ([]*policy.PolicyFile, error) {
 			log.Errorf("could not read policy files: %s", err)
 			return nil, err
 		}
-		policyFiles = append(policyFiles, files...)
+		if bundleSrc != nil && bundleSrc.Revision() != "" {
+			p.out.ColorPrintf("[white][bold]Policy bundle revision: [reset][white]%s\n", bundleSrc.Revision())
+			log.Infof("Policy bundle %s revision: %s", policyConfig.Bundle, bundleSrc.Revision())
+		}
+		if embeddedSrc != nil {
+			p.out.ColorPrintf("[white][bold]Embedded policies version: [reset][white]%s\n", embeddedSrc.Version())
+			log.Infof("Embedded policies version: %s", embeddedSrc.Version())
+		}
+		sources = append(sources, files)
+		p.policySources = append(p.policySources, policySrc.String())
+	}
+	policyFiles, err := policy.MergePolicyFiles(sources)
+	if err != nil {
+		p.out.ErrorPrint("could not merge policy files", err)
+		log.Errorf("could not merge policy files: %s", err)
+		return nil, err
 	}
 	return policyFiles, nil
 }
@@ -154,19 +1501,125 @@ func newConfigFromFile(path string) (*ConfigNg, error) {
 	return ReadConfig(path, os.ReadFile)
 }
 
+// newOutputsFromCli creates outputs from the output flag, a comma separated list of formats, each
+// optionally followed by a colon and a file path, i.e. text,sarif:report.sarif. The output file flag
+// sets the file of a single format given without one. Without formats, the output file is written
+// in the format derived from its extension.
+func newOutputsFromCli(formats string, file string) []ConfigOutput {
+	if strings.TrimSpace(formats) == "" && file != "" {
+		return []ConfigOutput{{File: file}}
+	}
+	var configOutputs []ConfigOutput
+	for _, format := range strings.Split(formats, ",") {
+		format = strings.TrimSpace(format)
+		if format == "" {
+			continue
+		}
+		output := ConfigOutput{Format: format}
+		if i := strings.Index(format, ":"); i >= 0 {
+			output.Format, output.File = format[:i], format[i+1:]
+		}
+		configOutputs = append(configOutputs, output)
+	}
+	if len(configOutputs) == 1 && configOutputs[0].File == "" {
+		configOutputs[0].File = file
+	}
+	return configOutputs
+}
+
 func newConfigFromCli(cliConfig *CliConfig) *ConfigNg {
 	config := &ConfigNg{}
 	config.SilentMode = cliConfig.SilentMode
+	config.LogLevel = cliConfig.LogLevel
 	config.CredentialsFile = cliConfig.CredentialsFile
-	config.Clusters = []ConfigCluster{
-		{
-			Name:     cliConfig.ClusterName,
-			Location: cliConfig.ClusterLocation,
-			Project:  cliConfig.ProjectName,
-		},
+	config.ImpersonateServiceAccount = cliConfig.ImpersonateSA
+	config.CACertFile = cliConfig.CACertFile
+	config.IncludeVersions = cliConfig.IncludeVersions
+	config.IncludeIAM = cliConfig.IncludeIAM
+	config.IncludeSecurityPosture = cliConfig.IncludeSecurityPosture
+	config.ExceptionsReport = cliConfig.ExceptionsReport
+	config.ListPolicies = cliConfig.ListPolicies
+	config.Timings = cliConfig.Timings
+	config.Coverage = cliConfig.Coverage
+	config.DumpInput = cliConfig.DumpInput
+	config.Manifest = cliConfig.Manifest
+	config.NoDefaultPolicies = cliConfig.NoDefaultPolicies
+	config.StrictMetadata = cliConfig.StrictMetadata
+	config.StrictCompile = cliConfig.StrictCompile
+	config.NoDeprecated = cliConfig.NoDeprecated
+	config.FailFast = cliConfig.FailFast
+	config.ShowPrints = cliConfig.ShowPrints
+	config.Explain = cliConfig.Explain
+	config.ExplainPolicy = cliConfig.ExplainPolicy
+	config.Trend = ConfigTrend{
+		Directory: cliConfig.TrendDirectory,
+		Format:    cliConfig.TrendFormat,
+		File:      cliConfig.TrendFile,
+	}
+	config.Color = cliConfig.Color
+	config.Summary = cliConfig.Summary
+	if cliConfig.Count {
+		config.Count = outputs.CountViolated
 	}
-	if cliConfig.LocalDirectory != "" {
-		config.Policies = append(config.Policies, ConfigPolicy{LocalDirectory: cliConfig.LocalDirectory})
+	config.Watch = cliConfig.Watch
+	config.QuietOnSuccess = cliConfig.QuietOnSuccess
+	config.ErrorsAsViolations = cliConfig.ErrorsAsViolations
+	config.AllowEmpty = cliConfig.AllowEmpty
+	config.Listen = cliConfig.Listen
+	config.MaxViolations = cliConfig.MaxViolations
+	config.MinSeverity = cliConfig.MinSeverity
+	config.FailOn = cliConfig.FailOn
+	config.FailOnGroups = cliConfig.FailOnGroups
+	config.WaiversFile = cliConfig.WaiversFile
+	config.Baseline = cliConfig.Baseline
+	config.WriteBaseline = cliConfig.WriteBaseline
+	config.Include = cliConfig.Include
+	config.Exclude = cliConfig.Exclude
+	config.Tags = cliConfig.Tags
+	config.TagMatch = cliConfig.TagMatch
+	config.GroupBy = cliConfig.GroupBy
+	config.Query = cliConfig.Query
+	config.ViolationRule = cliConfig.ViolationRule
+	config.ValidRule = cliConfig.ValidRule
+	config.DataFiles = cliConfig.DataFiles
+	config.DataPath = cliConfig.DataPath
+	if cliConfig.Timeout > 0 {
+		config.Timeout = cliConfig.Timeout.String()
+	}
+	if cliConfig.InputFile != "" {
+		config.Clusters = []ConfigCluster{{File: cliConfig.InputFile}}
+	} else if cliConfig.TerraformFile != "" {
+		config.Clusters = []ConfigCluster{{Terraform: cliConfig.TerraformFile}}
+	} else if cliConfig.AllClusters {
+		config.Discovery = []ConfigDiscovery{
+			{
+				Project:  cliConfig.ProjectName,
+				Location: cliConfig.ClusterLocation,
+			},
+		}
+	} else if cliConfig.FleetProject != "" {
+		config.Fleets = []ConfigFleet{{Project: cliConfig.FleetProject}}
+	} else if cliConfig.ClusterName != "" || cliConfig.ClusterLocation != "" || cliConfig.ProjectName != "" {
+		config.Clusters = []ConfigCluster{
+			{
+				Name:     cliConfig.ClusterName,
+				Location: cliConfig.ClusterLocation,
+				Project:  cliConfig.ProjectName,
+			},
+		}
+	}
+	for _, id := range cliConfig.ClusterIDs {
+		config.Clusters = append(config.Clusters, ConfigCluster{ID: id})
+	}
+	config.Outputs = newOutputsFromCli(cliConfig.OutputFormat, cliConfig.OutputFile)
+	for _, directory := range cliConfig.LocalDirectories {
+		config.Policies = append(config.Policies, ConfigPolicy{LocalDirectory: directory})
+	}
+	if cliConfig.Bundle != "" {
+		config.Policies = append(config.Policies, ConfigPolicy{Bundle: cliConfig.Bundle})
+	}
+	if cliConfig.PolicyURL != "" {
+		config.Policies = append(config.Policies, ConfigPolicy{URL: cliConfig.PolicyURL})
 	}
 	if cliConfig.GitRepository != "" {
 		config.Policies = append(config.Policies, ConfigPolicy{
@@ -178,6 +1631,163 @@ func newConfigFromCli(cliConfig *CliConfig) *ConfigNg {
 	return config
 }
 
+// cliOverrides copy settings of explicitly set flags over the configuration file settings.
+// Flags selecting clusters, outputs or policy sources replace whole lists of the file. GIT branch
+// and directory flags without the GIT repository flag are applied with overrideGitPolicies.
+var cliOverrides = []struct {
+	flags []string
+	apply func(config, cliConfig *ConfigNg)
+}{
+	{[]string{"log-level"}, func(c, cli *ConfigNg) { c.LogLevel = cli.LogLevel }},
+	{[]string{"creds"}, func(c, cli *ConfigNg) { c.CredentialsFile = cli.CredentialsFile }},
+	{[]string{"impersonate-service-account"}, func(c, cli *ConfigNg) { c.ImpersonateServiceAccount = cli.ImpersonateServiceAccount }},
+	{[]string{"ca-cert"}, func(c, cli *ConfigNg) { c.CACertFile = cli.CACertFile }},
+	{[]string{"include-versions"}, func(c, cli *ConfigNg) { c.IncludeVersions = cli.IncludeVersions }},
+	{[]string{"include-iam"}, func(c, cli *ConfigNg) { c.IncludeIAM = cli.IncludeIAM }},
+	{[]string{"include-security-posture"}, func(c, cli *ConfigNg) { c.IncludeSecurityPosture = cli.IncludeSecurityPosture }},
+	{[]string{"exceptions"}, func(c, cli *ConfigNg) { c.ExceptionsReport = cli.ExceptionsReport }},
+	{[]string{"list-policies"}, func(c, cli *ConfigNg) { c.ListPolicies = cli.ListPolicies }},
+	{[]string{"timings"}, func(c, cli *ConfigNg) { c.Timings = cli.Timings }},
+	{[]string{"coverage"}, func(c, cli *ConfigNg) { c.Coverage = cli.Coverage }},
+	{[]string{"dump-input"}, func(c, cli *ConfigNg) { c.DumpInput = cli.DumpInput }},
+	{[]string{"manifest"}, func(c, cli *ConfigNg) { c.Manifest = cli.Manifest }},
+	{[]string{"no-default-policies"}, func(c, cli *ConfigNg) { c.NoDefaultPolicies = cli.NoDefaultPolicies }},
+	{[]string{"strict-metadata"}, func(c, cli *ConfigNg) { c.StrictMetadata = cli.StrictMetadata }},
+	{[]string{"strict"}, func(c, cli *ConfigNg) { c.StrictCompile = cli.StrictCompile }},
+	{[]string{"no-deprecated"}, func(c, cli *ConfigNg) { c.NoDeprecated = cli.NoDeprecated }},
+	{[]string{"fail-fast"}, func(c, cli *ConfigNg) { c.FailFast = cli.FailFast }},
+	{[]string{"show-prints"}, func(c, cli *ConfigNg) { c.ShowPrints = cli.ShowPrints }},
+	{[]string{"explain"}, func(c, cli *ConfigNg) { c.Explain = cli.Explain }},
+	{[]string{"explain-policy"}, func(c, cli *ConfigNg) { c.ExplainPolicy = cli.ExplainPolicy }},
+	{[]string{"snapshot-dir"}, func(c, cli *ConfigNg) { c.Trend.Directory = cli.Trend.Directory }},
+	{[]string{"trend-format"}, func(c, cli *ConfigNg) { c.Trend.Format = cli.Trend.Format }},
+	{[]string{"trend-file"}, func(c, cli *ConfigNg) { c.Trend.File = cli.Trend.File }},
+	{[]string{"color"}, func(c, cli *ConfigNg) { c.Color = cli.Color }},
+	{[]string{"summary"}, func(c, cli *ConfigNg) { c.Summary = cli.Summary }},
+	{[]string{"count"}, func(c, cli *ConfigNg) { c.Count = cli.Count }},
+	{[]string{"watch"}, func(c, cli *ConfigNg) { c.Watch = cli.Watch }},
+	{[]string{"quiet-on-success"}, func(c, cli *ConfigNg) { c.QuietOnSuccess = cli.QuietOnSuccess }},
+	{[]string{"errors-as-violations"}, func(c, cli *ConfigNg) { c.ErrorsAsViolations = cli.ErrorsAsViolations }},
+	{[]string{"allow-empty"}, func(c, cli *ConfigNg) { c.AllowEmpty = cli.AllowEmpty }},
+	{[]string{"listen"}, func(c, cli *ConfigNg) { c.Listen = cli.Listen }},
+	{[]string{"max-violations"}, func(c, cli *ConfigNg) { c.MaxViolations = cli.MaxViolations }},
+	{[]string{"min-severity"}, func(c, cli *ConfigNg) { c.MinSeverity = cli.MinSeverity }},
+	{[]string{"fail-on"}, func(c, cli *ConfigNg) { c.FailOn = cli.FailOn }},
+	{[]string{"fail-on-group"}, func(c, cli *ConfigNg) { c.FailOnGroups = cli.FailOnGroups }},
+	{[]string{"waivers"}, func(c, cli *ConfigNg) { c.WaiversFile = cli.WaiversFile }},
+	{[]string{"baseline"}, func(c, cli *ConfigNg) { c.Baseline = cli.Baseline }},
+	{[]string{"write-baseline"}, func(c, cli *ConfigNg) { c.WriteBaseline = cli.WriteBaseline }},
+	{[]string{"timeout"}, func(c, cli *ConfigNg) { c.Timeout = cli.Timeout }},
+	{[]string{"include"}, func(c, cli *ConfigNg) { c.Include = cli.Include }},
+	{[]string{"exclude"}, func(c, cli *ConfigNg) { c.Exclude = cli.Exclude }},
+	{[]string{"tag"}, func(c, cli *ConfigNg) { c.Tags = cli.Tags }},
+	{[]string{"tag-match"}, func(c, cli *ConfigNg) { c.TagMatch = cli.TagMatch }},
+	{[]string{"group-by"}, func(c, cli *ConfigNg) { c.GroupBy = cli.GroupBy }},
+	{[]string{"query"}, func(c, cli *ConfigNg) { c.Query = cli.Query }},
+	{[]string{"violation-rule"}, func(c, cli *ConfigNg) { c.ViolationRule = cli.ViolationRule }},
+	{[]string{"valid-rule"}, func(c, cli *ConfigNg) { c.ValidRule = cli.ValidRule }},
+	{[]string{"data"}, func(c, cli *ConfigNg) { c.DataFiles = cli.DataFiles }},
+	{[]string{"data-path"}, func(c, cli *ConfigNg) { c.DataPath = cli.DataPath }},
+	{[]string{"project", "name", "location", "all-clusters", "fleet-project", "cluster-id", "input-file", "terraform"}, func(c, cli *ConfigNg) {
+		c.Clusters = cli.Clusters
+		c.Discovery = cli.Discovery
+		c.Fleets = cli.Fleets
+	}},
+	{[]string{"output", "output-file"}, func(c, cli *ConfigNg) { c.Outputs = cli.Outputs }},
+	{[]string{"local-policy-dir", "bundle", "policy-url", "git-policy-repo"}, func(c, cli *ConfigNg) { c.Policies = cli.Policies }},
+}
+
+// overrideConfig applies settings of flags for which isSet returns true.
+func overrideConfig(config *ConfigNg, cliConfig *ConfigNg, isSet func(name string) bool) {
+	for _, override := range cliOverrides {
+		for _, flag := range override.flags {
+			if isSet(flag) {
+				override.apply(config, cliConfig)
+				break
+			}
+		}
+	}
+}
+
+func newResultWriter(output ConfigOutput, w io.Writer) (outputs.ResultWriter, error) {
+	switch output.Format {
+	case "", OutputFormatText:
+		return nil, nil
+	case OutputFormatSarif:
+		return outputs.NewSarifResultWriter(w), nil
+	case OutputFormatJSON:
+		return outputs.NewJSONResultWriter(w), nil
+	case OutputFormatJSONL:
+		return outputs.NewJSONLResultWriter(w), nil
+	case OutputFormatJUnit:
+		return outputs.NewJUnitResultWriter(w), nil
+	case OutputFormatCis:
+		return outputs.NewCisResultWriter(w), nil
+	case OutputFormatMarkdown:
+		return outputs.NewMarkdownResultWriter(w), nil
+	case OutputFormatYAML:
+		return outputs.NewYAMLResultWriter(w), nil
+	case OutputFormatHTML:
+		return outputs.NewHTMLResultWriter(w), nil
+	case OutputFormatTAP:
+		return outputs.NewTAPResultWriter(w), nil
+	case OutputFormatLine:
+		return outputs.NewLineResultWriter(w), nil
+	case OutputFormatPrometheus:
+		return outputs.NewPrometheusResultWriter(w), nil
+	}
+	return nil, fmt.Errorf("unsupported output format %q", output.Format)
+}
+
+// newSummaryResultWriter creates writer of policy counts only, for formats that support it.
+func newSummaryResultWriter(output ConfigOutput, w io.Writer) (outputs.ResultWriter, error) {
+	switch output.Format {
+	case "", OutputFormatText:
+		return nil, nil
+	case OutputFormatJSON:
+		return outputs.NewJSONSummaryResultWriter(w), nil
+	case OutputFormatYAML:
+		return outputs.NewYAMLSummaryResultWriter(w), nil
+	}
+	return nil, fmt.Errorf("summary is not supported by %q output format", output.Format)
+}
+
+// clusterDisplayName returns name of the cluster used in the output.
+func clusterDisplayName(c ConfigCluster) string {
+	if c.Terraform != "" {
+		return terraformClusterName(c)
+	}
+	if c.File == StdinInputFile {
+		return "stdin"
+	}
+	if c.File != "" {
+		return c.File
+	}
+	if name, err := getClusterName(c); err == nil {
+		return name
+	}
+	return c.Name
+}
+
+// missingClusterParameters returns names of parameters required to fetch the cluster
+// that are not set. Clusters given with ID or input file need no other parameters.
+func missingClusterParameters(c ConfigCluster) []string {
+	missing := make([]string, 0)
+	if c.ID != "" || c.File != "" || c.Terraform != "" {
+		return missing
+	}
+	if c.Project == "" {
+		missing = append(missing, "project")
+	}
+	if c.Location == "" {
+		missing = append(missing, "location")
+	}
+	if c.Name == "" {
+		missing = append(missing, "name")
+	}
+	return missing
+}
+
 func getClusterName(c ConfigCluster) (string, error) {
 	if c.ID != "" {
 		return c.ID, nil
@@ -189,21 +1799,233 @@ func getClusterName(c ConfigCluster) (string, error) {
 }
 
 func (p *PolicyAutomationApp) printEvaluationResults(results []*policy.PolicyEvaluationResult) {
+	p.out.ColorPrintf("[white][bold]Reviewed with %s: [reset][white]%s\n", outputs.ToolName, version.String())
+	log.Infof("Reviewed with %s %s", outputs.ToolName, version.String())
 	for _, result := range results {
+		if result.ClusterError != nil {
+			p.out.ColorPrintf("[yellow][bold]GKE Cluster [%s]: [red]could not be reviewed: [reset][red]%s\n",
+				result.ClusterName, result.ClusterError)
+			continue
+		}
 		p.out.ColorPrintf("[yellow][bold]GKE Cluster [%s]:", result.ClusterName)
-		for _, group := range result.Groups() {
-			p.out.ColorPrintf("\n[white][bold]Group %q:\n\n", group)
-			for _, policy := range result.Valid[group] {
-				p.out.ColorPrintf("[bold][green][\u2713] %s: [reset][green]%s\n", policy.Title, policy.Description)
-			}
-			for _, policy := range result.Violated[group] {
-				p.out.ColorPrintf("[bold][red][x] %s: [reset][red]%s. [bold]Violations:[reset][red] %s\n", policy.Title, policy.Description, policy.Violations[0])
-			}
+		p.printLabels(result.ClusterLabels)
+		if p.config.Summary {
+			p.printGroupCounts(result)
+		} else {
+			p.printPolicies(result)
 		}
-		p.out.ColorPrintf("\n[bold][green]GKE cluster [%s]: Policies: %d valid, %d violated, %d errored.\n",
+		p.out.ColorPrintf("\n[bold][green]GKE cluster [%s]: Policies: %d valid, %d violated, %d warned, %d audited, %d waived, %d errored.\n",
 			result.ClusterName,
 			result.ValidCount(),
 			result.ViolatedCount(),
+			result.WarnedCount(),
+			result.AuditedCount(),
+			result.WaivedCount(),
 			result.ErroredCount())
+		if filtered := result.FilteredCount(); filtered > 0 {
+			p.out.ColorPrintf("[bold][green]GKE cluster [%s]: Policies below minimum severity: %d.\n",
+				result.ClusterName,
+				filtered)
+		}
+		if suppressed := result.SuppressedViolationsCount(); suppressed > 0 {
+			p.out.ColorPrintf("[bold][green]GKE cluster [%s]: Violations suppressed: %d, policies with all violations suppressed: %d.\n",
+				result.ClusterName,
+				suppressed,
+				result.SuppressedCount())
+		}
+		if truncated := result.TruncatedViolationsCount(); truncated > 0 {
+			p.out.ColorPrintf("[bold][green]GKE cluster [%s]: Violations over the limit of %d per policy: %d.\n",
+				result.ClusterName,
+				p.maxViolations(),
+				truncated)
+		}
+		if skipped := result.SkippedCount(); skipped > 0 {
+			p.out.ColorPrintf("[bold][green]GKE cluster [%s]: Policies not applicable to the cluster type: %d.\n",
+				result.ClusterName,
+				skipped)
+		}
+	}
+	p.printSeverityCounts(results)
+}
+
+// printSeverityCounts prints a single line with number of violated policies of each severity
+// on all reviewed clusters, for quick scanning and CI log annotations.
+func (p *PolicyAutomationApp) printSeverityCounts(results []*policy.PolicyEvaluationResult) {
+	reviewed := make([]*policy.PolicyEvaluationResult, 0, len(results))
+	for _, result := range results {
+		if result.ClusterError == nil {
+			reviewed = append(reviewed, result)
+		}
+	}
+	if len(reviewed) == 0 {
+		return
+	}
+	counts := outputs.NewSeverityCounts(reviewed)
+	p.out.ColorPrintf("\n[bold][white]Violated policies by severity: [reset][white]%s\n", counts)
+	log.Infof("Violated policies by severity: %s", counts)
+}
+
+// printLabels prints resource labels of the cluster, sorted by key, in the cluster header.
+func (p *PolicyAutomationApp) printLabels(labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + labels[key]
+	}
+	p.out.ColorPrintf("\n[white][bold]Labels: [reset][white]%s\n", strings.Join(pairs, ", "))
+}
+
+func (p *PolicyAutomationApp) printPolicies(result *policy.PolicyEvaluationResult) {
+	for _, group := range result.Groups() {
+		p.out.ColorPrintf("\n[white][bold]%s %q:\n\n", groupLabel(result), group)
+		for _, policy := range result.Valid[group] {
+			p.out.ColorPrintf("[bold][green][\u2713] %s: [reset][green]%s\n", policy.Label(), policy.Description)
+			p.printPrints(policy, "green")
+		}
+		for _, policy := range result.Violated[group] {
+			p.out.ColorPrintf("[bold][red][x] %s: [reset][red]%s. [bold]Violations:[reset][red] %s\n", policy.Label(), policy.Description, policy.Violations[0])
+			p.printTruncated(policy, "red")
+			p.printRemediation(policy, "red")
+			p.printReferences(policy, "red")
+			p.printPrints(policy, "red")
+		}
+		for _, policy := range result.Warned[group] {
+			p.out.ColorPrintf("[bold][yellow][!] %s: [reset][yellow]%s. [bold]Violations:[reset][yellow] %s\n", policy.Label(), policy.Description, policy.Violations[0])
+			p.printTruncated(policy, "yellow")
+			p.printRemediation(policy, "yellow")
+			p.printReferences(policy, "yellow")
+			p.printPrints(policy, "yellow")
+		}
+		for _, waived := range result.Waived[group] {
+			p.out.ColorPrintf("[bold][blue][w] %s: [reset][blue]%s. [bold]Waived until %s:[reset][blue] %s\n",
+				waived.Label(), waived.Description, waived.Waiver.Expires.Format(policy.WaiverDateFormat), waived.Waiver.Reason)
+		}
+		for _, suppressed := range result.Suppressed[group] {
+			p.out.ColorPrintf("[bold][blue][s] %s: [reset][blue]%s. [bold]Suppressed violations:[reset][blue] %d\n",
+				suppressed.Label(), suppressed.Description, suppressed.SuppressedViolations)
+		}
+		for _, policy := range result.Audited[group] {
+			p.out.ColorPrintf("[bold][cyan][i] %s: [reset][cyan]%s. [bold]Violations:[reset][cyan] %s\n", policy.Label(), policy.Description, policy.Violations[0])
+			p.printTruncated(policy, "cyan")
+			p.printRemediation(policy, "cyan")
+			p.printReferences(policy, "cyan")
+			p.printPrints(policy, "cyan")
+		}
+	}
+}
+
+func (p *PolicyAutomationApp) printGroupCounts(result *policy.PolicyEvaluationResult) {
+	p.out.ColorPrintf("\n")
+	for _, counts := range outputs.NewGroupCounts(result) {
+		p.out.ColorPrintf("[white][bold]%s %q: [reset][white]%d valid, %d violated, %d errored\n",
+			groupLabel(result), counts.Group, counts.Valid, counts.Violated, counts.Errored)
+	}
+}
+
+// groupLabel names the grouping of policies in the text output.
+func groupLabel(result *policy.PolicyEvaluationResult) string {
+	if result.IsGroupedBySeverity() {
+		return "Severity"
+	}
+	return "Group"
+}
+
+// printTruncated notes violations of the policy over the limit, that are not reported.
+func (p *PolicyAutomationApp) printTruncated(policy *policy.Policy, color string) {
+	if policy.TruncatedViolations == 0 {
+		return
+	}
+	p.out.ColorPrintf("    ["+color+"](+%d more violations over the limit)\n", policy.TruncatedViolations)
+}
+
+func (p *PolicyAutomationApp) printRemediation(policy *policy.Policy, color string) {
+	if policy.Remediation == "" {
+		return
+	}
+	p.out.ColorPrintf("    [bold]["+color+"]Remediation:[reset]["+color+"] %s\n", policy.Remediation)
+}
+
+func (p *PolicyAutomationApp) printReferences(policy *policy.Policy, color string) {
+	for _, reference := range policy.References {
+		p.out.ColorPrintf("    [bold]["+color+"]Reference:[reset]["+color+"] %s\n", reference)
+	}
+}
+
+// printPrints prints output of rego print() calls of the policy when enabled with show prints.
+func (p *PolicyAutomationApp) printPrints(policy *policy.Policy, color string) {
+	if !p.config.ShowPrints {
+		return
+	}
+	for _, msg := range policy.Prints {
+		p.out.ColorPrintf("    [bold]["+color+"]Print:[reset]["+color+"] %s\n", msg)
+	}
+}
+
+func (p *PolicyAutomationApp) printBaselineDiffs(results []*policy.PolicyEvaluationResult) {
+	for _, result := range results {
+		if result.ClusterError != nil {
+			continue
+		}
+		diff := p.baseline.Diff(result)
+		p.out.ColorPrintf("\n[bold][green]GKE cluster [%s] compared to baseline: %d new, %d fixed, %d still violated.\n",
+			diff.Cluster, len(diff.New), len(diff.Fixed), len(diff.Still))
+		for _, violated := range diff.New {
+			p.out.ColorPrintf("[bold][red][+] %s (%s)\n", violated.Title, violated.Name)
+		}
+		for _, fixed := range diff.Fixed {
+			p.out.ColorPrintf("[bold][green][-] %s\n", fixed)
+		}
+		for _, violated := range diff.Still {
+			p.out.ColorPrintf("[yellow][=] %s (%s)\n", violated.Title, violated.Name)
+		}
+	}
+}
+
+func (p *PolicyAutomationApp) printExceptionsReport(results []*policy.PolicyEvaluationResult) {
+	for _, result := range results {
+		if result.ClusterError != nil {
+			continue
+		}
+		exceptions := result.Exceptions()
+		p.out.ColorPrintf("\n[yellow][bold]GKE Cluster [%s] exceptions: %d\n", result.ClusterName, len(exceptions))
+		for _, exception := range exceptions {
+			p.out.ColorPrintf("[bold][yellow][%s] %s (%s): [reset][yellow]%s\n",
+				exception.Status,
+				exception.Policy.Title,
+				exception.Policy.Name,
+				exception.Reason)
+		}
+	}
+}
+
+func (p *PolicyAutomationApp) printCoverage(results []*policy.PolicyEvaluationResult) {
+	for _, result := range results {
+		if result.Coverage == nil {
+			continue
+		}
+		p.out.ColorPrintf("\n[white][bold]GKE Cluster [%s] policy coverage: %.2f%%\n", result.ClusterName, result.Coverage.Coverage)
+		for _, file := range result.Coverage.Files {
+			p.out.Printf("%7.2f%%  %4d/%-4d lines  %s\n", file.Coverage, file.CoveredLines,
+				file.CoveredLines+file.NotCoveredLines, file.File)
+		}
+	}
+}
+
+func (p *PolicyAutomationApp) printTimings(results []*policy.PolicyEvaluationResult) {
+	for _, result := range results {
+		if result.ClusterError != nil {
+			continue
+		}
+		p.out.ColorPrintf("\n[white][bold]GKE Cluster [%s] policy evaluation times:\n", result.ClusterName)
+		for _, timing := range result.Timings() {
+			p.out.Printf("%12s  %s\n", timing.Duration.Round(time.Microsecond), timing.Policy)
+		}
 	}
 }
@@ -15,12 +15,22 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/mikouaj/gke-review/internal/outputs"
+	"github.com/mikouaj/gke-review/internal/policy"
 	"gopkg.in/yaml.v2"
 )
 
@@ -64,9 +74,112 @@ func TestLoadCliConfig_file(t *testing.T) {
 	}
 }
 
+func TestLoadCliConfig_fileOverride(t *testing.T) {
+	configFile := t.TempDir() + "/config.yaml"
+	data := "failOn: HIGH\n" +
+		"dumpInput: input.json\n" +
+		"clusters:\n" +
+		"- file: cluster.json\n" +
+		"policies:\n" +
+		"- local: ./policies\n" +
+		"outputs:\n" +
+		"- format: json\n"
+	if err := os.WriteFile(configFile, []byte(data), 0644); err != nil {
+		t.Fatalf("could not write file: %s", err)
+	}
+	cliConfig := &CliConfig{
+		ConfigFile:       configFile,
+		FailOn:           policy.SeverityCritical,
+		MinSeverity:      policy.SeverityLow,
+		OutputFormat:     OutputFormatYAML,
+		LocalDirectories: []string{"./other"},
+		SetFlags:         []string{"config", "fail-on", "output", "o"},
+	}
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	if err := pa.LoadCliConfig(cliConfig); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := &ConfigNg{
+		FailOn:    policy.SeverityCritical,
+		DumpInput: "input.json",
+		Clusters:  []ConfigCluster{{File: "cluster.json"}},
+		Policies:  []ConfigPolicy{{LocalDirectory: "./policies"}},
+		Outputs:   []ConfigOutput{{Format: OutputFormatYAML}},
+	}
+	if !reflect.DeepEqual(pa.config, expected) {
+		t.Errorf("config = %+v; want %+v", pa.config, expected)
+	}
+}
+
+func TestLoadCliConfig_gitPolicyOverride(t *testing.T) {
+	configFile := t.TempDir() + "/config.yaml"
+	data := "clusters:\n" +
+		"- file: cluster.json\n" +
+		"policies:\n" +
+		"- repository: https://github.com/example/policies\n" +
+		"  branch: main\n" +
+		"  directory: policies\n"
+	if err := os.WriteFile(configFile, []byte(data), 0644); err != nil {
+		t.Fatalf("could not write file: %s", err)
+	}
+	cliConfig := &CliConfig{
+		ConfigFile:   configFile,
+		GitBranch:    "dev",
+		GitDirectory: DefaultGitPolicyDir,
+		SetFlags:     []string{"config", "git-policy-branch"},
+	}
+	config, err := newConfigFromFile(configFile)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := overrideGitPolicies(config, cliConfig); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := []ConfigPolicy{{GitRepository: "https://github.com/example/policies", GitBranch: "dev", GitDirectory: "policies"}}
+	if !reflect.DeepEqual(config.Policies, expected) {
+		t.Errorf("policies = %+v; want %+v", config.Policies, expected)
+	}
+	config = &ConfigNg{Policies: []ConfigPolicy{{LocalDirectory: "./policies"}}}
+	if err := overrideGitPolicies(config, cliConfig); err == nil {
+		t.Errorf("no GIT policy source: err = nil; want error")
+	}
+}
+
+func TestLoadCliConfig_defaultFile(t *testing.T) {
+	configFile := t.TempDir() + "/config.yaml"
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput(), defaultConfigFile: configFile}
+	if err := pa.LoadCliConfig(&CliConfig{InputFile: "cluster.json"}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if pa.config.FailOn != "" {
+		t.Errorf("failOn = %q; want empty without default file", pa.config.FailOn)
+	}
+	if err := os.WriteFile(configFile, []byte("failOn: HIGH\n"), 0644); err != nil {
+		t.Fatalf("could not write file: %s", err)
+	}
+	if err := pa.LoadCliConfig(&CliConfig{InputFile: "cluster.json", SetFlags: []string{"input-file"}}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if pa.config.FailOn != policy.SeverityHigh {
+		t.Errorf("failOn = %q; want %q from default file", pa.config.FailOn, policy.SeverityHigh)
+	}
+}
+
+func TestLoadConfig_credentialsFile(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	err := pa.LoadConfig(&ConfigNg{
+		CredentialsFile: "./test-fixtures/missing.json",
+		Clusters:        []ConfigCluster{{Name: "warsaw", Project: "my-project", Location: "europe-central2"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "could not read credentials file") {
+		t.Errorf("err = %v; want credentials file error", err)
+	}
+}
+
 func TestLoadConfig(t *testing.T) {
 	config := &ConfigNg{
 		CredentialsFile: "./test-fixtures/test_credentials.json",
+		Clusters:        []ConfigCluster{{Name: "warsaw", Project: "my-project", Location: "europe-central2"}},
 	}
 	pa := PolicyAutomationApp{ctx: context.Background()}
 	err := pa.LoadConfig(config)
@@ -85,24 +198,90 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_minSeverity(t *testing.T) {
+	config := &ConfigNg{
+		CredentialsFile: "./test-fixtures/test_credentials.json",
+		MinSeverity:     "high",
+	}
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err is not nil; want nil; err = %s", err)
+	}
+	defer pa.Close()
+	if pa.config.MinSeverity != "HIGH" {
+		t.Errorf("minSeverity = %v; want %v", pa.config.MinSeverity, "HIGH")
+	}
+	pa = PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{MinSeverity: "bogus"}); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+	pa = PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{FailOn: "bogus"}); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
+
 func TestNewConfigFromCli(t *testing.T) {
 	input := &CliConfig{
-		SilentMode:      true,
-		CredentialsFile: "/path/to/creds.json",
-		ClusterName:     "testCluster",
-		ClusterLocation: "europe-central2",
-		LocalDirectory:  "/path/to/policies",
-		GitRepository:   "https://github.com/test/test",
-		GitBranch:       "main",
-		GitDirectory:    "policies",
+		SilentMode:       true,
+		LogLevel:         "debug",
+		CredentialsFile:  "/path/to/creds.json",
+		ImpersonateSA:    "auditor@project.iam.gserviceaccount.com",
+		IncludeVersions:  true,
+		IncludeIAM:       true,
+		ExceptionsReport: true,
+		Timings:          true,
+		OutputFormat:     OutputFormatSarif,
+		MinSeverity:      "HIGH",
+		FailOn:           "CRITICAL",
+		ClusterName:      "testCluster",
+		ClusterLocation:  "europe-central2",
+		LocalDirectories: []string{"/path/to/policies", "/path/to/overrides"},
+		GitRepository:    "https://github.com/test/test",
+		GitBranch:        "main",
+		GitDirectory:     "policies",
 	}
+	input.IncludeSecurityPosture = true
 	config := newConfigFromCli(input)
 	if config.SilentMode != input.SilentMode {
 		t.Errorf("silentMode = %v; want %v", config.SilentMode, input.SilentMode)
 	}
+	if config.LogLevel != input.LogLevel {
+		t.Errorf("logLevel = %v; want %v", config.LogLevel, input.LogLevel)
+	}
 	if config.CredentialsFile != input.CredentialsFile {
 		t.Errorf("credentialsFile = %v; want %v", config.CredentialsFile, input.CredentialsFile)
 	}
+	if config.ImpersonateServiceAccount != input.ImpersonateSA {
+		t.Errorf("impersonateServiceAccount = %v; want %v", config.ImpersonateServiceAccount, input.ImpersonateSA)
+	}
+	if config.IncludeVersions != input.IncludeVersions {
+		t.Errorf("includeVersions = %v; want %v", config.IncludeVersions, input.IncludeVersions)
+	}
+	if config.IncludeIAM != input.IncludeIAM {
+		t.Errorf("includeIam = %v; want %v", config.IncludeIAM, input.IncludeIAM)
+	}
+	if config.IncludeSecurityPosture != input.IncludeSecurityPosture {
+		t.Errorf("includeSecurityPosture = %v; want %v", config.IncludeSecurityPosture, input.IncludeSecurityPosture)
+	}
+	if config.Timings != input.Timings {
+		t.Errorf("timings = %v; want %v", config.Timings, input.Timings)
+	}
+	if config.ExceptionsReport != input.ExceptionsReport {
+		t.Errorf("exceptionsReport = %v; want %v", config.ExceptionsReport, input.ExceptionsReport)
+	}
+	if config.MinSeverity != input.MinSeverity {
+		t.Errorf("minSeverity = %v; want %v", config.MinSeverity, input.MinSeverity)
+	}
+	if config.FailOn != input.FailOn {
+		t.Errorf("failOn = %v; want %v", config.FailOn, input.FailOn)
+	}
+	if len(config.Outputs) != 1 {
+		t.Fatalf("len(outputs) = %v; want %v", len(config.Outputs), 1)
+	}
+	if config.Outputs[0].Format != input.OutputFormat {
+		t.Errorf("outputs[0] format = %v; want %v", config.Outputs[0].Format, input.OutputFormat)
+	}
 	if len(config.Clusters) != 1 {
 		t.Fatalf("len(clusters) = %v; want %v", len(config.Clusters), 1)
 	}
@@ -115,20 +294,63 @@ func TestNewConfigFromCli(t *testing.T) {
 	if config.Clusters[0].Project != input.ProjectName {
 		t.Errorf("cluster[0] project = %v; want %v", config.Clusters[0].Project, input.ProjectName)
 	}
-	if len(config.Policies) != 2 {
-		t.Fatalf("len(policies) = %v; want %v", len(config.Policies), 2)
+	if len(config.Policies) != 3 {
+		t.Fatalf("len(policies) = %v; want %v", len(config.Policies), 3)
+	}
+	for i, directory := range input.LocalDirectories {
+		if config.Policies[i].LocalDirectory != directory {
+			t.Errorf("policies[%d] localDirectory = %v; want %v", i, config.Policies[i].LocalDirectory, directory)
+		}
+	}
+	if config.Policies[2].GitRepository != input.GitRepository {
+		t.Errorf("policies[2] gitRepository = %v; want %v", config.Policies[2].GitRepository, input.GitRepository)
+	}
+	if config.Policies[2].GitBranch != input.GitBranch {
+		t.Errorf("policies[2] gitBranch = %v; want %v", config.Policies[2].GitBranch, input.GitBranch)
+	}
+	if config.Policies[2].GitDirectory != input.GitDirectory {
+		t.Errorf("policies[2] gitDirectory = %v; want %v", config.Policies[2].GitDirectory, input.GitDirectory)
+	}
+}
+
+func TestNewResultWriter(t *testing.T) {
+	for _, format := range []string{"", OutputFormatText} {
+		writer, err := newResultWriter(ConfigOutput{Format: format}, io.Discard)
+		if err != nil {
+			t.Errorf("err = %v; want nil", err)
+		}
+		if writer != nil {
+			t.Errorf("writer for format %q is not nil; want nil", format)
+		}
 	}
-	if config.Policies[0].LocalDirectory != input.LocalDirectory {
-		t.Errorf("policies[0] localDirectory = %v; want %v", config.Policies[0].LocalDirectory, input.LocalDirectory)
+	for _, format := range []string{OutputFormatSarif, OutputFormatJSON, OutputFormatJUnit, OutputFormatCis, OutputFormatMarkdown, OutputFormatYAML, OutputFormatPrometheus} {
+		writer, err := newResultWriter(ConfigOutput{Format: format}, io.Discard)
+		if err != nil {
+			t.Errorf("err = %v; want nil", err)
+		}
+		if writer == nil {
+			t.Errorf("writer for format %q is nil; want writer", format)
+		}
 	}
-	if config.Policies[1].GitRepository != input.GitRepository {
-		t.Errorf("policies[1] gitRepository = %v; want %v", config.Policies[1].GitRepository, input.GitRepository)
+	if _, err := newResultWriter(ConfigOutput{Format: "bogus"}, io.Discard); err == nil {
+		t.Errorf("err is nil; want error")
 	}
-	if config.Policies[1].GitBranch != input.GitBranch {
-		t.Errorf("policies[1] gitBranch = %v; want %v", config.Policies[1].GitBranch, input.GitBranch)
+}
+
+func TestNewSummaryResultWriter(t *testing.T) {
+	for _, format := range []string{OutputFormatJSON, OutputFormatYAML} {
+		writer, err := newSummaryResultWriter(ConfigOutput{Format: format}, io.Discard)
+		if err != nil {
+			t.Errorf("err = %v; want nil", err)
+		}
+		if writer == nil {
+			t.Errorf("writer for format %q is nil; want writer", format)
+		}
 	}
-	if config.Policies[1].GitDirectory != input.GitDirectory {
-		t.Errorf("policies[1] gitDirectory = %v; want %v", config.Policies[1].GitDirectory, input.GitDirectory)
+	for _, format := range []string{OutputFormatSarif, OutputFormatJUnit, OutputFormatMarkdown} {
+		if _, err := newSummaryResultWriter(ConfigOutput{Format: format}, io.Discard); err == nil {
+			t.Errorf("format %q: err is nil; want error", format)
+		}
 	}
 }
 
@@ -260,3 +482,1590 @@ func TestGetPolicySource_local(t *testing.T) {
 	}
 }
 */
+
+func TestNewConfigFromCli_inputFile(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{InputFile: "cluster.json", ClusterName: "test"})
+	expected := []ConfigCluster{{File: "cluster.json"}}
+	if !reflect.DeepEqual(config.Clusters, expected) {
+		t.Errorf("clusters = %v; want %v", config.Clusters, expected)
+	}
+}
+
+func TestNewConfigFromCli_terraform(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{TerraformFile: "plan.json"})
+	expected := []ConfigCluster{{Terraform: "plan.json"}}
+	if !reflect.DeepEqual(config.Clusters, expected) {
+		t.Errorf("clusters = %v; want %v", config.Clusters, expected)
+	}
+}
+
+func TestNeedsGKEClient(t *testing.T) {
+	pa := PolicyAutomationApp{config: &ConfigNg{}}
+	if pa.needsGKEClient() {
+		t.Errorf("needsGKEClient = %v; want %v", true, false)
+	}
+	pa.config.Clusters = []ConfigCluster{{File: "one.json"}, {Terraform: "plan.json"}}
+	if pa.needsGKEClient() {
+		t.Errorf("needsGKEClient = %v; want %v", true, false)
+	}
+	pa.config.Clusters = append(pa.config.Clusters, ConfigCluster{ID: "projects/p/locations/l/clusters/c"})
+	if !pa.needsGKEClient() {
+		t.Errorf("needsGKEClient = %v; want %v", false, true)
+	}
+}
+
+func TestReadClusterInputFile(t *testing.T) {
+	readFn := func(path string) ([]byte, error) {
+		return []byte(`{"name": "warsaw", "current_node_count": 3}`), nil
+	}
+	input, err := readClusterInputFile("cluster.json", readFn)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if input["name"] != "warsaw" {
+		t.Errorf("input name = %v; want %v", input["name"], "warsaw")
+	}
+	readFn = func(path string) ([]byte, error) {
+		return []byte(`{"name": "warsaw",}`), nil
+	}
+	if _, err := readClusterInputFile("cluster.json", readFn); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
+
+// writeNodeCountPolicy writes the policy violated by clusters with less than 3 nodes to the directory.
+func writeNodeCountPolicy(t *testing.T, dir string) {
+	t.Helper()
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.node_count\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.current_node_count < 3; msg := \"not enough nodes\" }\n"
+	if err := os.WriteFile(dir+"/node_count.rego", []byte(policyContent), 0644); err != nil {
+		t.Fatalf("could not write policy file: %s", err)
+	}
+}
+
+func TestClusterReview_inputFile(t *testing.T) {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	if err := os.WriteFile(dir+"/cluster.json", []byte(`{"name": "warsaw", "current_node_count": 1}`), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	for include, expectErr := range map[string]bool{"node_count$": false, "other": true} {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode: true,
+			Include:    include,
+			Clusters:   []ConfigCluster{{File: dir + "/cluster.json"}},
+			Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		err := pa.ClusterReview()
+		if expectErr && (err == nil || err == ErrEnforcedViolations) {
+			t.Errorf("include %q: err = %v; want selection error", include, err)
+		}
+		if !expectErr && err != ErrEnforcedViolations {
+			t.Errorf("include %q: err = %v; want %v", include, err, ErrEnforcedViolations)
+		}
+	}
+	inputs := map[string]error{
+		`{"name": "warsaw", "current_node_count": 3}`: nil,
+		`{"name": "warsaw", "current_node_count": 1}`: ErrEnforcedViolations,
+	}
+	for content, expectedErr := range inputs {
+		if err := os.WriteFile(dir+"/cluster.json", []byte(content), 0644); err != nil {
+			t.Fatalf("could not write input file: %s", err)
+		}
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode: true,
+			Clusters:   []ConfigCluster{{File: dir + "/cluster.json"}},
+			Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if pa.gke != nil {
+			t.Errorf("gke client is not nil; want nil")
+		}
+		if err := pa.ClusterReview(); err != expectedErr {
+			t.Errorf("input %s: err = %v; want %v", content, err, expectedErr)
+		}
+	}
+}
+
+func TestClusterReview_quietOnSuccess(t *testing.T) {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	inputs := map[string]bool{
+		`{"name": "warsaw", "current_node_count": 3}`: false,
+		`{"name": "warsaw", "current_node_count": 1}`: true,
+	}
+	for content, expectOutput := range inputs {
+		if err := os.WriteFile(dir+"/cluster.json", []byte(content), 0644); err != nil {
+			t.Fatalf("could not write input file: %s", err)
+		}
+		for _, format := range []string{OutputFormatText, OutputFormatJSON} {
+			pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+			config := &ConfigNg{
+				QuietOnSuccess: true,
+				Color:          ColorNever,
+				Clusters:       []ConfigCluster{{File: dir + "/cluster.json"}},
+				Policies:       []ConfigPolicy{{LocalDirectory: dir}},
+				Outputs:        []ConfigOutput{{Format: format}},
+			}
+			if err := pa.LoadConfig(config); err != nil {
+				t.Fatalf("err = %v; want nil", err)
+			}
+			var buff bytes.Buffer
+			pa.quiet.w = &buff
+			pa.ClusterReview()
+			if expectOutput && buff.Len() == 0 {
+				t.Errorf("input %s, format %s: output is empty; want report", content, format)
+			}
+			if !expectOutput && buff.Len() > 0 {
+				t.Errorf("input %s, format %s: output = %q; want empty", content, format, buff.String())
+			}
+		}
+	}
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		SilentMode:     true,
+		QuietOnSuccess: true,
+		Watch:          true,
+		Policies:       []ConfigPolicy{{LocalDirectory: dir}},
+	}
+	if err := pa.LoadConfig(config); err == nil {
+		t.Errorf("err is nil; want error for watch mode")
+	}
+}
+
+func TestClusterReview_quietOnSuccessClusters(t *testing.T) {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	clusters := make([]ConfigCluster, 8)
+	for i := range clusters {
+		path := fmt.Sprintf("%s/cluster-%d.json", dir, i)
+		content := fmt.Sprintf(`{"name": "cluster-%d", "current_node_count": %d}`, i, i)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write input file: %s", err)
+		}
+		clusters[i] = ConfigCluster{File: path}
+	}
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		QuietOnSuccess:          true,
+		Color:                   ColorNever,
+		ClusterFetchConcurrency: 4,
+		Clusters:                clusters,
+		Policies:                []ConfigPolicy{{LocalDirectory: dir}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	var buff bytes.Buffer
+	pa.quiet.w = &buff
+	pa.ClusterReview()
+	for i := range clusters {
+		if name := fmt.Sprintf("cluster-%d.json", i); !strings.Contains(buff.String(), name) {
+			t.Errorf("output = %q; want it to contain %q", buff.String(), name)
+		}
+	}
+}
+
+func TestClusterReview_errorsAsViolations(t *testing.T) {
+	dir := t.TempDir()
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.conflict\n" +
+		"valid = true { true }\n" +
+		"valid = false { true }\n"
+	if err := os.WriteFile(dir+"/conflict.rego", []byte(policyContent), 0644); err != nil {
+		t.Fatalf("could not write policy file: %s", err)
+	}
+	if err := os.WriteFile(dir+"/cluster.json", []byte(`{"name": "warsaw"}`), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	for errorsAsViolations, expectedErr := range map[bool]error{false: ErrEvaluationErrors, true: ErrEnforcedViolations} {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode:         true,
+			ErrorsAsViolations: errorsAsViolations,
+			Clusters:           []ConfigCluster{{File: dir + "/cluster.json"}},
+			Policies:           []ConfigPolicy{{LocalDirectory: dir}},
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if err := pa.ClusterReview(); !errors.Is(err, expectedErr) {
+			t.Errorf("errorsAsViolations %v: err = %v; want %v", errorsAsViolations, err, expectedErr)
+		}
+	}
+}
+
+func TestClusterReview_severityOverrides(t *testing.T) {
+	dir := t.TempDir()
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"#   severity: Low\n" +
+		"package gke.policy.node_count\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.current_node_count < 3; msg := \"not enough nodes\" }\n"
+	if err := os.WriteFile(dir+"/node_count.rego", []byte(policyContent), 0644); err != nil {
+		t.Fatalf("could not write policy file: %s", err)
+	}
+	if err := os.WriteFile(dir+"/cluster.json", []byte(`{"name": "warsaw", "current_node_count": 1}`), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	inputs := []struct {
+		overrides map[string]string
+		expected  error
+	}{
+		{nil, nil},
+		{map[string]string{"node_count": "high"}, ErrEnforcedViolations},
+		{map[string]string{"gke.policy.node_count": "HIGH", "gke.policy.unknown": "LOW"}, ErrEnforcedViolations},
+	}
+	for _, input := range inputs {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode:        true,
+			FailOn:            policy.SeverityHigh,
+			SeverityOverrides: input.overrides,
+			Clusters:          []ConfigCluster{{File: dir + "/cluster.json"}},
+			Policies:          []ConfigPolicy{{LocalDirectory: dir}},
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if err := pa.ClusterReview(); !errors.Is(err, input.expected) {
+			t.Errorf("overrides %v: err = %v; want %v", input.overrides, err, input.expected)
+		}
+	}
+}
+
+func TestApplyReviewSettings_maxViolations(t *testing.T) {
+	violations := make([]string, DefaultMaxViolations+5)
+	for i := range violations {
+		violations[i] = fmt.Sprintf("violation %d", i)
+	}
+	inputs := []struct {
+		maxViolations int
+		expected      int
+	}{
+		{0, DefaultMaxViolations},
+		{2, 2},
+		{-1, DefaultMaxViolations + 5},
+	}
+	for _, input := range inputs {
+		result := policy.NewPolicyEvaluationResult()
+		result.AddPolicy(&policy.Policy{Name: "gke.policy.noisy", Group: "Test", Violations: append([]string{}, violations...)})
+		pa := PolicyAutomationApp{config: &ConfigNg{MaxViolations: input.maxViolations}}
+		result = pa.applyReviewSettings(result)
+		if reported := len(result.Violated["Test"][0].Violations); reported != input.expected {
+			t.Errorf("maxViolations %d: violations = %v; want %v", input.maxViolations, reported, input.expected)
+		}
+	}
+}
+
+func TestClusterReview_emptyPolicyDir(t *testing.T) {
+	dir := t.TempDir()
+	policyDir := dir + "/policies"
+	if err := os.Mkdir(policyDir, 0755); err != nil {
+		t.Fatalf("could not create policy directory: %s", err)
+	}
+	if err := os.WriteFile(dir+"/cluster.json", []byte(`{"name": "warsaw"}`), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	for _, allowEmpty := range []bool{false, true} {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode: true,
+			AllowEmpty: allowEmpty,
+			Clusters:   []ConfigCluster{{File: dir + "/cluster.json"}},
+			Policies:   []ConfigPolicy{{LocalDirectory: policyDir}},
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		err := pa.ClusterReview()
+		if allowEmpty && err != nil {
+			t.Errorf("allowEmpty: err = %v; want nil", err)
+		}
+		if !allowEmpty && (err == nil || !strings.Contains(err.Error(), "no policies loaded from local directory: "+policyDir)) {
+			t.Errorf("err = %v; want error with no policies loaded from %s", err, policyDir)
+		}
+	}
+}
+
+func TestClusterReview_terraform(t *testing.T) {
+	dir := t.TempDir()
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.private_cluster\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { not input.private_cluster_config.enable_private_nodes; msg := \"private nodes are disabled\" }\n"
+	if err := os.WriteFile(dir+"/private_cluster.rego", []byte(policyContent), 0644); err != nil {
+		t.Fatalf("could not write policy file: %s", err)
+	}
+	planTemplate := `{"format_version": "1.1", "planned_values": {"root_module": {"resources": [
+		{"address": "google_container_cluster.a", "mode": "managed", "type": "google_container_cluster",
+		 "values": {"name": "a", "private_cluster_config": [{"enable_private_nodes": true}]}},
+		{"address": "google_container_cluster.b", "mode": "managed", "type": "google_container_cluster",
+		 "values": {"name": "b", "private_cluster_config": [{"enable_private_nodes": %t}]}}
+	]}}}`
+	for private, expectedErr := range map[bool]error{true: nil, false: ErrEnforcedViolations} {
+		if err := os.WriteFile(dir+"/plan.json", []byte(fmt.Sprintf(planTemplate, private)), 0644); err != nil {
+			t.Fatalf("could not write plan file: %s", err)
+		}
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode: true,
+			Clusters:   []ConfigCluster{{Terraform: dir + "/plan.json"}},
+			Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if pa.gke != nil {
+			t.Errorf("gke client is not nil; want nil")
+		}
+		if err := pa.ClusterReview(); err != expectedErr {
+			t.Errorf("private %v: err = %v; want %v", private, err, expectedErr)
+		}
+		expected := []ConfigCluster{
+			{Terraform: dir + "/plan.json", Address: "google_container_cluster.a"},
+			{Terraform: dir + "/plan.json", Address: "google_container_cluster.b"},
+		}
+		if !reflect.DeepEqual(pa.config.Clusters, expected) {
+			t.Errorf("clusters = %v; want %v", pa.config.Clusters, expected)
+		}
+	}
+
+	if err := os.WriteFile(dir+"/plan.json", []byte(`{"format_version": "1.1", "planned_values": {"root_module": {}}}`), 0644); err != nil {
+		t.Fatalf("could not write plan file: %s", err)
+	}
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	if err := pa.LoadConfig(&ConfigNg{SilentMode: true, Clusters: []ConfigCluster{{Terraform: dir + "/plan.json"}}}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.ClusterReview(); err == nil || !strings.Contains(err.Error(), "no google_container_cluster resources found") {
+		t.Errorf("err = %v; want error about missing clusters", err)
+	}
+}
+
+func TestClusterReview_failFast(t *testing.T) {
+	dir := t.TempDir()
+	policyTemplate := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.%s\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.current_node_count < %d; msg := \"not enough nodes\" }\n"
+	for name, nodes := range map[string]int{"one": 1, "two": 2, "three": 3} {
+		if err := os.WriteFile(dir+"/"+name+".rego", []byte(fmt.Sprintf(policyTemplate, name, nodes)), 0644); err != nil {
+			t.Fatalf("could not write policy file: %s", err)
+		}
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(dir+"/"+name+".json", []byte(`{"name": "`+name+`", "current_node_count": 1}`), 0644); err != nil {
+			t.Fatalf("could not write input file: %s", err)
+		}
+	}
+	for failFast, expected := range map[bool][]int{false: {2, 2}, true: {1}} {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode: true,
+			FailFast:   failFast,
+			Clusters:   []ConfigCluster{{File: dir + "/a.json"}, {File: dir + "/b.json"}},
+			Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+			Outputs:    []ConfigOutput{{Format: OutputFormatJSON, File: dir + "/results.json"}},
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if err := pa.ClusterReview(); err != ErrEnforcedViolations {
+			t.Errorf("failFast %v: err = %v; want %v", failFast, err, ErrEnforcedViolations)
+		}
+		data, err := os.ReadFile(dir + "/results.json")
+		if err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		report := &outputs.JSONReport{}
+		if err := json.Unmarshal(data, report); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		violated := make([]int, len(report.Results))
+		for i, result := range report.Results {
+			violated[i] = len(result.Violated["Test"])
+		}
+		if !reflect.DeepEqual(violated, expected) {
+			t.Errorf("failFast %v: violated policies = %v; want %v", failFast, violated, expected)
+		}
+	}
+}
+
+func TestClusterReview_failFastReviewSettings(t *testing.T) {
+	dir := t.TempDir()
+	policyTemplate := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.%s\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.current_node_count < 3; msg := \"not enough nodes\" }\n"
+	suppressions := make([]ConfigSuppression, 0)
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("suppressed_%d", i)
+		if err := os.WriteFile(dir+"/"+name+".rego", []byte(fmt.Sprintf(policyTemplate, name)), 0644); err != nil {
+			t.Fatalf("could not write policy file: %s", err)
+		}
+		suppressions = append(suppressions, ConfigSuppression{Policy: name, Message: "nodes", Reason: "test"})
+	}
+	if err := os.WriteFile(dir+"/enforced.rego", []byte(fmt.Sprintf(policyTemplate, "enforced")), 0644); err != nil {
+		t.Fatalf("could not write policy file: %s", err)
+	}
+	if err := os.WriteFile(dir+"/a.json", []byte(`{"name": "a", "current_node_count": 1}`), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		SilentMode:   true,
+		FailFast:     true,
+		Suppressions: suppressions,
+		Clusters:     []ConfigCluster{{File: dir + "/a.json"}},
+		Policies:     []ConfigPolicy{{LocalDirectory: dir}},
+		Outputs:      []ConfigOutput{{Format: OutputFormatJSON, File: dir + "/results.json"}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.ClusterReview(); err != ErrEnforcedViolations {
+		t.Errorf("err = %v; want %v", err, ErrEnforcedViolations)
+	}
+	data, err := os.ReadFile(dir + "/results.json")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	report := &outputs.JSONReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(report.Results) != 1 {
+		t.Fatalf("results = %v; want %v", len(report.Results), 1)
+	}
+	violated := report.Results[0].Violated["Test"]
+	if len(violated) != 1 {
+		t.Fatalf("violated policies = %v; want %v", len(violated), 1)
+	}
+	if violated[0].Name != "gke.policy.enforced" {
+		t.Errorf("violated policy = %v; want %v", violated[0].Name, "gke.policy.enforced")
+	}
+}
+
+func TestClusterReview_evaluationError(t *testing.T) {
+	dir := t.TempDir()
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"#   input_selector: input.node_config\n" +
+		"package gke.policy.machine_type\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.machine_type == \"e2-micro\"; msg := \"machine type too small\" }\n"
+	if err := os.WriteFile(dir+"/machine_type.rego", []byte(policyContent), 0644); err != nil {
+		t.Fatalf("could not write policy file: %s", err)
+	}
+	inputs := map[string]string{
+		"a": `{"name": "a", "node_config": {"machine_type": "e2-standard-4"}}`,
+		"b": `{"name": "b"}`,
+		"c": `{"name": "c", "node_config": {"machine_type": "e2-standard-4"}}`,
+	}
+	for name, input := range inputs {
+		if err := os.WriteFile(dir+"/"+name+".json", []byte(input), 0644); err != nil {
+			t.Fatalf("could not write input file: %s", err)
+		}
+	}
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		SilentMode: true,
+		Clusters:   []ConfigCluster{{File: dir + "/a.json"}, {File: dir + "/b.json"}, {File: dir + "/c.json"}},
+		Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		Outputs:    []ConfigOutput{{Format: OutputFormatJSON, File: dir + "/results.json"}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.ClusterReview(); !errors.Is(err, ErrEvaluationErrors) {
+		t.Errorf("err = %v; want %v", err, ErrEvaluationErrors)
+	}
+	data, err := os.ReadFile(dir + "/results.json")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	report := &outputs.JSONReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(report.Results) != 3 {
+		t.Fatalf("results = %v; want %v", len(report.Results), 3)
+	}
+	for i, result := range report.Results {
+		expectErr := result.Cluster == dir+"/b.json"
+		if (result.Error != "") != expectErr {
+			t.Errorf("result [%d]: error = %q; want error %v", i, result.Error, expectErr)
+		}
+	}
+}
+
+// recordingStreamWriter records results written to it, as a result writer or as a stream.
+type recordingStreamWriter struct {
+	written  int
+	streamed []string
+}
+
+func (r *recordingStreamWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	r.written++
+	return nil
+}
+
+func (r *recordingStreamWriter) WriteResult(result *policy.PolicyEvaluationResult) error {
+	r.streamed = append(r.streamed, result.ClusterName)
+	return nil
+}
+
+func TestClusterReview_jsonl(t *testing.T) {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	if err := os.WriteFile(dir+"/a.json", []byte(`{"name": "a", "current_node_count": 1}`), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		SilentMode: true,
+		Clusters:   []ConfigCluster{{File: dir + "/a.json"}, {File: dir + "/missing.json"}},
+		Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		Outputs:    []ConfigOutput{{File: dir + "/results.jsonl"}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	recorder := &recordingStreamWriter{}
+	pa.resultWriters = append(pa.resultWriters, recorder)
+	if err := pa.ClusterReview(); !errors.Is(err, ErrEvaluationErrors) {
+		t.Errorf("err = %v; want %v", err, ErrEvaluationErrors)
+	}
+	sort.Strings(recorder.streamed)
+	if recorder.written != 0 || !reflect.DeepEqual(recorder.streamed, []string{dir + "/a.json", dir + "/missing.json"}) {
+		t.Errorf("written, streamed = %v, %v; want 0, both clusters", recorder.written, recorder.streamed)
+	}
+	data, err := os.ReadFile(dir + "/results.jsonl")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("len(lines) = %v; want %v", len(lines), 2)
+	}
+	for i, line := range lines {
+		result := &outputs.JSONLineResult{}
+		if err := json.Unmarshal([]byte(line), result); err != nil {
+			t.Fatalf("line [%d]: err = %v; want nil", i, err)
+		}
+		expectErr := result.Cluster == dir+"/missing.json"
+		if (result.Error != "") != expectErr {
+			t.Errorf("line [%d]: error = %q; want error %v", i, result.Error, expectErr)
+		}
+	}
+}
+
+// signalingWriter closes written on the first write.
+type signalingWriter struct {
+	written chan struct{}
+	once    sync.Once
+}
+
+func (w *signalingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.written) })
+	return len(p), nil
+}
+
+func TestClusterReview_streamsBeforeFetchCompletes(t *testing.T) {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	if err := os.WriteFile(dir+"/a.json", []byte(`{"name": "a", "current_node_count": 1}`), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	stdin, stdinWriter := io.Pipe()
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput(), stdin: stdin}
+	config := &ConfigNg{
+		SilentMode: true,
+		Clusters:   []ConfigCluster{{File: dir + "/a.json"}, {File: StdinInputFile}},
+		Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	w := &signalingWriter{written: make(chan struct{})}
+	pa.resultWriters = []outputs.ResultWriter{outputs.NewJSONLResultWriter(w)}
+	done := make(chan error, 1)
+	go func() {
+		done <- pa.ClusterReview()
+	}()
+	select {
+	case <-w.written:
+	case <-time.After(10 * time.Second):
+		t.Errorf("no result is written while a cluster is fetched; want first result written")
+	}
+	stdinWriter.Write([]byte(`{"name": "b", "current_node_count": 3}`))
+	stdinWriter.Close()
+	if err := <-done; !errors.Is(err, ErrEnforcedViolations) {
+		t.Errorf("err = %v; want %v", err, ErrEnforcedViolations)
+	}
+}
+
+func TestNewConfigFromCli_noCluster(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{LocalDirectories: []string{"/path/to/policies"}})
+	if len(config.Clusters) != 0 {
+		t.Errorf("len(clusters) = %v; want %v", len(config.Clusters), 0)
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	validPolicy := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.valid\n" +
+		"p = 1\n"
+	invalidPolicy := "# METADATA\n" +
+		"# title: Test\n" +
+		"package gke.policy.invalid\n" +
+		"p = 1\n"
+	testPolicy := "package gke.policy.valid_test\n" +
+		"test_p { true }\n"
+	unusedVarPolicy := strings.Replace(validPolicy, "p = 1", "p { x := 1; true }", 1)
+	inputs := []struct {
+		files    map[string]string
+		strict   bool
+		expected error
+	}{
+		{map[string]string{"valid.rego": validPolicy, "valid_test.rego": testPolicy}, false, nil},
+		{map[string]string{"valid.rego": validPolicy, "invalid.rego": invalidPolicy}, false, ErrInvalidPolicies},
+		{map[string]string{"broken.rego": "package gke.policy.broken\np = "}, false, ErrInvalidPolicies},
+		{map[string]string{"valid.rego": unusedVarPolicy}, false, nil},
+		{map[string]string{"valid.rego": unusedVarPolicy}, true, ErrInvalidPolicies},
+		{map[string]string{}, false, ErrInvalidPolicies},
+	}
+	for i, input := range inputs {
+		dir := t.TempDir()
+		for name, content := range input.files {
+			if err := os.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+				t.Fatalf("could not write policy file: %s", err)
+			}
+		}
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		if err := pa.LoadConfig(&ConfigNg{Policies: []ConfigPolicy{{LocalDirectory: dir}}, StrictCompile: input.strict}); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if err := pa.PolicyCheck(); err != input.expected {
+			t.Errorf("input [%d]: err = %v; want %v", i, err, input.expected)
+		}
+	}
+}
+
+func TestLoadConfig_tags(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		SilentMode: true,
+		Tags:       []string{" Networking", "IAM"},
+		TagMatch:   "Any",
+		Clusters:   []ConfigCluster{{File: "cluster.json"}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(pa.config.Tags, []string{"networking", "iam"}) {
+		t.Errorf("tags = %v; want %v", pa.config.Tags, []string{"networking", "iam"})
+	}
+	if pa.matchAllTags() {
+		t.Errorf("matchAllTags = true; want false")
+	}
+	pa = PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	if err := pa.LoadConfig(&ConfigNg{SilentMode: true, TagMatch: "some"}); err == nil {
+		t.Errorf("err = nil; want error for invalid tag match")
+	}
+}
+
+func TestPolicyList(t *testing.T) {
+	policyTemplate := "# METADATA\n" +
+		"# title: %s\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: %s\n" +
+		"#   severity: HIGH\n" +
+		"package gke.policy.%s\n" +
+		"p = 1\n"
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.rego":       fmt.Sprintf(policyTemplate, "Policy A", "Security", "policy_a"),
+		"b.rego":       fmt.Sprintf(policyTemplate, "Policy B", "Management", "policy_b"),
+		"invalid.rego": "# METADATA\n# title: Invalid\npackage gke.policy.invalid\np = 1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write policy file: %s", err)
+		}
+	}
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	config := &ConfigNg{
+		SilentMode:   true,
+		ListPolicies: true,
+		Exclude:      "policy_b",
+		Clusters:     []ConfigCluster{{ID: "projects/p/locations/l/clusters/c"}},
+		Policies:     []ConfigPolicy{{LocalDirectory: dir}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if pa.gke != nil {
+		t.Errorf("gke client is created; want nil when listing policies")
+	}
+	buff := new(bytes.Buffer)
+	pa.out = &Output{w: buff}
+	if err := pa.PolicyList(); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	out := buff.String()
+	if !strings.Contains(out, "gke.policy.policy_a  Policy A  Security  HIGH") {
+		t.Errorf("output = %q; want listed policy_a", out)
+	}
+	if strings.Contains(out, "gke.policy.policy_b") {
+		t.Errorf("output = %q; want policy_b excluded", out)
+	}
+	if !strings.Contains(out, "Policies: 1 listed, 1 with metadata errors.") {
+		t.Errorf("output = %q; want summary with counts", out)
+	}
+}
+
+func TestNewConfigFromCli_clusterIDs(t *testing.T) {
+	ids := []string{
+		"projects/p/locations/l/clusters/one",
+		"projects/p/locations/l/clusters/two",
+	}
+	config := newConfigFromCli(&CliConfig{ClusterIDs: ids})
+	expected := []ConfigCluster{{ID: ids[0]}, {ID: ids[1]}}
+	if !reflect.DeepEqual(config.Clusters, expected) {
+		t.Errorf("clusters = %v; want %v", config.Clusters, expected)
+	}
+}
+
+func TestGetClusterInputs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/good.json", []byte(`{"name": "good"}`), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	if err := os.WriteFile(dir+"/bad.json", []byte(`{"name": `), 0644); err != nil {
+		t.Fatalf("could not write input file: %s", err)
+	}
+	clusters := []ConfigCluster{
+		{File: dir + "/good.json"},
+		{File: dir + "/bad.json"},
+		{File: dir + "/missing.json"},
+		{File: dir + "/good.json"},
+	}
+	pa := PolicyAutomationApp{
+		ctx:    context.Background(),
+		out:    NewSilentOutput(),
+		config: &ConfigNg{Clusters: clusters, ClusterFetchConcurrency: 2},
+	}
+	inputs := pa.getClusterInputs()
+	if len(inputs) != len(clusters) {
+		t.Fatalf("len(inputs) = %v; want %v", len(inputs), len(clusters))
+	}
+	for i, expectErr := range []bool{false, true, true, false} {
+		if inputs[i].name != clusters[i].File {
+			t.Errorf("input [%d] name = %v; want %v", i, inputs[i].name, clusters[i].File)
+		}
+		if (inputs[i].err != nil) != expectErr {
+			t.Errorf("input [%d] err = %v; want error: %v", i, inputs[i].err, expectErr)
+		}
+	}
+}
+
+func TestPrintRemediation(t *testing.T) {
+	var buff bytes.Buffer
+	colorize := NewColorize()
+	colorize.Disable = true
+	pa := PolicyAutomationApp{out: &Output{w: &buff, colorize: colorize}}
+	pa.printRemediation(&policy.Policy{Remediation: "Enable private nodes"}, "red")
+	expected := "    Remediation: Enable private nodes\n"
+	if buff.String() != expected {
+		t.Errorf("printRemediation produced %q; want %q", buff.String(), expected)
+	}
+	buff.Reset()
+	pa.printRemediation(&policy.Policy{}, "red")
+	if buff.Len() != 0 {
+		t.Errorf("printRemediation produced %q; want empty output", buff.String())
+	}
+}
+
+func TestPrintTruncated(t *testing.T) {
+	var buff bytes.Buffer
+	colorize := NewColorize()
+	colorize.Disable = true
+	pa := PolicyAutomationApp{out: &Output{w: &buff, colorize: colorize}}
+	pa.printTruncated(&policy.Policy{TruncatedViolations: 5}, "red")
+	expected := "    (+5 more violations over the limit)\n"
+	if buff.String() != expected {
+		t.Errorf("printTruncated produced %q; want %q", buff.String(), expected)
+	}
+	buff.Reset()
+	pa.printTruncated(&policy.Policy{}, "red")
+	if buff.Len() != 0 {
+		t.Errorf("printTruncated produced %q; want empty output", buff.String())
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	inputs := map[error]int{
+		nil:                   ExitCodeClean,
+		ErrEnforcedViolations: ExitCodeViolations,
+		ErrEvaluationErrors:   ExitCodeErrors,
+		ErrInvalidPolicies:    ExitCodeErrors,
+		ErrInterrupted:        ExitCodeInterrupted,
+		ErrFailedTests:        ExitCodeViolations,
+		fmt.Errorf("%w: wrapped", ErrEnforcedViolations): ExitCodeViolations,
+	}
+	for err, expected := range inputs {
+		if code := ExitCode(err); code != expected {
+			t.Errorf("exitCode(%v) = %v; want %v", err, code, expected)
+		}
+	}
+}
+
+func TestReviewError(t *testing.T) {
+	violated := policy.NewPolicyEvaluationResult()
+	violated.AddPolicy(&policy.Policy{Name: "high", Group: "group", Severity: policy.SeverityHigh, Violations: []string{"error"}})
+	networking := policy.NewPolicyEvaluationResult()
+	networking.AddPolicy(&policy.Policy{Name: "low", Group: "Networking", Severity: policy.SeverityLow, Violations: []string{"error"}})
+	errored := policy.NewPolicyEvaluationResult()
+	errored.AddPolicy(&policy.Policy{Name: "errored", ProcessingErrors: []error{fmt.Errorf("error")}})
+	inputs := []struct {
+		failOn       string
+		failOnGroups []string
+		results      []*policy.PolicyEvaluationResult
+		expected     int
+	}{
+		{"", nil, []*policy.PolicyEvaluationResult{policy.NewPolicyEvaluationResult()}, ExitCodeClean},
+		{"", nil, []*policy.PolicyEvaluationResult{violated}, ExitCodeViolations},
+		{policy.SeverityHigh, nil, []*policy.PolicyEvaluationResult{violated}, ExitCodeViolations},
+		{policy.SeverityCritical, nil, []*policy.PolicyEvaluationResult{violated}, ExitCodeClean},
+		{"", nil, []*policy.PolicyEvaluationResult{violated, errored}, ExitCodeErrors},
+		{"", []string{"networking"}, []*policy.PolicyEvaluationResult{violated}, ExitCodeClean},
+		{"", []string{"networking"}, []*policy.PolicyEvaluationResult{violated, networking}, ExitCodeViolations},
+		{"", []string{"iam", "group"}, []*policy.PolicyEvaluationResult{violated, networking}, ExitCodeViolations},
+		{policy.SeverityHigh, []string{"networking"}, []*policy.PolicyEvaluationResult{violated, networking}, ExitCodeClean},
+		{policy.SeverityLow, []string{"networking"}, []*policy.PolicyEvaluationResult{networking}, ExitCodeViolations},
+	}
+	for i, input := range inputs {
+		pa := PolicyAutomationApp{config: &ConfigNg{FailOn: input.failOn, FailOnGroups: input.failOnGroups}}
+		if code := ExitCode(pa.reviewError(input.results)); code != input.expected {
+			t.Errorf("input [%d]: exitCode = %v; want %v", i, code, input.expected)
+		}
+	}
+}
+
+func TestGetClusterInputs_stdin(t *testing.T) {
+	inputs := map[string]bool{
+		`{"name": "warsaw"}`: false,
+		`{"name": "warsaw"`:  true,
+	}
+	for data, expectErr := range inputs {
+		pa := PolicyAutomationApp{
+			ctx:    context.Background(),
+			out:    NewSilentOutput(),
+			config: &ConfigNg{Clusters: []ConfigCluster{{File: StdinInputFile}}},
+			stdin:  strings.NewReader(data),
+		}
+		clusterInputs := pa.getClusterInputs()
+		if len(clusterInputs) != 1 {
+			t.Fatalf("len(inputs) = %v; want %v", len(clusterInputs), 1)
+		}
+		if (clusterInputs[0].err != nil) != expectErr {
+			t.Errorf("input %s: err = %v; want error: %v", data, clusterInputs[0].err, expectErr)
+		}
+		if !expectErr && clusterInputs[0].input["name"] != "warsaw" {
+			t.Errorf("input name = %v; want %v", clusterInputs[0].input["name"], "warsaw")
+		}
+	}
+}
+
+func TestLoadConfig_multipleStdinClusters(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	config := &ConfigNg{Clusters: []ConfigCluster{{File: StdinInputFile}, {File: StdinInputFile}}}
+	if err := pa.LoadConfig(config); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
+
+func TestLoadConfig_timeout(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{Timeout: "1m"}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	defer pa.Close()
+	if pa.timeout != time.Minute {
+		t.Errorf("timeout = %v; want %v", pa.timeout, time.Minute)
+	}
+	if _, ok := pa.ctx.Deadline(); !ok {
+		t.Errorf("context has no deadline; want deadline")
+	}
+	for _, timeout := range []string{"bogus", "-1s"} {
+		pa := PolicyAutomationApp{ctx: context.Background()}
+		if err := pa.LoadConfig(&ConfigNg{Timeout: timeout}); err == nil {
+			t.Errorf("timeout %q: err is nil; want error", timeout)
+		}
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	pa := PolicyAutomationApp{ctx: ctx, timeout: time.Second}
+	err := pa.timeoutError(errors.New("rpc error: code = DeadlineExceeded"))
+	if err == nil || err.Error() != "cluster review timed out after 1s" {
+		t.Errorf("err = %v; want timeout error", err)
+	}
+	pa = PolicyAutomationApp{ctx: context.Background(), timeout: time.Second}
+	original := errors.New("not found")
+	if err := pa.timeoutError(original); err != original {
+		t.Errorf("err = %v; want %v", err, original)
+	}
+}
+
+func TestNewConfigFromCli_timeout(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{Timeout: 90 * time.Second})
+	if config.Timeout != "1m30s" {
+		t.Errorf("timeout = %v; want %v", config.Timeout, "1m30s")
+	}
+}
+
+func TestLoadConfig_filters(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{Include: "control_plane", Exclude: "endpoint$"}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if pa.includeRe == nil || pa.excludeRe == nil {
+		t.Errorf("include or exclude expression is nil; want compiled expressions")
+	}
+	for _, config := range []*ConfigNg{{Include: "("}, {Exclude: "["}} {
+		pa := PolicyAutomationApp{ctx: context.Background()}
+		if err := pa.LoadConfig(config); err == nil {
+			t.Errorf("include %q exclude %q: err is nil; want error", config.Include, config.Exclude)
+		}
+	}
+}
+
+func TestNewConfigFromCli_allClusters(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{
+		AllClusters:     true,
+		ProjectName:     "test-project",
+		ClusterLocation: "europe-central2",
+	})
+	if len(config.Clusters) != 0 {
+		t.Errorf("len(clusters) = %v; want %v", len(config.Clusters), 0)
+	}
+	expected := []ConfigDiscovery{{Project: "test-project", Location: "europe-central2"}}
+	if !reflect.DeepEqual(config.Discovery, expected) {
+		t.Errorf("discovery = %v; want %v", config.Discovery, expected)
+	}
+}
+
+func TestLoadConfig_discoveryWithoutProject(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{Discovery: []ConfigDiscovery{{Location: "europe-central2"}}}); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
+
+func TestLoadConfig_invalidLogLevel(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{LogLevel: "verbose"}); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
+
+func TestLoadConfig_invalidSeverityOverride(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	config := &ConfigNg{SeverityOverrides: map[string]string{"gke.policy.node_count": "urgent"}}
+	if err := pa.LoadConfig(config); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
+
+func TestNewConfigFromCli_bundle(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{Bundle: "https://example.com/bundle.tar.gz"})
+	expected := []ConfigPolicy{{Bundle: "https://example.com/bundle.tar.gz"}}
+	if !reflect.DeepEqual(config.Policies, expected) {
+		t.Errorf("policies = %v; want %v", config.Policies, expected)
+	}
+}
+
+func TestNewConfigFromCli_policyURL(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{PolicyURL: "https://example.com/policies.zip"})
+	expected := []ConfigPolicy{{URL: "https://example.com/policies.zip"}}
+	if !reflect.DeepEqual(config.Policies, expected) {
+		t.Errorf("policies = %v; want %v", config.Policies, expected)
+	}
+}
+
+func TestNewConfigFromCli_noDeprecated(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{NoDeprecated: true})
+	if !config.NoDeprecated {
+		t.Errorf("noDeprecated = %v; want %v", config.NoDeprecated, true)
+	}
+}
+
+func TestWarnDeprecated(t *testing.T) {
+	buff := new(bytes.Buffer)
+	pa := PolicyAutomationApp{ctx: context.Background(), out: &Output{w: buff}}
+	pa.warnDeprecated([]*policy.Policy{
+		{Name: "gke.policy.old"},
+		{Name: "gke.policy.older", DeprecatedMessage: "use gke.policy.new"},
+	})
+	out := buff.String()
+	if !strings.Contains(out, "policy gke.policy.old is deprecated\n") {
+		t.Errorf("output = %q; want warning for gke.policy.old", out)
+	}
+	if !strings.Contains(out, "policy gke.policy.older is deprecated: use gke.policy.new") {
+		t.Errorf("output = %q; want warning with message for gke.policy.older", out)
+	}
+}
+
+func TestLoadConfig_color(t *testing.T) {
+	for color, expectErr := range map[string]bool{"": false, "auto": false, "Always": false, "never": false, "sometimes": true} {
+		pa := PolicyAutomationApp{ctx: context.Background()}
+		if err := pa.LoadConfig(&ConfigNg{Color: color}); (err != nil) != expectErr {
+			t.Errorf("color %q: err = %v; want error: %v", color, err, expectErr)
+		}
+	}
+}
+
+func TestLoadConfig_query(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{Query: "data.company.gke.results"}); err != nil {
+		t.Errorf("err = %v; want nil", err)
+	}
+	pa = PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{Query: "input.gke"}); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+	config := newConfigFromCli(&CliConfig{Query: "data.company.gke.results"})
+	if config.Query != "data.company.gke.results" {
+		t.Errorf("query = %v; want %v", config.Query, "data.company.gke.results")
+	}
+}
+
+func TestLoadConfig_ruleNames(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{ViolationRule: "deny", ValidRule: "allow"}); err != nil {
+		t.Errorf("err = %v; want nil", err)
+	}
+	pa = PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{ValidRule: "allow"}); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+	pa = PolicyAutomationApp{ctx: context.Background(), config: &ConfigNg{ViolationRule: "deny[0]"}}
+	if _, err := pa.newPolicyAgent(); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+	config := newConfigFromCli(&CliConfig{ViolationRule: "deny", ValidRule: "allow"})
+	if config.ViolationRule != "deny" || config.ValidRule != "allow" {
+		t.Errorf("rules = %v, %v; want %v, %v", config.ViolationRule, config.ValidRule, "deny", "allow")
+	}
+}
+
+func TestClusterReview_dataFiles(t *testing.T) {
+	dir := t.TempDir()
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.node_count\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.current_node_count < data.limits.nodes.min; msg := \"not enough nodes\" }\n"
+	files := map[string]string{
+		"node_count.rego": policyContent,
+		"cluster.json":    `{"name": "warsaw", "current_node_count": 2}`,
+		"min_one.json":    `{"nodes": {"min": 1}}`,
+		"min_three.json":  `{"nodes": {"min": 3}}`,
+		"max.json":        `{"nodes": {"max": 10}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write file: %s", err)
+		}
+	}
+	inputs := []struct {
+		dataFiles []string
+		expected  error
+	}{
+		{[]string{"min_one.json", "max.json"}, nil},
+		{[]string{"min_three.json", "max.json"}, ErrEnforcedViolations},
+	}
+	for _, input := range inputs {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode: true,
+			DataPath:   "limits",
+			Clusters:   []ConfigCluster{{File: dir + "/cluster.json"}},
+			Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		}
+		for _, file := range input.dataFiles {
+			config.DataFiles = append(config.DataFiles, dir+"/"+file)
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if err := pa.ClusterReview(); err != input.expected {
+			t.Errorf("data files %v: err = %v; want %v", input.dataFiles, err, input.expected)
+		}
+	}
+
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		SilentMode: true,
+		DataFiles:  []string{dir + "/min_one.json", dir + "/min_three.json"},
+		Clusters:   []ConfigCluster{{File: dir + "/cluster.json"}},
+		Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.ClusterReview(); err == nil || err == ErrEnforcedViolations {
+		t.Errorf("conflicting data files: err = %v; want data error", err)
+	}
+}
+
+func TestClusterReview_dumpInput(t *testing.T) {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	files := map[string]string{
+		"one.json": `{"name": "one", "current_node_count": 2}`,
+		"two.json": `{"name": "two", "current_node_count": 5}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write file: %s", err)
+		}
+	}
+	inputs := []struct {
+		clusters []string
+		dumps    map[string]string
+	}{
+		{[]string{"one.json"}, map[string]string{"input.json": "one"}},
+		{[]string{"one.json", "two.json"}, map[string]string{"input-1.json": "one", "input-2.json": "two"}},
+	}
+	for _, input := range inputs {
+		dumpDir := t.TempDir()
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode: true,
+			DumpInput:  dumpDir + "/input.json",
+			Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		}
+		for _, cluster := range input.clusters {
+			config.Clusters = append(config.Clusters, ConfigCluster{File: dir + "/" + cluster})
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if err := pa.ClusterReview(); !errors.Is(err, ErrEnforcedViolations) {
+			t.Errorf("err = %v; want %v", err, ErrEnforcedViolations)
+		}
+		for name, clusterName := range input.dumps {
+			data, err := os.ReadFile(dumpDir + "/" + name)
+			if err != nil {
+				t.Fatalf("could not read dump: %s", err)
+			}
+			dumped := make(map[string]interface{})
+			if err := json.Unmarshal(data, &dumped); err != nil {
+				t.Fatalf("could not parse dump %s: %s", name, err)
+			}
+			if dumped["name"] != clusterName {
+				t.Errorf("dump %s: name = %v; want %v", name, dumped["name"], clusterName)
+			}
+			if _, ok := dumped["node_pools"]; !ok {
+				t.Errorf("dump %s: node_pools is not set; want assembled input", name)
+			}
+		}
+	}
+}
+
+func TestLoadPolicyFiles_embeddedDefault(t *testing.T) {
+	pa := &PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput(), config: &ConfigNg{}}
+	files, err := pa.loadPolicyFiles()
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(files) == 0 {
+		t.Errorf("len(files) = 0; want embedded policy files")
+	}
+	for _, file := range files {
+		if strings.Contains(file.Name, "_test") {
+			t.Errorf("file %s is embedded; want no policy tests", file.FullName)
+		}
+	}
+}
+
+func TestLoadPolicyFiles_noDefaultPolicies(t *testing.T) {
+	pa := &PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput(), config: &ConfigNg{NoDefaultPolicies: true}}
+	if _, err := pa.loadPolicyFiles(); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
+
+func TestClusterReview_outputFile(t *testing.T) {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	files := map[string]string{
+		"one.json": `{"name": "one", "current_node_count": 2}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write file: %s", err)
+		}
+	}
+	outputFile := t.TempDir() + "/report.html"
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		SilentMode: true,
+		Clusters:   []ConfigCluster{{File: dir + "/one.json"}},
+		Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		Outputs:    []ConfigOutput{{File: outputFile}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.ClusterReview(); err != ErrEnforcedViolations {
+		t.Fatalf("err = %v; want %v", err, ErrEnforcedViolations)
+	}
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !strings.Contains(string(data), "<li>not enough nodes</li>") {
+		t.Errorf("output file = %q; want HTML report with violation", data)
+	}
+}
+
+func TestClusterReview_manifest(t *testing.T) {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	files := map[string]string{
+		"one.json": `{"name": "one", "current_node_count": 5}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write file: %s", err)
+		}
+	}
+	manifestFile := t.TempDir() + "/manifest.json"
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	config := &ConfigNg{
+		SilentMode: true,
+		Manifest:   manifestFile,
+		Clusters:   []ConfigCluster{{File: dir + "/one.json"}},
+		Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.ClusterReview(); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	manifest := &policy.PolicyManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0].Name != "gke.policy.node_count" || len(manifest.Files[0].SHA256) != 64 {
+		t.Errorf("manifest files = %+v; want gke.policy.node_count with hash", manifest.Files)
+	}
+}
+
+func TestLoadConfig_outputFileDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/file", []byte{}, 0644); err != nil {
+		t.Fatalf("could not write file: %s", err)
+	}
+	for _, path := range []string{dir + "/missing/report.json", dir + "/file/report.json"} {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		err := pa.LoadConfig(&ConfigNg{
+			Clusters: []ConfigCluster{{File: "cluster.json"}},
+			Outputs:  []ConfigOutput{{File: path}},
+		})
+		if err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("path %s: err = %v; want error with the path", path, err)
+		}
+	}
+}
+
+func TestLoadConfig_outputFileFormat(t *testing.T) {
+	inputs := []ConfigOutput{
+		{File: "report.txt"},
+		{Format: OutputFormatText, File: "report.json"},
+	}
+	for _, input := range inputs {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		err := pa.LoadConfig(&ConfigNg{
+			Clusters: []ConfigCluster{{File: "cluster.json"}},
+			Outputs:  []ConfigOutput{input},
+		})
+		if err == nil {
+			t.Errorf("output %+v: err = nil; want error", input)
+		}
+	}
+}
+
+func TestLoadConfig_groupBy(t *testing.T) {
+	inputs := map[string]bool{"": true, "group": true, "Severity": true, "owner": false}
+	for groupBy, valid := range inputs {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		err := pa.LoadConfig(&ConfigNg{
+			GroupBy:  groupBy,
+			Clusters: []ConfigCluster{{File: "cluster.json"}},
+		})
+		if (err == nil) != valid {
+			t.Errorf("groupBy %q: err = %v; want valid %v", groupBy, err, valid)
+		}
+	}
+}
+
+func TestReviewClusters_interrupted(t *testing.T) {
+	dir := t.TempDir()
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.test\n" +
+		"default valid = true\n"
+	if err := os.WriteFile(dir+"/test.rego", []byte(policyContent), 0644); err != nil {
+		t.Fatalf("could not write file: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	pa := PolicyAutomationApp{ctx: ctx, out: &Output{w: &out}, config: &ConfigNg{
+		Policies: []ConfigPolicy{{LocalDirectory: dir}},
+	}}
+	inputsFn := func() (<-chan *clusterInput, int) {
+		cancel()
+		return sendClusterInputs([]*clusterInput{
+			{name: "one"},
+			{name: "two"},
+		})
+	}
+	err := pa.reviewClusters(inputsFn)
+	if err != ErrInterrupted {
+		t.Fatalf("err = %v; want %v", err, ErrInterrupted)
+	}
+	if !strings.Contains(out.String(), "Review was interrupted, results of 0 of 2 clusters are reported.") {
+		t.Errorf("output = %q; want interruption note", out.String())
+	}
+}
+
+func TestLoadConfig_missingClusterParameters(t *testing.T) {
+	inputs := map[string]ConfigCluster{
+		"location, name":          {Project: "project"},
+		"project":                 {Name: "cluster", Location: "europe-central2"},
+		"project, location, name": {},
+	}
+	for expected, cluster := range inputs {
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		err := pa.LoadConfig(&ConfigNg{Clusters: []ConfigCluster{cluster}})
+		if err == nil {
+			t.Errorf("cluster %+v: err = nil; want error", cluster)
+			continue
+		}
+		if !strings.Contains(err.Error(), "missing "+expected+" (") {
+			t.Errorf("cluster %+v: err = %v; want it to list missing %s", cluster, err, expected)
+		}
+	}
+	for _, cluster := range []ConfigCluster{{ID: "projects/p/locations/l/clusters/c"}, {File: "cluster.json"}} {
+		if missing := missingClusterParameters(cluster); len(missing) != 0 {
+			t.Errorf("cluster %+v: missing = %v; want none", cluster, missing)
+		}
+	}
+}
+
+func TestLoadConfig_count(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	err := pa.LoadConfig(&ConfigNg{
+		Count:    "Errored",
+		Clusters: []ConfigCluster{{File: "cluster.json"}},
+		Outputs:  []ConfigOutput{{Format: OutputFormatJSON}},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if pa.config.Count != outputs.CountErrored {
+		t.Errorf("count = %q; want %q", pa.config.Count, outputs.CountErrored)
+	}
+	if len(pa.resultWriters) != 1 {
+		t.Errorf("len(resultWriters) = %d; want 1", len(pa.resultWriters))
+	}
+	if pa.out.w != io.Discard {
+		t.Errorf("output is not silent")
+	}
+	err = pa.LoadConfig(&ConfigNg{Count: "valid", Clusters: []ConfigCluster{{File: "cluster.json"}}})
+	if err == nil {
+		t.Errorf("count valid: err = nil; want error")
+	}
+}
+
+func TestNewOutputsFromCli(t *testing.T) {
+	inputs := []struct {
+		formats  string
+		file     string
+		expected []ConfigOutput
+	}{
+		{"", "", nil},
+		{"json", "", []ConfigOutput{{Format: "json"}}},
+		{"html", "report.html", []ConfigOutput{{Format: "html", File: "report.html"}}},
+		{"", "report.html", []ConfigOutput{{File: "report.html"}}},
+		{"text, sarif:out/report.sarif", "", []ConfigOutput{{Format: "text"}, {Format: "sarif", File: "out/report.sarif"}}},
+		{"sarif:report.sarif,json:report.json", "", []ConfigOutput{{Format: "sarif", File: "report.sarif"}, {Format: "json", File: "report.json"}}},
+	}
+	for _, input := range inputs {
+		outputs := newOutputsFromCli(input.formats, input.file)
+		if !reflect.DeepEqual(outputs, input.expected) {
+			t.Errorf("formats %q: outputs = %v; want %v", input.formats, outputs, input.expected)
+		}
+	}
+}
+
+func TestLoadConfig_multipleStdoutOutputs(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	err := pa.LoadConfig(&ConfigNg{
+		Clusters: []ConfigCluster{{File: "cluster.json"}},
+		Outputs:  []ConfigOutput{{Format: OutputFormatText}, {Format: OutputFormatJSON}},
+	})
+	if err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
+
+func TestLoadCliConfig_outputFileWithMultipleFormats(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	err := pa.LoadCliConfig(&CliConfig{InputFile: "cluster.json", OutputFormat: "text,json", OutputFile: "report.json"})
+	if err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
+
+func TestNewConfigFromCli_fleetProject(t *testing.T) {
+	config := newConfigFromCli(&CliConfig{FleetProject: "fleet-project"})
+	if len(config.Clusters) != 0 {
+		t.Errorf("len(clusters) = %v; want %v", len(config.Clusters), 0)
+	}
+	expected := []ConfigFleet{{Project: "fleet-project"}}
+	if !reflect.DeepEqual(config.Fleets, expected) {
+		t.Errorf("fleets = %v; want %v", config.Fleets, expected)
+	}
+}
+
+func TestLoadConfig_fleetWithoutProject(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background()}
+	if err := pa.LoadConfig(&ConfigNg{Fleets: []ConfigFleet{{}}}); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
+
+func TestPrintPrints(t *testing.T) {
+	var buff bytes.Buffer
+	colorize := NewColorize()
+	colorize.Disable = true
+	pa := PolicyAutomationApp{out: &Output{w: &buff, colorize: colorize}, config: &ConfigNg{}}
+	pol := &policy.Policy{Prints: []string{"value 5"}}
+	pa.printPrints(pol, "green")
+	if buff.Len() != 0 {
+		t.Errorf("printPrints produced %q; want empty output without show prints", buff.String())
+	}
+	pa.config.ShowPrints = true
+	pa.printPrints(pol, "green")
+	expected := "    Print: value 5\n"
+	if buff.String() != expected {
+		t.Errorf("printPrints produced %q; want %q", buff.String(), expected)
+	}
+}
+
+func TestPrintLabels(t *testing.T) {
+	var buff bytes.Buffer
+	colorize := NewColorize()
+	colorize.Disable = true
+	pa := PolicyAutomationApp{out: &Output{w: &buff, colorize: colorize}}
+	pa.printLabels(map[string]string{"team": "platform", "env": "prod"})
+	expected := "\nLabels: env=prod, team=platform\n"
+	if buff.String() != expected {
+		t.Errorf("printLabels produced %q; want %q", buff.String(), expected)
+	}
+	buff.Reset()
+	pa.printLabels(map[string]string{})
+	if buff.Len() != 0 {
+		t.Errorf("printLabels produced %q; want empty output", buff.String())
+	}
+}
+
+func TestPrintSeverityCounts(t *testing.T) {
+	var buff bytes.Buffer
+	colorize := NewColorize()
+	colorize.Disable = true
+	pa := PolicyAutomationApp{out: &Output{w: &buff, colorize: colorize}}
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.a", Group: "Security", Severity: policy.SeverityHigh, Violations: []string{"a"}})
+	failed := policy.NewPolicyEvaluationResult()
+	failed.ClusterError = errors.New("not found")
+	pa.printSeverityCounts([]*policy.PolicyEvaluationResult{result, failed})
+	expected := "\nViolated policies by severity: CRITICAL: 0  HIGH: 1  MEDIUM: 0  LOW: 0\n"
+	if buff.String() != expected {
+		t.Errorf("printSeverityCounts produced %q; want %q", buff.String(), expected)
+	}
+	buff.Reset()
+	pa.printSeverityCounts([]*policy.PolicyEvaluationResult{failed})
+	if buff.Len() != 0 {
+		t.Errorf("printSeverityCounts produced %q; want empty output without reviewed clusters", buff.String())
+	}
+}
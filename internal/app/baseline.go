@@ -0,0 +1,95 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/mikouaj/gke-review/internal/outputs"
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+// Baseline holds names of violated policies of each cluster from a previous review.
+type Baseline struct {
+	violated map[string]map[string]bool
+}
+
+// BaselineDiff compares violated policies of a cluster with the baseline.
+type BaselineDiff struct {
+	Cluster string
+	New     []*policy.Policy
+	Still   []*policy.Policy
+	Fixed   []string
+}
+
+// ReadBaseline reads baseline from a JSON report, as written by WriteBaseline or
+// the JSON output.
+func ReadBaseline(path string, readFn ReadFileFn) (*Baseline, error) {
+	data, err := readFn(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &outputs.JSONReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, err
+	}
+	baseline := &Baseline{violated: make(map[string]map[string]bool)}
+	for _, result := range report.Results {
+		violated := make(map[string]bool)
+		for _, policies := range result.Violated {
+			for _, p := range policies {
+				violated[p.Name] = true
+			}
+		}
+		baseline.violated[result.Cluster] = violated
+	}
+	return baseline, nil
+}
+
+// WriteBaseline writes evaluation results as a JSON report to be used as a baseline.
+func WriteBaseline(path string, results []*policy.PolicyEvaluationResult) error {
+	data, err := json.MarshalIndent(outputs.NewJSONReport(results), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Diff compares violated policies of the result with the baseline of the same cluster.
+// All violations of a cluster that is not in the baseline are new.
+func (b *Baseline) Diff(result *policy.PolicyEvaluationResult) *BaselineDiff {
+	diff := &BaselineDiff{Cluster: result.ClusterName}
+	previous := b.violated[result.ClusterName]
+	current := make(map[string]bool)
+	for _, group := range result.Groups() {
+		for _, p := range result.Violated[group] {
+			current[p.Name] = true
+			if previous[p.Name] {
+				diff.Still = append(diff.Still, p)
+			} else {
+				diff.New = append(diff.New, p)
+			}
+		}
+	}
+	for name := range previous {
+		if !current[name] {
+			diff.Fixed = append(diff.Fixed, name)
+		}
+	}
+	sort.Strings(diff.Fixed)
+	return diff
+}
@@ -0,0 +1,94 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+func newBaselineTestResult(cluster string, violated ...string) *policy.PolicyEvaluationResult {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = cluster
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	for _, name := range violated {
+		result.AddPolicy(&policy.Policy{Name: name, Group: "Security", Violations: []string{"violation"}})
+	}
+	return result
+}
+
+func policyNames(policies []*policy.Policy) []string {
+	names := make([]string, len(policies))
+	for i, p := range policies {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func TestBaseline(t *testing.T) {
+	path := t.TempDir() + "/baseline.json"
+	previous := []*policy.PolicyEvaluationResult{
+		newBaselineTestResult("clusterOne", "gke.policy.a", "gke.policy.b"),
+	}
+	if err := WriteBaseline(path, previous); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	baseline, err := ReadBaseline(path, os.ReadFile)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	diff := baseline.Diff(newBaselineTestResult("clusterOne", "gke.policy.b", "gke.policy.c"))
+	if names := policyNames(diff.New); !reflect.DeepEqual(names, []string{"gke.policy.c"}) {
+		t.Errorf("new = %v; want %v", names, []string{"gke.policy.c"})
+	}
+	if names := policyNames(diff.Still); !reflect.DeepEqual(names, []string{"gke.policy.b"}) {
+		t.Errorf("still = %v; want %v", names, []string{"gke.policy.b"})
+	}
+	if !reflect.DeepEqual(diff.Fixed, []string{"gke.policy.a"}) {
+		t.Errorf("fixed = %v; want %v", diff.Fixed, []string{"gke.policy.a"})
+	}
+
+	diff = baseline.Diff(newBaselineTestResult("clusterTwo", "gke.policy.a"))
+	if names := policyNames(diff.New); !reflect.DeepEqual(names, []string{"gke.policy.a"}) {
+		t.Errorf("new of other cluster = %v; want %v", names, []string{"gke.policy.a"})
+	}
+}
+
+func TestReadBaseline_negative(t *testing.T) {
+	readFn := func(path string) ([]byte, error) {
+		return []byte("not json"), nil
+	}
+	if _, err := ReadBaseline("baseline.json", readFn); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
+
+func TestReviewError_baseline(t *testing.T) {
+	baseline := &Baseline{violated: map[string]map[string]bool{
+		"clusterOne": {"gke.policy.a": true},
+	}}
+	pa := PolicyAutomationApp{config: &ConfigNg{}, baseline: baseline}
+	if err := pa.reviewError([]*policy.PolicyEvaluationResult{newBaselineTestResult("clusterOne", "gke.policy.a")}); err != nil {
+		t.Errorf("err = %v; want nil", err)
+	}
+	err := pa.reviewError([]*policy.PolicyEvaluationResult{newBaselineTestResult("clusterOne", "gke.policy.a", "gke.policy.b")})
+	if err != ErrEnforcedViolations {
+		t.Errorf("err = %v; want %v", err, ErrEnforcedViolations)
+	}
+}
@@ -14,27 +14,108 @@
 
 package app
 
-import cli "github.com/urfave/cli/v2"
+import (
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+	cli "github.com/urfave/cli/v2"
+)
 
 type CliConfig struct {
-	ConfigFile      string
-	SilentMode      bool
-	CredentialsFile string
-	ClusterName     string
-	ClusterLocation string
-	ProjectName     string
-	GitRepository   string
-	GitBranch       string
-	GitDirectory    string
-	LocalDirectory  string
+	ConfigFile        string
+	SilentMode        bool
+	LogLevel          string
+	CredentialsFile   string
+	ImpersonateSA     string
+	CACertFile        string
+	IncludeVersions   bool
+	IncludeIAM        bool
+	ExceptionsReport  bool
+	ListPolicies      bool
+	Timings           bool
+	Coverage          bool
+	Summary           bool
+	Count             bool
+	Watch             bool
+	OutputFormat      string
+	OutputFile        string
+	MinSeverity       string
+	FailOn            string
+	FailOnGroups      []string
+	WaiversFile       string
+	Baseline          string
+	WriteBaseline     string
+	Timeout           time.Duration
+	Include           string
+	Exclude           string
+	Tags              []string
+	TagMatch          string
+	GroupBy           string
+	Query             string
+	ViolationRule     string
+	ValidRule         string
+	DataFiles         []string
+	DataPath          string
+	ClusterName       string
+	ClusterLocation   string
+	ProjectName       string
+	ClusterIDs        []string
+	AllClusters       bool
+	FleetProject      string
+	InputFile         string
+	TerraformFile     string
+	DumpInput         string
+	Manifest          string
+	GitRepository     string
+	GitBranch         string
+	GitDirectory      string
+	LocalDirectories  []string
+	Bundle            string
+	PolicyURL         string
+	NoDefaultPolicies bool
+	StrictMetadata    bool
+	StrictCompile     bool
+	NoDeprecated      bool
+	Color             string
+	FailFast          bool
+	ShowPrints        bool
+	Explain           bool
+	ExplainPolicy     string
+	TrendDirectory    string
+	TrendFormat       string
+	TrendFile         string
+	QuietOnSuccess    bool
+	// ErrorsAsViolations reports policies that could not be evaluated as violated
+	ErrorsAsViolations bool
+	AllowEmpty         bool
+	Listen             string
+	MaxViolations      int
+	// IncludeSecurityPosture fetches security posture findings of clusters
+	IncludeSecurityPosture bool
+	// SetFlags are names of flags set explicitly, they override the configuration file
+	SetFlags []string
+}
+
+func (c *CliConfig) isSet(name string) bool {
+	for _, flag := range c.SetFlags {
+		if flag == name {
+			return true
+		}
+	}
+	return false
 }
 
 func NewPolicyAutomationCli(p PolicyAutomation) *cli.App {
 	app := &cli.App{
-		Name:  "gke-policy",
-		Usage: "Manage GKE policies",
+		Name:    "gke-policy",
+		Usage:   "Manage GKE policies",
+		Version: version.String(),
 		Commands: []*cli.Command{
 			CreateClusterCommand(p),
+			CreateValidateCommand(p),
+			CreateTestCommand(p),
+			CreateServeCommand(p),
 		},
 	}
 	return app
@@ -52,15 +133,31 @@ func CreateClusterCommand(p PolicyAutomation) *cli.Command {
 				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:        "config",
-						Aliases:     []string{"c"},
-						Usage:       "Path to the configuration file",
+						Aliases:     []string{"c", "profile"},
+						Usage:       "Path to the configuration file, flags set explicitly override its settings",
 						Destination: &config.ConfigFile,
 					},
+					&cli.StringFlag{
+						Name:        "log-level",
+						Usage:       "Log messages to standard error with a given level: debug, info, warn, error",
+						Destination: &config.LogLevel,
+					},
 					&cli.StringFlag{
 						Name:        "creds",
-						Usage:       "Path to GCP JSON credentials file",
+						Aliases:     []string{"credentials-file"},
+						Usage:       "Path to GCP JSON credentials file, i.e. a service account key, used instead of Application Default Credentials",
 						Destination: &config.CredentialsFile,
 					},
+					&cli.StringFlag{
+						Name:        "impersonate-service-account",
+						Usage:       "Email of a service account to impersonate when calling GKE API",
+						Destination: &config.ImpersonateSA,
+					},
+					&cli.StringFlag{
+						Name:        "ca-cert",
+						Usage:       "Path to PEM file with CA certificates to trust when calling Google APIs, i.e. of a proxy",
+						Destination: &config.CACertFile,
+					},
 					&cli.StringFlag{
 						Name:        "project",
 						Aliases:     []string{"p"},
@@ -69,8 +166,8 @@ func CreateClusterCommand(p PolicyAutomation) *cli.Command {
 					},
 					&cli.StringFlag{
 						Name:        "name",
-						Aliases:     []string{"n"},
-						Usage:       "Name of a GKE cluster to review",
+						Aliases:     []string{"n", "cluster"},
+						Usage:       "Name of a GKE cluster to review, requires project and location",
 						Destination: &config.ClusterName,
 					},
 					&cli.StringFlag{
@@ -79,37 +176,546 @@ func CreateClusterCommand(p PolicyAutomation) *cli.Command {
 						Usage:       "GKE cluster location (region or zone)",
 						Destination: &config.ClusterLocation,
 					},
+					&cli.BoolFlag{
+						Name:        "all-clusters",
+						Usage:       "Review all clusters in the project, optionally narrowed with the location flag",
+						Destination: &config.AllClusters,
+					},
+					&cli.StringFlag{
+						Name:        "fleet-project",
+						Usage:       "Fleet host project, all GKE clusters registered in its fleet are reviewed",
+						Destination: &config.FleetProject,
+					},
+					&cli.StringSliceFlag{
+						Name:  "cluster-id",
+						Usage: "Full GKE cluster identifier (projects/P/locations/L/clusters/N), can be repeated to review multiple clusters",
+					},
+					&cli.StringFlag{
+						Name:        "input-file",
+						Usage:       "Path to a JSON file with GKE cluster details, used instead of fetching the cluster. Use - to read from standard input",
+						Destination: &config.InputFile,
+					},
+					&cli.StringFlag{
+						Name:        "terraform",
+						Usage:       "Path to a JSON Terraform plan or state (terraform show -json), clusters defined in it are reviewed instead of fetched",
+						Destination: &config.TerraformFile,
+					},
+					&cli.StringFlag{
+						Name:        "dump-input",
+						Usage:       "Path to write the evaluation input of the cluster to as JSON, for debugging policies",
+						Destination: &config.DumpInput,
+					},
+					&cli.StringFlag{
+						Name:        "manifest",
+						Usage:       "Path to write a JSON manifest of compiled policy files with their SHA-256 hashes to",
+						Destination: &config.Manifest,
+					},
+					&cli.BoolFlag{
+						Name:        "include-versions",
+						Usage:       "Include available GKE versions in the evaluation input",
+						Destination: &config.IncludeVersions,
+					},
+					&cli.BoolFlag{
+						Name:        "include-iam",
+						Usage:       "Include IAM policy of the cluster project in the evaluation input",
+						Destination: &config.IncludeIAM,
+					},
+					&cli.BoolFlag{
+						Name:        "include-security-posture",
+						Usage:       "Include security posture findings of the cluster in the evaluation input, needs Container Security API",
+						Destination: &config.IncludeSecurityPosture,
+					},
+					&cli.BoolFlag{
+						Name:        "exceptions",
+						Usage:       "Report policies that were neither plainly valid nor violated, with reasons",
+						Destination: &config.ExceptionsReport,
+					},
+					&cli.BoolFlag{
+						Name:        "list-policies",
+						Usage:       "List policies that would be evaluated, without fetching and reviewing clusters",
+						Destination: &config.ListPolicies,
+					},
+					&cli.BoolFlag{
+						Name:        "timings",
+						Usage:       "Report policy evaluation times, slowest first",
+						Destination: &config.Timings,
+					},
+					&cli.BoolFlag{
+						Name:        "coverage",
+						Usage:       "Report share of rego lines of each policy file exercised by the evaluation",
+						Destination: &config.Coverage,
+					},
+					&cli.BoolFlag{
+						Name:        "summary",
+						Usage:       "Report only counts of valid, violated and errored policies per group, supported by text, json and yaml outputs",
+						Destination: &config.Summary,
+					},
+					&cli.BoolFlag{
+						Name:        "count",
+						Usage:       "Print only the number of violated policies to stdout",
+						Destination: &config.Count,
+					},
+					&cli.BoolFlag{
+						Name:        "errors-as-violations",
+						Usage:       "Report policies that could not be evaluated as violated, so they fail the review as violations",
+						Destination: &config.ErrorsAsViolations,
+					},
+					&cli.IntFlag{
+						Name:        "max-violations",
+						Usage:       "Report at most this many violations of each policy, the remaining ones are counted, 0 for the default, negative for no limit",
+						Value:       DefaultMaxViolations,
+						Destination: &config.MaxViolations,
+					},
+					&cli.BoolFlag{
+						Name:        "quiet-on-success",
+						Usage:       "Print nothing when no policies are violated and no errors occurred",
+						Destination: &config.QuietOnSuccess,
+					},
+					&cli.BoolFlag{
+						Name:        "watch",
+						Usage:       "Watch local policy directory and re-run the review whenever a REGO file changes",
+						Destination: &config.Watch,
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       "Comma separated output formats for evaluation results, optionally with file paths, i.e. text,sarif:report.sarif. Formats: text, json, jsonl, yaml, junit, sarif, cis, markdown, html, tap, line, prometheus",
+						Destination: &config.OutputFormat,
+					},
+					&cli.StringFlag{
+						Name:        "output-file",
+						Usage:       "Path to write evaluation results to instead of the standard output",
+						Destination: &config.OutputFile,
+					},
+					&cli.StringFlag{
+						Name:        "min-severity",
+						Usage:       "Minimum severity of reported violations: LOW, MEDIUM, HIGH, CRITICAL",
+						Destination: &config.MinSeverity,
+					},
+					&cli.StringFlag{
+						Name:        "fail-on",
+						Usage:       "Minimum severity of violations that fail the review: LOW, MEDIUM, HIGH, CRITICAL",
+						Destination: &config.FailOn,
+					},
+					&cli.BoolFlag{
+						Name:        "fail-fast",
+						Usage:       "Stop the review on the first errored or violated policy and report only that one",
+						Destination: &config.FailFast,
+					},
+					&cli.StringSliceFlag{
+						Name:  "fail-on-group",
+						Usage: "Fail the review only on violations of policies in a given group, can be repeated",
+					},
+					&cli.StringFlag{
+						Name:        "waivers",
+						Usage:       "Path to the YAML file with waivers for accepted policy violations",
+						Destination: &config.WaiversFile,
+					},
+					&cli.StringFlag{
+						Name:        "baseline",
+						Usage:       "Path to the baseline JSON report, only violations that are not in the baseline fail the review",
+						Destination: &config.Baseline,
+					},
+					&cli.StringFlag{
+						Name:        "write-baseline",
+						Usage:       "Path to write the evaluation results to as a baseline JSON report",
+						Destination: &config.WriteBaseline,
+					},
+					&cli.DurationFlag{
+						Name:        "timeout",
+						Usage:       "Maximum duration of the review, including fetching clusters and evaluating policies, i.e. 2m",
+						Destination: &config.Timeout,
+					},
+					&cli.StringSliceFlag{
+						Name:  "data",
+						Usage: "Path to a JSON file with data for policies, can be repeated to merge multiple files",
+					},
+					&cli.StringFlag{
+						Name:        "data-path",
+						Usage:       "Dot separated path under which data files are available to policies",
+						Value:       DefaultDataPath,
+						DefaultText: DefaultDataPath,
+						Destination: &config.DataPath,
+					},
+					&cli.StringFlag{
+						Name:        "include",
+						Usage:       "Regular expression for names of policies to evaluate, i.e. gke.policy.control_plane.*",
+						Destination: &config.Include,
+					},
+					&cli.StringFlag{
+						Name:        "exclude",
+						Usage:       "Regular expression for names of policies to skip, takes precedence over include",
+						Destination: &config.Exclude,
+					},
+					&cli.StringSliceFlag{
+						Name:  "tag",
+						Usage: "Evaluate only policies with a given tag, can be repeated",
+					},
+					&cli.StringFlag{
+						Name:        "tag-match",
+						Usage:       "Whether policies need all or any of the given tags: all, any",
+						Value:       TagMatchAll,
+						DefaultText: TagMatchAll,
+						Destination: &config.TagMatch,
+					},
+					&cli.StringFlag{
+						Name:        "group-by",
+						Usage:       "Group reported policies by: group, severity",
+						Value:       GroupByGroup,
+						DefaultText: GroupByGroup,
+						Destination: &config.GroupBy,
+					},
+					&cli.BoolFlag{
+						Name:        "strict-metadata",
+						Usage:       "Fail when policies have metadata errors, instead of evaluating them and reporting warnings",
+						Destination: &config.StrictMetadata,
+					},
+					&cli.BoolFlag{
+						Name:        "strict",
+						Usage:       "Compile policies in OPA strict mode, rejecting deprecated and unsafe constructs",
+						Destination: &config.StrictCompile,
+					},
+					&cli.BoolFlag{
+						Name:        "allow-empty",
+						Usage:       "Allow policy sources without any policy, instead of failing the review",
+						Destination: &config.AllowEmpty,
+					},
+					&cli.BoolFlag{
+						Name:        "no-deprecated",
+						Usage:       "Skip evaluation of deprecated policies",
+						Destination: &config.NoDeprecated,
+					},
+					&cli.BoolFlag{
+						Name:        "show-prints",
+						Usage:       "Show output of rego print() calls under evaluated policies, for debugging policies",
+						Destination: &config.ShowPrints,
+					},
+					&cli.BoolFlag{
+						Name:        "explain",
+						Usage:       "Print evaluation trace of a single policy, set with explain-policy, instead of the review report",
+						Destination: &config.Explain,
+					},
+					&cli.StringFlag{
+						Name:        "explain-policy",
+						Usage:       "Name of the policy to explain, i.e. gke.policy.node_pool_autoupgrade",
+						Destination: &config.ExplainPolicy,
+					},
+					&cli.StringFlag{
+						Name:        "color",
+						Usage:       "Color the output: auto (when writing to a terminal), always, never",
+						Value:       ColorAuto,
+						DefaultText: ColorAuto,
+						Destination: &config.Color,
+					},
 				}, getPolicySourceFlags(config)...),
 				Action: func(c *cli.Context) error {
 					defer p.Close()
+					config.ClusterIDs = c.StringSlice("cluster-id")
+					config.DataFiles = c.StringSlice("data")
+					config.Tags = c.StringSlice("tag")
+					config.FailOnGroups = c.StringSlice("fail-on-group")
+					config.LocalDirectories = c.StringSlice("local-policy-dir")
+					config.SetFlags = c.LocalFlagNames()
 					if err := p.LoadCliConfig(config); err != nil {
 						cli.ShowSubcommandHelp(c)
-						return err
+						return cli.Exit(err, ExitCodeErrors)
+					}
+					if config.ListPolicies {
+						if err := p.PolicyList(); err != nil {
+							return cli.Exit("", ExitCode(err))
+						}
+						return nil
+					}
+					if config.Watch {
+						if err := p.PolicyWatch(); err != nil {
+							return cli.Exit("", ExitCode(err))
+						}
+						return nil
+					}
+					if config.Explain {
+						if err := p.PolicyExplain(); err != nil {
+							return cli.Exit("", ExitCode(err))
+						}
+						return nil
+					}
+					if err := p.ClusterReview(); err != nil {
+						return cli.Exit("", ExitCode(err))
 					}
-					p.ClusterReview()
 					return nil
 				},
 			},
+			createTrendCommand(p),
+		},
+	}
+}
+
+func createTrendCommand(p PolicyAutomation) *cli.Command {
+	config := &CliConfig{}
+	return &cli.Command{
+		Name:  "trend",
+		Usage: "Evaluate policies against timestamped cluster snapshots and write the series of policy counts per day",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:        "config",
+				Aliases:     []string{"c", "profile"},
+				Usage:       "Path to the configuration file, flags set explicitly override its settings",
+				Destination: &config.ConfigFile,
+			},
+			&cli.StringFlag{
+				Name:        "log-level",
+				Usage:       "Log messages to standard error with a given level: debug, info, warn, error",
+				Destination: &config.LogLevel,
+			},
+			&cli.StringFlag{
+				Name:        "snapshot-dir",
+				Usage:       "Directory with JSON files of GKE cluster details, with timestamps in file names, i.e. 2022-03-15.json",
+				Destination: &config.TrendDirectory,
+			},
+			&cli.StringFlag{
+				Name:        "trend-format",
+				Usage:       "Format of the series: json, csv",
+				Value:       TrendFormatJSON,
+				DefaultText: TrendFormatJSON,
+				Destination: &config.TrendFormat,
+			},
+			&cli.StringFlag{
+				Name:        "trend-file",
+				Usage:       "Path to the file to write the series to, instead of the standard output",
+				Destination: &config.TrendFile,
+			},
+		}, getPolicySourceFlags(config)...),
+		Action: func(c *cli.Context) error {
+			defer p.Close()
+			config.LocalDirectories = c.StringSlice("local-policy-dir")
+			config.SetFlags = c.LocalFlagNames()
+			if err := p.LoadCliConfig(config); err != nil {
+				cli.ShowSubcommandHelp(c)
+				return cli.Exit(err, ExitCodeErrors)
+			}
+			if err := p.PolicyTrend(); err != nil {
+				return cli.Exit("", ExitCode(err))
+			}
+			return nil
+		},
+	}
+}
+
+func CreateValidateCommand(p PolicyAutomation) *cli.Command {
+	config := &CliConfig{}
+	return &cli.Command{
+		Name:  "validate",
+		Usage: "Validate policy files and their metadata",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "config",
+				Aliases:     []string{"c", "profile"},
+				Usage:       "Path to the configuration file, flags set explicitly override its settings",
+				Destination: &config.ConfigFile,
+			},
+			&cli.StringSliceFlag{
+				Name:    "local-policy-dir",
+				Aliases: []string{"policy-dir"},
+				Usage:   "Local directory with GKE policies, can be repeated to layer policy sets",
+			},
+			&cli.StringFlag{
+				Name:        "query",
+				Usage:       "Rego query resolving to the object with policy packages",
+				Value:       policy.DefaultQuery,
+				DefaultText: policy.DefaultQuery,
+				Destination: &config.Query,
+			},
+			&cli.BoolFlag{
+				Name:        "strict",
+				Usage:       "Compile policies in OPA strict mode, rejecting deprecated and unsafe constructs",
+				Destination: &config.StrictCompile,
+			},
+			&cli.BoolFlag{
+				Name:        "allow-empty",
+				Usage:       "Allow policy sources without any policy, instead of failing the validation",
+				Destination: &config.AllowEmpty,
+			},
+			&cli.StringFlag{
+				Name:        "color",
+				Usage:       "Color the output: auto (when writing to a terminal), always, never",
+				Value:       ColorAuto,
+				DefaultText: ColorAuto,
+				Destination: &config.Color,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			defer p.Close()
+			config.LocalDirectories = c.StringSlice("local-policy-dir")
+			config.SetFlags = c.LocalFlagNames()
+			if err := p.LoadCliConfig(config); err != nil {
+				cli.ShowSubcommandHelp(c)
+				return cli.Exit(err, ExitCodeErrors)
+			}
+			if err := p.PolicyCheck(); err != nil {
+				return cli.Exit("", ExitCode(err))
+			}
+			return nil
+		},
+	}
+}
+
+func CreateTestCommand(p PolicyAutomation) *cli.Command {
+	config := &CliConfig{}
+	return &cli.Command{
+		Name:  "test",
+		Usage: "Run rego unit tests of policies from _test.rego files",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:        "config",
+				Aliases:     []string{"c", "profile"},
+				Usage:       "Path to the configuration file, flags set explicitly override its settings",
+				Destination: &config.ConfigFile,
+			},
+			&cli.StringFlag{
+				Name:        "log-level",
+				Usage:       "Log messages to standard error with a given level: debug, info, warn, error",
+				Destination: &config.LogLevel,
+			},
+			&cli.StringSliceFlag{
+				Name:  "data",
+				Usage: "Path to a JSON file with data for policies, can be repeated to merge multiple files",
+			},
+			&cli.StringFlag{
+				Name:        "data-path",
+				Usage:       "Dot separated path under which data files are available to policies",
+				Value:       DefaultDataPath,
+				DefaultText: DefaultDataPath,
+				Destination: &config.DataPath,
+			},
+			&cli.BoolFlag{
+				Name:        "strict",
+				Usage:       "Compile policies in OPA strict mode, rejecting deprecated and unsafe constructs",
+				Destination: &config.StrictCompile,
+			},
+			&cli.StringFlag{
+				Name:        "color",
+				Usage:       "Color the output: auto (when writing to a terminal), always, never",
+				Value:       ColorAuto,
+				DefaultText: ColorAuto,
+				Destination: &config.Color,
+			},
+		}, getPolicySourceFlags(config)...),
+		Action: func(c *cli.Context) error {
+			defer p.Close()
+			config.DataFiles = c.StringSlice("data")
+			config.LocalDirectories = c.StringSlice("local-policy-dir")
+			config.SetFlags = c.LocalFlagNames()
+			if err := p.LoadCliConfig(config); err != nil {
+				cli.ShowSubcommandHelp(c)
+				return cli.Exit(err, ExitCodeErrors)
+			}
+			if err := p.PolicyTest(); err != nil {
+				return cli.Exit("", ExitCode(err))
+			}
+			return nil
+		},
+	}
+}
+
+func CreateServeCommand(p PolicyAutomation) *cli.Command {
+	config := &CliConfig{}
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serve on-demand reviews of cluster details posted over HTTP, with policies compiled once",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:        "config",
+				Aliases:     []string{"c", "profile"},
+				Usage:       "Path to the configuration file, flags set explicitly override its settings",
+				Destination: &config.ConfigFile,
+			},
+			&cli.StringFlag{
+				Name:        "log-level",
+				Usage:       "Log messages to standard error with a given level: debug, info, warn, error",
+				Destination: &config.LogLevel,
+			},
+			&cli.StringFlag{
+				Name:        "listen",
+				Usage:       "Address to listen on for HTTP requests",
+				Value:       DefaultListenAddress,
+				DefaultText: DefaultListenAddress,
+				Destination: &config.Listen,
+			},
+			&cli.StringSliceFlag{
+				Name:  "data",
+				Usage: "Path to a JSON file with data for policies, can be repeated to merge multiple files",
+			},
+			&cli.StringFlag{
+				Name:        "data-path",
+				Usage:       "Dot separated path under which data files are available to policies",
+				Value:       DefaultDataPath,
+				DefaultText: DefaultDataPath,
+				Destination: &config.DataPath,
+			},
+			&cli.BoolFlag{
+				Name:        "strict",
+				Usage:       "Compile policies in OPA strict mode, rejecting deprecated and unsafe constructs",
+				Destination: &config.StrictCompile,
+			},
+		}, getPolicySourceFlags(config)...),
+		Action: func(c *cli.Context) error {
+			defer p.Close()
+			config.DataFiles = c.StringSlice("data")
+			config.LocalDirectories = c.StringSlice("local-policy-dir")
+			config.SetFlags = c.LocalFlagNames()
+			if err := p.LoadCliConfig(config); err != nil {
+				cli.ShowSubcommandHelp(c)
+				return cli.Exit(err, ExitCodeErrors)
+			}
+			if err := p.PolicyServe(); err != nil {
+				return cli.Exit("", ExitCode(err))
+			}
+			return nil
 		},
 	}
 }
 
 func getPolicySourceFlags(config *CliConfig) []cli.Flag {
 	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "local-policy-dir",
+			Aliases: []string{"policy-dir"},
+			Usage:   "Local directory with GKE policies, can be repeated to layer policy sets",
+		},
 		&cli.StringFlag{
-			Name:        "local-policy-dir",
-			Usage:       "Local directory with GKE policies",
-			Destination: &config.LocalDirectory,
+			Name:        "bundle",
+			Usage:       "Path or HTTP(S) URL of OPA bundle (.tar.gz) with GKE policies",
+			Destination: &config.Bundle,
+		},
+		&cli.StringFlag{
+			Name:        "policy-url",
+			Usage:       "HTTP(S) URL of GKE policies: a single .rego file, .tar.gz or .zip archive",
+			Destination: &config.PolicyURL,
+		},
+		&cli.StringFlag{
+			Name:        "query",
+			Usage:       "Rego query resolving to the object with policy packages",
+			Value:       policy.DefaultQuery,
+			DefaultText: policy.DefaultQuery,
+			Destination: &config.Query,
+		},
+		&cli.StringFlag{
+			Name:        "violation-rule",
+			Usage:       "Evaluate rules of each policy package individually, reading violations from the rule with this name, i.e. deny",
+			Destination: &config.ViolationRule,
+		},
+		&cli.StringFlag{
+			Name:        "valid-rule",
+			Usage:       "Name of the boolean rule with the policy verdict when evaluating rules individually, by default policies without violations are valid",
+			Destination: &config.ValidRule,
 		},
 		&cli.StringFlag{
 			Name:        "git-policy-repo",
-			Usage:       "GIT repository with GKE policies",
-			Value:       DefaultGitRepository,
+			Usage:       "GIT repository with GKE policies, i.e. " + DefaultGitRepository,
 			Destination: &config.GitRepository,
 		},
 		&cli.StringFlag{
 			Name:        "git-policy-branch",
-			Usage:       "Branch name for policies GIT repository",
+			Usage:       "Branch, tag or commit SHA for policies GIT repository",
 			Value:       DefaultGitBranch,
 			DefaultText: DefaultGitBranch,
 			Destination: &config.GitBranch,
@@ -121,5 +727,10 @@ func getPolicySourceFlags(config *CliConfig) []cli.Flag {
 			DefaultText: DefaultGitPolicyDir,
 			Destination: &config.GitDirectory,
 		},
+		&cli.BoolFlag{
+			Name:        "no-default-policies",
+			Usage:       "Do not use the embedded default policies when no other policy source is given",
+			Destination: &config.NoDefaultPolicies,
+		},
 	}
 }
@@ -15,6 +15,9 @@
 package app
 
 import (
+	"os"
+	"path/filepath"
+
 	"gopkg.in/yaml.v2"
 )
 
@@ -22,15 +25,87 @@ const (
 	DefaultGitRepository = "https://github.com/mikouaj/gke-review"
 	DefaultGitBranch     = "main"
 	DefaultGitPolicyDir  = "gke-policies"
+
+	DefaultClusterFetchConcurrency = 4
+	DefaultDataPath                = "config"
+	TagMatchAll                    = "all"
+	TagMatchAny                    = "any"
+	GroupByGroup                   = "group"
+	GroupBySeverity                = "severity"
+	DefaultListenAddress           = ":8080"
+	DefaultMaxViolations           = 100
 )
 
 type ReadFileFn func(string) ([]byte, error)
 
+// DefaultConfigFile returns path of the configuration file read when none is given,
+// i.e. ~/.config/gke-policy/config.yaml. It is empty if the user configuration directory is unknown.
+func DefaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gke-policy", "config.yaml")
+}
+
 type ConfigNg struct {
-	SilentMode      bool            `yaml:"silent"`
-	CredentialsFile string          `yaml:"credentialsFile"`
-	Clusters        []ConfigCluster `yaml:"clusters"`
-	Policies        []ConfigPolicy  `yaml:"policies"`
+	SilentMode                bool                `yaml:"silent"`
+	LogLevel                  string              `yaml:"logLevel"`
+	CredentialsFile           string              `yaml:"credentialsFile"`
+	ImpersonateServiceAccount string              `yaml:"impersonateServiceAccount"`
+	CACertFile                string              `yaml:"caCertFile"`
+	IncludeVersions           bool                `yaml:"includeVersions"`
+	IncludeIAM                bool                `yaml:"includeIam"`
+	IncludeSecurityPosture    bool                `yaml:"includeSecurityPosture"`
+	ExceptionsReport          bool                `yaml:"exceptionsReport"`
+	ListPolicies              bool                `yaml:"listPolicies"`
+	Timings                   bool                `yaml:"timings"`
+	Coverage                  bool                `yaml:"coverage"`
+	DumpInput                 string              `yaml:"dumpInput"`
+	Manifest                  string              `yaml:"manifest"`
+	Summary                   bool                `yaml:"summary"`
+	Count                     string              `yaml:"count"`
+	Watch                     bool                `yaml:"watch"`
+	MinSeverity               string              `yaml:"minSeverity"`
+	FailOn                    string              `yaml:"failOn"`
+	FailOnGroups              []string            `yaml:"failOnGroups"`
+	WaiversFile               string              `yaml:"waiversFile"`
+	Suppressions              []ConfigSuppression `yaml:"suppressions"`
+	Baseline                  string              `yaml:"baseline"`
+	WriteBaseline             string              `yaml:"writeBaseline"`
+	Timeout                   string              `yaml:"timeout"`
+	Include                   string              `yaml:"include"`
+	Exclude                   string              `yaml:"exclude"`
+	Tags                      []string            `yaml:"tags"`
+	TagMatch                  string              `yaml:"tagMatch"`
+	GroupBy                   string              `yaml:"groupBy"`
+	Query                     string              `yaml:"query"`
+	ViolationRule             string              `yaml:"violationRule"`
+	ValidRule                 string              `yaml:"validRule"`
+	DataFiles                 []string            `yaml:"data"`
+	DataPath                  string              `yaml:"dataPath"`
+	ClusterFetchConcurrency   int                 `yaml:"clusterFetchConcurrency"`
+	Clusters                  []ConfigCluster     `yaml:"clusters"`
+	Discovery                 []ConfigDiscovery   `yaml:"discovery"`
+	Fleets                    []ConfigFleet       `yaml:"fleets"`
+	Policies                  []ConfigPolicy      `yaml:"policies"`
+	NoDefaultPolicies         bool                `yaml:"noDefaultPolicies"`
+	StrictMetadata            bool                `yaml:"strictMetadata"`
+	StrictCompile             bool                `yaml:"strict"`
+	NoDeprecated              bool                `yaml:"noDeprecated"`
+	Color                     string              `yaml:"color"`
+	FailFast                  bool                `yaml:"failFast"`
+	ShowPrints                bool                `yaml:"showPrints"`
+	Explain                   bool                `yaml:"explain"`
+	ExplainPolicy             string              `yaml:"explainPolicy"`
+	Trend                     ConfigTrend         `yaml:"trend"`
+	QuietOnSuccess            bool                `yaml:"quietOnSuccess"`
+	ErrorsAsViolations        bool                `yaml:"errorsAsViolations"`
+	SeverityOverrides         map[string]string   `yaml:"severityOverrides"`
+	AllowEmpty                bool                `yaml:"allowEmpty"`
+	Listen                    string              `yaml:"listen"`
+	MaxViolations             int                 `yaml:"maxViolations"`
+	Outputs                   []ConfigOutput      `yaml:"outputs"`
 }
 
 type ConfigPolicy struct {
@@ -38,22 +113,54 @@ type ConfigPolicy struct {
 	GitRepository  string `yaml:"repository"`
 	GitBranch      string `yaml:"branch"`
 	GitDirectory   string `yaml:"directory"`
+	Bundle         string `yaml:"bundle"`
+	URL            string `yaml:"url"`
+	Embedded       bool   `yaml:"embedded"`
+}
+
+type ConfigOutput struct {
+	Format string `yaml:"format"`
+	File   string `yaml:"file"`
 }
 
 type ConfigCluster struct {
-	ID       string `yaml:"id"`
-	Name     string `yaml:"name"`
+	ID        string `yaml:"id"`
+	Name      string `yaml:"name"`
+	Project   string `yaml:"project"`
+	Location  string `yaml:"location"`
+	File      string `yaml:"file"`
+	Terraform string `yaml:"terraform"`
+	Address   string `yaml:"address"`
+}
+
+// ConfigDiscovery selects a project, and optionally a location, to review all clusters in.
+type ConfigDiscovery struct {
 	Project  string `yaml:"project"`
 	Location string `yaml:"location"`
 }
 
+// ConfigFleet selects a fleet host project to review all GKE clusters registered in its fleet.
+type ConfigFleet struct {
+	Project string `yaml:"project"`
+}
+
+// ConfigTrend sets the directory with cluster snapshots evaluated by the trend command and
+// the format and file of the series. The series is written to the standard output when the file is not set.
+type ConfigTrend struct {
+	Directory string `yaml:"snapshots"`
+	Format    string `yaml:"format"`
+	File      string `yaml:"file"`
+}
+
+// ReadConfig reads the YAML configuration file. Unknown keys are reported as errors, so typos
+// are not silently ignored.
 func ReadConfig(path string, readFn ReadFileFn) (*ConfigNg, error) {
 	data, err := readFn(path)
 	if err != nil {
 		return nil, err
 	}
 	config := &ConfigNg{}
-	if err := yaml.Unmarshal(data, config); err != nil {
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
 		return nil, err
 	}
 	return config, nil
@@ -16,6 +16,7 @@ package app
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -31,6 +32,7 @@ func TestReadConfig(t *testing.T) {
 	policy2Repository := "https://github.com/test/test"
 	policy2Branch := "test"
 	policy2Directory := "policies"
+	outputFormat := "sarif"
 	fileData := fmt.Sprintf("silent: %t\n"+
 		"credentialsFile: %s\n"+
 		"clusters:\n"+
@@ -42,10 +44,15 @@ func TestReadConfig(t *testing.T) {
 		"- local: %s\n"+
 		"- repository: %s\n"+
 		"  branch: %s\n"+
-		"  directory: %s\n",
+		"  directory: %s\n"+
+		"severityOverrides:\n"+
+		"  gke.policy.node_count: HIGH\n"+
+		"outputs:\n"+
+		"- format: %s\n",
 		silent, credsFile,
 		cluster1Name, cluster1Location, cluster1Project, cluster2Id,
 		policy1Directory, policy2Repository, policy2Branch, policy2Directory,
+		outputFormat,
 	)
 	readFn := func(path string) ([]byte, error) {
 		if path != filePath {
@@ -94,4 +101,28 @@ func TestReadConfig(t *testing.T) {
 	if config.Policies[1].GitDirectory != policy2Directory {
 		t.Errorf("config policies[1] gitDirectory = %v; want %v", config.Policies[1].GitDirectory, policy2Directory)
 	}
+	if severity := config.SeverityOverrides["gke.policy.node_count"]; severity != "HIGH" {
+		t.Errorf("config severityOverrides[gke.policy.node_count] = %v; want %v", severity, "HIGH")
+	}
+	if len(config.Outputs) < 1 {
+		t.Fatalf("config outputs length = %v; want %v", len(config.Outputs), 1)
+	}
+	if config.Outputs[0].Format != outputFormat {
+		t.Errorf("config outputs[0] format = %v; want %v", config.Outputs[0].Format, outputFormat)
+	}
+}
+
+func TestReadConfig_unknownKey(t *testing.T) {
+	readFn := func(path string) ([]byte, error) {
+		return []byte("silent: true\nfailon: HIGH\npolicies:\n- repo: https://github.com/test/test\n"), nil
+	}
+	_, err := ReadConfig("config.yaml", readFn)
+	if err == nil {
+		t.Fatalf("err = nil; want error")
+	}
+	for _, key := range []string{"failon", "repo"} {
+		if !strings.Contains(err.Error(), "field "+key+" not found") {
+			t.Errorf("err = %v; want error with unknown key %s", err, key)
+		}
+	}
 }
@@ -0,0 +1,98 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/mikouaj/gke-review/internal/log"
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+// PolicyExplain evaluates a single policy, set with explain policy, against cluster inputs
+// with tracing enabled and prints the verdict along with the evaluation trace. Other policies
+// are not evaluated and no review report is written.
+func (p *PolicyAutomationApp) PolicyExplain() error {
+	if err := p.prepareClusters(); err != nil {
+		return err
+	}
+	files, err := p.loadPolicyFiles()
+	if err != nil {
+		return err
+	}
+	pa, err := p.newPolicyAgent()
+	if err != nil {
+		p.out.ErrorPrint("could not set policy query", err)
+		return err
+	}
+	if err := p.loadDataFiles(pa); err != nil {
+		p.out.ErrorPrint("could not load data files", err)
+		log.Errorf("could not load data files: %s", err)
+		return err
+	}
+	p.out.ColorPrintf("[white][bold]Parsing REGO policies...\n")
+	log.Info("Parsing rego policies")
+	if err := p.withPolicyFiles(pa, files); err != nil {
+		return err
+	}
+	p.overrideSeverities(pa)
+	failedClusters := 0
+	clusters := p.getClusterInputs()
+	for _, cluster := range clusters {
+		if cluster.err != nil {
+			failedClusters++
+			continue
+		}
+		p.out.ColorPrintf("[white][bold]Explaining policy against GKE cluster... [%s]\n", cluster.name)
+		explanation, err := pa.Explain(p.config.ExplainPolicy, cluster.input)
+		if err != nil {
+			err = p.timeoutError(err)
+			p.out.ErrorPrint("could not explain policy", err)
+			log.Errorf("could not explain policy %s on cluster %s: %s", p.config.ExplainPolicy, cluster.name, err)
+			return err
+		}
+		p.printExplanation(explanation)
+	}
+	if failedClusters > 0 {
+		return fmt.Errorf("%w: could not explain policy on %d of %d clusters", ErrEvaluationErrors, failedClusters, len(clusters))
+	}
+	return nil
+}
+
+// printExplanation prints the verdict of the explained policy followed by its indented
+// evaluation trace. Trace lines are printed as they are, as rego references use brackets
+// that would be taken for color codes.
+func (p *PolicyAutomationApp) printExplanation(explanation *policy.PolicyExplanation) {
+	explained := explanation.Policy
+	p.out.ColorPrintf("\n[white][bold]Evaluation trace of %s:\n\n", explained.Name)
+	for _, line := range explanation.Trace {
+		p.out.Printf("  %s\n", line)
+	}
+	p.out.Printf("\n")
+	switch {
+	case len(explained.ProcessingErrors) > 0:
+		for _, err := range explained.ProcessingErrors {
+			p.out.ColorPrintf("[bold][red][x] %s: [reset][red]could not evaluate policy: %s\n", explained.Label(), err)
+		}
+	case explained.Valid:
+		p.out.ColorPrintf("[bold][green][\u2713] %s: [reset][green]policy is valid\n", explained.Label())
+	default:
+		p.out.ColorPrintf("[bold][red][x] %s: [reset][red]policy is violated\n", explained.Label())
+		for _, violation := range explained.Violations {
+			p.out.ColorPrintf("    [bold][red]Violation:[reset][red] %s\n", violation)
+		}
+	}
+	log.Infof("Explained policy %s: valid %t, %d violations", explained.Name, explained.Valid, len(explained.Violations))
+}
@@ -0,0 +1,53 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+func TestLoadConfig_explainWithoutPolicy(t *testing.T) {
+	pa := &PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	err := pa.LoadConfig(&ConfigNg{
+		Explain:  true,
+		Clusters: []ConfigCluster{{File: "cluster.json"}},
+		Policies: []ConfigPolicy{{LocalDirectory: "policies"}},
+	})
+	if err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
+
+func TestPrintExplanation(t *testing.T) {
+	var buff bytes.Buffer
+	colorize := NewColorize()
+	colorize.Disable = true
+	pa := PolicyAutomationApp{out: &Output{w: &buff, colorize: colorize}, config: &ConfigNg{}}
+	pa.printExplanation(&policy.PolicyExplanation{
+		Policy: &policy.Policy{Name: "gke.policy.test", Title: "Test", Violations: []string{"bad value"}},
+		Trace:  []string{"policy.rego:1     Enter data.gke.policy.test[x]"},
+	})
+	expected := "\nEvaluation trace of gke.policy.test:\n\n" +
+		"  policy.rego:1     Enter data.gke.policy.test[x]\n\n" +
+		"[x] Test: policy is violated\n" +
+		"    Violation: bad value\n"
+	if buff.String() != expected {
+		t.Errorf("printExplanation produced %q; want %q", buff.String(), expected)
+	}
+}
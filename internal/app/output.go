@@ -15,25 +15,98 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/mikouaj/gke-review/internal/outputs"
+	"github.com/mikouaj/gke-review/internal/policy"
 	"github.com/mitchellh/colorstring"
 )
 
+const (
+	OutputFormatText       = "text"
+	OutputFormatSarif      = "sarif"
+	OutputFormatJSON       = "json"
+	OutputFormatJSONL      = "jsonl"
+	OutputFormatJUnit      = "junit"
+	OutputFormatCis        = "cis"
+	OutputFormatMarkdown   = "markdown"
+	OutputFormatYAML       = "yaml"
+	OutputFormatHTML       = "html"
+	OutputFormatTAP        = "tap"
+	OutputFormatLine       = "line"
+	OutputFormatPrometheus = "prometheus"
+)
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
 type Output struct {
 	w        io.Writer
 	colorize *colorstring.Colorize
 }
 
-func NewStdOutOutput() *Output {
+// NewStdOutOutput creates output to the standard output. In auto color mode, the output
+// is colored only when the standard output is a terminal.
+func NewStdOutOutput(colorMode string) *Output {
+	return newOutput(os.Stdout, colorMode)
+}
+
+func newOutput(w io.Writer, colorMode string) *Output {
+	colorize := NewColorize()
+	switch colorMode {
+	case ColorAlways:
+	case ColorNever:
+		colorize.Disable = true
+	default:
+		colorize.Disable = !isTerminal(w)
+	}
 	return &Output{
-		w:        os.Stdout,
-		colorize: NewColorize(),
+		w:        w,
+		colorize: colorize,
 	}
 }
 
+// quietWriter holds the standard output of a review in the quiet on success mode, so it can be
+// dropped when the review has nothing to report. It is safe for concurrent use, as clusters
+// are fetched concurrently.
+type quietWriter struct {
+	w      io.Writer
+	mu     sync.Mutex
+	buffer bytes.Buffer
+	// findings is set when evaluation results have violated or errored policies
+	findings bool
+}
+
+func (q *quietWriter) Write(p []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.buffer.Write(p)
+}
+
+// Flush writes the held output to the underlying writer.
+func (q *quietWriter) Flush() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, err := q.buffer.WriteTo(q.w)
+	return err
+}
+
+// Discard drops the held output.
+func (q *quietWriter) Discard() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.buffer.Reset()
+}
+
 func NewSilentOutput() *Output {
 	return &Output{
 		w: io.Discard,
@@ -68,3 +141,134 @@ func NewColorize() *colorstring.Colorize {
 		Reset:  true,
 	}
 }
+
+// isTerminal returns true if w is a file of a character device, i.e. a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// outputFormatFromFile returns output format matching extension of the file name or empty
+// string when there is no such format.
+func outputFormatFromFile(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return OutputFormatJSON
+	case ".jsonl":
+		return OutputFormatJSONL
+	case ".yaml", ".yml":
+		return OutputFormatYAML
+	case ".md":
+		return OutputFormatMarkdown
+	case ".html", ".htm":
+		return OutputFormatHTML
+	case ".sarif":
+		return OutputFormatSarif
+	case ".tap":
+		return OutputFormatTAP
+	case ".prom":
+		return OutputFormatPrometheus
+	}
+	return ""
+}
+
+// checkOutputFileDirectory checks that the directory of the output file exists, so the review
+// fails before clusters are evaluated rather than when results are written.
+func checkOutputFileDirectory(path string) error {
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("could not write output file %q: directory %q does not exist", path, dir)
+		}
+		return fmt.Errorf("could not write output file %q: %s", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("could not write output file %q: %q is not a directory", path, dir)
+	}
+	return nil
+}
+
+// fileResultWriter writes evaluation results to a file, that is created on each write.
+type fileResultWriter struct {
+	output    ConfigOutput
+	newWriter func(output ConfigOutput, w io.Writer) (outputs.ResultWriter, error)
+}
+
+func newFileResultWriter(output ConfigOutput, newWriter func(output ConfigOutput, w io.Writer) (outputs.ResultWriter, error)) outputs.ResultWriter {
+	return &fileResultWriter{output: output, newWriter: newWriter}
+}
+
+func (f *fileResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	file, err := os.Create(f.output.File)
+	if err != nil {
+		return err
+	}
+	writer, err := f.newWriter(f.output, file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Write(results); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// fileStreamResultWriter streams evaluation results to a file, that is created on the first
+// result and kept open until Close, so each review writes a new file.
+type fileStreamResultWriter struct {
+	output    ConfigOutput
+	newWriter func(output ConfigOutput, w io.Writer) (outputs.ResultWriter, error)
+	file      *os.File
+	writer    outputs.StreamResultWriter
+}
+
+func newFileStreamResultWriter(output ConfigOutput, newWriter func(output ConfigOutput, w io.Writer) (outputs.ResultWriter, error)) outputs.StreamResultWriter {
+	return &fileStreamResultWriter{output: output, newWriter: newWriter}
+}
+
+func (f *fileStreamResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	for _, result := range results {
+		if err := f.WriteResult(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fileStreamResultWriter) WriteResult(result *policy.PolicyEvaluationResult) error {
+	if f.file == nil {
+		file, err := os.Create(f.output.File)
+		if err != nil {
+			return err
+		}
+		writer, err := f.newWriter(f.output, file)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		stream, ok := writer.(outputs.StreamResultWriter)
+		if !ok {
+			file.Close()
+			return fmt.Errorf("%q output format can not be streamed", f.output.Format)
+		}
+		f.file, f.writer = file, stream
+	}
+	return f.writer.WriteResult(result)
+}
+
+// Close closes the file, if any result was written.
+func (f *fileStreamResultWriter) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file, f.writer = nil, nil
+	return err
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"os"
 	"testing"
 )
 
@@ -46,3 +47,54 @@ func TestErrorPrint(t *testing.T) {
 		t.Errorf("ErrorPrint produced %s: want %s", result, expected)
 	}
 }
+
+func TestQuietWriter(t *testing.T) {
+	var buff bytes.Buffer
+	quiet := &quietWriter{w: &buff}
+	out := Output{w: quiet}
+
+	out.Printf("dropped")
+	quiet.Discard()
+	out.Printf("kept")
+	if buff.Len() != 0 {
+		t.Errorf("output before flush = %q; want empty", buff.String())
+	}
+	if err := quiet.Flush(); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if buff.String() != "kept" {
+		t.Errorf("output = %q; want %q", buff.String(), "kept")
+	}
+}
+
+func TestNewOutput_color(t *testing.T) {
+	inputs := map[string]string{
+		ColorAuto:   "Error: test\n",
+		"":          "Error: test\n",
+		ColorNever:  "Error: test\n",
+		ColorAlways: "\033[1m\033[31mError: \033[97mtest\n\033[0m",
+	}
+	for colorMode, expected := range inputs {
+		var buff bytes.Buffer
+		out := newOutput(&buff, colorMode)
+		out.ColorPrintf("[bold][red]Error: [white]%s\n", "test")
+		if result := buff.String(); result != expected {
+			t.Errorf("color %q: output = %q; want %q", colorMode, result, expected)
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	var buff bytes.Buffer
+	if isTerminal(&buff) {
+		t.Errorf("isTerminal(buffer) = true; want false")
+	}
+	file, err := os.CreateTemp(t.TempDir(), "output")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	defer file.Close()
+	if isTerminal(file) {
+		t.Errorf("isTerminal(regular file) = true; want false")
+	}
+}
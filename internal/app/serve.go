@@ -0,0 +1,198 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/gke"
+	"github.com/mikouaj/gke-review/internal/log"
+	"github.com/mikouaj/gke-review/internal/outputs"
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+const (
+	// serveMaxInputSize limits the size of cluster details posted for a review
+	serveMaxInputSize    = 10 << 20
+	serveShutdownTimeout = 5 * time.Second
+)
+
+// servePolicies is the response of the policies endpoint.
+type servePolicies struct {
+	Version  string                `json:"version"`
+	Tool     outputs.JSONTool      `json:"tool"`
+	Policies []*outputs.JSONPolicy `json:"policies"`
+}
+
+// serveError is the response of a request that failed.
+type serveError struct {
+	Error string `json:"error"`
+}
+
+// PolicyServe compiles policies once and serves on-demand reviews over HTTP until the app
+// context is done, i.e. interrupted. Cluster details in the JSON format of GKE API are
+// reviewed with POST /review, policies are listed with GET /policies and GET /healthz
+// reports the server is up. Compiled policies are shared by concurrent requests.
+func (p *PolicyAutomationApp) PolicyServe() error {
+	pa, err := p.loadServeAgent()
+	if err != nil {
+		return err
+	}
+	address := p.config.Listen
+	if address == "" {
+		address = DefaultListenAddress
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		p.out.ErrorPrint("could not listen", err)
+		log.Errorf("could not listen on %s: %s", address, err)
+		return err
+	}
+	server := &http.Server{Handler: p.newServeHandler(pa)}
+	p.out.ColorPrintf("[white][bold]Serving policy reviews... [%s, %d policies]\n", listener.Addr(), len(pa.Policies()))
+	log.Infof("Serving policy reviews on %s", listener.Addr())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	select {
+	case err := <-serveErr:
+		p.out.ErrorPrint("could not serve policy reviews", err)
+		log.Errorf("could not serve policy reviews: %s", err)
+		return err
+	case <-p.ctx.Done():
+	}
+	log.Info("Shutting down the server")
+	ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// loadServeAgent compiles policies served by the server, with data files and severity overrides applied.
+func (p *PolicyAutomationApp) loadServeAgent() (*policy.PolicyAgent, error) {
+	files, err := p.loadPolicyFiles()
+	if err != nil {
+		return nil, err
+	}
+	pa, err := p.newPolicyAgent()
+	if err != nil {
+		p.out.ErrorPrint("could not set policy query", err)
+		return nil, err
+	}
+	if err := p.loadDataFiles(pa); err != nil {
+		p.out.ErrorPrint("could not load data files", err)
+		log.Errorf("could not load data files: %s", err)
+		return nil, err
+	}
+	p.out.ColorPrintf("[white][bold]Parsing REGO policies...\n")
+	log.Info("Parsing rego policies")
+	if err := p.withPolicyFiles(pa, files); err != nil {
+		return nil, err
+	}
+	for _, warning := range pa.MetadataWarnings() {
+		p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]%s\n", warning)
+		log.Warnf("%s", warning)
+	}
+	p.overrideSeverities(pa)
+	return pa, nil
+}
+
+// newServeHandler creates handler of the server endpoints, evaluating policies with a given agent.
+func (p *PolicyAutomationApp) newServeHandler(pa *policy.PolicyAgent) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/review", func(w http.ResponseWriter, r *http.Request) {
+		p.serveReview(pa, w, r)
+	})
+	mux.HandleFunc("/policies", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeServeJSON(w, http.StatusOK, &servePolicies{
+			Version:  outputs.JSONReportVersion,
+			Tool:     outputs.NewJSONTool(),
+			Policies: outputs.NewJSONPolicyList(pa.Policies()),
+		})
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// serveReview evaluates policies against cluster details from the request body and responds
+// with the evaluation result in the JSON Lines format of a single cluster.
+func (p *PolicyAutomationApp) serveReview(pa *policy.PolicyAgent, w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, serveMaxInputSize))
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("could not read cluster details: %s", err))
+		return
+	}
+	input, err := gke.NewClusterInputFromJSON(data)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("could not decode cluster details: %s", err))
+		return
+	}
+	evalResult, err := pa.WithContext(r.Context()).Evaluate(input)
+	if err != nil {
+		log.Errorf("could not evaluate rego policies: %s", err)
+		writeServeError(w, http.StatusInternalServerError, fmt.Errorf("could not evaluate policies: %s", err))
+		return
+	}
+	evalResult.ClusterName, _ = input["name"].(string)
+	evalResult.ClusterLabels = input.Labels()
+	evalResult = p.applyReviewSettings(evalResult)
+	log.Infof("Reviewed cluster %s: %d valid, %d violated, %d errored", evalResult.ClusterName,
+		evalResult.ValidCount(), evalResult.ViolatedCount(), evalResult.ErroredCount())
+	writeServeJSON(w, http.StatusOK, &outputs.JSONLineResult{
+		Version:           outputs.JSONReportVersion,
+		Tool:              outputs.NewJSONTool(),
+		JSONClusterResult: outputs.NewJSONClusterResult(evalResult),
+	})
+}
+
+// allowMethod responds with the method not allowed status to requests with other methods.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeServeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+	return false
+}
+
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	writeServeJSON(w, status, &serveError{Error: err.Error()})
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Warnf("could not write response: %s", err)
+	}
+}
@@ -0,0 +1,121 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/outputs"
+)
+
+func newTestServeHandler(t *testing.T) http.Handler {
+	dir := t.TempDir()
+	writeNodeCountPolicy(t, dir)
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	if err := pa.LoadConfig(&ConfigNg{SilentMode: true, Policies: []ConfigPolicy{{LocalDirectory: dir}}}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	agent, err := pa.loadServeAgent()
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	return pa.newServeHandler(agent)
+}
+
+func TestServeReview(t *testing.T) {
+	handler := newTestServeHandler(t)
+	var wg sync.WaitGroup
+	for i := 1; i <= 6; i++ {
+		wg.Add(1)
+		go func(nodeCount int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"name": "cluster-%d", "current_node_count": %d}`, nodeCount, nodeCount)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/review", strings.NewReader(body)))
+			if rec.Code != http.StatusOK {
+				t.Errorf("cluster-%d: status = %v; want %v", nodeCount, rec.Code, http.StatusOK)
+				return
+			}
+			result := &outputs.JSONLineResult{}
+			if err := json.Unmarshal(rec.Body.Bytes(), result); err != nil {
+				t.Errorf("cluster-%d: could not decode response: %s", nodeCount, err)
+				return
+			}
+			if result.Cluster != fmt.Sprintf("cluster-%d", nodeCount) {
+				t.Errorf("cluster = %v; want cluster-%d", result.Cluster, nodeCount)
+			}
+			violated := 0
+			if nodeCount < 3 {
+				violated = 1
+			}
+			if result.Counts.Violated != violated || result.Counts.Valid != 1-violated {
+				t.Errorf("cluster-%d: counts = %+v; want %d violated", nodeCount, result.Counts, violated)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestServeReview_errors(t *testing.T) {
+	handler := newTestServeHandler(t)
+	inputs := []struct {
+		method   string
+		body     string
+		expected int
+	}{
+		{http.MethodPost, `{"name": `, http.StatusBadRequest},
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+	for _, input := range inputs {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(input.method, "/review", strings.NewReader(input.body)))
+		if rec.Code != input.expected {
+			t.Errorf("%s %q: status = %v; want %v", input.method, input.body, rec.Code, input.expected)
+		}
+		response := &serveError{}
+		if err := json.Unmarshal(rec.Body.Bytes(), response); err != nil || response.Error == "" {
+			t.Errorf("%s %q: response = %s; want error", input.method, input.body, rec.Body.String())
+		}
+	}
+}
+
+func TestServePolicies(t *testing.T) {
+	handler := newTestServeHandler(t)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/policies", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v; want %v", rec.Code, http.StatusOK)
+	}
+	response := &servePolicies{}
+	if err := json.Unmarshal(rec.Body.Bytes(), response); err != nil {
+		t.Fatalf("could not decode response: %s", err)
+	}
+	if len(response.Policies) != 1 || response.Policies[0].Name != "gke.policy.node_count" {
+		t.Errorf("policies = %v; want [gke.policy.node_count]", response.Policies)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok\n" {
+		t.Errorf("healthz = %v %q; want %v %q", rec.Code, rec.Body.String(), http.StatusOK, "ok\n")
+	}
+}
@@ -0,0 +1,66 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+// ConfigSuppression suppresses violations of a policy with messages matching a regular expression.
+type ConfigSuppression struct {
+	Policy  string `yaml:"policy"`
+	Cluster string `yaml:"cluster"`
+	Message string `yaml:"message"`
+	Reason  string `yaml:"reason"`
+}
+
+// newViolationSuppressions validates suppressions of the configuration file and compiles
+// their message expressions.
+func newViolationSuppressions(suppressions []ConfigSuppression) ([]*policy.ViolationSuppression, error) {
+	result := make([]*policy.ViolationSuppression, len(suppressions))
+	for i, s := range suppressions {
+		suppression, err := s.toViolationSuppression()
+		if err != nil {
+			return nil, fmt.Errorf("suppression [%d]: %s", i, err)
+		}
+		result[i] = suppression
+	}
+	return result, nil
+}
+
+func (s ConfigSuppression) toViolationSuppression() (*policy.ViolationSuppression, error) {
+	if s.Policy == "" {
+		return nil, fmt.Errorf("policy is not set")
+	}
+	if s.Message == "" {
+		return nil, fmt.Errorf("message expression is not set for policy %s", s.Policy)
+	}
+	if s.Reason == "" {
+		return nil, fmt.Errorf("reason is not set for policy %s", s.Policy)
+	}
+	message, err := regexp.Compile(s.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message expression for policy %s: %s", s.Policy, err)
+	}
+	return &policy.ViolationSuppression{
+		Policy:  s.Policy,
+		Cluster: s.Cluster,
+		Message: message,
+		Reason:  s.Reason,
+	}, nil
+}
@@ -0,0 +1,52 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"testing"
+)
+
+func TestNewViolationSuppressions(t *testing.T) {
+	suppressions, err := newViolationSuppressions([]ConfigSuppression{
+		{Policy: "node_pool_autoupgrade", Message: "^node pool \"batch-.*\"", Reason: "Batch pools are upgraded manually"},
+		{Policy: "gke.policy.private_cluster", Cluster: "projects/p/locations/l/clusters/c", Message: "public", Reason: "Demo cluster"},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(suppressions) != 2 {
+		t.Fatalf("len(suppressions) = %v; want %v", len(suppressions), 2)
+	}
+	if !suppressions[0].Message.MatchString("node pool \"batch-1\" has auto-upgrade disabled") {
+		t.Errorf("suppression[0] message %q does not match batch pool", suppressions[0].Message)
+	}
+	if suppressions[1].Cluster != "projects/p/locations/l/clusters/c" {
+		t.Errorf("suppression[1] cluster = %v; want %v", suppressions[1].Cluster, "projects/p/locations/l/clusters/c")
+	}
+}
+
+func TestNewViolationSuppressions_negative(t *testing.T) {
+	inputs := []ConfigSuppression{
+		{Message: "no policy", Reason: "test"},
+		{Policy: "test", Reason: "no message"},
+		{Policy: "test", Message: "no reason"},
+		{Policy: "test", Message: "(invalid", Reason: "invalid expression"},
+	}
+	for _, input := range inputs {
+		if _, err := newViolationSuppressions([]ConfigSuppression{input}); err == nil {
+			t.Errorf("input %+v: err is nil; want error", input)
+		}
+	}
+}
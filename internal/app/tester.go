@@ -0,0 +1,78 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"github.com/mikouaj/gke-review/internal/log"
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+// PolicyTest runs rego unit tests from _test.rego files of policy sources, reporting
+// the result of every test and the location failed tests stopped at.
+func (p *PolicyAutomationApp) PolicyTest() error {
+	files, err := p.loadPolicyFiles()
+	if err != nil {
+		return err
+	}
+	pa, err := p.newPolicyAgent()
+	if err != nil {
+		p.out.ErrorPrint("could not set policy query", err)
+		return err
+	}
+	if err := p.loadDataFiles(pa); err != nil {
+		p.out.ErrorPrint("could not load data files", err)
+		log.Errorf("could not load data files: %s", err)
+		return err
+	}
+	p.out.ColorPrintf("[white][bold]Running REGO policy tests...\n")
+	log.Info("Running rego policy tests")
+	results, err := pa.RunTests(files)
+	if err != nil {
+		p.out.ColorPrintf("[bold][red][x] Compilation failed:\n")
+		for _, diagnostic := range policy.CompileDiagnostics(err) {
+			p.out.ColorPrintf("[bold][red][x] [reset][red]%s\n", diagnostic)
+			log.Errorf("could not compile policy files: %s", diagnostic)
+		}
+		return ErrInvalidPolicies
+	}
+	passed, failed, errored, skipped := 0, 0, 0, 0
+	for _, result := range results {
+		name := result.Package + "." + result.Name
+		switch {
+		case result.Skipped:
+			skipped++
+			p.out.ColorPrintf("[bold][yellow][-] %s: [reset][yellow]skipped (%s)\n", name, result.Location())
+		case result.Passed:
+			passed++
+			p.out.ColorPrintf("[bold][green][\u2713] %s: [reset][green]passed (%s)\n", name, result.Location())
+		case result.Error != nil:
+			errored++
+			p.out.ColorPrintf("[bold][red][x] %s: [reset][red]errored (%s): %s\n", name, result.Location(), result.Error)
+			log.Errorf("test %s at %s errored: %s", name, result.Location(), result.Error)
+		default:
+			failed++
+			p.out.ColorPrintf("[bold][red][x] %s: [reset][red]failed (%s)\n", name, result.Location())
+			if result.FailedAt != "" {
+				p.out.ColorPrintf("    [red]failed at %s\n", result.FailedAt)
+			}
+			log.Errorf("test %s at %s failed", name, result.Location())
+		}
+	}
+	p.out.ColorPrintf("\n[bold][green]Tests: %d passed, %d failed, %d errored, %d skipped.\n", passed, failed, errored, skipped)
+	if failed+errored > 0 {
+		return ErrFailedTests
+	}
+	return nil
+}
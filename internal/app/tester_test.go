@@ -0,0 +1,57 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyTest(t *testing.T) {
+	inputs := []struct {
+		name        string
+		testContent string
+		expected    error
+	}{
+		{"passing", "package gke.policy.node_count\n" +
+			"test_enough_nodes { valid with input as {\"current_node_count\": 3} }\n", nil},
+		{"failing", "package gke.policy.node_count\n" +
+			"test_enough_nodes { valid with input as {\"current_node_count\": 3} }\n" +
+			"test_not_enough_nodes { valid with input as {\"current_node_count\": 1} }\n", ErrFailedTests},
+		{"invalid", "package gke.policy.node_count\n" +
+			"test_undefined { undefined_function(1) }\n", ErrInvalidPolicies},
+	}
+	for _, input := range inputs {
+		dir := t.TempDir()
+		writeNodeCountPolicy(t, dir)
+		if err := os.WriteFile(filepath.Join(dir, "node_count_test.rego"), []byte(input.testContent), 0644); err != nil {
+			t.Fatalf("could not write file: %s", err)
+		}
+		pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+		config := &ConfigNg{
+			SilentMode: true,
+			Policies:   []ConfigPolicy{{LocalDirectory: dir}},
+		}
+		if err := pa.LoadConfig(config); err != nil {
+			t.Fatalf("%s: err = %v; want nil", input.name, err)
+		}
+		if err := pa.PolicyTest(); !errors.Is(err, input.expected) {
+			t.Errorf("%s: err = %v; want %v", input.name, err, input.expected)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/log"
+	"github.com/mikouaj/gke-review/internal/outputs"
+)
+
+const (
+	TrendFormatJSON = "json"
+	TrendFormatCSV  = "csv"
+)
+
+// snapshotTimestamp matches the timestamp in a snapshot file name: a date, optionally followed
+// by the time, i.e. 2022-03-15, 2022-03-15T06:30:00Z or 2022-03-15T06-30-00Z.
+var snapshotTimestamp = regexp.MustCompile(`(\d{4}-\d{2}-\d{2})(?:[T_](\d{2})[:-]?(\d{2})[:-]?(\d{2})(Z|[+-]\d{2}:?\d{2})?)?`)
+
+// trendSnapshot is a cluster input file of the trend series, taken at a given time.
+type trendSnapshot struct {
+	path string
+	time time.Time
+}
+
+// PolicyTrend evaluates policies against cluster snapshots from the trend directory and writes
+// the series of policy counts, with a point per day ordered by time. When a day has more
+// than one snapshot, the latest one is used. Snapshots that can not be read or evaluated
+// are skipped with a warning.
+func (p *PolicyAutomationApp) PolicyTrend() error {
+	if p.config.Trend.Directory == "" {
+		err := errors.New("snapshot directory is not set")
+		p.out.ErrorPrint("could not evaluate snapshots", err)
+		return err
+	}
+	if p.config.Trend.File == "" {
+		p.out = NewSilentOutput()
+	}
+	snapshots, err := listTrendSnapshots(p.config.Trend.Directory, os.ReadDir)
+	if err != nil {
+		p.out.ErrorPrint("could not list snapshots", err)
+		log.Errorf("could not list snapshots: %s", err)
+		return err
+	}
+	snapshots = latestSnapshotPerDay(snapshots)
+	if len(snapshots) == 0 {
+		err := fmt.Errorf("no snapshots with timestamps in file names found in %s", p.config.Trend.Directory)
+		p.out.ErrorPrint("could not evaluate snapshots", err)
+		return err
+	}
+	files, err := p.loadPolicyFiles()
+	if err != nil {
+		return err
+	}
+	pa, err := p.newPolicyAgent()
+	if err != nil {
+		p.out.ErrorPrint("could not set policy query", err)
+		return err
+	}
+	if err := p.loadDataFiles(pa); err != nil {
+		p.out.ErrorPrint("could not load data files", err)
+		log.Errorf("could not load data files: %s", err)
+		return err
+	}
+	p.out.ColorPrintf("[white][bold]Parsing REGO policies...\n")
+	log.Info("Parsing rego policies")
+	if err := p.withPolicyFiles(pa, files); err != nil {
+		return err
+	}
+	p.overrideSeverities(pa)
+	points := make([]*outputs.TrendPoint, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if p.interrupted() {
+			return ErrInterrupted
+		}
+		p.out.ColorPrintf("[white][bold]Evaluating policies against snapshot... [%s]\n", snapshot.path)
+		input, err := readClusterInputFile(snapshot.path, os.ReadFile)
+		if err != nil {
+			p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]skipping snapshot %s: %s\n", snapshot.path, err)
+			log.Warnf("skipping snapshot %s: %s", snapshot.path, err)
+			continue
+		}
+		evalResult, err := pa.Evaluate(input)
+		if err != nil {
+			if p.interrupted() {
+				return ErrInterrupted
+			}
+			p.out.ColorPrintf("[bold][yellow]Warning: [reset][yellow]skipping snapshot %s: %s\n", snapshot.path, err)
+			log.Warnf("skipping snapshot %s: %s", snapshot.path, err)
+			continue
+		}
+		evalResult.ClusterName = snapshot.path
+		evalResult.ApplySuppressions(p.suppressions)
+		evalResult.ApplyWaivers(p.waivers, snapshot.time)
+		points = append(points, outputs.NewTrendPoint(snapshot.time, snapshot.path, evalResult))
+	}
+	p.out.ColorPrintf("\n[bold][green]Trend: %d of %d snapshots evaluated.\n", len(points), len(snapshots))
+	if err := p.writeTrend(points); err != nil {
+		p.out.ErrorPrint("could not write trend", err)
+		log.Errorf("could not write trend: %s", err)
+		return err
+	}
+	return nil
+}
+
+// writeTrend writes the series in the configured format to the configured file or the standard output.
+func (p *PolicyAutomationApp) writeTrend(points []*outputs.TrendPoint) error {
+	write := outputs.WriteTrendJSON
+	if p.config.Trend.Format == TrendFormatCSV {
+		write = outputs.WriteTrendCSV
+	}
+	if p.config.Trend.File == "" {
+		return write(os.Stdout, points)
+	}
+	file, err := os.Create(p.config.Trend.File)
+	if err != nil {
+		return err
+	}
+	if err := write(file, points); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// listTrendSnapshots returns JSON files from the directory with timestamps in their names, ordered
+// by the timestamp and then by the name. Files without timestamps are skipped with a warning.
+func listTrendSnapshots(dir string, readDirFn func(string) ([]os.DirEntry, error)) ([]*trendSnapshot, error) {
+	entries, err := readDirFn(dir)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]*trendSnapshot, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		t, err := snapshotTime(entry.Name())
+		if err != nil {
+			log.Warnf("skipping snapshot %s: %s", entry.Name(), err)
+			continue
+		}
+		snapshots = append(snapshots, &trendSnapshot{path: filepath.Join(dir, entry.Name()), time: t})
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if !snapshots[i].time.Equal(snapshots[j].time) {
+			return snapshots[i].time.Before(snapshots[j].time)
+		}
+		return snapshots[i].path < snapshots[j].path
+	})
+	return snapshots, nil
+}
+
+// snapshotTime parses the timestamp from the snapshot file name. Timestamps without a zone are in UTC.
+func snapshotTime(name string) (time.Time, error) {
+	m := snapshotTimestamp.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("file name has no timestamp")
+	}
+	if m[2] == "" {
+		return time.Parse(outputs.TrendDateFormat, m[1])
+	}
+	zone := strings.Replace(m[5], ":", "", 1)
+	if zone == "" || zone == "Z" {
+		zone = "+0000"
+	}
+	return time.Parse("2006-01-02 150405 -0700", fmt.Sprintf("%s %s%s%s %s", m[1], m[2], m[3], m[4], zone))
+}
+
+// latestSnapshotPerDay keeps the latest snapshot of each day of sorted snapshots.
+func latestSnapshotPerDay(snapshots []*trendSnapshot) []*trendSnapshot {
+	latest := make([]*trendSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		day := snapshot.time.UTC().Format(outputs.TrendDateFormat)
+		if n := len(latest); n > 0 && latest[n-1].time.UTC().Format(outputs.TrendDateFormat) == day {
+			log.Debugf("snapshot %s is replaced by the later snapshot %s of the same day", latest[n-1].path, snapshot.path)
+			latest[n-1] = snapshot
+			continue
+		}
+		latest = append(latest, snapshot)
+	}
+	return latest
+}
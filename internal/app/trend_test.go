@@ -0,0 +1,127 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotTime(t *testing.T) {
+	inputs := map[string]time.Time{
+		"2022-03-15.json":                   time.Date(2022, 3, 15, 0, 0, 0, 0, time.UTC),
+		"cluster-2022-03-15T06:30:00Z.json": time.Date(2022, 3, 15, 6, 30, 0, 0, time.UTC),
+		"cluster-2022-03-15T063000Z.json":   time.Date(2022, 3, 15, 6, 30, 0, 0, time.UTC),
+		"cluster_2022-03-15_06-30-00.json":  time.Date(2022, 3, 15, 6, 30, 0, 0, time.UTC),
+		"2022-03-15T08:30:00+02:00.json":    time.Date(2022, 3, 15, 6, 30, 0, 0, time.UTC),
+	}
+	for name, expected := range inputs {
+		result, err := snapshotTime(name)
+		if err != nil {
+			t.Errorf("%s: err = %v; want nil", name, err)
+			continue
+		}
+		if !result.Equal(expected) {
+			t.Errorf("%s: time = %v; want %v", name, result, expected)
+		}
+	}
+	for _, name := range []string{"cluster.json", "2022-13-45.json"} {
+		if _, err := snapshotTime(name); err == nil {
+			t.Errorf("%s: err = nil; want error", name)
+		}
+	}
+}
+
+func TestListTrendSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"2022-03-16.json", "2022-03-15T18:00:00Z.json", "2022-03-15.json", "latest.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatalf("could not write file: %s", err)
+		}
+	}
+	snapshots, err := listTrendSnapshots(dir, os.ReadDir)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := []string{"2022-03-15.json", "2022-03-15T18:00:00Z.json", "2022-03-16.json"}
+	if len(snapshots) != len(expected) {
+		t.Fatalf("len(snapshots) = %v; want %v", len(snapshots), len(expected))
+	}
+	for i := range expected {
+		if snapshots[i].path != filepath.Join(dir, expected[i]) {
+			t.Errorf("snapshots[%d] = %v; want %v", i, snapshots[i].path, filepath.Join(dir, expected[i]))
+		}
+	}
+	latest := latestSnapshotPerDay(snapshots)
+	if len(latest) != 2 || latest[0].path != filepath.Join(dir, "2022-03-15T18:00:00Z.json") {
+		t.Errorf("latest snapshots = %v; want latest of 2022-03-15 and 2022-03-16", latest)
+	}
+}
+
+func TestPolicyTrend(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"snapshots/2022-03-16.json":   `{"name": "warsaw", "current_node_count": 3}`,
+		"snapshots/2022-03-15.json":   `{"name": "warsaw", "current_node_count": 2}`,
+		"snapshots/2022-03-17.json":   `{"name": `,
+		"snapshots/no-timestamp.json": `{"name": "warsaw", "current_node_count": 1}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("could not create directory: %s", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write file: %s", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "policies"), 0755); err != nil {
+		t.Fatalf("could not create directory: %s", err)
+	}
+	writeNodeCountPolicy(t, filepath.Join(dir, "policies"))
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	trendFile := filepath.Join(dir, "trend.csv")
+	config := &ConfigNg{
+		SilentMode: true,
+		Policies:   []ConfigPolicy{{LocalDirectory: filepath.Join(dir, "policies")}},
+		Trend:      ConfigTrend{Directory: filepath.Join(dir, "snapshots"), Format: "CSV", File: trendFile},
+	}
+	if err := pa.LoadConfig(config); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.PolicyTrend(); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	data, err := os.ReadFile(trendFile)
+	if err != nil {
+		t.Fatalf("could not read trend file: %s", err)
+	}
+	expected := "date,time,snapshot,valid,violated,warned,waived,errored,violations\n" +
+		"2022-03-15,2022-03-15T00:00:00Z," + filepath.Join(dir, "snapshots", "2022-03-15.json") + ",0,1,0,0,0,1\n" +
+		"2022-03-16,2022-03-16T00:00:00Z," + filepath.Join(dir, "snapshots", "2022-03-16.json") + ",1,0,0,0,0,0\n"
+	if string(data) != expected {
+		t.Errorf("trend = %q; want %q", string(data), expected)
+	}
+}
+
+func TestLoadConfig_invalidTrendFormat(t *testing.T) {
+	pa := PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	if err := pa.LoadConfig(&ConfigNg{Trend: ConfigTrend{Directory: "snapshots", Format: "xml"}}); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
@@ -0,0 +1,76 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"gopkg.in/yaml.v2"
+)
+
+type WaiversConfig struct {
+	Waivers []ConfigWaiver `yaml:"waivers"`
+}
+
+type ConfigWaiver struct {
+	Policy  string `yaml:"policy"`
+	Cluster string `yaml:"cluster"`
+	Reason  string `yaml:"reason"`
+	Expires string `yaml:"expires"`
+}
+
+func ReadWaivers(path string, readFn ReadFileFn) ([]*policy.PolicyWaiver, error) {
+	data, err := readFn(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &WaiversConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	waivers := make([]*policy.PolicyWaiver, len(config.Waivers))
+	for i, w := range config.Waivers {
+		waiver, err := w.toPolicyWaiver()
+		if err != nil {
+			return nil, fmt.Errorf("waiver [%d]: %s", i, err)
+		}
+		waivers[i] = waiver
+	}
+	return waivers, nil
+}
+
+func (w ConfigWaiver) toPolicyWaiver() (*policy.PolicyWaiver, error) {
+	if w.Policy == "" {
+		return nil, fmt.Errorf("policy is not set")
+	}
+	if w.Reason == "" {
+		return nil, fmt.Errorf("reason is not set for policy %s", w.Policy)
+	}
+	if w.Expires == "" {
+		return nil, fmt.Errorf("expiry date is not set for policy %s", w.Policy)
+	}
+	expires, err := time.Parse(policy.WaiverDateFormat, w.Expires)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry date %q for policy %s, expected YYYY-MM-DD", w.Expires, w.Policy)
+	}
+	return &policy.PolicyWaiver{
+		Policy:  w.Policy,
+		Cluster: w.Cluster,
+		Reason:  w.Reason,
+		Expires: expires,
+	}, nil
+}
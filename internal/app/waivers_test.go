@@ -0,0 +1,73 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadWaivers(t *testing.T) {
+	filePath := "/some/test/path/waivers.yaml"
+	fileData := "waivers:\n" +
+		"- policy: gke.policy.private_cluster\n" +
+		"  reason: Accepted risk\n" +
+		"  expires: 2022-12-31\n" +
+		"- policy: control_plane_access\n" +
+		"  cluster: projects/p/locations/l/clusters/c\n" +
+		"  reason: Public endpoint for CI\n" +
+		"  expires: \"2023-01-15\"\n"
+	readFn := func(path string) ([]byte, error) {
+		if path != filePath {
+			t.Fatalf("file path = %v; want %v", path, filePath)
+		}
+		return []byte(fileData), nil
+	}
+	waivers, err := ReadWaivers(filePath, readFn)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(waivers) != 2 {
+		t.Fatalf("len(waivers) = %v; want %v", len(waivers), 2)
+	}
+	if waivers[0].Policy != "gke.policy.private_cluster" {
+		t.Errorf("waiver[0] policy = %v; want %v", waivers[0].Policy, "gke.policy.private_cluster")
+	}
+	expires := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+	if !waivers[0].Expires.Equal(expires) {
+		t.Errorf("waiver[0] expires = %v; want %v", waivers[0].Expires, expires)
+	}
+	if waivers[1].Cluster != "projects/p/locations/l/clusters/c" {
+		t.Errorf("waiver[1] cluster = %v; want %v", waivers[1].Cluster, "projects/p/locations/l/clusters/c")
+	}
+}
+
+func TestReadWaivers_negative(t *testing.T) {
+	inputs := []string{
+		"waivers:\n- reason: no policy\n  expires: 2022-12-31\n",
+		"waivers:\n- policy: test\n  expires: 2022-12-31\n",
+		"waivers:\n- policy: test\n  reason: no expiry\n",
+		"waivers:\n- policy: test\n  reason: bad expiry\n  expires: 31/12/2022\n",
+		"waivers: [",
+	}
+	for _, input := range inputs {
+		readFn := func(path string) ([]byte, error) {
+			return []byte(input), nil
+		}
+		if _, err := ReadWaivers("waivers.yaml", readFn); err == nil {
+			t.Errorf("input %q: err is nil; want error", input)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/log"
+)
+
+const (
+	policyWatchInterval = 250 * time.Millisecond
+	policyWatchDebounce = 500 * time.Millisecond
+	policyWatchFileExt  = ".rego"
+)
+
+// PolicyWatch reviews clusters and re-runs the review whenever policy files in local
+// policy directories change. Cluster inputs are fetched once and reused. Compilation
+// and evaluation errors are reported without ending the watch, that lasts until
+// the app context is done, i.e. interrupted or after the configured timeout.
+func (p *PolicyAutomationApp) PolicyWatch() error {
+	if err := p.prepareClusters(); err != nil {
+		return err
+	}
+	var inputs []*clusterInput
+	inputsFn := func() (<-chan *clusterInput, int) {
+		if inputs == nil {
+			inputs = p.getClusterInputs()
+		}
+		return sendClusterInputs(inputs)
+	}
+	dirs := p.watchedDirectories()
+	watcher := newPolicyWatcher(dirs, policyWatchInterval, policyWatchDebounce)
+	for {
+		if err := p.reviewClusters(inputsFn); err != nil {
+			log.Infof("review finished with error: %s", err)
+		}
+		p.out.ColorPrintf("\n[white][bold]Watching policy files for changes... [%s]\n", strings.Join(dirs, ", "))
+		if err := watcher.Wait(p.ctx); err != nil {
+			return nil
+		}
+		p.out.ColorPrintf("[white][bold]Policy files changed, reviewing again...\n\n")
+		log.Info("Policy files changed")
+	}
+}
+
+// watchedDirectories returns local policy directories from the configuration.
+func (p *PolicyAutomationApp) watchedDirectories() []string {
+	dirs := make([]string, 0)
+	for _, policyConfig := range p.config.Policies {
+		if policyConfig.LocalDirectory != "" {
+			dirs = append(dirs, policyConfig.LocalDirectory)
+		}
+	}
+	return dirs
+}
+
+type policyFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// policyWatcher detects changes of REGO files in directories by polling their
+// modification times and sizes.
+type policyWatcher struct {
+	dirs     []string
+	interval time.Duration
+	debounce time.Duration
+	state    map[string]policyFileState
+}
+
+func newPolicyWatcher(dirs []string, interval, debounce time.Duration) *policyWatcher {
+	w := &policyWatcher{dirs: dirs, interval: interval, debounce: debounce}
+	w.state = w.snapshot()
+	return w
+}
+
+// Wait blocks until policy files change and then stay unchanged for the debounce period,
+// so rapid successive writes result in a single notification. It returns the context
+// error when the context is done first.
+func (w *policyWatcher) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			if state := w.snapshot(); !equalPolicyFileStates(w.state, state) {
+				w.state = state
+				changedAt = now
+			}
+			if !changedAt.IsZero() && now.Sub(changedAt) >= w.debounce {
+				return nil
+			}
+		}
+	}
+}
+
+func (w *policyWatcher) snapshot() map[string]policyFileState {
+	state := make(map[string]policyFileState)
+	for _, dir := range w.dirs {
+		err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && filepath.Ext(path) == policyWatchFileExt {
+				state[path] = policyFileState{modTime: info.ModTime(), size: info.Size()}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Warnf("could not watch policy directory %s: %s", dir, err)
+		}
+	}
+	return state
+}
+
+func equalPolicyFileStates(a, b map[string]policyFileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stateA := range a {
+		stateB, ok := b[path]
+		if !ok || !stateA.modTime.Equal(stateB.modTime) || stateA.size != stateB.size {
+			return false
+		}
+	}
+	return true
+}
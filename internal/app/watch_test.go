@@ -0,0 +1,63 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPolicyWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.rego")
+	if err := os.WriteFile(path, []byte("package gke.policy.one\n"), 0644); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	watcher := newPolicyWatcher([]string{dir}, 5*time.Millisecond, 30*time.Millisecond)
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			os.WriteFile(path, []byte("package gke.policy.one\n"+string(make([]byte, i+1))), 0644)
+			os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := watcher.Wait(ctx); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := watcher.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("err = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLoadConfig_watchWithoutLocalDirectory(t *testing.T) {
+	pa := &PolicyAutomationApp{ctx: context.Background(), out: NewSilentOutput()}
+	err := pa.LoadConfig(&ConfigNg{
+		Watch:    true,
+		Clusters: []ConfigCluster{{File: "cluster.json"}},
+		Policies: []ConfigPolicy{{GitRepository: "https://github.com/example/policies"}},
+	})
+	if err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
@@ -0,0 +1,107 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/gkehub/v1"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// gkeResourceLinkPrefix prefixes full resource names of GKE clusters registered in a fleet.
+const gkeResourceLinkPrefix = "//container.googleapis.com/"
+
+// HubClient lists fleet memberships of projects.
+type HubClient interface {
+	ListMemberships(ctx context.Context, project string) ([]*gkehub.Membership, error)
+}
+
+type hubClient struct {
+	service *gkehub.Service
+}
+
+// ListMemberships returns memberships of the fleet host project in all locations.
+func (c *hubClient) ListMemberships(ctx context.Context, project string) ([]*gkehub.Membership, error) {
+	memberships := make([]*gkehub.Membership, 0)
+	parent := fmt.Sprintf("projects/%s/locations/%s", project, AllLocations)
+	err := c.service.Projects.Locations.Memberships.List(parent).Pages(ctx, func(resp *gkehub.ListMembershipsResponse) error {
+		memberships = append(memberships, resp.Resources...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// ListFleetClusters returns names of GKE clusters registered in the fleet of the given host project.
+// Memberships of clusters other than GKE, i.e. attached clusters, are skipped. GKE Hub client is
+// created on first use, so it is not needed unless fleets are reviewed.
+func (c *GKEClient) ListFleetClusters(project string) ([]string, error) {
+	client, err := c.getHub()
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := client.ListMemberships(c.ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(memberships))
+	for _, membership := range memberships {
+		if name, ok := membershipClusterName(membership); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// membershipClusterName returns the full name of GKE cluster of the membership, i.e.
+// projects/P/locations/L/clusters/N, and false when the membership has no GKE cluster.
+func membershipClusterName(membership *gkehub.Membership) (string, bool) {
+	if membership.Endpoint == nil || membership.Endpoint.GkeCluster == nil {
+		return "", false
+	}
+	name := strings.TrimPrefix(membership.Endpoint.GkeCluster.ResourceLink, gkeResourceLinkPrefix)
+	if _, err := GetLocationName(name); err != nil || !strings.HasPrefix(name, "projects/") {
+		return "", false
+	}
+	return name, true
+}
+
+func (c *GKEClient) getHub() (HubClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hub == nil {
+		opts := c.opts
+		if c.transport != nil {
+			rt, err := htransport.NewTransport(c.ctx, c.transport, append(c.opts, option.WithScopes(cloudPlatformScope))...)
+			if err != nil {
+				return nil, err
+			}
+			opts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: rt})}
+		}
+		service, err := gkehub.NewService(c.ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.hub = &hubClient{service: service}
+	}
+	return c.hub, nil
+}
@@ -0,0 +1,77 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/gkehub/v1"
+)
+
+type mockHubClient struct {
+}
+
+func (mockHubClient) ListMemberships(ctx context.Context, project string) ([]*gkehub.Membership, error) {
+	if project != "fleet-project" {
+		return nil, fmt.Errorf("project %q is not mocked", project)
+	}
+	return []*gkehub.Membership{
+		{Name: "projects/fleet-project/locations/global/memberships/one", Endpoint: &gkehub.MembershipEndpoint{
+			GkeCluster: &gkehub.GkeCluster{ResourceLink: "//container.googleapis.com/projects/project-one/locations/europe-central2/clusters/cluster-one"},
+		}},
+		{Name: "projects/fleet-project/locations/global/memberships/attached", Endpoint: &gkehub.MembershipEndpoint{
+			KubernetesMetadata: &gkehub.KubernetesMetadata{KubernetesApiServerVersion: "v1.22.0"},
+		}},
+		{Name: "projects/fleet-project/locations/europe-west1/memberships/two", Endpoint: &gkehub.MembershipEndpoint{
+			GkeCluster: &gkehub.GkeCluster{ResourceLink: "//container.googleapis.com/projects/project-two/locations/europe-west1-b/clusters/cluster-two"},
+		}},
+		{Name: "projects/fleet-project/locations/global/memberships/empty"},
+	}, nil
+}
+
+func TestListFleetClusters(t *testing.T) {
+	client := GKEClient{
+		ctx:    context.Background(),
+		client: &mockClusterManagerClient{},
+		hub:    &mockHubClient{},
+	}
+	names, err := client.ListFleetClusters("fleet-project")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := []string{
+		"projects/project-one/locations/europe-central2/clusters/cluster-one",
+		"projects/project-two/locations/europe-west1-b/clusters/cluster-two",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("names = %v; want %v", names, expected)
+	}
+	if _, err := client.ListFleetClusters("other-project"); err == nil {
+		t.Errorf("other project: err is nil; want error")
+	}
+}
+
+func TestMembershipClusterName(t *testing.T) {
+	invalid := []string{"", "//container.googleapis.com/", "//container.googleapis.com/projects/p/locations/l"}
+	for _, link := range invalid {
+		membership := &gkehub.Membership{Endpoint: &gkehub.MembershipEndpoint{GkeCluster: &gkehub.GkeCluster{ResourceLink: link}}}
+		if name, ok := membershipClusterName(membership); ok {
+			t.Errorf("link %q: name = %q, ok = true; want false", link, name)
+		}
+	}
+}
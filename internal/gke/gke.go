@@ -17,39 +17,146 @@ package gke
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 
 	container "cloud.google.com/go/container/apiv1"
 	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 	containerpb "google.golang.org/genproto/googleapis/container/v1"
 )
 
+// AllLocations is the wildcard location matching all regions and zones
+const AllLocations = "-"
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// newImpersonatedTokenSource creates token source for impersonated credentials, replaced in tests
+var newImpersonatedTokenSource = impersonate.CredentialsTokenSource
+
 type ClusterManagerClient interface {
 	GetCluster(ctx context.Context, req *containerpb.GetClusterRequest, opts ...gax.CallOption) (*containerpb.Cluster, error)
+	GetServerConfig(ctx context.Context, req *containerpb.GetServerConfigRequest, opts ...gax.CallOption) (*containerpb.ServerConfig, error)
+	ListClusters(ctx context.Context, req *containerpb.ListClustersRequest, opts ...gax.CallOption) (*containerpb.ListClustersResponse, error)
+	ListNodePools(ctx context.Context, req *containerpb.ListNodePoolsRequest, opts ...gax.CallOption) (*containerpb.ListNodePoolsResponse, error)
 	Close() error
 }
 
 type GKEClient struct {
 	ctx    context.Context
 	client ClusterManagerClient
+	opts   []option.ClientOption
+	// transport is set when HTTP requests need custom TLS configuration, see ClientConfig
+	transport *http.Transport
+
+	mu              sync.Mutex
+	resourceManager ResourceManagerClient
+	hub             HubClient
+	securityPosture SecurityPostureClient
+}
+
+// ClientConfig holds optional settings of GKE client.
+type ClientConfig struct {
+	// CredentialsFile is used instead of Application Default Credentials
+	CredentialsFile string
+	// ImpersonateServiceAccount is the service account GKE API is called as
+	ImpersonateServiceAccount string
+	// CACertFile holds PEM encoded CA certificates trusted in addition to the system ones,
+	// i.e. of a TLS intercepting proxy
+	CACertFile string
 }
 
 func NewClient(ctx context.Context) (*GKEClient, error) {
-	return newGKEClient(ctx)
+	return NewClientWithConfig(ctx, ClientConfig{})
 }
 
 func NewClientWithCredentialsFile(ctx context.Context, credentialsFile string) (*GKEClient, error) {
-	return newGKEClient(ctx, option.WithCredentialsFile(credentialsFile))
+	return NewClientWithConfig(ctx, ClientConfig{CredentialsFile: credentialsFile})
+}
+
+// NewClientWithImpersonation creates client that calls GKE API as the given service account.
+// Source credentials are read from credentialsFile or, when empty, from Application Default Credentials.
+func NewClientWithImpersonation(ctx context.Context, serviceAccount string, credentialsFile string) (*GKEClient, error) {
+	return NewClientWithConfig(ctx, ClientConfig{ImpersonateServiceAccount: serviceAccount, CredentialsFile: credentialsFile})
 }
 
-func newGKEClient(ctx context.Context, opts ...option.ClientOption) (*GKEClient, error) {
-	cli, err := container.NewClusterManagerClient(ctx, opts...)
+// NewClientWithConfig creates client with the given settings. With CA certificates file, all
+// API and token requests trust its certificates and are routed through the proxy set with
+// HTTPS_PROXY and NO_PROXY variables.
+func NewClientWithConfig(ctx context.Context, config ClientConfig) (*GKEClient, error) {
+	var transport *http.Transport
+	if config.CACertFile != "" {
+		var err error
+		if transport, err = newCATransport(config.CACertFile); err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+	}
+	var opts []option.ClientOption
+	if config.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(config.CredentialsFile))
+	}
+	if config.ImpersonateServiceAccount == "" {
+		return newGKEClient(ctx, transport, opts...)
+	}
+	ts, err := impersonatedTokenSource(ctx, config.ImpersonateServiceAccount, transport, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newGKEClient(ctx, transport, option.WithTokenSource(ts))
+}
+
+// impersonatedTokenSource creates token source of the service account, using the source
+// credentials options. With transport, IAM credentials API is called through it.
+func impersonatedTokenSource(ctx context.Context, serviceAccount string, transport *http.Transport, sourceOpts []option.ClientOption) (oauth2.TokenSource, error) {
+	if transport != nil {
+		rt, err := htransport.NewTransport(ctx, transport, append(sourceOpts, option.WithScopes(cloudPlatformScope))...)
+		if err != nil {
+			return nil, fmt.Errorf("could not impersonate service account %s: %w", serviceAccount, err)
+		}
+		sourceOpts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: rt})}
+	}
+	ts, err := newImpersonatedTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: serviceAccount,
+		Scopes:          container.DefaultAuthScopes(),
+	}, sourceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not impersonate service account %s: %w", serviceAccount, err)
+	}
+	if err := verifyTokenSource(ts, serviceAccount); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// verifyTokenSource mints the first token, so missing impersonation permissions
+// are reported before any cluster is fetched
+func verifyTokenSource(ts oauth2.TokenSource, serviceAccount string) error {
+	if _, err := ts.Token(); err != nil {
+		return fmt.Errorf("could not impersonate service account %s, make sure the caller has "+
+			"roles/iam.serviceAccountTokenCreator role on it: %w", serviceAccount, err)
+	}
+	return nil
+}
+
+func newGKEClient(ctx context.Context, transport *http.Transport, opts ...option.ClientOption) (*GKEClient, error) {
+	clientOpts := opts
+	if transport != nil {
+		clientOpts = append(append([]option.ClientOption{}, opts...), grpcTransportOption(transport))
+	}
+	cli, err := container.NewClusterManagerClient(ctx, clientOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return &GKEClient{
-		ctx:    ctx,
-		client: cli,
+		ctx:       ctx,
+		client:    cli,
+		opts:      opts,
+		transport: transport,
 	}, nil
 }
 
@@ -59,6 +166,58 @@ func (c *GKEClient) GetCluster(name string) (*containerpb.Cluster, error) {
 	return c.client.GetCluster(c.ctx, req)
 }
 
+// ListClusters returns names of clusters in the given project and location. Location "-"
+// or empty location lists clusters in all locations. Listing fails when any of the locations
+// could not be reached, so that no cluster is silently skipped.
+func (c *GKEClient) ListClusters(project string, location string) ([]string, error) {
+	if location == "" {
+		location = AllLocations
+	}
+	req := &containerpb.ListClustersRequest{
+		Parent: fmt.Sprintf("projects/%s/locations/%s", project, location)}
+	resp, err := c.client.ListClusters(c.ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.MissingZones) > 0 {
+		return nil, fmt.Errorf("could not list clusters in locations: %s", strings.Join(resp.MissingZones, ", "))
+	}
+	names := make([]string, len(resp.Clusters))
+	for i, cluster := range resp.Clusters {
+		names[i] = GetClusterName(project, cluster.Location, cluster.Name)
+	}
+	return names, nil
+}
+
+// ListNodePools returns node pools of the cluster with the given full name.
+func (c *GKEClient) ListNodePools(clusterName string) ([]*containerpb.NodePool, error) {
+	req := &containerpb.ListNodePoolsRequest{
+		Parent: clusterName}
+	resp, err := c.client.ListNodePools(c.ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.NodePools, nil
+}
+
+func (c *GKEClient) GetServerConfig(locationName string) (*containerpb.ServerConfig, error) {
+	req := &containerpb.GetServerConfigRequest{
+		Name: locationName}
+	return c.client.GetServerConfig(c.ctx, req)
+}
+
+func (c *GKEClient) GetClusterVersions(clusterName string, cluster *containerpb.Cluster) (*ClusterVersions, error) {
+	locationName, err := GetLocationName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	serverConfig, err := c.GetServerConfig(locationName)
+	if err != nil {
+		return nil, err
+	}
+	return NewClusterVersions(cluster, serverConfig), nil
+}
+
 func (c *GKEClient) Close() error {
 	return c.client.Close()
 }
@@ -66,3 +225,11 @@ func (c *GKEClient) Close() error {
 func GetClusterName(project string, location string, name string) string {
 	return fmt.Sprintf("projects/%s/locations/%s/clusters/%s", project, location, name)
 }
+
+func GetLocationName(clusterName string) (string, error) {
+	idx := strings.LastIndex(clusterName, "/clusters/")
+	if idx < 0 {
+		return "", fmt.Errorf("cluster name %q is not in projects/*/locations/*/clusters/* format", clusterName)
+	}
+	return clusterName[:idx], nil
+}
@@ -19,11 +19,14 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 
 	container "cloud.google.com/go/container/apiv1"
 	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/option"
 	containerpb "google.golang.org/genproto/googleapis/container/v1"
 )
@@ -43,6 +46,44 @@ func (mockClusterManagerClient) GetCluster(ctx context.Context, req *containerpb
 	}, nil
 }
 
+func (mockClusterManagerClient) GetServerConfig(ctx context.Context, req *containerpb.GetServerConfigRequest, opts ...gax.CallOption) (*containerpb.ServerConfig, error) {
+	re := regexp.MustCompile(`^projects/([^/]+)/locations/([^/]+)$`)
+	if !re.MatchString(req.Name) {
+		return nil, fmt.Errorf("request name: %q, does not match regexp: %q", req.Name, re.String())
+	}
+	return &containerpb.ServerConfig{
+		DefaultClusterVersion: "1.21.6-gke.1500",
+		ValidMasterVersions:   []string{"1.22.6-gke.300", "1.21.6-gke.1500"},
+	}, nil
+}
+
+func (mockClusterManagerClient) ListClusters(ctx context.Context, req *containerpb.ListClustersRequest, opts ...gax.CallOption) (*containerpb.ListClustersResponse, error) {
+	switch req.Parent {
+	case "projects/test-project/locations/-":
+		return &containerpb.ListClustersResponse{Clusters: []*containerpb.Cluster{
+			{Name: "cluster-one", Location: "europe-central2"},
+			{Name: "cluster-two", Location: "europe-west1-b"},
+		}}, nil
+	case "projects/test-project/locations/europe-central2":
+		return &containerpb.ListClustersResponse{Clusters: []*containerpb.Cluster{
+			{Name: "cluster-one", Location: "europe-central2"},
+		}}, nil
+	case "projects/missing-zones/locations/-":
+		return &containerpb.ListClustersResponse{MissingZones: []string{"us-east1-b"}}, nil
+	}
+	return nil, fmt.Errorf("request parent: %q is not mocked", req.Parent)
+}
+
+func (mockClusterManagerClient) ListNodePools(ctx context.Context, req *containerpb.ListNodePoolsRequest, opts ...gax.CallOption) (*containerpb.ListNodePoolsResponse, error) {
+	if req.Parent != "projects/test-project/locations/europe-central2/clusters/warsaw" {
+		return nil, fmt.Errorf("request parent: %q is not mocked", req.Parent)
+	}
+	return &containerpb.ListNodePoolsResponse{NodePools: []*containerpb.NodePool{
+		{Name: "default-pool", InitialNodeCount: 3},
+		{Name: "spot-pool", Locations: []string{"europe-central2-a", "europe-central2-b"}},
+	}}, nil
+}
+
 func (mockClusterManagerClient) Close() error {
 	return fmt.Errorf("mocked error")
 }
@@ -53,7 +94,7 @@ func TestNewGKEClient(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error when creating JSON credentials: %v", err)
 	}
-	c, err := newGKEClient(context.Background(), option.WithCredentials(testCreds))
+	c, err := newGKEClient(context.Background(), nil, option.WithCredentials(testCreds))
 	if err != nil {
 		t.Fatalf("error when creating client: %v", err)
 	}
@@ -64,6 +105,52 @@ func TestNewGKEClient(t *testing.T) {
 	}
 }
 
+type mockTokenSource struct {
+	err error
+}
+
+func (m mockTokenSource) Token() (*oauth2.Token, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &oauth2.Token{AccessToken: "token"}, nil
+}
+
+func TestNewClientWithImpersonation(t *testing.T) {
+	defer func(fn func(context.Context, impersonate.CredentialsConfig, ...option.ClientOption) (oauth2.TokenSource, error)) {
+		newImpersonatedTokenSource = fn
+	}(newImpersonatedTokenSource)
+
+	var config impersonate.CredentialsConfig
+	newImpersonatedTokenSource = func(ctx context.Context, c impersonate.CredentialsConfig, opts ...option.ClientOption) (oauth2.TokenSource, error) {
+		config = c
+		return mockTokenSource{}, nil
+	}
+	sa := "auditor@my-project.iam.gserviceaccount.com"
+	c, err := NewClientWithImpersonation(context.Background(), sa, "")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	defer c.client.Close()
+	if config.TargetPrincipal != sa {
+		t.Errorf("target principal = %v; want %v", config.TargetPrincipal, sa)
+	}
+	if !reflect.DeepEqual(config.Scopes, container.DefaultAuthScopes()) {
+		t.Errorf("scopes = %v; want %v", config.Scopes, container.DefaultAuthScopes())
+	}
+
+	newImpersonatedTokenSource = func(ctx context.Context, c impersonate.CredentialsConfig, opts ...option.ClientOption) (oauth2.TokenSource, error) {
+		return mockTokenSource{err: fmt.Errorf("permission denied")}, nil
+	}
+	_, err = NewClientWithImpersonation(context.Background(), sa, "")
+	if err == nil {
+		t.Fatalf("err is nil; want error")
+	}
+	if !strings.Contains(err.Error(), "roles/iam.serviceAccountTokenCreator") {
+		t.Errorf("err = %v; want error mentioning roles/iam.serviceAccountTokenCreator", err)
+	}
+}
+
 func TestGetCluster(t *testing.T) {
 	client := GKEClient{
 		ctx:    context.Background(),
@@ -84,6 +171,78 @@ func TestGetCluster(t *testing.T) {
 	}
 }
 
+func TestListNodePools(t *testing.T) {
+	client := GKEClient{
+		ctx:    context.Background(),
+		client: &mockClusterManagerClient{},
+	}
+	nodePools, err := client.ListNodePools(GetClusterName("test-project", "europe-central2", "warsaw"))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(nodePools) != 2 {
+		t.Fatalf("len(nodePools) = %v; want %v", len(nodePools), 2)
+	}
+	if nodePools[1].Name != "spot-pool" {
+		t.Errorf("nodePools[1].Name = %v; want %v", nodePools[1].Name, "spot-pool")
+	}
+	if _, err := client.ListNodePools(GetClusterName("test-project", "europe-central2", "missing")); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
+
+func TestListClusters(t *testing.T) {
+	client := GKEClient{
+		ctx:    context.Background(),
+		client: &mockClusterManagerClient{},
+	}
+	inputs := map[string][]string{
+		"": {
+			"projects/test-project/locations/europe-central2/clusters/cluster-one",
+			"projects/test-project/locations/europe-west1-b/clusters/cluster-two",
+		},
+		"europe-central2": {
+			"projects/test-project/locations/europe-central2/clusters/cluster-one",
+		},
+	}
+	for location, expected := range inputs {
+		names, err := client.ListClusters("test-project", location)
+		if err != nil {
+			t.Fatalf("location %q: err = %v; want nil", location, err)
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("location %q: names = %v; want %v", location, names, expected)
+		}
+	}
+	if _, err := client.ListClusters("missing-zones", AllLocations); err == nil {
+		t.Errorf("missing zones: err is nil; want error")
+	}
+}
+
+func TestGetClusterVersions(t *testing.T) {
+	client := GKEClient{
+		ctx:    context.Background(),
+		client: &mockClusterManagerClient{},
+	}
+	cluster := &containerpb.Cluster{
+		CurrentMasterVersion: "1.21.6-gke.1500",
+		CurrentNodeVersion:   "1.20.9-gke.700",
+	}
+	versions, err := client.GetClusterVersions(GetClusterName("test-project", "europe-central2", "warsaw"), cluster)
+	if err != nil {
+		t.Fatalf("error when fetching cluster versions: %v", err)
+	}
+	if versions.Master != cluster.CurrentMasterVersion {
+		t.Errorf("versions.Master = %s; want %s", versions.Master, cluster.CurrentMasterVersion)
+	}
+	if versions.DefaultVersion != "1.21.6-gke.1500" {
+		t.Errorf("versions.DefaultVersion = %s; want %s", versions.DefaultVersion, "1.21.6-gke.1500")
+	}
+	if len(versions.AvailableVersions) != 2 {
+		t.Errorf("len(versions.AvailableVersions) = %d; want %d", len(versions.AvailableVersions), 2)
+	}
+}
+
 func TestClose(t *testing.T) {
 	client := GKEClient{
 		ctx:    nil,
@@ -114,3 +273,16 @@ func TestGetClusterName(t *testing.T) {
 		t.Errorf("match[3] = %v; want %v", matches[3], clusterName)
 	}
 }
+
+func TestGetLocationName(t *testing.T) {
+	name, err := GetLocationName("projects/test-project/locations/europe-central2/clusters/warsaw")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if name != "projects/test-project/locations/europe-central2" {
+		t.Errorf("name = %v; want %v", name, "projects/test-project/locations/europe-central2")
+	}
+	if _, err := GetLocationName("warsaw"); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
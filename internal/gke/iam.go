@@ -0,0 +1,134 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const inputIAMPolicyKey = "iam_policy"
+
+// ResourceManagerClient reads IAM policies of projects.
+type ResourceManagerClient interface {
+	GetProjectIAMPolicy(ctx context.Context, project string) (*cloudresourcemanager.Policy, error)
+}
+
+type resourceManagerClient struct {
+	service *cloudresourcemanager.Service
+}
+
+func (c *resourceManagerClient) GetProjectIAMPolicy(ctx context.Context, project string) (*cloudresourcemanager.Policy, error) {
+	return c.service.Projects.GetIamPolicy(project, &cloudresourcemanager.GetIamPolicyRequest{}).Context(ctx).Do()
+}
+
+// IAMPolicy is the IAM policy of the cluster project, set under the iam_policy key of the input.
+type IAMPolicy struct {
+	Bindings []*IAMBinding `json:"bindings"`
+}
+
+type IAMBinding struct {
+	Role      string        `json:"role"`
+	Members   []string      `json:"members"`
+	Condition *IAMCondition `json:"condition,omitempty"`
+}
+
+type IAMCondition struct {
+	Title      string `json:"title"`
+	Expression string `json:"expression"`
+}
+
+// NewIAMPolicy maps the Resource Manager policy to the input representation.
+func NewIAMPolicy(policy *cloudresourcemanager.Policy) *IAMPolicy {
+	iamPolicy := &IAMPolicy{Bindings: make([]*IAMBinding, 0, len(policy.Bindings))}
+	for _, binding := range policy.Bindings {
+		iamBinding := &IAMBinding{Role: binding.Role, Members: binding.Members}
+		if iamBinding.Members == nil {
+			iamBinding.Members = make([]string, 0)
+		}
+		if binding.Condition != nil {
+			iamBinding.Condition = &IAMCondition{Title: binding.Condition.Title, Expression: binding.Condition.Expression}
+		}
+		iamPolicy.Bindings = append(iamPolicy.Bindings, iamBinding)
+	}
+	return iamPolicy
+}
+
+// GetProjectIAMPolicy returns IAM policy of the project of the cluster with the given full name.
+// Resource Manager client is created on first use, so it is not needed unless IAM policies are fetched.
+func (c *GKEClient) GetProjectIAMPolicy(clusterName string) (*IAMPolicy, error) {
+	project, err := GetProjectID(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.getResourceManager()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := client.GetProjectIAMPolicy(c.ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	return NewIAMPolicy(policy), nil
+}
+
+func (c *GKEClient) getResourceManager() (ResourceManagerClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resourceManager == nil {
+		opts := c.opts
+		if c.transport != nil {
+			rt, err := htransport.NewTransport(c.ctx, c.transport, append(c.opts, option.WithScopes(cloudPlatformScope))...)
+			if err != nil {
+				return nil, err
+			}
+			opts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: rt})}
+		}
+		service, err := cloudresourcemanager.NewService(c.ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.resourceManager = &resourceManagerClient{service: service}
+	}
+	return c.resourceManager, nil
+}
+
+// IsPermissionDenied tells whether the API call failed because the caller lacks permissions.
+func IsPermissionDenied(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden
+}
+
+// GetProjectID returns the project of the cluster with the given full name.
+func GetProjectID(clusterName string) (string, error) {
+	parts := strings.Split(clusterName, "/")
+	if len(parts) < 2 || parts[0] != "projects" || parts[1] == "" {
+		return "", fmt.Errorf("cluster name %q is not in projects/*/locations/*/clusters/* format", clusterName)
+	}
+	return parts[1], nil
+}
+
+// SetIAMPolicy sets IAM policy of the cluster project in the input.
+func (i ClusterInput) SetIAMPolicy(policy *IAMPolicy) {
+	i[inputIAMPolicyKey] = policy
+}
@@ -0,0 +1,83 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+)
+
+type mockResourceManagerClient struct {
+}
+
+func (mockResourceManagerClient) GetProjectIAMPolicy(ctx context.Context, project string) (*cloudresourcemanager.Policy, error) {
+	switch project {
+	case "test-project":
+		return &cloudresourcemanager.Policy{Bindings: []*cloudresourcemanager.Binding{
+			{Role: "roles/container.admin", Members: []string{"user:admin@example.com"}},
+			{Role: "roles/viewer", Condition: &cloudresourcemanager.Expr{Title: "temporary", Expression: "request.time < timestamp('2023-01-01T00:00:00Z')"}},
+		}}, nil
+	case "denied-project":
+		return nil, &googleapi.Error{Code: http.StatusForbidden, Message: "permission denied"}
+	}
+	return nil, fmt.Errorf("project %q is not mocked", project)
+}
+
+func TestGetProjectIAMPolicy(t *testing.T) {
+	client := GKEClient{
+		ctx:             context.Background(),
+		client:          &mockClusterManagerClient{},
+		resourceManager: &mockResourceManagerClient{},
+	}
+	policy, err := client.GetProjectIAMPolicy(GetClusterName("test-project", "europe-central2", "warsaw"))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := &IAMPolicy{Bindings: []*IAMBinding{
+		{Role: "roles/container.admin", Members: []string{"user:admin@example.com"}},
+		{Role: "roles/viewer", Members: []string{}, Condition: &IAMCondition{Title: "temporary", Expression: "request.time < timestamp('2023-01-01T00:00:00Z')"}},
+	}}
+	if !reflect.DeepEqual(policy, expected) {
+		t.Errorf("policy = %v; want %v", policy, expected)
+	}
+	_, err = client.GetProjectIAMPolicy(GetClusterName("denied-project", "europe-central2", "warsaw"))
+	if !IsPermissionDenied(err) {
+		t.Errorf("IsPermissionDenied(%v) = false; want true", err)
+	}
+	if IsPermissionDenied(fmt.Errorf("other error")) {
+		t.Errorf("IsPermissionDenied(other error) = true; want false")
+	}
+}
+
+func TestGetProjectID(t *testing.T) {
+	project, err := GetProjectID("projects/test-project/locations/europe-central2/clusters/warsaw")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if project != "test-project" {
+		t.Errorf("project = %q; want %q", project, "test-project")
+	}
+	for _, name := range []string{"", "warsaw", "projects//locations/l/clusters/c"} {
+		if _, err := GetProjectID(name); err == nil {
+			t.Errorf("name %q: err = nil; want error", name)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	inputVersionsKey   = "versions"
+	inputNodePoolsKey  = "node_pools"
+	inputAutopilotKey  = "autopilot"
+	inputCreateTimeKey = "create_time"
+	inputAgeDaysKey    = "age_days"
+	inputAddonsKey     = "addons_config"
+	inputBinAuthzKey   = "binary_authorization"
+	inputLabelsKey     = "resource_labels"
+
+	inputReleaseChannelKey    = "release_channel"
+	inputMaintenancePolicyKey = "maintenance_policy"
+)
+
+// addonFields are boolean fields of cluster add-ons, add-ons are either disabled or enabled by them.
+var addonFields = map[string]string{
+	"http_load_balancing":                   "disabled",
+	"horizontal_pod_autoscaling":            "disabled",
+	"kubernetes_dashboard":                  "disabled",
+	"network_policy_config":                 "disabled",
+	"cloud_run_config":                      "disabled",
+	"dns_cache_config":                      "enabled",
+	"config_connector_config":               "enabled",
+	"gce_persistent_disk_csi_driver_config": "enabled",
+	"gcp_filestore_csi_driver_config":       "enabled",
+}
+
+// missingAddonFields are values of boolean fields of add-ons that are not returned at all, as
+// they are disabled by default on GKE. Other missing add-ons have the field set to false, the
+// same as a missing field of a returned add-on.
+var missingAddonFields = map[string]bool{
+	"kubernetes_dashboard":  true,
+	"network_policy_config": true,
+	"cloud_run_config":      true,
+}
+
+// ClusterInput is an input document for policy evaluation. It holds GKE cluster
+// data on its root level, along with additional data added under dedicated keys.
+type ClusterInput map[string]interface{}
+
+type ClusterVersions struct {
+	Master            string   `json:"master"`
+	Node              string   `json:"node"`
+	ReleaseChannel    string   `json:"release_channel"`
+	DefaultVersion    string   `json:"default_version"`
+	AvailableVersions []string `json:"available_versions"`
+}
+
+// NewClusterInput creates input document from GKE cluster. The autopilot key is always set
+// to a boolean. The node_pools key is always set, to an empty list for clusters without node
+// pools and for Autopilot clusters, which node pools are managed by GKE.
+func NewClusterInput(cluster *containerpb.Cluster) (ClusterInput, error) {
+	input := ClusterInput{}
+	if err := decodeJSONValue(cluster, &input); err != nil {
+		return nil, err
+	}
+	if err := input.setMaintenancePolicy(cluster.MaintenancePolicy); err != nil {
+		return nil, err
+	}
+	input.normalize()
+	return input, nil
+}
+
+// NewClusterInputFromJSON creates input document from JSON data of the same shape
+// as produced for GKE cluster fetched from the API, i.e. a JSON encoded cluster.
+// Output of gcloud container clusters describe is detected and converted with
+// NewClusterInputFromGcloudJSON.
+func NewClusterInputFromJSON(data []byte) (ClusterInput, error) {
+	input := ClusterInput{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&input); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("invalid JSON at byte offset %d: %s", syntaxErr.Offset, syntaxErr)
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("invalid JSON at byte offset %d: expected an object, got %s", typeErr.Offset, typeErr.Value)
+		}
+		return nil, fmt.Errorf("invalid JSON: %s", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid JSON at byte offset %d: unexpected data after the cluster object", decoder.InputOffset())
+	}
+	if input.isGcloudShape() {
+		return NewClusterInputFromGcloudJSON(data)
+	}
+	input.normalize()
+	return input, nil
+}
+
+// NewClusterInputFromGcloudJSON creates input document from output of
+// gcloud container clusters describe --format=json. The cluster is decoded from its
+// REST representation, so the input has the same shape as for cluster fetched from the API.
+// Fields unknown to the GKE API client are dropped.
+func NewClusterInputFromGcloudJSON(data []byte) (ClusterInput, error) {
+	cluster := &containerpb.Cluster{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, cluster); err != nil {
+		return nil, fmt.Errorf("invalid gcloud cluster JSON: %s", strings.TrimSpace(err.Error()))
+	}
+	return NewClusterInput(cluster)
+}
+
+// isGcloudShape returns true when the input has camel case keys of the REST representation
+// of a cluster, as produced by gcloud. Keys of the API client representation are lower case.
+func (i ClusterInput) isGcloudShape() bool {
+	for key := range i {
+		for _, r := range key {
+			if unicode.IsUpper(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetNodePools replaces node pools of the cluster, i.e. with node pools listed separately.
+func (i ClusterInput) SetNodePools(nodePools []*containerpb.NodePool) error {
+	value := make([]interface{}, 0, len(nodePools))
+	if len(nodePools) > 0 {
+		if err := decodeJSONValue(nodePools, &value); err != nil {
+			return err
+		}
+	}
+	i[inputNodePoolsKey] = value
+	return nil
+}
+
+// IsAutopilot returns true if the input describes Autopilot cluster.
+func (i ClusterInput) IsAutopilot() bool {
+	autopilot, _ := i[inputAutopilotKey].(bool)
+	return autopilot
+}
+
+// normalize replaces the autopilot object of the cluster with a boolean, sets node pools
+// to an empty list when they are missing or cluster is in Autopilot mode, sets add-ons,
+// sets resource labels to an empty object when the cluster has none, sets the release channel
+// and the maintenance policy and computes the cluster age.
+func (i ClusterInput) normalize() {
+	autopilot := false
+	switch value := i[inputAutopilotKey].(type) {
+	case bool:
+		autopilot = value
+	case map[string]interface{}:
+		autopilot, _ = value["enabled"].(bool)
+	}
+	i[inputAutopilotKey] = autopilot
+	if autopilot || i[inputNodePoolsKey] == nil {
+		i[inputNodePoolsKey] = make([]interface{}, 0)
+	}
+	i.setAddons()
+	if _, ok := i[inputLabelsKey].(map[string]interface{}); !ok {
+		i[inputLabelsKey] = make(map[string]interface{})
+	}
+	i.setReleaseChannel()
+	if _, ok := i[inputMaintenancePolicyKey].(map[string]interface{}); !ok {
+		i[inputMaintenancePolicyKey] = make(map[string]interface{})
+	}
+	i.setAgeDays(time.Now())
+}
+
+// setReleaseChannel sets the release channel of clusters not enrolled in any channel to
+// UNSPECIFIED, as the API omits it. Channels are enum numbers, as other enums of the cluster.
+func (i ClusterInput) setReleaseChannel() {
+	channel, ok := i[inputReleaseChannelKey].(map[string]interface{})
+	if !ok {
+		channel = make(map[string]interface{})
+		i[inputReleaseChannelKey] = channel
+	}
+	if _, ok := channel["channel"]; !ok {
+		channel["channel"] = json.Number(strconv.Itoa(int(containerpb.ReleaseChannel_UNSPECIFIED)))
+	}
+}
+
+// setMaintenancePolicy sets the maintenance policy with the daily or recurring window in its
+// window object, i.e. window.daily_maintenance_window, and times as RFC 3339 strings. The generic
+// JSON encoding of the cluster nests the window in a type specific object instead.
+func (i ClusterInput) setMaintenancePolicy(policy *containerpb.MaintenancePolicy) error {
+	delete(i, inputMaintenancePolicyKey)
+	if policy == nil {
+		return nil
+	}
+	data, err := (protojson.MarshalOptions{UseProtoNames: true, UseEnumNumbers: true}).Marshal(policy)
+	if err != nil {
+		return err
+	}
+	value := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	i[inputMaintenancePolicyKey] = value
+	return nil
+}
+
+// Labels returns resource labels of the cluster, empty when the cluster has none.
+func (i ClusterInput) Labels() map[string]string {
+	labels := make(map[string]string)
+	values, _ := i[inputLabelsKey].(map[string]interface{})
+	for key, value := range values {
+		if str, ok := value.(string); ok {
+			labels[key] = str
+		}
+	}
+	return labels
+}
+
+// setAddons sets all known add-ons and binary authorization with their boolean fields. The API
+// omits fields with default values, so a missing field is set to false. A missing add-on is set
+// with its GKE default, see missingAddonFields.
+func (i ClusterInput) setAddons() {
+	addons, ok := i[inputAddonsKey].(map[string]interface{})
+	if !ok {
+		addons = make(map[string]interface{})
+		i[inputAddonsKey] = addons
+	}
+	for addon, field := range addonFields {
+		config, ok := addons[addon].(map[string]interface{})
+		if !ok {
+			addons[addon] = map[string]interface{}{field: missingAddonFields[addon]}
+			continue
+		}
+		if _, ok := config[field]; !ok {
+			config[field] = false
+		}
+	}
+	binAuthz, ok := i[inputBinAuthzKey].(map[string]interface{})
+	if !ok {
+		binAuthz = make(map[string]interface{})
+		i[inputBinAuthzKey] = binAuthz
+	}
+	if _, ok := binAuthz["enabled"]; !ok {
+		binAuthz["enabled"] = false
+	}
+}
+
+// setAgeDays sets the number of full days since the cluster creation time. The age is not
+// set when the creation time is missing, blank or invalid.
+func (i ClusterInput) setAgeDays(now time.Time) {
+	delete(i, inputAgeDaysKey)
+	createTime, _ := i[inputCreateTimeKey].(string)
+	if strings.TrimSpace(createTime) == "" {
+		return
+	}
+	created, err := time.Parse(time.RFC3339, createTime)
+	if err != nil {
+		return
+	}
+	days := int(now.Sub(created).Hours() / 24)
+	if days < 0 {
+		days = 0
+	}
+	i[inputAgeDaysKey] = days
+}
+
+// decodeJSONValue converts value to its generic JSON representation, keeping numbers as json.Number.
+func decodeJSONValue(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+func (i ClusterInput) SetVersions(versions *ClusterVersions) {
+	i[inputVersionsKey] = versions
+}
+
+func NewClusterVersions(cluster *containerpb.Cluster, serverConfig *containerpb.ServerConfig) *ClusterVersions {
+	versions := &ClusterVersions{
+		Master:            cluster.CurrentMasterVersion,
+		Node:              cluster.CurrentNodeVersion,
+		ReleaseChannel:    containerpb.ReleaseChannel_UNSPECIFIED.String(),
+		DefaultVersion:    serverConfig.DefaultClusterVersion,
+		AvailableVersions: serverConfig.ValidMasterVersions,
+	}
+	if cluster.ReleaseChannel != nil && cluster.ReleaseChannel.Channel != containerpb.ReleaseChannel_UNSPECIFIED {
+		versions.ReleaseChannel = cluster.ReleaseChannel.Channel.String()
+		for _, channel := range serverConfig.Channels {
+			if channel.Channel == cluster.ReleaseChannel.Channel {
+				versions.DefaultVersion = channel.DefaultVersion
+				versions.AvailableVersions = channel.ValidVersions
+				break
+			}
+		}
+	}
+	if versions.AvailableVersions == nil {
+		versions.AvailableVersions = make([]string, 0)
+	}
+	return versions
+}
@@ -0,0 +1,563 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+)
+
+func TestNewClusterInput(t *testing.T) {
+	cluster := &containerpb.Cluster{
+		Name:     "warsaw",
+		Location: "europe-central2",
+		MasterAuthorizedNetworksConfig: &containerpb.MasterAuthorizedNetworksConfig{
+			Enabled: true,
+		},
+	}
+	input, err := NewClusterInput(cluster)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if input["name"] != cluster.Name {
+		t.Errorf("input name = %v; want %v", input["name"], cluster.Name)
+	}
+	manc, ok := input["master_authorized_networks_config"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("input master_authorized_networks_config is not a map")
+	}
+	if manc["enabled"] != true {
+		t.Errorf("input master_authorized_networks_config.enabled = %v; want %v", manc["enabled"], true)
+	}
+}
+
+func TestNewClusterInputFromJSON(t *testing.T) {
+	input, err := NewClusterInputFromJSON([]byte(`{"name": "warsaw", "current_node_count": 3}`))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if input["name"] != "warsaw" {
+		t.Errorf("input name = %v; want %v", input["name"], "warsaw")
+	}
+	if input["current_node_count"] != json.Number("3") {
+		t.Errorf("input current_node_count = %v; want %v", input["current_node_count"], json.Number("3"))
+	}
+}
+
+func TestSetAgeDays(t *testing.T) {
+	now := time.Date(2022, 3, 10, 12, 0, 0, 0, time.UTC)
+	inputs := []struct {
+		createTime interface{}
+		expected   interface{}
+	}{
+		{"2022-03-01T13:00:00+00:00", 8},
+		{"2022-03-10T11:00:00Z", 0},
+		{"2022-03-11T00:00:00Z", 0},
+		{"", nil},
+		{"  ", nil},
+		{"yesterday", nil},
+		{nil, nil},
+	}
+	for _, input := range inputs {
+		cluster := ClusterInput{inputAgeDaysKey: 100}
+		if input.createTime != nil {
+			cluster[inputCreateTimeKey] = input.createTime
+		}
+		cluster.setAgeDays(now)
+		if ageDays := cluster[inputAgeDaysKey]; ageDays != input.expected {
+			t.Errorf("createTime %v: age_days = %v; want %v", input.createTime, ageDays, input.expected)
+		}
+	}
+}
+
+func TestNewClusterInput_ageDays(t *testing.T) {
+	created := time.Now().AddDate(0, 0, -30).UTC().Format(time.RFC3339)
+	input, err := NewClusterInputFromJSON([]byte(`{"name": "warsaw", "create_time": "` + created + `"}`))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if input[inputAgeDaysKey] != 30 {
+		t.Errorf("age_days = %v; want %v", input[inputAgeDaysKey], 30)
+	}
+	input, err = NewClusterInput(&containerpb.Cluster{Name: "warsaw"})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if _, ok := input[inputAgeDaysKey]; ok {
+		t.Errorf("age_days is set for cluster without create time")
+	}
+}
+
+func TestNewClusterInputFromJSON_negative(t *testing.T) {
+	inputs := map[string]string{
+		`{"name": "warsaw",}`:   "byte offset 19",
+		`["warsaw"]`:            "byte offset 1",
+		`{"name": "warsaw"} {}`: "byte offset 19",
+		`{"name": "warsaw"`:     "invalid JSON",
+	}
+	for data, expected := range inputs {
+		_, err := NewClusterInputFromJSON([]byte(data))
+		if err == nil {
+			t.Errorf("input %s: err is nil; want error", data)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("input %s: err = %v; want it to contain %q", data, err, expected)
+		}
+	}
+}
+
+func TestNodePools(t *testing.T) {
+	data, err := os.ReadFile("test-fixtures/cluster_node_pools.json")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	input, err := NewClusterInputFromJSON(data)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	nodePools, ok := input[inputNodePoolsKey].([]interface{})
+	if !ok || len(nodePools) != 2 {
+		t.Fatalf("input node_pools = %v; want list of 2 node pools", input[inputNodePoolsKey])
+	}
+	nodePool := nodePools[1].(map[string]interface{})
+	if nodePool["name"] != "preemptible-pool" {
+		t.Errorf("node pool name = %v; want %v", nodePool["name"], "preemptible-pool")
+	}
+	if config := nodePool["config"].(map[string]interface{}); config["machine_type"] != "e2-standard-4" {
+		t.Errorf("node pool config.machine_type = %v; want %v", config["machine_type"], "e2-standard-4")
+	}
+
+	fromAPI := ClusterInput{}
+	err = fromAPI.SetNodePools([]*containerpb.NodePool{
+		{
+			Name: "default-pool",
+			Config: &containerpb.NodeConfig{
+				MachineType: "e2-medium", DiskSizeGb: 100, ImageType: "COS_CONTAINERD"},
+			InitialNodeCount: 1,
+			Locations:        []string{"europe-central2-a", "europe-central2-b"},
+			Autoscaling:      &containerpb.NodePoolAutoscaling{Enabled: true, MinNodeCount: 1, MaxNodeCount: 3},
+			Management:       &containerpb.NodeManagement{AutoUpgrade: true, AutoRepair: true},
+			Version:          "1.21.6-gke.1500",
+			Status:           containerpb.NodePool_RUNNING,
+		},
+		{
+			Name: "preemptible-pool",
+			Config: &containerpb.NodeConfig{
+				MachineType: "e2-standard-4", DiskSizeGb: 50, ImageType: "COS_CONTAINERD", Preemptible: true},
+			InitialNodeCount: 1,
+			Locations:        []string{"europe-central2-a"},
+			Management:       &containerpb.NodeManagement{AutoUpgrade: true},
+			Version:          "1.21.6-gke.1500",
+			Status:           containerpb.NodePool_RUNNING,
+		},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(fromAPI[inputNodePoolsKey], input[inputNodePoolsKey]) {
+		t.Errorf("node pools from API = %v; want %v", fromAPI[inputNodePoolsKey], input[inputNodePoolsKey])
+	}
+}
+
+func TestNodePools_empty(t *testing.T) {
+	fromJSON, err := NewClusterInputFromJSON([]byte(`{"name": "warsaw"}`))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	fromCluster, err := NewClusterInput(&containerpb.Cluster{Name: "warsaw"})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	fromList := ClusterInput{}
+	if err := fromList.SetNodePools(nil); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	for _, input := range []ClusterInput{fromJSON, fromCluster, fromList} {
+		if nodePools, ok := input[inputNodePoolsKey].([]interface{}); !ok || len(nodePools) != 0 {
+			t.Errorf("input node_pools = %#v; want empty list", input[inputNodePoolsKey])
+		}
+	}
+}
+
+func TestNewClusterInput_autopilot(t *testing.T) {
+	autopilot, err := NewClusterInput(&containerpb.Cluster{
+		Name:      "warsaw",
+		Autopilot: &containerpb.Autopilot{Enabled: true},
+		NodePools: []*containerpb.NodePool{{Name: "default-pool"}},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !autopilot.IsAutopilot() || autopilot[inputAutopilotKey] != true {
+		t.Errorf("input autopilot = %v; want %v", autopilot[inputAutopilotKey], true)
+	}
+	if nodePools, ok := autopilot[inputNodePoolsKey].([]interface{}); !ok || len(nodePools) != 0 {
+		t.Errorf("input node_pools = %#v; want empty list", autopilot[inputNodePoolsKey])
+	}
+	inputs := map[string]bool{
+		`{"name": "warsaw"}`:                          false,
+		`{"name": "warsaw", "autopilot": {}}`:         false,
+		`{"name": "warsaw", "autopilot": true}`:       true,
+		`{"autopilot": {"enabled": true}}`:            true,
+		`{"autopilot": {"enabled": false}, "x": "y"}`: false,
+	}
+	for data, expected := range inputs {
+		input, err := NewClusterInputFromJSON([]byte(data))
+		if err != nil {
+			t.Fatalf("input %s: err = %v; want nil", data, err)
+		}
+		if input[inputAutopilotKey] != expected {
+			t.Errorf("input %s: autopilot = %v; want %v", data, input[inputAutopilotKey], expected)
+		}
+	}
+}
+
+func TestSetVersions(t *testing.T) {
+	input := ClusterInput{}
+	versions := &ClusterVersions{Master: "1.21.6-gke.1500"}
+	input.SetVersions(versions)
+	if !reflect.DeepEqual(input[inputVersionsKey], versions) {
+		t.Errorf("input versions = %v; want %v", input[inputVersionsKey], versions)
+	}
+}
+
+func TestNewClusterVersions(t *testing.T) {
+	serverConfig := &containerpb.ServerConfig{
+		DefaultClusterVersion: "1.21.6-gke.1500",
+		ValidMasterVersions:   []string{"1.22.6-gke.300", "1.21.6-gke.1500", "1.20.15-gke.300"},
+		Channels: []*containerpb.ServerConfig_ReleaseChannelConfig{
+			{
+				Channel:        containerpb.ReleaseChannel_REGULAR,
+				DefaultVersion: "1.21.6-gke.1503",
+				ValidVersions:  []string{"1.21.6-gke.1503", "1.21.5-gke.1302"},
+			},
+		},
+	}
+	clusterNoChannel := &containerpb.Cluster{
+		CurrentMasterVersion: "1.20.15-gke.300",
+		CurrentNodeVersion:   "1.20.15-gke.300",
+	}
+	versions := NewClusterVersions(clusterNoChannel, serverConfig)
+	if versions.ReleaseChannel != "UNSPECIFIED" {
+		t.Errorf("release channel = %v; want %v", versions.ReleaseChannel, "UNSPECIFIED")
+	}
+	if versions.DefaultVersion != serverConfig.DefaultClusterVersion {
+		t.Errorf("default version = %v; want %v", versions.DefaultVersion, serverConfig.DefaultClusterVersion)
+	}
+	if !reflect.DeepEqual(versions.AvailableVersions, serverConfig.ValidMasterVersions) {
+		t.Errorf("available versions = %v; want %v", versions.AvailableVersions, serverConfig.ValidMasterVersions)
+	}
+
+	clusterRegular := &containerpb.Cluster{
+		CurrentMasterVersion: "1.21.5-gke.1302",
+		CurrentNodeVersion:   "1.21.5-gke.1302",
+		ReleaseChannel:       &containerpb.ReleaseChannel{Channel: containerpb.ReleaseChannel_REGULAR},
+	}
+	versions = NewClusterVersions(clusterRegular, serverConfig)
+	if versions.ReleaseChannel != "REGULAR" {
+		t.Errorf("release channel = %v; want %v", versions.ReleaseChannel, "REGULAR")
+	}
+	if versions.DefaultVersion != "1.21.6-gke.1503" {
+		t.Errorf("default version = %v; want %v", versions.DefaultVersion, "1.21.6-gke.1503")
+	}
+	if len(versions.AvailableVersions) != 2 {
+		t.Errorf("len(available versions) = %v; want %v", len(versions.AvailableVersions), 2)
+	}
+
+	versions = NewClusterVersions(clusterRegular, &containerpb.ServerConfig{})
+	if versions.AvailableVersions == nil {
+		t.Errorf("available versions is nil; want empty list")
+	}
+}
+
+func TestNewClusterInputFromGcloudJSON(t *testing.T) {
+	data, err := os.ReadFile("test-fixtures/gcloud_cluster.json")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	fromGcloud, err := NewClusterInputFromJSON(data)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	fromAPI, err := NewClusterInput(&containerpb.Cluster{
+		Name:                     "warsaw",
+		Location:                 "europe-central2",
+		Zone:                     "europe-central2",
+		Locations:                []string{"europe-central2-a", "europe-central2-b", "europe-central2-c"},
+		SelfLink:                 "https://container.googleapis.com/v1/projects/my-project/locations/europe-central2/clusters/warsaw",
+		CreateTime:               "2022-03-01T10:00:00+00:00",
+		CurrentMasterVersion:     "1.21.6-gke.1500",
+		CurrentNodeVersion:       "1.21.6-gke.1500",
+		CurrentNodeCount:         3,
+		Status:                   containerpb.Cluster_RUNNING,
+		DefaultMaxPodsConstraint: &containerpb.MaxPodsConstraint{MaxPodsPerNode: 110},
+		MasterAuthorizedNetworksConfig: &containerpb.MasterAuthorizedNetworksConfig{
+			Enabled: true,
+			CidrBlocks: []*containerpb.MasterAuthorizedNetworksConfig_CidrBlock{
+				{DisplayName: "internal", CidrBlock: "10.0.0.0/8"},
+			},
+		},
+		PrivateClusterConfig: &containerpb.PrivateClusterConfig{
+			EnablePrivateNodes:  true,
+			MasterIpv4CidrBlock: "172.16.0.0/28",
+		},
+		ReleaseChannel: &containerpb.ReleaseChannel{Channel: containerpb.ReleaseChannel_REGULAR},
+		NodePools: []*containerpb.NodePool{
+			{
+				Name: "default-pool",
+				Config: &containerpb.NodeConfig{
+					MachineType: "e2-medium", DiskSizeGb: 100, ImageType: "COS_CONTAINERD"},
+				InitialNodeCount:  1,
+				Autoscaling:       &containerpb.NodePoolAutoscaling{Enabled: true, MinNodeCount: 1, MaxNodeCount: 3},
+				Management:        &containerpb.NodeManagement{AutoUpgrade: true, AutoRepair: true},
+				MaxPodsConstraint: &containerpb.MaxPodsConstraint{MaxPodsPerNode: 110},
+				PodIpv4CidrSize:   24,
+				Status:            containerpb.NodePool_RUNNING,
+				Version:           "1.21.6-gke.1500",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(fromGcloud, fromAPI) {
+		t.Errorf("input from gcloud = %v; want %v", fromGcloud, fromAPI)
+	}
+}
+
+func TestNewClusterInputFromGcloudJSON_negative(t *testing.T) {
+	if _, err := NewClusterInputFromJSON([]byte(`{"name": "warsaw", "currentNodeCount": "three"}`)); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
+
+func TestNewClusterInputFromJSON_addons(t *testing.T) {
+	inputs := map[string]bool{
+		"test-fixtures/cluster_addons_enabled.json":  true,
+		"test-fixtures/cluster_addons_disabled.json": false,
+	}
+	for path, enabled := range inputs {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		input, err := NewClusterInputFromJSON(data)
+		if err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		addons, ok := input[inputAddonsKey].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s: addons_config = %v; want object", path, input[inputAddonsKey])
+		}
+		for addon, field := range addonFields {
+			config, _ := addons[addon].(map[string]interface{})
+			expected := enabled
+			if field == "disabled" {
+				expected = !enabled
+			}
+			if config[field] != expected {
+				t.Errorf("%s: addons_config.%s.%s = %v; want %v", path, addon, field, config[field], expected)
+			}
+		}
+		binAuthz, _ := input[inputBinAuthzKey].(map[string]interface{})
+		if binAuthz["enabled"] != enabled {
+			t.Errorf("%s: binary_authorization.enabled = %v; want %v", path, binAuthz["enabled"], enabled)
+		}
+	}
+}
+
+func TestNewClusterInputFromJSON_addonsMissing(t *testing.T) {
+	data, err := os.ReadFile("test-fixtures/cluster_addons_missing.json")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	input, err := NewClusterInputFromJSON(data)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := map[string]interface{}{
+		"http_load_balancing":                   map[string]interface{}{"disabled": false},
+		"horizontal_pod_autoscaling":            map[string]interface{}{"disabled": false},
+		"kubernetes_dashboard":                  map[string]interface{}{"disabled": true},
+		"network_policy_config":                 map[string]interface{}{"disabled": true},
+		"cloud_run_config":                      map[string]interface{}{"disabled": true},
+		"dns_cache_config":                      map[string]interface{}{"enabled": false},
+		"config_connector_config":               map[string]interface{}{"enabled": false},
+		"gce_persistent_disk_csi_driver_config": map[string]interface{}{"enabled": false},
+		"gcp_filestore_csi_driver_config":       map[string]interface{}{"enabled": false},
+	}
+	if !reflect.DeepEqual(input[inputAddonsKey], expected) {
+		t.Errorf("addons_config = %v; want %v", input[inputAddonsKey], expected)
+	}
+	if !reflect.DeepEqual(input[inputBinAuthzKey], map[string]interface{}{"enabled": false}) {
+		t.Errorf("binary_authorization = %v; want enabled false", input[inputBinAuthzKey])
+	}
+}
+
+func TestNewClusterInput_addons(t *testing.T) {
+	input, err := NewClusterInput(&containerpb.Cluster{
+		Name: "warsaw",
+		AddonsConfig: &containerpb.AddonsConfig{
+			NetworkPolicyConfig: &containerpb.NetworkPolicyConfig{Disabled: false},
+			DnsCacheConfig:      &containerpb.DnsCacheConfig{Enabled: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	addons := input[inputAddonsKey].(map[string]interface{})
+	expected := map[string]interface{}{
+		"network_policy_config.disabled":  false,
+		"dns_cache_config.enabled":        true,
+		"http_load_balancing.disabled":    false,
+		"config_connector_config.enabled": false,
+		"cloud_run_config.disabled":       true,
+	}
+	for path, value := range expected {
+		parts := strings.Split(path, ".")
+		config, _ := addons[parts[0]].(map[string]interface{})
+		if config[parts[1]] != value {
+			t.Errorf("addons_config.%s = %v; want %v", path, config[parts[1]], value)
+		}
+	}
+	if !reflect.DeepEqual(input[inputBinAuthzKey], map[string]interface{}{"enabled": false}) {
+		t.Errorf("binary_authorization = %v; want enabled false", input[inputBinAuthzKey])
+	}
+}
+
+func TestNewClusterInput_labels(t *testing.T) {
+	input, err := NewClusterInput(&containerpb.Cluster{
+		Name:           "warsaw",
+		ResourceLabels: map[string]string{"team": "platform", "env": "prod"},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := map[string]string{"team": "platform", "env": "prod"}
+	if labels := input.Labels(); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("labels = %v; want %v", labels, expected)
+	}
+}
+
+func TestNewClusterInput_noLabels(t *testing.T) {
+	fromAPI, err := NewClusterInput(&containerpb.Cluster{Name: "warsaw"})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	fromJSON, err := NewClusterInputFromJSON([]byte(`{"name": "warsaw", "resource_labels": null}`))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	for name, input := range map[string]ClusterInput{"api": fromAPI, "json": fromJSON} {
+		labels, ok := input[inputLabelsKey].(map[string]interface{})
+		if !ok || labels == nil || len(labels) != 0 {
+			t.Errorf("%s: resource_labels = %#v; want empty object", name, input[inputLabelsKey])
+		}
+		if labels := input.Labels(); labels == nil || len(labels) != 0 {
+			t.Errorf("%s: labels = %#v; want empty map", name, labels)
+		}
+	}
+}
+
+func TestNewClusterInput_releaseChannel(t *testing.T) {
+	fromAPI, err := NewClusterInput(&containerpb.Cluster{
+		Name:           "warsaw",
+		ReleaseChannel: &containerpb.ReleaseChannel{Channel: containerpb.ReleaseChannel_STABLE},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := map[string]interface{}{"channel": json.Number("3")}
+	if !reflect.DeepEqual(fromAPI[inputReleaseChannelKey], expected) {
+		t.Errorf("release_channel = %v; want %v", fromAPI[inputReleaseChannelKey], expected)
+	}
+	noChannelAPI, err := NewClusterInput(&containerpb.Cluster{Name: "warsaw"})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	noChannelJSON, err := NewClusterInputFromJSON([]byte(`{"name": "warsaw", "release_channel": {}}`))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected = map[string]interface{}{"channel": json.Number("0")}
+	for name, input := range map[string]ClusterInput{"api": noChannelAPI, "json": noChannelJSON} {
+		if !reflect.DeepEqual(input[inputReleaseChannelKey], expected) {
+			t.Errorf("%s: release_channel = %v; want %v", name, input[inputReleaseChannelKey], expected)
+		}
+	}
+}
+
+func TestNewClusterInput_maintenancePolicy(t *testing.T) {
+	fromAPI, err := NewClusterInput(&containerpb.Cluster{
+		Name: "warsaw",
+		MaintenancePolicy: &containerpb.MaintenancePolicy{
+			Window: &containerpb.MaintenanceWindow{
+				Policy: &containerpb.MaintenanceWindow_DailyMaintenanceWindow{
+					DailyMaintenanceWindow: &containerpb.DailyMaintenanceWindow{StartTime: "03:00", Duration: "PT4H0M0S"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := map[string]interface{}{
+		"window": map[string]interface{}{
+			"daily_maintenance_window": map[string]interface{}{"start_time": "03:00", "duration": "PT4H0M0S"},
+		},
+	}
+	if !reflect.DeepEqual(fromAPI[inputMaintenancePolicyKey], expected) {
+		t.Errorf("maintenance_policy = %v; want %v", fromAPI[inputMaintenancePolicyKey], expected)
+	}
+
+	data, err := os.ReadFile("test-fixtures/gcloud_cluster_maintenance.json")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	fromGcloud, err := NewClusterInputFromJSON(data)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected = map[string]interface{}{
+		"window": map[string]interface{}{
+			"recurring_window": map[string]interface{}{
+				"window":     map[string]interface{}{"start_time": "2022-01-01T09:00:00Z", "end_time": "2022-01-01T17:00:00Z"},
+				"recurrence": "FREQ=WEEKLY;BYDAY=SA,SU",
+			},
+			"maintenance_exclusions": map[string]interface{}{
+				"holidays": map[string]interface{}{"start_time": "2022-12-20T00:00:00Z", "end_time": "2023-01-03T00:00:00Z"},
+			},
+		},
+		"resource_version": "ce912209",
+	}
+	if !reflect.DeepEqual(fromGcloud[inputMaintenancePolicyKey], expected) {
+		t.Errorf("maintenance_policy = %v; want %v", fromGcloud[inputMaintenancePolicyKey], expected)
+	}
+
+	noPolicy, err := NewClusterInputFromJSON([]byte(`{"name": "warsaw"}`))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if policy, ok := noPolicy[inputMaintenancePolicyKey].(map[string]interface{}); !ok || len(policy) != 0 {
+		t.Errorf("maintenance_policy = %#v; want empty object", noPolicy[inputMaintenancePolicyKey])
+	}
+}
@@ -0,0 +1,197 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	inputSecurityPostureKey = "security_posture"
+	// securityPostureEndpoint is the Container Security API endpoint with security posture findings
+	securityPostureEndpoint = "https://containersecurity.googleapis.com/v1beta/"
+)
+
+// SecurityPostureClient lists security posture findings in a location of a project.
+type SecurityPostureClient interface {
+	ListFindings(ctx context.Context, parent string) ([]*SecurityPostureFinding, error)
+}
+
+// SecurityPosture holds security posture findings of the cluster and its workloads, set under
+// the security_posture key of the input.
+type SecurityPosture struct {
+	Findings []*SecurityPostureFinding `json:"findings"`
+}
+
+// SecurityPostureFinding is a misconfiguration or a vulnerability found by GKE security posture.
+type SecurityPostureFinding struct {
+	Name         string `json:"name"`
+	ResourceName string `json:"resource_name"`
+	// Type is the finding type, i.e. MISCONFIG or VULNERABILITY
+	Type string `json:"type"`
+	// State is the finding state, i.e. ACTIVE or REMEDIATED
+	State string `json:"state"`
+	// Finding identifies the issue, i.e. a CVE ID or a misconfiguration check
+	Finding   string `json:"finding"`
+	Severity  string `json:"severity"`
+	EventTime string `json:"event_time"`
+}
+
+// securityPostureFinding is the finding in the API response.
+type securityPostureFinding struct {
+	Name         string `json:"name"`
+	ResourceName string `json:"resourceName"`
+	Type         string `json:"type"`
+	State        string `json:"state"`
+	Finding      string `json:"finding"`
+	Severity     string `json:"severity"`
+	EventTime    string `json:"eventTime"`
+}
+
+type listFindingsResponse struct {
+	Findings      []*securityPostureFinding `json:"findings"`
+	NextPageToken string                    `json:"nextPageToken"`
+}
+
+type securityPostureClient struct {
+	client   *http.Client
+	endpoint string
+}
+
+// ListFindings returns findings of the parent, i.e. projects/P/locations/L, from all pages.
+func (c *securityPostureClient) ListFindings(ctx context.Context, parent string) ([]*SecurityPostureFinding, error) {
+	findings := make([]*SecurityPostureFinding, 0)
+	pageToken := ""
+	for {
+		query := url.Values{}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		page, err := c.listFindingsPage(ctx, parent, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range page.Findings {
+			findings = append(findings, &SecurityPostureFinding{
+				Name:         finding.Name,
+				ResourceName: finding.ResourceName,
+				Type:         finding.Type,
+				State:        finding.State,
+				Finding:      finding.Finding,
+				Severity:     finding.Severity,
+				EventTime:    finding.EventTime,
+			})
+		}
+		if page.NextPageToken == "" {
+			return findings, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (c *securityPostureClient) listFindingsPage(ctx context.Context, parent string, query url.Values) (*listFindingsResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+parent+"/findings?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+	page := &listFindingsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// GetSecurityPosture returns security posture findings of the cluster with the given full name
+// and of its workloads. Security posture client is created on first use, so it is not needed
+// unless findings are fetched.
+func (c *GKEClient) GetSecurityPosture(clusterName string) (*SecurityPosture, error) {
+	parent, err := GetLocationName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.getSecurityPosture()
+	if err != nil {
+		return nil, err
+	}
+	findings, err := client.ListFindings(c.ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	return newClusterSecurityPosture(clusterName, findings), nil
+}
+
+// newClusterSecurityPosture keeps findings of resources of the cluster with the given full name.
+func newClusterSecurityPosture(clusterName string, findings []*SecurityPostureFinding) *SecurityPosture {
+	resourceName := gkeResourceLinkPrefix + clusterName
+	posture := &SecurityPosture{Findings: make([]*SecurityPostureFinding, 0)}
+	for _, finding := range findings {
+		if finding.ResourceName == resourceName || strings.HasPrefix(finding.ResourceName, resourceName+"/") {
+			posture.Findings = append(posture.Findings, finding)
+		}
+	}
+	return posture
+}
+
+func (c *GKEClient) getSecurityPosture() (SecurityPostureClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.securityPosture == nil {
+		opts := append(c.opts, option.WithScopes(cloudPlatformScope))
+		var client *http.Client
+		if c.transport != nil {
+			rt, err := htransport.NewTransport(c.ctx, c.transport, opts...)
+			if err != nil {
+				return nil, err
+			}
+			client = &http.Client{Transport: rt}
+		} else {
+			var err error
+			if client, _, err = htransport.NewClient(c.ctx, opts...); err != nil {
+				return nil, err
+			}
+		}
+		c.securityPosture = &securityPostureClient{client: client, endpoint: securityPostureEndpoint}
+	}
+	return c.securityPosture, nil
+}
+
+// IsSecurityPostureUnavailable tells whether findings could not be listed because the Container
+// Security API is not enabled in the project or the caller lacks permissions.
+func IsSecurityPostureUnavailable(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && (apiErr.Code == http.StatusForbidden || apiErr.Code == http.StatusNotFound)
+}
+
+// SetSecurityPosture sets security posture findings of the cluster in the input.
+func (i ClusterInput) SetSecurityPosture(posture *SecurityPosture) {
+	i[inputSecurityPostureKey] = posture
+}
@@ -0,0 +1,96 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type mockSecurityPostureClient struct {
+}
+
+func (mockSecurityPostureClient) ListFindings(ctx context.Context, parent string) ([]*SecurityPostureFinding, error) {
+	if parent != "projects/test-project/locations/europe-central2" {
+		return nil, fmt.Errorf("parent %q is not mocked", parent)
+	}
+	return []*SecurityPostureFinding{
+		{Name: "one", ResourceName: "//container.googleapis.com/projects/test-project/locations/europe-central2/clusters/warsaw", Type: "MISCONFIG"},
+		{Name: "two", ResourceName: "//container.googleapis.com/projects/test-project/locations/europe-central2/clusters/warsaw/k8s/namespaces/default/apps/deployments/app", Type: "VULNERABILITY"},
+		{Name: "three", ResourceName: "//container.googleapis.com/projects/test-project/locations/europe-central2/clusters/warsaw-2", Type: "MISCONFIG"},
+	}, nil
+}
+
+func TestGetSecurityPosture(t *testing.T) {
+	client := GKEClient{
+		ctx:             context.Background(),
+		client:          &mockClusterManagerClient{},
+		securityPosture: &mockSecurityPostureClient{},
+	}
+	posture, err := client.GetSecurityPosture(GetClusterName("test-project", "europe-central2", "warsaw"))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	names := make([]string, 0, len(posture.Findings))
+	for _, finding := range posture.Findings {
+		names = append(names, finding.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"one", "two"}) {
+		t.Errorf("findings = %v; want %v", names, []string{"one", "two"})
+	}
+}
+
+func TestSecurityPostureClient_ListFindings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/projects/test-project/locations/europe-central2/findings" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"findings": [{"name": "one", "resourceName": "//container.googleapis.com/c", "type": "VULNERABILITY",
+				"state": "ACTIVE", "finding": "CVE-2022-0001", "severity": "HIGH", "eventTime": "2022-03-15T06:30:00Z"}],
+				"nextPageToken": "next"}`)
+			return
+		}
+		fmt.Fprint(w, `{"findings": [{"name": "two", "type": "MISCONFIG"}]}`)
+	}))
+	defer server.Close()
+	client := &securityPostureClient{client: server.Client(), endpoint: server.URL + "/v1beta/"}
+	findings, err := client.ListFindings(context.Background(), "projects/test-project/locations/europe-central2")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := []*SecurityPostureFinding{
+		{Name: "one", ResourceName: "//container.googleapis.com/c", Type: "VULNERABILITY", State: "ACTIVE",
+			Finding: "CVE-2022-0001", Severity: "HIGH", EventTime: "2022-03-15T06:30:00Z"},
+		{Name: "two", Type: "MISCONFIG"},
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("findings = %v; want %v", findings, expected)
+	}
+
+	_, err = client.ListFindings(context.Background(), "projects/disabled-project/locations/europe-central2")
+	if !IsSecurityPostureUnavailable(err) {
+		t.Errorf("IsSecurityPostureUnavailable(%v) = false; want true", err)
+	}
+	if IsSecurityPostureUnavailable(fmt.Errorf("other error")) {
+		t.Errorf("IsSecurityPostureUnavailable(other error) = true; want false")
+	}
+}
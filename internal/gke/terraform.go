@@ -0,0 +1,416 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+)
+
+const (
+	terraformClusterType  = "google_container_cluster"
+	terraformNodePoolType = "google_container_node_pool"
+)
+
+// terraformListBlocks are nested blocks that may repeat. Other blocks hold a single
+// object, which Terraform represents as a list of one element.
+var terraformListBlocks = map[string]bool{
+	"node_pool":             true,
+	"cidr_blocks":           true,
+	"guest_accelerator":     true,
+	"taint":                 true,
+	"maintenance_exclusion": true,
+	"resource_limits":       true,
+}
+
+// terraformEnums are attributes that are enums in the cluster input, with their values.
+var terraformEnums = map[string]map[string]int32{
+	"release_channel.channel":   containerpb.ReleaseChannel_Channel_value,
+	"network_policy.provider":   containerpb.NetworkPolicy_Provider_value,
+	"database_encryption.state": containerpb.DatabaseEncryption_State_value,
+}
+
+// terraformExclusionScopes are values of the scope of maintenance exclusions.
+var terraformExclusionScopes = map[string]int{
+	"NO_UPGRADES":               0,
+	"NO_MINOR_UPGRADES":         1,
+	"NO_MINOR_OR_NODE_UPGRADES": 2,
+}
+
+var terraformIndex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// TerraformCluster is a cluster defined in Terraform plan or state, with its resource address.
+type TerraformCluster struct {
+	Address string
+	Input   ClusterInput
+}
+
+type terraformDocument struct {
+	FormatVersion string                  `json:"format_version"`
+	PlannedValues *terraformValues        `json:"planned_values"`
+	Values        *terraformValues        `json:"values"`
+	Configuration *terraformConfiguration `json:"configuration"`
+}
+
+type terraformValues struct {
+	RootModule *terraformModule `json:"root_module"`
+}
+
+type terraformModule struct {
+	Resources    []*terraformResource `json:"resources"`
+	ChildModules []*terraformModule   `json:"child_modules"`
+}
+
+type terraformResource struct {
+	Address string                 `json:"address"`
+	Mode    string                 `json:"mode"`
+	Type    string                 `json:"type"`
+	Values  map[string]interface{} `json:"values"`
+}
+
+type terraformConfiguration struct {
+	RootModule *terraformConfigModule `json:"root_module"`
+}
+
+type terraformConfigModule struct {
+	Resources   []*terraformConfigResource `json:"resources"`
+	ModuleCalls map[string]*struct {
+		Module *terraformConfigModule `json:"module"`
+	} `json:"module_calls"`
+}
+
+type terraformConfigResource struct {
+	Address     string `json:"address"`
+	Type        string `json:"type"`
+	Expressions struct {
+		Cluster *struct {
+			References []string `json:"references"`
+		} `json:"cluster"`
+	} `json:"expressions"`
+}
+
+// NewClusterInputsFromTerraformJSON creates input documents of clusters defined in output of
+// terraform show -json for a plan or a state. Resources of google_container_cluster are adapted
+// to the shape of clusters fetched from the API, with google_container_node_pool resources added
+// to node pools of their clusters. Clusters are sorted by their resource addresses.
+func NewClusterInputsFromTerraformJSON(data []byte) ([]*TerraformCluster, error) {
+	doc := &terraformDocument{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(doc); err != nil {
+		return nil, fmt.Errorf("invalid Terraform JSON: %s", err)
+	}
+	values := doc.PlannedValues
+	if values == nil {
+		values = doc.Values
+	}
+	if doc.FormatVersion == "" || values == nil || values.RootModule == nil {
+		return nil, errors.New("not a Terraform plan or state, expected output of terraform show -json")
+	}
+	clusters := make([]*TerraformCluster, 0)
+	nodePools := make([]*terraformResource, 0)
+	for _, resource := range values.RootModule.managedResources() {
+		switch resource.Type {
+		case terraformClusterType:
+			clusters = append(clusters, &TerraformCluster{
+				Address: resource.Address,
+				Input:   newClusterInputFromTerraform(resource.Values),
+			})
+		case terraformNodePoolType:
+			nodePools = append(nodePools, resource)
+		}
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no %s resources found in Terraform plan or state", terraformClusterType)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Address < clusters[j].Address })
+	references := make(map[string]string)
+	if doc.Configuration != nil && doc.Configuration.RootModule != nil {
+		doc.Configuration.RootModule.collectClusterReferences("", references)
+	}
+	for _, nodePool := range nodePools {
+		cluster := findTerraformCluster(clusters, nodePool, references)
+		if cluster == nil {
+			return nil, fmt.Errorf("could not find cluster of node pool %s", nodePool.Address)
+		}
+		pools, _ := cluster.Input[inputNodePoolsKey].([]interface{})
+		cluster.Input[inputNodePoolsKey] = append(pools, newNodePoolInputFromTerraform(nodePool.Values))
+	}
+	for _, cluster := range clusters {
+		cluster.Input.normalize()
+	}
+	return clusters, nil
+}
+
+// managedResources returns managed resources of the module and all its child modules.
+func (m *terraformModule) managedResources() []*terraformResource {
+	resources := make([]*terraformResource, 0, len(m.Resources))
+	for _, resource := range m.Resources {
+		if resource.Mode == "managed" {
+			resources = append(resources, resource)
+		}
+	}
+	for _, child := range m.ChildModules {
+		resources = append(resources, child.managedResources()...)
+	}
+	return resources
+}
+
+// collectClusterReferences maps addresses of node pools to addresses of clusters their
+// cluster attribute refers to. Addresses in the configuration have no instance keys.
+func (m *terraformConfigModule) collectClusterReferences(prefix string, references map[string]string) {
+	for _, resource := range m.Resources {
+		if resource.Type != terraformNodePoolType || resource.Expressions.Cluster == nil {
+			continue
+		}
+		for _, reference := range resource.Expressions.Cluster.References {
+			parts := strings.Split(reference, ".")
+			if len(parts) >= 2 && parts[0] == terraformClusterType {
+				references[prefix+resource.Address] = prefix + parts[0] + "." + parts[1]
+				break
+			}
+		}
+	}
+	for name, call := range m.ModuleCalls {
+		if call != nil && call.Module != nil {
+			call.Module.collectClusterReferences(prefix+"module."+name+".", references)
+		}
+	}
+}
+
+// findTerraformCluster returns the cluster of a node pool, found by the reference in the
+// configuration, by the value of its cluster attribute or, as the last resort, the only cluster.
+func findTerraformCluster(clusters []*TerraformCluster, nodePool *terraformResource, references map[string]string) *TerraformCluster {
+	if reference, ok := references[terraformIndex.ReplaceAllString(nodePool.Address, "")]; ok {
+		candidates := make([]*TerraformCluster, 0)
+		for _, cluster := range clusters {
+			if terraformIndex.ReplaceAllString(cluster.Address, "") == reference {
+				candidates = append(candidates, cluster)
+			}
+		}
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+		index := strings.Join(terraformIndex.FindAllString(nodePool.Address, -1), "")
+		for _, cluster := range candidates {
+			if strings.Join(terraformIndex.FindAllString(cluster.Address, -1), "") == index {
+				return cluster
+			}
+		}
+	}
+	if value, ok := nodePool.Values["cluster"].(string); ok && value != "" {
+		for _, cluster := range clusters {
+			name, _ := cluster.Input["name"].(string)
+			id, _ := cluster.Input["id"].(string)
+			if value == name || value == id || (name != "" && strings.HasSuffix(value, "/clusters/"+name)) {
+				return cluster
+			}
+		}
+	}
+	if len(clusters) == 1 {
+		return clusters[0]
+	}
+	return nil
+}
+
+// newClusterInputFromTerraform adapts attributes of google_container_cluster resource
+// to the cluster input.
+func newClusterInputFromTerraform(values map[string]interface{}) ClusterInput {
+	input := ClusterInput{}
+	for key, value := range values {
+		switch key {
+		case "enable_autopilot":
+			input[inputAutopilotKey] = value
+		case "enable_legacy_abac":
+			input["legacy_abac"] = map[string]interface{}{"enabled": value}
+		case "enable_shielded_nodes":
+			input["shielded_nodes"] = map[string]interface{}{"enabled": value}
+		case "enable_binary_authorization":
+			if _, ok := values["binary_authorization"]; !ok && value != nil {
+				input[inputBinAuthzKey] = map[string]interface{}{"enabled": value}
+			}
+		case "master_version":
+			input["current_master_version"] = value
+		case "node_locations":
+			input["locations"] = value
+		case "maintenance_policy":
+			if converted, ok := terraformValue(key, value); ok {
+				input[inputMaintenancePolicyKey] = newMaintenancePolicyFromTerraform(converted)
+			}
+		case "node_pool":
+			pools := make([]interface{}, 0)
+			list, _ := value.([]interface{})
+			for _, item := range list {
+				if pool, ok := item.(map[string]interface{}); ok {
+					pools = append(pools, newNodePoolInputFromTerraform(pool))
+				}
+			}
+			input[inputNodePoolsKey] = pools
+		default:
+			if converted, ok := terraformValue(key, value); ok {
+				input[key] = converted
+			}
+		}
+	}
+	if _, ok := input["current_master_version"]; !ok && input["min_master_version"] != nil {
+		input["current_master_version"] = input["min_master_version"]
+	}
+	if config, ok := input["master_authorized_networks_config"].(map[string]interface{}); ok {
+		config["enabled"] = true
+	}
+	for path, enum := range terraformEnums {
+		parts := strings.SplitN(path, ".", 2)
+		block, ok := input[parts[0]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := block[parts[1]].(string); ok {
+			if number, ok := enum[name]; ok {
+				block[parts[1]] = json.Number(strconv.Itoa(int(number)))
+			}
+		}
+	}
+	return input
+}
+
+// newMaintenancePolicyFromTerraform adapts maintenance_policy block to the maintenance policy
+// of the cluster input, with the daily or recurring window and exclusions in its window object.
+// Exclusions are keyed by their names.
+func newMaintenancePolicyFromTerraform(value interface{}) map[string]interface{} {
+	block, _ := value.(map[string]interface{})
+	window := make(map[string]interface{})
+	if daily, ok := block["daily_maintenance_window"]; ok {
+		window["daily_maintenance_window"] = daily
+	}
+	if recurring, ok := block["recurring_window"].(map[string]interface{}); ok {
+		window["recurring_window"] = map[string]interface{}{
+			"window":     newTimeWindowFromTerraform(recurring),
+			"recurrence": recurring["recurrence"],
+		}
+	}
+	exclusions := make(map[string]interface{})
+	list, _ := block["maintenance_exclusion"].([]interface{})
+	for _, item := range list {
+		exclusion, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		timeWindow := newTimeWindowFromTerraform(exclusion)
+		if options, ok := exclusion["exclusion_options"].(map[string]interface{}); ok {
+			scope, _ := options["scope"].(string)
+			if number, ok := terraformExclusionScopes[scope]; ok {
+				timeWindow["maintenance_exclusion_options"] = map[string]interface{}{"scope": json.Number(strconv.Itoa(number))}
+			}
+		}
+		name, _ := exclusion["exclusion_name"].(string)
+		exclusions[name] = timeWindow
+	}
+	if len(exclusions) > 0 {
+		window["maintenance_exclusions"] = exclusions
+	}
+	if len(window) == 0 {
+		return make(map[string]interface{})
+	}
+	return map[string]interface{}{"window": window}
+}
+
+// newTimeWindowFromTerraform returns the time window with start and end times of the block.
+func newTimeWindowFromTerraform(block map[string]interface{}) map[string]interface{} {
+	timeWindow := make(map[string]interface{})
+	for _, key := range []string{"start_time", "end_time"} {
+		if value, ok := block[key]; ok {
+			timeWindow[key] = value
+		}
+	}
+	return timeWindow
+}
+
+// newNodePoolInputFromTerraform adapts attributes of a node pool, either google_container_node_pool
+// resource or node_pool block of a cluster, to the node pool of the cluster input.
+func newNodePoolInputFromTerraform(values map[string]interface{}) map[string]interface{} {
+	pool := make(map[string]interface{})
+	for key, value := range values {
+		switch key {
+		case "cluster":
+		case "node_config":
+			if converted, ok := terraformValue(key, value); ok {
+				pool["config"] = converted
+			}
+		case "node_locations":
+			pool["locations"] = value
+		default:
+			if converted, ok := terraformValue(key, value); ok {
+				pool[key] = converted
+			}
+		}
+	}
+	if autoscaling, ok := pool["autoscaling"].(map[string]interface{}); ok {
+		autoscaling["enabled"] = true
+	}
+	return pool
+}
+
+// terraformValue converts blocks holding a single object from lists to objects. Null values
+// and blocks that are not set are dropped, so they are undefined in policies as for the API input.
+func terraformValue(key string, value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			if converted, ok := terraformValue(k, item); ok {
+				object[k] = converted
+			}
+		}
+		return object, true
+	case []interface{}:
+		if !isObjectList(v) {
+			return v, true
+		}
+		if terraformListBlocks[key] {
+			list := make([]interface{}, 0, len(v))
+			for _, item := range v {
+				converted, _ := terraformValue("", item)
+				list = append(list, converted)
+			}
+			return list, true
+		}
+		if len(v) == 0 {
+			return nil, false
+		}
+		return terraformValue("", v[0])
+	}
+	return value, true
+}
+
+// isObjectList returns true for non empty lists of objects and for empty lists, which
+// represent blocks that are not set.
+func isObjectList(list []interface{}) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
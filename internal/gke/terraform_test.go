@@ -0,0 +1,181 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewClusterInputsFromTerraformJSON(t *testing.T) {
+	data, err := os.ReadFile("test-fixtures/terraform_plan.json")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	clusters, err := NewClusterInputsFromTerraformJSON(data)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	addresses := make([]string, len(clusters))
+	for i := range clusters {
+		addresses[i] = clusters[i].Address
+	}
+	expected := []string{"google_container_cluster.warsaw", "module.gke.google_container_cluster.primary"}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Fatalf("addresses = %v; want %v", addresses, expected)
+	}
+
+	warsaw := clusters[0].Input
+	if warsaw["location"] != "europe-central2" {
+		t.Errorf("location = %v; want %v", warsaw["location"], "europe-central2")
+	}
+	if warsaw[inputAutopilotKey] != false {
+		t.Errorf("autopilot = %v; want %v", warsaw[inputAutopilotKey], false)
+	}
+	if _, ok := warsaw["description"]; ok {
+		t.Errorf("description is set; want null values dropped")
+	}
+	if _, ok := warsaw["network_policy"]; ok {
+		t.Errorf("network_policy is set; want blocks that are not set dropped")
+	}
+	if warsaw["current_master_version"] != "1.21.6-gke.1500" {
+		t.Errorf("current_master_version = %v; want %v", warsaw["current_master_version"], "1.21.6-gke.1500")
+	}
+	if !reflect.DeepEqual(warsaw["legacy_abac"], map[string]interface{}{"enabled": false}) {
+		t.Errorf("legacy_abac = %v; want enabled false", warsaw["legacy_abac"])
+	}
+	private, _ := warsaw["private_cluster_config"].(map[string]interface{})
+	if private["enable_private_nodes"] != true {
+		t.Errorf("private_cluster_config.enable_private_nodes = %v; want %v", private["enable_private_nodes"], true)
+	}
+	manc, _ := warsaw["master_authorized_networks_config"].(map[string]interface{})
+	if manc["enabled"] != true {
+		t.Errorf("master_authorized_networks_config.enabled = %v; want %v", manc["enabled"], true)
+	}
+	if blocks, _ := manc["cidr_blocks"].([]interface{}); len(blocks) != 1 {
+		t.Errorf("master_authorized_networks_config.cidr_blocks = %v; want one block", manc["cidr_blocks"])
+	}
+	channel, _ := warsaw["release_channel"].(map[string]interface{})
+	if channel["channel"] != json.Number("2") {
+		t.Errorf("release_channel.channel = %v; want %v", channel["channel"], json.Number("2"))
+	}
+	expectedPolicy := map[string]interface{}{
+		"window": map[string]interface{}{
+			"recurring_window": map[string]interface{}{
+				"window":     map[string]interface{}{"start_time": "2022-01-01T09:00:00Z", "end_time": "2022-01-01T17:00:00Z"},
+				"recurrence": "FREQ=WEEKLY;BYDAY=SA,SU",
+			},
+			"maintenance_exclusions": map[string]interface{}{
+				"holidays": map[string]interface{}{
+					"start_time":                    "2022-12-20T00:00:00Z",
+					"end_time":                      "2023-01-03T00:00:00Z",
+					"maintenance_exclusion_options": map[string]interface{}{"scope": json.Number("1")},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(warsaw[inputMaintenancePolicyKey], expectedPolicy) {
+		t.Errorf("maintenance_policy = %v; want %v", warsaw[inputMaintenancePolicyKey], expectedPolicy)
+	}
+	pools, _ := warsaw[inputNodePoolsKey].([]interface{})
+	if len(pools) != 1 {
+		t.Fatalf("node pools = %v; want one node pool", warsaw[inputNodePoolsKey])
+	}
+	pool := pools[0].(map[string]interface{})
+	if !reflect.DeepEqual(pool["locations"], []interface{}{"europe-central2-a"}) {
+		t.Errorf("node pool locations = %v; want %v", pool["locations"], []interface{}{"europe-central2-a"})
+	}
+	config, _ := pool["config"].(map[string]interface{})
+	if config["machine_type"] != "e2-standard-4" {
+		t.Errorf("node pool config.machine_type = %v; want %v", config["machine_type"], "e2-standard-4")
+	}
+	if taints, ok := config["taint"].([]interface{}); !ok || len(taints) != 0 {
+		t.Errorf("node pool config.taint = %v; want empty list", config["taint"])
+	}
+	autoscaling, _ := pool["autoscaling"].(map[string]interface{})
+	if autoscaling["enabled"] != true || autoscaling["max_node_count"] != json.Number("3") {
+		t.Errorf("node pool autoscaling = %v; want enabled with max 3 nodes", autoscaling)
+	}
+
+	primary := clusters[1].Input
+	if _, ok := primary["private_cluster_config"]; ok {
+		t.Errorf("private_cluster_config is set; want blocks that are not set dropped")
+	}
+	pools, _ = primary[inputNodePoolsKey].([]interface{})
+	if len(pools) != 1 {
+		t.Fatalf("node pools = %v; want node pool resource added", primary[inputNodePoolsKey])
+	}
+	pool = pools[0].(map[string]interface{})
+	if pool["name"] != "pool" {
+		t.Errorf("node pool name = %v; want %v", pool["name"], "pool")
+	}
+	management, _ := pool["management"].(map[string]interface{})
+	if management["auto_upgrade"] != false {
+		t.Errorf("node pool management.auto_upgrade = %v; want %v", management["auto_upgrade"], false)
+	}
+}
+
+func TestNewClusterInputsFromTerraformJSON_state(t *testing.T) {
+	data := `{"format_version": "1.0", "values": {"root_module": {"resources": [
+		{"address": "google_container_cluster.a", "mode": "managed", "type": "google_container_cluster",
+		 "values": {"name": "a", "id": "projects/p/locations/l/clusters/a", "enable_autopilot": true}},
+		{"address": "google_container_cluster.b", "mode": "managed", "type": "google_container_cluster",
+		 "values": {"name": "b", "node_pool": []}},
+		{"address": "google_container_node_pool.b", "mode": "managed", "type": "google_container_node_pool",
+		 "values": {"name": "pool-b", "cluster": "projects/p/locations/l/clusters/b"}}
+	]}}}`
+	clusters, err := NewClusterInputsFromTerraformJSON([]byte(data))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("clusters = %v; want 2", len(clusters))
+	}
+	if !clusters[0].Input.IsAutopilot() {
+		t.Errorf("cluster a is not autopilot")
+	}
+	pools, _ := clusters[1].Input[inputNodePoolsKey].([]interface{})
+	if len(pools) != 1 || pools[0].(map[string]interface{})["name"] != "pool-b" {
+		t.Errorf("cluster b node pools = %v; want pool-b", pools)
+	}
+}
+
+func TestNewClusterInputsFromTerraformJSON_negative(t *testing.T) {
+	inputs := map[string]string{
+		`{"format_version": "1.0"`: "invalid Terraform JSON",
+		`{"name": "warsaw"}`:       "not a Terraform plan or state",
+		`{"format_version": "1.0", "planned_values": {"root_module": {"resources": [
+			{"address": "google_compute_network.vpc", "mode": "managed", "type": "google_compute_network", "values": {}}
+		]}}}`: "no google_container_cluster resources found",
+		`{"format_version": "1.0", "values": {"root_module": {"resources": [
+			{"address": "google_container_cluster.a", "mode": "managed", "type": "google_container_cluster", "values": {"name": "a"}},
+			{"address": "google_container_cluster.b", "mode": "managed", "type": "google_container_cluster", "values": {"name": "b"}},
+			{"address": "google_container_node_pool.c", "mode": "managed", "type": "google_container_node_pool", "values": {"cluster": "c"}}
+		]}}}`: "could not find cluster of node pool google_container_node_pool.c",
+	}
+	for data, expected := range inputs {
+		_, err := NewClusterInputsFromTerraformJSON([]byte(data))
+		if err == nil {
+			t.Errorf("input %s: err is nil; want error", data)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("input %s: err = %v; want %q", data, err, expected)
+		}
+	}
+}
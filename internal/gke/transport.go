@@ -0,0 +1,53 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// newCATransport creates HTTP transport that trusts CA certificates from the PEM file in addition
+// to the system ones. Requests are routed through the proxy set with HTTPS_PROXY and NO_PROXY variables.
+func newCATransport(caCertFile string) (*http.Transport, error) {
+	data, err := os.ReadFile(caCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificates: %s", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("could not parse CA certificates from %s: no PEM encoded certificates found", caCertFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return transport, nil
+}
+
+// grpcTransportOption returns option that makes gRPC connections use TLS configuration of the transport.
+// gRPC connections use the proxy from the environment on their own.
+func grpcTransportOption(transport *http.Transport) option.ClientOption {
+	return option.WithGRPCDialOption(grpc.WithTransportCredentials(credentials.NewTLS(transport.TLSClientConfig)))
+}
@@ -0,0 +1,68 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package gke
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewCATransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0600); err != nil {
+		t.Fatalf("could not write CA file: %s", err)
+	}
+
+	transport, err := newCATransport(caFile)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if _, err := http.Get(server.URL); err == nil {
+		t.Errorf("request without CA: err = nil; want error")
+	}
+}
+
+func TestNewCATransport_negative(t *testing.T) {
+	invalidFile := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalidFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("could not write CA file: %s", err)
+	}
+	inputs := map[string]string{
+		invalidFile: "no PEM encoded certificates",
+		filepath.Join(t.TempDir(), "missing.pem"): "could not read CA certificates",
+	}
+	for file, expected := range inputs {
+		if _, err := newCATransport(file); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("file %s: err = %v; want error with %q", file, err, expected)
+		}
+		if _, err := NewClientWithConfig(context.Background(), ClientConfig{CACertFile: file}); err == nil {
+			t.Errorf("file %s: client err = nil; want error", file)
+		}
+	}
+}
@@ -54,7 +54,11 @@ func NewLogger() *logrus.Logger {
 }
 
 func getLogLevel(p envProvider) (logrus.Level, error) {
-	switch value := p.Getenv(levelVarName); strings.ToLower(value) {
+	return parseLogLevel(p.Getenv(levelVarName))
+}
+
+func parseLogLevel(value string) (logrus.Level, error) {
+	switch strings.ToLower(value) {
 	case "trace":
 		return logrus.TraceLevel, nil
 	case "debug":
@@ -71,6 +75,23 @@ func getLogLevel(p envProvider) (logrus.Level, error) {
 	return defaultLogLevel, fmt.Errorf("unsupported or missing log level")
 }
 
+// SetLevel enables logging to the standard error with a given level,
+// overriding the level from the environment.
+func SetLevel(value string) error {
+	level, err := parseLogLevel(value)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q", value)
+	}
+	log.SetOutput(os.Stderr)
+	log.SetLevel(level)
+	return nil
+}
+
+// Disable discards all log messages.
+func Disable() {
+	log.SetOutput(io.Discard)
+}
+
 func Debugf(format string, args ...interface{}) {
 	log.Debugf(format, args...)
 }
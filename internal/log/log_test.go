@@ -15,6 +15,7 @@
 package log
 
 import (
+	"io"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -53,3 +54,19 @@ func TestGetLogLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestSetLevel(t *testing.T) {
+	defer func(level logrus.Level, out io.Writer) {
+		log.SetLevel(level)
+		log.SetOutput(out)
+	}(log.GetLevel(), log.Out)
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if log.GetLevel() != logrus.DebugLevel {
+		t.Errorf("level = %v; want %v", log.GetLevel(), logrus.DebugLevel)
+	}
+	if err := SetLevel("verbose"); err == nil {
+		t.Errorf("err is nil; want error")
+	}
+}
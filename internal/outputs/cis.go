@@ -0,0 +1,224 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+const (
+	CisStatusPass   = "PASS"
+	CisStatusFail   = "FAIL"
+	CisStatusWaived = "WAIVED"
+	CisStatusError  = "ERROR"
+
+	cisUnmappedSection = "unmapped"
+)
+
+var cisStatusPriority = map[string]int{
+	CisStatusPass:   0,
+	CisStatusWaived: 1,
+	CisStatusFail:   2,
+	CisStatusError:  3,
+}
+
+// CisReport is a report of CIS benchmark controls for a single cluster.
+type CisReport struct {
+	ClusterName string
+	Sections    []*CisSection
+	Unmapped    []*CisPolicyStatus
+}
+
+// CisSection groups controls of the same CIS benchmark section, i.e. controls 5.6.3
+// and 5.6.5 belong to section 5.6.
+type CisSection struct {
+	ID       string
+	Controls []*CisControl
+}
+
+// CisControl is a CIS benchmark control with all policies mapped to it. The control
+// status is the most severe status of its policies.
+type CisControl struct {
+	ID       string
+	Status   string
+	Policies []*CisPolicyStatus
+}
+
+type CisPolicyStatus struct {
+	Policy *policy.Policy
+	Status string
+}
+
+type cisResultWriter struct {
+	w io.Writer
+}
+
+func NewCisResultWriter(w io.Writer) ResultWriter {
+	return &cisResultWriter{w: w}
+}
+
+// Write writes CIS benchmark report of each cluster, after a line with versions of gke-review
+// and the embedded OPA.
+func (c *cisResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	if _, err := fmt.Fprintf(c.w, "Reviewed with %s %s\n\n", ToolName, version.String()); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.ClusterError != nil {
+			if _, err := fmt.Fprintf(c.w, "GKE cluster [%s]: could not be reviewed: %s\n\n", result.ClusterName, result.ClusterError); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := NewCisReport(result).write(c.w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewCisReport creates CIS benchmark report from policy evaluation results of a cluster.
+// Policies without CIS controls are listed as unmapped.
+func NewCisReport(result *policy.PolicyEvaluationResult) *CisReport {
+	report := &CisReport{
+		ClusterName: result.ClusterName,
+		Sections:    make([]*CisSection, 0),
+		Unmapped:    make([]*CisPolicyStatus, 0),
+	}
+	controls := make(map[string]*CisControl)
+	for _, policyStatus := range cisPolicyStatuses(result) {
+		if len(policyStatus.Policy.CisControls) == 0 {
+			report.Unmapped = append(report.Unmapped, policyStatus)
+			continue
+		}
+		for _, id := range policyStatus.Policy.CisControls {
+			control, ok := controls[id]
+			if !ok {
+				control = &CisControl{ID: id, Status: CisStatusPass}
+				controls[id] = control
+			}
+			control.Policies = append(control.Policies, policyStatus)
+			if cisStatusPriority[policyStatus.Status] > cisStatusPriority[control.Status] {
+				control.Status = policyStatus.Status
+			}
+		}
+	}
+	ids := make([]string, 0, len(controls))
+	for id := range controls {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return compareCisIDs(ids[i], ids[j]) < 0
+	})
+	sections := make(map[string]*CisSection)
+	for _, id := range ids {
+		sectionID := cisSectionID(id)
+		section, ok := sections[sectionID]
+		if !ok {
+			section = &CisSection{ID: sectionID}
+			sections[sectionID] = section
+			report.Sections = append(report.Sections, section)
+		}
+		section.Controls = append(section.Controls, controls[id])
+	}
+	return report
+}
+
+func (r *CisReport) write(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("CIS GKE Benchmark report for GKE cluster [%s]:\n", r.ClusterName))
+	for _, section := range r.Sections {
+		sb.WriteString(fmt.Sprintf("\nSection %s:\n", section.ID))
+		for _, control := range section.Controls {
+			titles := make([]string, len(control.Policies))
+			for i := range control.Policies {
+				titles[i] = control.Policies[i].Policy.Title
+			}
+			sb.WriteString(fmt.Sprintf("  [%s] %s: %s\n", control.Status, control.ID, strings.Join(titles, ", ")))
+		}
+	}
+	if len(r.Unmapped) > 0 {
+		sb.WriteString(fmt.Sprintf("\nSection %s:\n", cisUnmappedSection))
+		for _, policyStatus := range r.Unmapped {
+			sb.WriteString(fmt.Sprintf("  [%s] %s: %s\n", policyStatus.Status, policyStatus.Policy.Name, policyStatus.Policy.Title))
+		}
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// cisPolicyStatuses returns status of every evaluated policy. Violations of all enforcement
+// levels and those below minimum severity are failures for the CIS benchmark.
+func cisPolicyStatuses(result *policy.PolicyEvaluationResult) []*CisPolicyStatus {
+	statuses := make([]*CisPolicyStatus, 0)
+	add := func(policies map[string][]*policy.Policy, status string) {
+		groups := make([]string, 0, len(policies))
+		for group := range policies {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+		for _, group := range groups {
+			for _, p := range policies[group] {
+				statuses = append(statuses, &CisPolicyStatus{Policy: p, Status: status})
+			}
+		}
+	}
+	add(result.Valid, CisStatusPass)
+	add(result.Violated, CisStatusFail)
+	add(result.Warned, CisStatusFail)
+	add(result.Audited, CisStatusFail)
+	add(result.Filtered, CisStatusFail)
+	add(result.Waived, CisStatusWaived)
+	for _, p := range result.Errored {
+		statuses = append(statuses, &CisPolicyStatus{Policy: p, Status: CisStatusError})
+	}
+	return statuses
+}
+
+func cisSectionID(controlID string) string {
+	if i := strings.LastIndex(controlID, "."); i > 0 {
+		return controlID[:i]
+	}
+	return controlID
+}
+
+// compareCisIDs compares control IDs numerically part by part, so 5.10 comes after 5.9.
+func compareCisIDs(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if aParts[i] == bParts[i] {
+			continue
+		}
+		var aNum, bNum int
+		_, aErr := fmt.Sscanf(aParts[i], "%d", &aNum)
+		_, bErr := fmt.Sscanf(bParts[i], "%d", &bNum)
+		if aErr == nil && bErr == nil && aNum != bNum {
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(aParts[i], bParts[i])
+	}
+	return len(aParts) - len(bParts)
+}
@@ -0,0 +1,93 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+func TestNewCisReport(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.one", Title: "One", Group: "Security", Valid: true, CisControls: []string{"5.6.3"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.two", Title: "Two", Group: "Security", Violations: []string{"error"}, CisControls: []string{"5.6.3", "5.10.1"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.three", Title: "Three", Group: "Security", Valid: true, CisControls: []string{"5.9.1"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.four", Title: "Four", Group: "Availability", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.five", ProcessingErrors: []error{errors.New("error")}})
+
+	report := NewCisReport(result)
+	if len(report.Sections) != 3 {
+		t.Fatalf("len(sections) = %v; want %v", len(report.Sections), 3)
+	}
+	expectedSections := []string{"5.6", "5.9", "5.10"}
+	for i := range expectedSections {
+		if report.Sections[i].ID != expectedSections[i] {
+			t.Errorf("section[%d] id = %v; want %v", i, report.Sections[i].ID, expectedSections[i])
+		}
+	}
+	control := report.Sections[0].Controls[0]
+	if control.ID != "5.6.3" || control.Status != CisStatusFail || len(control.Policies) != 2 {
+		t.Errorf("control = %v %v with %d policies; want 5.6.3 %v with 2 policies", control.ID, control.Status, len(control.Policies), CisStatusFail)
+	}
+	if status := report.Sections[1].Controls[0].Status; status != CisStatusPass {
+		t.Errorf("control 5.9.1 status = %v; want %v", status, CisStatusPass)
+	}
+	if len(report.Unmapped) != 2 {
+		t.Fatalf("len(unmapped) = %v; want %v", len(report.Unmapped), 2)
+	}
+	if report.Unmapped[1].Status != CisStatusError {
+		t.Errorf("unmapped[1] status = %v; want %v", report.Unmapped[1].Status, CisStatusError)
+	}
+}
+
+func TestCompareCisIDs(t *testing.T) {
+	inputs := []struct {
+		a, b     string
+		expected int
+	}{
+		{"5.6.3", "5.6.3", 0},
+		{"5.6", "5.6.3", -1},
+		{"5.9.1", "5.10.1", -1},
+		{"5.10", "5.9", 1},
+	}
+	for _, input := range inputs {
+		result := compareCisIDs(input.a, input.b)
+		if (result < 0 && input.expected >= 0) || (result > 0 && input.expected <= 0) || (result == 0 && input.expected != 0) {
+			t.Errorf("compareCisIDs(%v, %v) = %v; want %v", input.a, input.b, result, input.expected)
+		}
+	}
+}
+
+func TestCisResultWriter(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.one", Title: "One", Group: "Security", Valid: true, CisControls: []string{"5.6.3"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.two", Title: "Two", Group: "Security", Valid: true})
+	buff := new(bytes.Buffer)
+	if err := NewCisResultWriter(buff).Write([]*policy.PolicyEvaluationResult{result}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	for _, expected := range []string{"Reviewed with gke-review " + version.String() + "\n", "[PASS] 5.6.3: One", "Section unmapped:", "gke.policy.two: Two"} {
+		if !strings.Contains(buff.String(), expected) {
+			t.Errorf("output %q does not contain %q", buff.String(), expected)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+const (
+	CountViolated = "violated"
+	CountErrored  = "errored"
+)
+
+type countResultWriter struct {
+	w      io.Writer
+	status string
+}
+
+// NewCountResultWriter creates writer of a single number: count of policies with the given
+// status, CountViolated or CountErrored, of all clusters.
+func NewCountResultWriter(w io.Writer, status string) ResultWriter {
+	return &countResultWriter{w: w, status: status}
+}
+
+func (c *countResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	_, err := fmt.Fprintln(c.w, Count(results, c.status))
+	return err
+}
+
+// Count returns number of violated or errored policies of all clusters. Clusters that
+// could not be reviewed are counted as one error each.
+func Count(results []*policy.PolicyEvaluationResult, status string) int {
+	count := 0
+	for _, result := range results {
+		switch {
+		case status == CountErrored && result.ClusterError != nil:
+			count++
+		case status == CountErrored:
+			count += result.ErroredCount()
+		default:
+			count += result.ViolatedCount()
+		}
+	}
+	return count
+}
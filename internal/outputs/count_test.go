@@ -0,0 +1,47 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+func TestCountResultWriter(t *testing.T) {
+	one := policy.NewPolicyEvaluationResult()
+	one.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	one.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Group: "Security", Violations: []string{"one", "two"}})
+	one.AddPolicy(&policy.Policy{Name: "gke.policy.errored", Group: "Security", ProcessingErrors: []error{errors.New("error")}})
+	two := policy.NewPolicyEvaluationResult()
+	two.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Group: "Security", Violations: []string{"one"}})
+	two.AddPolicy(&policy.Policy{Name: "gke.policy.errored", Group: "Security", ProcessingErrors: []error{errors.New("error")}})
+	failed := policy.NewPolicyEvaluationResult()
+	failed.ClusterError = errors.New("not found")
+	results := []*policy.PolicyEvaluationResult{one, two, failed}
+
+	tests := map[string]string{CountViolated: "2\n", CountErrored: "3\n"}
+	for status, expected := range tests {
+		var buf bytes.Buffer
+		if err := NewCountResultWriter(&buf, status).Write(results); err != nil {
+			t.Fatalf("status %s: err = %v; want nil", status, err)
+		}
+		if buf.String() != expected {
+			t.Errorf("status %s: output = %q; want %q", status, buf.String(), expected)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+type htmlResultWriter struct {
+	w io.Writer
+}
+
+// NewHTMLResultWriter creates writer of a self-contained HTML page. All strings are
+// escaped by the template, so policy metadata can not inject markup.
+func NewHTMLResultWriter(w io.Writer) ResultWriter {
+	return &htmlResultWriter{w: w}
+}
+
+func (h *htmlResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	return htmlReportTemplate.Execute(h.w, newHTMLReport(results))
+}
+
+type htmlReport struct {
+	Tool     JSONTool
+	Counts   JSONCounts
+	Clusters []*htmlCluster
+}
+
+type htmlCluster struct {
+	*JSONClusterResult
+	Groups   []*htmlGroup
+	Policies []*JSONPolicy
+}
+
+// htmlGroup is a row of the group table, Severity is the highest severity of violated policies.
+type htmlGroup struct {
+	Name     string
+	Valid    int
+	Violated int
+	Errored  int
+	Severity string
+}
+
+func newHTMLReport(results []*policy.PolicyEvaluationResult) *htmlReport {
+	report := NewJSONReport(results)
+	htmlReport := &htmlReport{Tool: report.Tool, Clusters: make([]*htmlCluster, len(report.Results))}
+	for i, view := range report.Results {
+		htmlReport.Counts.Valid += view.Counts.Valid
+		htmlReport.Counts.Violated += view.Counts.Violated
+		htmlReport.Counts.Waived += view.Counts.Waived
+		htmlReport.Counts.Errored += view.Counts.Errored
+		htmlReport.Clusters[i] = newHTMLCluster(results[i], view)
+	}
+	return htmlReport
+}
+
+func newHTMLCluster(result *policy.PolicyEvaluationResult, view *JSONClusterResult) *htmlCluster {
+	cluster := &htmlCluster{JSONClusterResult: view}
+	for _, counts := range NewGroupCounts(result) {
+		g := &htmlGroup{
+			Name:     markdownGroup(counts.Group),
+			Valid:    counts.Valid,
+			Violated: counts.Violated,
+			Errored:  counts.Errored,
+		}
+		for _, p := range view.Violated[counts.Group] {
+			if g.Severity == "" || policy.SeverityLevel(p.Severity) > policy.SeverityLevel(g.Severity) {
+				g.Severity = p.Severity
+			}
+			cluster.Policies = append(cluster.Policies, p)
+		}
+		cluster.Groups = append(cluster.Groups, g)
+	}
+	return cluster
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"lower": strings.ToLower,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GKE cluster review</title>
+<style>
+body { font-family: Arial, Helvetica, sans-serif; margin: 2em; color: #202124; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #dadce0; padding: 0.4em 0.8em; text-align: left; }
+td.count { text-align: right; }
+.summary span { display: inline-block; margin-right: 1.5em; font-weight: bold; }
+.valid { color: #188038; }
+.violated { color: #d93025; }
+.severity-low { background: #fef7e0; }
+.severity-medium { background: #feefc3; }
+.severity-high { background: #fad2cf; }
+.severity-critical { background: #f28b82; }
+details { margin: 0.5em 0; padding: 0.5em; border: 1px solid #dadce0; }
+summary { cursor: pointer; font-weight: bold; }
+.error { color: #d93025; }
+.footer { color: #5f6368; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>GKE cluster review</h1>
+<div class="summary">
+<span>Clusters: {{len .Clusters}}</span>
+<span class="valid">Valid: {{.Counts.Valid}}</span>
+<span class="violated">Violated: {{.Counts.Violated}}</span>
+<span>Waived: {{.Counts.Waived}}</span>
+<span>Errored: {{.Counts.Errored}}</span>
+</div>
+{{- range .Clusters}}
+<h2>{{.Cluster}}</h2>
+{{- if .Error}}
+<p class="error">Cluster could not be reviewed: {{.Error}}</p>
+{{- else}}
+<table>
+<tr><th>Group</th><th>Valid</th><th>Violated</th><th>Errored</th><th>Severity</th></tr>
+{{- range .Groups}}
+<tr{{if .Severity}} class="severity-{{lower .Severity}}"{{end}}><td>{{.Name}}</td><td class="count">{{.Valid}}</td><td class="count">{{.Violated}}</td><td class="count">{{.Errored}}</td><td>{{.Severity}}</td></tr>
+{{- end}}
+<tr><th>Total</th><td class="count">{{.Counts.Valid}}</td><td class="count">{{.Counts.Violated}}</td><td class="count">{{.Counts.Errored}}</td><td></td></tr>
+</table>
+{{- range .Policies}}
+<details class="severity-{{lower .Severity}}">
+<summary>[{{.Severity}}] {{.Title}} ({{.Name}})</summary>
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+<ul>
+{{- range .Violations}}
+<li>{{.}}</li>
+{{- end}}
+{{- if .TruncatedViolations}}
+<li><em>(+{{.TruncatedViolations}} more)</em></li>
+{{- end}}
+</ul>
+{{- if .Remediation}}
+<p><strong>Remediation:</strong> {{.Remediation}}</p>
+{{- end}}
+</details>
+{{- end}}
+{{- end}}
+{{- end}}
+<p class="footer">Generated by {{.Tool.Name}} {{.Tool.Version}}, OPA {{.Tool.OPAVersion}}</p>
+</body>
+</html>
+`))
@@ -0,0 +1,68 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+func TestHTMLResultWriter(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Title: "Valid", Group: "Management", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.low", Title: "Low", Group: "Security", Severity: policy.SeverityLow,
+		Violations: []string{"low violation"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.high", Title: "<script>alert(1)</script>", Group: "Security",
+		Severity: policy.SeverityHigh, Description: "Must be <private>", Remediation: "Enable & restart",
+		Violations: []string{"<b>bold</b>"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.errored", ProcessingErrors: []error{errors.New("error one")}})
+	clusterErr := policy.NewPolicyEvaluationResult()
+	clusterErr.ClusterName = "clusterTwo"
+	clusterErr.ClusterError = errors.New("not <found>")
+
+	var buf bytes.Buffer
+	if err := NewHTMLResultWriter(&buf).Write([]*policy.PolicyEvaluationResult{result, clusterErr}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	report := buf.String()
+	expected := []string{
+		"<span>Clusters: 2</span>",
+		"<span class=\"violated\">Violated: 2</span>",
+		"<tr><td>Management</td><td class=\"count\">1</td><td class=\"count\">0</td><td class=\"count\">0</td><td></td></tr>",
+		"<tr class=\"severity-high\"><td>Security</td><td class=\"count\">0</td><td class=\"count\">2</td><td class=\"count\">0</td><td>HIGH</td></tr>",
+		"<tr><td>Ungrouped</td><td class=\"count\">0</td><td class=\"count\">0</td><td class=\"count\">1</td><td></td></tr>",
+		"<summary>[HIGH] &lt;script&gt;alert(1)&lt;/script&gt; (gke.policy.high)</summary>",
+		"<p>Must be &lt;private&gt;</p>",
+		"<li>&lt;b&gt;bold&lt;/b&gt;</li>",
+		"<p><strong>Remediation:</strong> Enable &amp; restart</p>",
+		"<details class=\"severity-low\">",
+		"Cluster could not be reviewed: not &lt;found&gt;",
+		"<p class=\"footer\">Generated by gke-review " + version.Version + ", OPA " + version.OPAVersion() + "</p>",
+	}
+	for _, e := range expected {
+		if !strings.Contains(report, e) {
+			t.Errorf("report = %q; want it to contain %q", report, e)
+		}
+	}
+	if strings.Contains(report, "<script>") {
+		t.Errorf("report contains unescaped policy metadata")
+	}
+}
@@ -0,0 +1,361 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+// JSONReportVersion is the version of the JSON report schema. It is changed only when
+// the existing fields are renamed or removed.
+const JSONReportVersion = "1"
+
+// JSONReport is the top level document of the JSON output. The YAML output serializes
+// the same view, so both formats carry the same fields.
+type JSONReport struct {
+	Version string               `json:"version" yaml:"version"`
+	Tool    JSONTool             `json:"tool" yaml:"tool"`
+	Results []*JSONClusterResult `json:"results" yaml:"results"`
+	// ViolatedBySeverity is the number of violated policies of each severity on all clusters
+	ViolatedBySeverity JSONSeverityCounts `json:"violatedBySeverity" yaml:"violatedBySeverity"`
+}
+
+// JSONTool identifies the gke-review version and the embedded OPA version that produced the report.
+type JSONTool struct {
+	Name       string `json:"name" yaml:"name"`
+	Version    string `json:"version" yaml:"version"`
+	OPAVersion string `json:"opaVersion" yaml:"opaVersion"`
+}
+
+// NewJSONTool returns versions of this build.
+func NewJSONTool() JSONTool {
+	return JSONTool{Name: ToolName, Version: version.Version, OPAVersion: version.OPAVersion()}
+}
+
+// JSONClusterResult is the evaluation result for a single cluster. Policies are grouped
+// by the outcome of the evaluation and then by the policy group. Error is set when
+// the cluster could not be reviewed.
+type JSONClusterResult struct {
+	Cluster  string                   `json:"cluster" yaml:"cluster"`
+	Labels   map[string]string        `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Error    string                   `json:"error,omitempty" yaml:"error,omitempty"`
+	Counts   JSONCounts               `json:"counts" yaml:"counts"`
+	Groups   []string                 `json:"groups" yaml:"groups"`
+	Valid    map[string][]*JSONPolicy `json:"valid" yaml:"valid"`
+	Violated map[string][]*JSONPolicy `json:"violated" yaml:"violated"`
+	Warned   map[string][]*JSONPolicy `json:"warned" yaml:"warned"`
+	Audited  map[string][]*JSONPolicy `json:"audited" yaml:"audited"`
+	Filtered map[string][]*JSONPolicy `json:"filtered" yaml:"filtered"`
+	Waived   map[string][]*JSONPolicy `json:"waived" yaml:"waived"`
+	// Suppressed holds violated policies with all violations suppressed
+	Suppressed map[string][]*JSONPolicy `json:"suppressed" yaml:"suppressed"`
+	Skipped    map[string][]*JSONPolicy `json:"skipped" yaml:"skipped"`
+	Errored    []*JSONPolicy            `json:"errored" yaml:"errored"`
+	Timings    []*JSONTiming            `json:"timings,omitempty" yaml:"timings,omitempty"`
+	Coverage   *JSONCoverage            `json:"coverage,omitempty" yaml:"coverage,omitempty"`
+	// ViolatedBySeverity is the number of violated policies of each severity on the cluster
+	ViolatedBySeverity JSONSeverityCounts `json:"violatedBySeverity" yaml:"violatedBySeverity"`
+}
+
+// JSONCounts holds number of policies for each evaluation outcome.
+type JSONCounts struct {
+	Valid    int `json:"valid" yaml:"valid"`
+	Violated int `json:"violated" yaml:"violated"`
+	Warned   int `json:"warned" yaml:"warned"`
+	Audited  int `json:"audited" yaml:"audited"`
+	Filtered int `json:"filtered" yaml:"filtered"`
+	Waived   int `json:"waived" yaml:"waived"`
+	Skipped  int `json:"skipped" yaml:"skipped"`
+	Errored  int `json:"errored" yaml:"errored"`
+	// Suppressed is the number of policies with all violations suppressed, SuppressedViolations
+	// is the number of suppressed violations of all policies
+	Suppressed           int `json:"suppressed" yaml:"suppressed"`
+	SuppressedViolations int `json:"suppressedViolations" yaml:"suppressedViolations"`
+	// TruncatedViolations is the number of violations over the per policy limit
+	TruncatedViolations int `json:"truncatedViolations" yaml:"truncatedViolations"`
+}
+
+// JSONSeverityCounts holds number of violated policies of each severity. Policies without
+// a valid severity count under the default severity.
+type JSONSeverityCounts struct {
+	Critical int `json:"critical" yaml:"critical"`
+	High     int `json:"high" yaml:"high"`
+	Medium   int `json:"medium" yaml:"medium"`
+	Low      int `json:"low" yaml:"low"`
+}
+
+// NewSeverityCounts returns number of violated policies of each severity on all given clusters.
+func NewSeverityCounts(results []*policy.PolicyEvaluationResult) JSONSeverityCounts {
+	counts := JSONSeverityCounts{}
+	for _, result := range results {
+		bySeverity := result.ViolatedCountBySeverity()
+		counts.Critical += bySeverity[policy.SeverityCritical]
+		counts.High += bySeverity[policy.SeverityHigh]
+		counts.Medium += bySeverity[policy.SeverityMedium]
+		counts.Low += bySeverity[policy.SeverityLow]
+	}
+	return counts
+}
+
+// String returns counts in a single line from the most severe, i.e. CRITICAL: 2  HIGH: 5  MEDIUM: 10  LOW: 0.
+func (c JSONSeverityCounts) String() string {
+	return fmt.Sprintf("%s: %d  %s: %d  %s: %d  %s: %d",
+		policy.SeverityCritical, c.Critical,
+		policy.SeverityHigh, c.High,
+		policy.SeverityMedium, c.Medium,
+		policy.SeverityLow, c.Low)
+}
+
+// JSONPolicy describes a single evaluated policy. Processing errors are stringified.
+type JSONPolicy struct {
+	Name        string      `json:"name" yaml:"name"`
+	Title       string      `json:"title" yaml:"title"`
+	Description string      `json:"description" yaml:"description"`
+	Group       string      `json:"group" yaml:"group"`
+	File        string      `json:"file" yaml:"file"`
+	Enforcement string      `json:"enforcement" yaml:"enforcement"`
+	Severity    string      `json:"severity" yaml:"severity"`
+	AppliesTo   string      `json:"appliesTo" yaml:"appliesTo"`
+	Remediation string      `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+	CisControls []string    `json:"cis" yaml:"cis"`
+	References  []string    `json:"references" yaml:"references"`
+	Tags        []string    `json:"tags" yaml:"tags"`
+	Waiver      *JSONWaiver `json:"waiver,omitempty" yaml:"waiver,omitempty"`
+	Violations  []string    `json:"violations" yaml:"violations"`
+	Errors      []string    `json:"errors" yaml:"errors"`
+
+	// SuppressedViolations is the number of violations removed by suppressions
+	SuppressedViolations int `json:"suppressedViolations,omitempty" yaml:"suppressedViolations,omitempty"`
+	// TruncatedViolations is the number of violations over the limit, that are not listed
+	TruncatedViolations int `json:"truncatedViolations,omitempty" yaml:"truncatedViolations,omitempty"`
+
+	// ViolationDetails are set only for policies that report structured violations
+	ViolationDetails []*JSONViolation `json:"violationDetails,omitempty" yaml:"violationDetails,omitempty"`
+
+	Deprecated        bool   `json:"deprecated" yaml:"deprecated"`
+	DeprecatedMessage string `json:"deprecatedMessage,omitempty" yaml:"deprecatedMessage,omitempty"`
+}
+
+// JSONViolation is a structured violation with the offending resource and details, i.e. actual
+// and expected values.
+type JSONViolation struct {
+	Message  string                 `json:"message" yaml:"message"`
+	Resource string                 `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty" yaml:"details,omitempty"`
+}
+
+// JSONTiming is the evaluation time of a single policy in milliseconds.
+type JSONTiming struct {
+	Policy     string  `json:"policy" yaml:"policy"`
+	Group      string  `json:"group" yaml:"group"`
+	DurationMs float64 `json:"durationMs" yaml:"durationMs"`
+}
+
+// JSONCoverage is the share of rego lines exercised by the evaluation in percent,
+// in total and for each policy file.
+type JSONCoverage struct {
+	Coverage float64             `json:"coverage" yaml:"coverage"`
+	Files    []*JSONFileCoverage `json:"files" yaml:"files"`
+}
+
+// JSONFileCoverage is the coverage of a single policy file.
+type JSONFileCoverage struct {
+	File            string  `json:"file" yaml:"file"`
+	Coverage        float64 `json:"coverage" yaml:"coverage"`
+	CoveredLines    int     `json:"coveredLines" yaml:"coveredLines"`
+	NotCoveredLines int     `json:"notCoveredLines" yaml:"notCoveredLines"`
+}
+
+// JSONWaiver describes a waiver of a violated policy, expiry date is in YYYY-MM-DD format.
+type JSONWaiver struct {
+	Cluster string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Reason  string `json:"reason" yaml:"reason"`
+	Expires string `json:"expires" yaml:"expires"`
+}
+
+type jsonResultWriter struct {
+	w io.Writer
+}
+
+func NewJSONResultWriter(w io.Writer) ResultWriter {
+	return &jsonResultWriter{w: w}
+}
+
+func (j *jsonResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	encoder := json.NewEncoder(j.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewJSONReport(results))
+}
+
+// NewJSONReport creates JSON report view of policy evaluation results.
+func NewJSONReport(results []*policy.PolicyEvaluationResult) *JSONReport {
+	report := &JSONReport{
+		Version:            JSONReportVersion,
+		Tool:               NewJSONTool(),
+		Results:            make([]*JSONClusterResult, len(results)),
+		ViolatedBySeverity: NewSeverityCounts(results),
+	}
+	for i, result := range results {
+		report.Results[i] = NewJSONClusterResult(result)
+	}
+	return report
+}
+
+// NewJSONClusterResult creates JSON view of policy evaluation result of a single cluster.
+func NewJSONClusterResult(result *policy.PolicyEvaluationResult) *JSONClusterResult {
+	clusterResult := &JSONClusterResult{
+		Cluster: result.ClusterName,
+		Labels:  result.ClusterLabels,
+		Counts: JSONCounts{
+			Valid:                result.ValidCount(),
+			Violated:             result.ViolatedCount(),
+			Warned:               result.WarnedCount(),
+			Audited:              result.AuditedCount(),
+			Filtered:             result.FilteredCount(),
+			Waived:               result.WaivedCount(),
+			Skipped:              result.SkippedCount(),
+			Errored:              result.ErroredCount(),
+			Suppressed:           result.SuppressedCount(),
+			SuppressedViolations: result.SuppressedViolationsCount(),
+			TruncatedViolations:  result.TruncatedViolationsCount(),
+		},
+		Groups:     result.Groups(),
+		Valid:      newJSONPolicyMap(result.Valid),
+		Violated:   newJSONPolicyMap(result.Violated),
+		Warned:     newJSONPolicyMap(result.Warned),
+		Audited:    newJSONPolicyMap(result.Audited),
+		Filtered:   newJSONPolicyMap(result.Filtered),
+		Waived:     newJSONPolicyMap(result.Waived),
+		Skipped:    newJSONPolicyMap(result.Skipped),
+		Suppressed: newJSONPolicyMap(result.Suppressed),
+		Errored:    NewJSONPolicyList(result.Errored),
+		Timings:    newJSONTimings(result.Timings()),
+		Coverage:   newJSONCoverage(result.Coverage),
+	}
+	clusterResult.ViolatedBySeverity = NewSeverityCounts([]*policy.PolicyEvaluationResult{result})
+	if result.ClusterError != nil {
+		clusterResult.Error = result.ClusterError.Error()
+	}
+	return clusterResult
+}
+
+func newJSONCoverage(coverage *policy.PolicyCoverage) *JSONCoverage {
+	if coverage == nil {
+		return nil
+	}
+	jsonCoverage := &JSONCoverage{
+		Coverage: coverage.Coverage,
+		Files:    make([]*JSONFileCoverage, len(coverage.Files)),
+	}
+	for i, file := range coverage.Files {
+		jsonCoverage.Files[i] = &JSONFileCoverage{
+			File:            file.File,
+			Coverage:        file.Coverage,
+			CoveredLines:    file.CoveredLines,
+			NotCoveredLines: file.NotCoveredLines,
+		}
+	}
+	return jsonCoverage
+}
+
+func newJSONTimings(timings []*policy.PolicyTiming) []*JSONTiming {
+	list := make([]*JSONTiming, len(timings))
+	for i, t := range timings {
+		list[i] = &JSONTiming{
+			Policy:     t.Policy,
+			Group:      t.Group,
+			DurationMs: float64(t.Duration) / float64(time.Millisecond),
+		}
+	}
+	return list
+}
+
+func newJSONPolicyMap(policies map[string][]*policy.Policy) map[string][]*JSONPolicy {
+	m := make(map[string][]*JSONPolicy, len(policies))
+	for group, groupPolicies := range policies {
+		m[group] = NewJSONPolicyList(groupPolicies)
+	}
+	return m
+}
+
+// NewJSONPolicyList creates JSON views of policies, keeping their order.
+func NewJSONPolicyList(policies []*policy.Policy) []*JSONPolicy {
+	list := make([]*JSONPolicy, len(policies))
+	for i, p := range policies {
+		list[i] = newJSONPolicy(p)
+	}
+	return list
+}
+
+func newJSONPolicy(p *policy.Policy) *JSONPolicy {
+	jsonPolicy := &JSONPolicy{
+		Name:        p.Name,
+		Title:       p.Label(),
+		Description: p.Description,
+		Group:       p.Group,
+		File:        p.File,
+		Enforcement: p.Enforcement,
+		Severity:    p.Severity,
+		AppliesTo:   p.AppliesTo,
+		Remediation: p.Remediation,
+		CisControls: make([]string, len(p.CisControls)),
+		References:  make([]string, len(p.References)),
+		Tags:        make([]string, len(p.Tags)),
+		Violations:  make([]string, len(p.Violations)),
+		Errors:      make([]string, len(p.ProcessingErrors)),
+
+		SuppressedViolations: p.SuppressedViolations,
+		TruncatedViolations:  p.TruncatedViolations,
+
+		Deprecated:        p.Deprecated,
+		DeprecatedMessage: p.DeprecatedMessage,
+	}
+	copy(jsonPolicy.Violations, p.Violations)
+	if hasStructuredViolations(p) {
+		jsonPolicy.ViolationDetails = make([]*JSONViolation, len(p.ViolationDetails))
+		for i, v := range p.ViolationDetails {
+			jsonPolicy.ViolationDetails[i] = &JSONViolation{Message: v.Message, Resource: v.Resource, Details: v.Details}
+		}
+	}
+	copy(jsonPolicy.CisControls, p.CisControls)
+	copy(jsonPolicy.References, p.References)
+	copy(jsonPolicy.Tags, p.Tags)
+	if p.Waiver != nil {
+		jsonPolicy.Waiver = &JSONWaiver{
+			Cluster: p.Waiver.Cluster,
+			Reason:  p.Waiver.Reason,
+			Expires: p.Waiver.Expires.Format(policy.WaiverDateFormat),
+		}
+	}
+	for i := range p.ProcessingErrors {
+		jsonPolicy.Errors[i] = p.ProcessingErrors[i].Error()
+	}
+	return jsonPolicy
+}
+
+func hasStructuredViolations(p *policy.Policy) bool {
+	for _, v := range p.ViolationDetails {
+		if v.IsStructured() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+func TestNewJSONReport(t *testing.T) {
+	violatedPolicy := &policy.Policy{
+		Name:           "gke.policy.private_cluster",
+		Description:    "GKE cluster should be private",
+		Group:          "Security",
+		Remediation:    "Enable private nodes",
+		CisControls:    []string{"5.6.5"},
+		References:     []string{"https://example.com/private"},
+		Tags:           []string{"networking"},
+		Violations:     []string{"violation one"},
+		EvaluationTime: 1500 * time.Microsecond,
+	}
+	erroredPolicy := &policy.Policy{
+		Name:             "gke.policy.errored",
+		Group:            "Security",
+		ProcessingErrors: []error{errors.New("error one"), errors.New("error two")},
+	}
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(violatedPolicy)
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Management", Valid: true})
+	result.AddPolicy(erroredPolicy)
+
+	report := NewJSONReport([]*policy.PolicyEvaluationResult{result})
+	if report.Version != JSONReportVersion {
+		t.Errorf("version = %v; want %v", report.Version, JSONReportVersion)
+	}
+	expectedTool := JSONTool{Name: ToolName, Version: version.Version, OPAVersion: version.OPAVersion()}
+	if report.Tool != expectedTool {
+		t.Errorf("tool = %+v; want %+v", report.Tool, expectedTool)
+	}
+	if len(report.Results) != 1 {
+		t.Fatalf("len(results) = %v; want %v", len(report.Results), 1)
+	}
+	clusterResult := report.Results[0]
+	if clusterResult.Cluster != "clusterOne" {
+		t.Errorf("cluster = %v; want %v", clusterResult.Cluster, "clusterOne")
+	}
+	if !reflect.DeepEqual(clusterResult.Groups, []string{"Management", "Security"}) {
+		t.Errorf("groups = %v; want %v", clusterResult.Groups, []string{"Management", "Security"})
+	}
+	expectedCounts := JSONCounts{Valid: 1, Violated: 1, Errored: 1}
+	if clusterResult.Counts != expectedCounts {
+		t.Errorf("counts = %+v; want %+v", clusterResult.Counts, expectedCounts)
+	}
+	violated := clusterResult.Violated["Security"]
+	if len(violated) != 1 {
+		t.Fatalf("len(violated) = %v; want %v", len(violated), 1)
+	}
+	if violated[0].Description != violatedPolicy.Description {
+		t.Errorf("description = %v; want %v", violated[0].Description, violatedPolicy.Description)
+	}
+	if !reflect.DeepEqual(violated[0].CisControls, violatedPolicy.CisControls) {
+		t.Errorf("cis = %v; want %v", violated[0].CisControls, violatedPolicy.CisControls)
+	}
+	if !reflect.DeepEqual(violated[0].References, violatedPolicy.References) {
+		t.Errorf("references = %v; want %v", violated[0].References, violatedPolicy.References)
+	}
+	if !reflect.DeepEqual(violated[0].Tags, violatedPolicy.Tags) {
+		t.Errorf("tags = %v; want %v", violated[0].Tags, violatedPolicy.Tags)
+	}
+	if violated[0].Remediation != violatedPolicy.Remediation {
+		t.Errorf("remediation = %v; want %v", violated[0].Remediation, violatedPolicy.Remediation)
+	}
+	if !reflect.DeepEqual(violated[0].Violations, violatedPolicy.Violations) {
+		t.Errorf("violations = %v; want %v", violated[0].Violations, violatedPolicy.Violations)
+	}
+	if len(clusterResult.Errored) != 1 {
+		t.Fatalf("len(errored) = %v; want %v", len(clusterResult.Errored), 1)
+	}
+	if !reflect.DeepEqual(clusterResult.Errored[0].Errors, []string{"error one", "error two"}) {
+		t.Errorf("errors = %v; want %v", clusterResult.Errored[0].Errors, []string{"error one", "error two"})
+	}
+	expectedTimings := []*JSONTiming{{Policy: "gke.policy.private_cluster", Group: "Security", DurationMs: 1.5}}
+	if !reflect.DeepEqual(clusterResult.Timings, expectedTimings) {
+		t.Errorf("timings = %+v; want %+v", clusterResult.Timings, expectedTimings)
+	}
+}
+
+func TestNewJSONReport_clusterError(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.ClusterError = errors.New("not found")
+	report := NewJSONReport([]*policy.PolicyEvaluationResult{result})
+	if report.Results[0].Error != "not found" {
+		t.Errorf("error = %v; want %v", report.Results[0].Error, "not found")
+	}
+}
+
+func TestJSONResultWriter(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	buff := new(bytes.Buffer)
+	if err := NewJSONResultWriter(buff).Write([]*policy.PolicyEvaluationResult{result}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(buff.Bytes(), &document); err != nil {
+		t.Fatalf("output is not valid JSON: %s", err)
+	}
+	for _, key := range []string{"version", "results"} {
+		if _, ok := document[key]; !ok {
+			t.Errorf("output has no %q key", key)
+		}
+	}
+}
+
+func TestNewJSONReport_coverage(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "cluster"
+	if report := NewJSONReport([]*policy.PolicyEvaluationResult{result}); report.Results[0].Coverage != nil {
+		t.Errorf("coverage = %v; want nil", report.Results[0].Coverage)
+	}
+	result.Coverage = &policy.PolicyCoverage{
+		Coverage: 50,
+		Files:    []*policy.PolicyFileCoverage{{File: "a.rego", Coverage: 50, CoveredLines: 2, NotCoveredLines: 2}},
+	}
+	report := NewJSONReport([]*policy.PolicyEvaluationResult{result})
+	expected := &JSONCoverage{
+		Coverage: 50,
+		Files:    []*JSONFileCoverage{{File: "a.rego", Coverage: 50, CoveredLines: 2, NotCoveredLines: 2}},
+	}
+	if !reflect.DeepEqual(report.Results[0].Coverage, expected) {
+		t.Errorf("coverage = %+v; want %+v", report.Results[0].Coverage, expected)
+	}
+}
+
+func TestNewJSONReport_violationDetails(t *testing.T) {
+	structured := &policy.Violation{Message: "not enough nodes", Resource: "nodePools/default", Details: map[string]interface{}{"expected": 3}}
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.structured", Group: "Security",
+		Violations: []string{structured.String()}, ViolationDetails: []*policy.Violation{structured}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.legacy", Group: "Security",
+		Violations: []string{"legacy"}, ViolationDetails: []*policy.Violation{{Message: "legacy"}}})
+
+	violated := NewJSONReport([]*policy.PolicyEvaluationResult{result}).Results[0].Violated["Security"]
+	if len(violated) != 2 {
+		t.Fatalf("len(violated) = %d; want 2", len(violated))
+	}
+	if violated[1].ViolationDetails != nil {
+		t.Errorf("legacy violation details = %v; want nil", violated[1].ViolationDetails)
+	}
+	expected := []*JSONViolation{{Message: "not enough nodes", Resource: "nodePools/default", Details: map[string]interface{}{"expected": 3}}}
+	if !reflect.DeepEqual(violated[0].ViolationDetails, expected) {
+		t.Errorf("structured violation details = %v; want %v", violated[0].ViolationDetails, expected)
+	}
+}
+
+func TestNewJSONReport_titleFallback(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.titled", Title: "Titled policy", Group: "Security", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.untitled", Group: "Security", Valid: true})
+
+	valid := NewJSONReport([]*policy.PolicyEvaluationResult{result}).Results[0].Valid["Security"]
+	titles := []string{valid[0].Title, valid[1].Title}
+	expected := []string{"Titled policy", "gke.policy.untitled"}
+	if !reflect.DeepEqual(titles, expected) {
+		t.Errorf("titles = %v; want %v", titles, expected)
+	}
+}
+
+func TestNewJSONReport_deprecated(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.old", Group: "Security", Valid: true,
+		Deprecated: true, DeprecatedMessage: "Use gke.policy.new"})
+
+	jsonPolicy := NewJSONReport([]*policy.PolicyEvaluationResult{result}).Results[0].Valid["Security"][0]
+	if !jsonPolicy.Deprecated {
+		t.Errorf("deprecated = %v; want %v", jsonPolicy.Deprecated, true)
+	}
+	if jsonPolicy.DeprecatedMessage != "Use gke.policy.new" {
+		t.Errorf("deprecated message = %v; want %v", jsonPolicy.DeprecatedMessage, "Use gke.policy.new")
+	}
+}
+
+func TestNewJSONReport_suppressed(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.noisy", Group: "Security", Violations: []string{"pool batch-1"}})
+	result.ApplySuppressions([]*policy.ViolationSuppression{{Policy: "noisy", Message: regexp.MustCompile("batch"), Reason: "test"}})
+	clusterResult := NewJSONReport([]*policy.PolicyEvaluationResult{result}).Results[0]
+	expectedCounts := JSONCounts{Suppressed: 1, SuppressedViolations: 1}
+	if clusterResult.Counts != expectedCounts {
+		t.Errorf("counts = %+v; want %+v", clusterResult.Counts, expectedCounts)
+	}
+	suppressed := clusterResult.Suppressed["Security"]
+	if len(suppressed) != 1 || suppressed[0].SuppressedViolations != 1 {
+		t.Errorf("suppressed = %+v; want single policy with one suppressed violation", suppressed)
+	}
+}
+
+func TestNewJSONReport_truncated(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.noisy", Group: "Security", Violations: []string{"one", "two", "three"}})
+	result.TruncateViolations(1)
+	clusterResult := NewJSONReport([]*policy.PolicyEvaluationResult{result}).Results[0]
+	expectedCounts := JSONCounts{Violated: 1, TruncatedViolations: 2}
+	if clusterResult.Counts != expectedCounts {
+		t.Errorf("counts = %+v; want %+v", clusterResult.Counts, expectedCounts)
+	}
+	violated := clusterResult.Violated["Security"]
+	if len(violated) != 1 || violated[0].TruncatedViolations != 2 || len(violated[0].Violations) != 1 {
+		t.Errorf("violated = %+v; want single policy with one violation", violated)
+	}
+}
+
+func TestNewSeverityCounts(t *testing.T) {
+	one := policy.NewPolicyEvaluationResult()
+	one.AddPolicy(&policy.Policy{Name: "gke.policy.a", Group: "Security", Severity: policy.SeverityCritical, Violations: []string{"a"}})
+	one.AddPolicy(&policy.Policy{Name: "gke.policy.b", Group: "Security", Violations: []string{"b"}})
+	two := policy.NewPolicyEvaluationResult()
+	two.AddPolicy(&policy.Policy{Name: "gke.policy.a", Group: "Security", Severity: policy.SeverityCritical, Violations: []string{"a"}})
+	two.AddPolicy(&policy.Policy{Name: "gke.policy.c", Group: "Security", Severity: policy.SeverityHigh, Valid: true})
+	counts := NewSeverityCounts([]*policy.PolicyEvaluationResult{one, two})
+	expected := JSONSeverityCounts{Critical: 2, Medium: 1}
+	if counts != expected {
+		t.Errorf("counts = %+v; want %+v", counts, expected)
+	}
+	if line := counts.String(); line != "CRITICAL: 2  HIGH: 0  MEDIUM: 1  LOW: 0" {
+		t.Errorf("line = %q; want %q", line, "CRITICAL: 2  HIGH: 0  MEDIUM: 1  LOW: 0")
+	}
+	report := NewJSONReport([]*policy.PolicyEvaluationResult{one, two})
+	if report.ViolatedBySeverity != expected {
+		t.Errorf("report violatedBySeverity = %+v; want %+v", report.ViolatedBySeverity, expected)
+	}
+	if clusterCounts := report.Results[1].ViolatedBySeverity; clusterCounts != (JSONSeverityCounts{Critical: 1}) {
+		t.Errorf("cluster violatedBySeverity = %+v; want %+v", clusterCounts, JSONSeverityCounts{Critical: 1})
+	}
+}
@@ -0,0 +1,66 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+// JSONLineResult is a line of the JSON Lines output, the evaluation result of a single
+// cluster with the version of the JSON report and the tool that produced it.
+type JSONLineResult struct {
+	Version string   `json:"version"`
+	Tool    JSONTool `json:"tool"`
+	*JSONClusterResult
+}
+
+// flusher is implemented by buffered writers, that are flushed after each line.
+type flusher interface {
+	Flush() error
+}
+
+type jsonlResultWriter struct {
+	w io.Writer
+}
+
+// NewJSONLResultWriter creates writer of JSON Lines, with one JSON object per cluster.
+// Results are written as clusters are reviewed.
+func NewJSONLResultWriter(w io.Writer) StreamResultWriter {
+	return &jsonlResultWriter{w: w}
+}
+
+func (j *jsonlResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	for _, result := range results {
+		if err := j.WriteResult(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteResult writes the result of a cluster as a single line and flushes the writer.
+func (j *jsonlResultWriter) WriteResult(result *policy.PolicyEvaluationResult) error {
+	line := &JSONLineResult{Version: JSONReportVersion, Tool: NewJSONTool(), JSONClusterResult: NewJSONClusterResult(result)}
+	if err := json.NewEncoder(j.w).Encode(line); err != nil {
+		return err
+	}
+	if f, ok := j.w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
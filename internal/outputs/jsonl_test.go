@@ -0,0 +1,78 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+func TestJSONLResultWriter(t *testing.T) {
+	one := policy.NewPolicyEvaluationResult()
+	one.ClusterName = "clusterOne"
+	one.AddPolicy(&policy.Policy{Name: "gke.policy.private_cluster", Group: "Security", Violations: []string{"violation\none"}})
+	two := policy.NewPolicyEvaluationResult()
+	two.ClusterName = "clusterTwo"
+	two.ClusterError = errors.New("not found")
+
+	var buff bytes.Buffer
+	if err := NewJSONLResultWriter(&buff).Write([]*policy.PolicyEvaluationResult{one, two}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("len(lines) = %v; want %v", len(lines), 2)
+	}
+	expected := []struct {
+		cluster  string
+		violated int
+		err      string
+	}{
+		{"clusterOne", 1, ""},
+		{"clusterTwo", 0, "not found"},
+	}
+	for i, line := range lines {
+		var result JSONLineResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("line [%d]: err = %v; want nil", i, err)
+		}
+		if result.Version != JSONReportVersion {
+			t.Errorf("line [%d]: version = %v; want %v", i, result.Version, JSONReportVersion)
+		}
+		if result.Cluster != expected[i].cluster || result.Counts.Violated != expected[i].violated || result.Error != expected[i].err {
+			t.Errorf("line [%d]: cluster, violated, error = %v, %v, %q; want %v, %v, %q", i,
+				result.Cluster, result.Counts.Violated, result.Error, expected[i].cluster, expected[i].violated, expected[i].err)
+		}
+	}
+}
+
+func TestJSONLResultWriter_flush(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	var buff bytes.Buffer
+	w := bufio.NewWriter(&buff)
+	if err := NewJSONLResultWriter(w).WriteResult(result); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !strings.HasPrefix(buff.String(), `{"version":"1","tool":{"name":"gke-review"`) || !strings.Contains(buff.String(), `"cluster":"clusterOne"`) {
+		t.Errorf("output = %q; want flushed line of clusterOne", buff.String())
+	}
+}
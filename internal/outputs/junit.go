@@ -0,0 +1,210 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+const junitUngroupedSuite = "Ungrouped"
+
+type JUnitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Suites   []*JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Properties []*JUnitProperty `xml:"properties>property,omitempty"`
+	TestCases  []*JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *JUnitMessage `xml:"failure,omitempty"`
+	Error     *JUnitMessage `xml:"error,omitempty"`
+	Skipped   *JUnitMessage `xml:"skipped,omitempty"`
+}
+
+type JUnitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitResultWriter struct {
+	w io.Writer
+}
+
+func NewJUnitResultWriter(w io.Writer) ResultWriter {
+	return &junitResultWriter{w: w}
+}
+
+func (j *junitResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	if _, err := io.WriteString(j.w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(j.w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(NewJUnitReport(results)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n")
+	return err
+}
+
+// NewJUnitReport creates JUnit report with one test suite per cluster and policy group.
+// Valid policies are passing test cases, violated policies are failures and errored
+// policies are errors. Violations of warn and audit policies and waived violations are
+// reported as skipped.
+func NewJUnitReport(results []*policy.PolicyEvaluationResult) *JUnitTestSuites {
+	report := &JUnitTestSuites{
+		Name:   ToolName,
+		Suites: make([]*JUnitTestSuite, 0),
+	}
+	for _, result := range results {
+		if result.ClusterError != nil {
+			suite := newJUnitTestSuite(result.ClusterName, result.ClusterName)
+			suite.addTestCase(&JUnitTestCase{
+				Name:      "cluster review",
+				ClassName: result.ClusterName,
+				Error:     &JUnitMessage{Message: result.ClusterError.Error()},
+			})
+			report.addSuite(suite)
+			continue
+		}
+		suites := make(map[string]*JUnitTestSuite)
+		order := make([]string, 0)
+		getSuite := func(group string) *JUnitTestSuite {
+			if group == "" {
+				group = junitUngroupedSuite
+			}
+			if _, ok := suites[group]; !ok {
+				suites[group] = newJUnitTestSuite(group, result.ClusterName)
+				order = append(order, group)
+			}
+			return suites[group]
+		}
+		for _, group := range result.Groups() {
+			suite := getSuite(group)
+			for _, p := range result.Valid[group] {
+				suite.addTestCase(newJUnitTestCase(p))
+			}
+			for _, p := range result.Violated[group] {
+				testCase := newJUnitTestCase(p)
+				testCase.Failure = newJUnitViolationsMessage(p)
+				suite.addTestCase(testCase)
+			}
+			for _, p := range append(result.Warned[group], result.Audited[group]...) {
+				testCase := newJUnitTestCase(p)
+				testCase.Skipped = newJUnitViolationsMessage(p)
+				suite.addTestCase(testCase)
+			}
+			for _, p := range result.Waived[group] {
+				testCase := newJUnitTestCase(p)
+				testCase.Skipped = &JUnitMessage{
+					Message: fmt.Sprintf("waived until %s: %s", p.Waiver.Expires.Format(policy.WaiverDateFormat), p.Waiver.Reason),
+					Text:    strings.Join(p.Violations, "\n"),
+				}
+				suite.addTestCase(testCase)
+			}
+			for _, p := range result.Suppressed[group] {
+				testCase := newJUnitTestCase(p)
+				testCase.Skipped = &JUnitMessage{Message: fmt.Sprintf("all %d violations suppressed", p.SuppressedViolations)}
+				suite.addTestCase(testCase)
+			}
+		}
+		for _, p := range result.Errored {
+			errs := make([]string, len(p.ProcessingErrors))
+			for i := range p.ProcessingErrors {
+				errs[i] = p.ProcessingErrors[i].Error()
+			}
+			testCase := newJUnitTestCase(p)
+			testCase.Error = &JUnitMessage{Message: strings.Join(errs, ", ")}
+			getSuite(p.Group).addTestCase(testCase)
+		}
+		for _, group := range order {
+			report.addSuite(suites[group])
+		}
+	}
+	return report
+}
+
+// newJUnitTestSuite creates test suite of a cluster, with the cluster name and versions of
+// gke-review and the embedded OPA as properties.
+func newJUnitTestSuite(name string, clusterName string) *JUnitTestSuite {
+	properties := []*JUnitProperty{
+		{Name: "cluster", Value: clusterName},
+		{Name: "version", Value: version.Version},
+		{Name: "opaVersion", Value: version.OPAVersion()},
+	}
+	return &JUnitTestSuite{
+		Name:       name,
+		Properties: properties,
+		TestCases:  make([]*JUnitTestCase, 0),
+	}
+}
+
+func newJUnitTestCase(p *policy.Policy) *JUnitTestCase {
+	return &JUnitTestCase{Name: p.Label(), ClassName: p.Name}
+}
+
+func newJUnitViolationsMessage(p *policy.Policy) *JUnitMessage {
+	return &JUnitMessage{
+		Message: strings.Join(p.Violations, ", "),
+		Text:    fmt.Sprintf("%s\n%s", p.Description, strings.Join(p.Violations, "\n")),
+	}
+}
+
+func (s *JUnitTestSuite) addTestCase(testCase *JUnitTestCase) {
+	s.TestCases = append(s.TestCases, testCase)
+	s.Tests++
+	switch {
+	case testCase.Failure != nil:
+		s.Failures++
+	case testCase.Error != nil:
+		s.Errors++
+	case testCase.Skipped != nil:
+		s.Skipped++
+	}
+}
+
+func (r *JUnitTestSuites) addSuite(suite *JUnitTestSuite) {
+	r.Suites = append(r.Suites, suite)
+	r.Tests += suite.Tests
+	r.Failures += suite.Failures
+	r.Errors += suite.Errors
+	r.Skipped += suite.Skipped
+}
@@ -0,0 +1,110 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+func TestNewJUnitReport(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Title: "Valid", Group: "Security", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Title: "Violated", Group: "Security", Violations: []string{"one", "two"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.other", Title: "Other", Group: "Management", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.errored", ProcessingErrors: []error{errors.New("error one"), errors.New("error two")}})
+
+	report := NewJUnitReport([]*policy.PolicyEvaluationResult{result})
+	if report.Tests != 4 || report.Failures != result.ViolatedCount() || report.Errors != result.ErroredCount() {
+		t.Errorf("report counts tests=%d failures=%d errors=%d; want 4, %d, %d",
+			report.Tests, report.Failures, report.Errors, result.ViolatedCount(), result.ErroredCount())
+	}
+	if len(report.Suites) != 3 {
+		t.Fatalf("len(suites) = %v; want %v", len(report.Suites), 3)
+	}
+	expectedNames := []string{"Management", "Security", junitUngroupedSuite}
+	for i := range expectedNames {
+		if report.Suites[i].Name != expectedNames[i] {
+			t.Errorf("suite[%d] name = %v; want %v", i, report.Suites[i].Name, expectedNames[i])
+		}
+	}
+	security := report.Suites[1]
+	expectedProperties := []*JUnitProperty{
+		{Name: "cluster", Value: "clusterOne"},
+		{Name: "version", Value: version.Version},
+		{Name: "opaVersion", Value: version.OPAVersion()},
+	}
+	if !reflect.DeepEqual(security.Properties, expectedProperties) {
+		t.Errorf("security suite properties = %v; want %v", security.Properties, expectedProperties)
+	}
+	if security.Tests != 2 || security.Failures != 1 {
+		t.Errorf("security suite tests=%d failures=%d; want 2, 1", security.Tests, security.Failures)
+	}
+	if failure := security.TestCases[1].Failure; failure == nil || failure.Message != "one, two" {
+		t.Errorf("violated test case failure = %v; want message %q", failure, "one, two")
+	}
+	if testCaseErr := report.Suites[2].TestCases[0].Error; testCaseErr == nil || testCaseErr.Message != "error one, error two" {
+		t.Errorf("errored test case error = %v; want message %q", testCaseErr, "error one, error two")
+	}
+}
+
+func TestNewJUnitReport_suppressed(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Title: "Valid", Group: "Security", Valid: true})
+	result.Suppressed["Security"] = []*policy.Policy{{Name: "gke.policy.suppressed", Title: "Suppressed", Group: "Security", SuppressedViolations: 2}}
+
+	report := NewJUnitReport([]*policy.PolicyEvaluationResult{result})
+	if report.Tests != 2 || report.Skipped != 1 {
+		t.Errorf("report counts tests=%d skipped=%d; want 2, 1", report.Tests, report.Skipped)
+	}
+	skipped := report.Suites[0].TestCases[1].Skipped
+	if skipped == nil || skipped.Message != "all 2 violations suppressed" {
+		t.Errorf("suppressed test case skipped = %v; want message %q", skipped, "all 2 violations suppressed")
+	}
+}
+
+func TestNewJUnitReport_clusterError(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.ClusterError = errors.New("not found")
+	report := NewJUnitReport([]*policy.PolicyEvaluationResult{result})
+	if report.Errors != 1 {
+		t.Errorf("errors = %v; want %v", report.Errors, 1)
+	}
+}
+
+func TestJUnitResultWriter(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	buff := new(bytes.Buffer)
+	if err := NewJUnitResultWriter(buff).Write([]*policy.PolicyEvaluationResult{result}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	report := &JUnitTestSuites{}
+	if err := xml.Unmarshal(buff.Bytes(), report); err != nil {
+		t.Fatalf("output is not valid XML: %s", err)
+	}
+	if report.Tests != 1 {
+		t.Errorf("tests = %v; want %v", report.Tests, 1)
+	}
+}
@@ -0,0 +1,79 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+// lineError is the severity field of lines with errors.
+const lineError = "ERROR"
+
+type lineResultWriter struct {
+	w io.Writer
+}
+
+func NewLineResultWriter(w io.Writer) ResultWriter {
+	return &lineResultWriter{w: w}
+}
+
+func (l *lineResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	_, err := io.WriteString(l.w, NewLineReport(results))
+	return err
+}
+
+// NewLineReport renders one tab separated line with severity, group, policy name and message
+// per violation of violated, warned and audited policies, and per processing error, with ERROR
+// severity. Clusters that could not be reviewed are reported as errors without group and policy.
+func NewLineReport(results []*policy.PolicyEvaluationResult) string {
+	var sb strings.Builder
+	line := func(severity, group, name, message string) {
+		sb.WriteString(strings.Join([]string{severity, lineEscape(group), name, lineEscape(message)}, "\t") + "\n")
+	}
+	for _, result := range results {
+		if result.ClusterError != nil {
+			line(lineError, "", "", fmt.Sprintf("cluster %s could not be reviewed: %s", result.ClusterName, result.ClusterError))
+			continue
+		}
+		for _, group := range result.Groups() {
+			for _, policies := range [][]*policy.Policy{result.Violated[group], result.Warned[group], result.Audited[group]} {
+				for _, p := range policies {
+					severity := p.Severity
+					if severity == "" {
+						severity = policy.DefaultSeverity
+					}
+					for _, violation := range p.Violations {
+						line(severity, group, p.Name, violation)
+					}
+				}
+			}
+		}
+		for _, p := range result.Errored {
+			for _, err := range p.ProcessingErrors {
+				line(lineError, result.GroupOf(p), p.Name, err.Error())
+			}
+		}
+	}
+	return sb.String()
+}
+
+// lineEscape replaces tabs and new lines with spaces, so that a value does not break the fields.
+func lineEscape(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
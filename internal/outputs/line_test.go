@@ -0,0 +1,46 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+func TestNewLineReport(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Group: "Security", Severity: policy.SeverityCritical,
+		Violations: []string{"one", "two\twith tab"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.warned", Group: "Availability", Enforcement: policy.EnforcementWarn,
+		Violations: []string{"three"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.errored", Group: "Security", ProcessingErrors: []error{errors.New("error one")}})
+	clusterErr := policy.NewPolicyEvaluationResult()
+	clusterErr.ClusterName = "clusterTwo"
+	clusterErr.ClusterError = errors.New("not found")
+
+	report := NewLineReport([]*policy.PolicyEvaluationResult{result, clusterErr})
+	expected := "MEDIUM\tAvailability\tgke.policy.warned\tthree\n" +
+		"CRITICAL\tSecurity\tgke.policy.violated\tone\n" +
+		"CRITICAL\tSecurity\tgke.policy.violated\ttwo with tab\n" +
+		"ERROR\tSecurity\tgke.policy.errored\terror one\n" +
+		"ERROR\t\t\tcluster clusterTwo could not be reviewed: not found\n"
+	if report != expected {
+		t.Errorf("report = %q; want %q", report, expected)
+	}
+}
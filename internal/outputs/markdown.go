@@ -0,0 +1,148 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+const (
+	// MarkdownMaxSize keeps the report within the size limit of GitHub pull request comment.
+	MarkdownMaxSize = 65536
+	// MarkdownMaxViolations is the number of violations listed for a single policy.
+	MarkdownMaxViolations = 20
+
+	markdownUngroupedGroup = "Ungrouped"
+	// markdownNoteReserve is the space kept for the omitted policies note of each cluster.
+	markdownNoteReserve = 128
+)
+
+type markdownResultWriter struct {
+	w io.Writer
+}
+
+func NewMarkdownResultWriter(w io.Writer) ResultWriter {
+	return &markdownResultWriter{w: w}
+}
+
+func (m *markdownResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	_, err := io.WriteString(m.w, NewMarkdownReport(results))
+	return err
+}
+
+type markdownCluster struct {
+	summary    string
+	violations []string
+}
+
+// NewMarkdownReport renders results as Markdown document with a summary table for each cluster,
+// followed by a collapsible section for each violated policy. Long violation lists are truncated.
+// Summaries are always included, violated policies that do not fit in MarkdownMaxSize are omitted.
+// The document ends with versions of gke-review and the embedded OPA.
+func NewMarkdownReport(results []*policy.PolicyEvaluationResult) string {
+	clusters := make([]*markdownCluster, len(results))
+	footer := fmt.Sprintf("_Reviewed with %s %s._\n", ToolName, markdownEscape(version.String()))
+	budget := MarkdownMaxSize - len(footer)
+	for i, result := range results {
+		clusters[i] = newMarkdownCluster(result)
+		budget -= len(clusters[i].summary) + markdownNoteReserve
+	}
+	var sb strings.Builder
+	for _, cluster := range clusters {
+		sb.WriteString(cluster.summary)
+		omitted := 0
+		for _, section := range cluster.violations {
+			if omitted > 0 || len(section) > budget {
+				omitted++
+				continue
+			}
+			budget -= len(section)
+			sb.WriteString(section)
+		}
+		if omitted > 0 {
+			sb.WriteString(fmt.Sprintf("_%d more violated policies omitted to fit the comment size._\n\n", omitted))
+		}
+	}
+	sb.WriteString(footer)
+	return sb.String()
+}
+
+func newMarkdownCluster(result *policy.PolicyEvaluationResult) *markdownCluster {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## GKE cluster review: %s\n\n", markdownEscape(result.ClusterName)))
+	if result.ClusterError != nil {
+		sb.WriteString(fmt.Sprintf("Cluster could not be reviewed: %s\n\n", markdownEscape(result.ClusterError.Error())))
+		return &markdownCluster{summary: sb.String()}
+	}
+	sb.WriteString("| Group | Valid | Violated | Errored |\n")
+	sb.WriteString("| --- | ---: | ---: | ---: |\n")
+	for _, counts := range NewGroupCounts(result) {
+		sb.WriteString(fmt.Sprintf("| %s | %d | %d | %d |\n", markdownEscape(markdownGroup(counts.Group)),
+			counts.Valid, counts.Violated, counts.Errored))
+	}
+	sb.WriteString(fmt.Sprintf("| **Total** | %d | %d | %d |\n\n",
+		result.ValidCount(), result.ViolatedCount(), result.ErroredCount()))
+
+	cluster := &markdownCluster{summary: sb.String()}
+	for _, group := range result.Groups() {
+		for _, p := range result.Violated[group] {
+			cluster.violations = append(cluster.violations, markdownViolationSection(p))
+		}
+	}
+	return cluster
+}
+
+func markdownViolationSection(p *policy.Policy) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<details>\n<summary>%s (%s)</summary>\n\n",
+		html.EscapeString(p.Title), html.EscapeString(p.Name)))
+	if p.Description != "" {
+		sb.WriteString(markdownEscape(p.Description) + "\n\n")
+	}
+	omitted := p.TruncatedViolations
+	for i, violation := range p.Violations {
+		if i == MarkdownMaxViolations {
+			omitted += len(p.Violations) - MarkdownMaxViolations
+			break
+		}
+		sb.WriteString("- " + markdownEscape(violation) + "\n")
+	}
+	if omitted > 0 {
+		sb.WriteString(fmt.Sprintf("- _%d more violations omitted_\n", omitted))
+	}
+	sb.WriteString("\n</details>\n\n")
+	return sb.String()
+}
+
+func markdownGroup(group string) string {
+	if group == "" {
+		return markdownUngroupedGroup
+	}
+	return group
+}
+
+// markdownEscape escapes characters that break tables and inline HTML, and joins lines.
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	return strings.Join(strings.Fields(s), " ")
+}
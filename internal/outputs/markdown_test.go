@@ -0,0 +1,106 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+func TestNewMarkdownReport(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Title: "Valid", Group: "Security", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Title: "Violated", Description: "Must be <private>",
+		Group: "Security", Violations: []string{"one", "two | three"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.other", Title: "Other", Group: "Management", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.errored", ProcessingErrors: []error{errors.New("error one")}})
+
+	report := NewMarkdownReport([]*policy.PolicyEvaluationResult{result})
+	expected := []string{
+		"## GKE cluster review: clusterOne\n",
+		"| Management | 1 | 0 | 0 |\n",
+		"| Security | 1 | 1 | 0 |\n",
+		"| Ungrouped | 0 | 0 | 1 |\n",
+		fmt.Sprintf("| **Total** | %d | %d | %d |\n", result.ValidCount(), result.ViolatedCount(), result.ErroredCount()),
+		"<summary>Violated (gke.policy.violated)</summary>",
+		"Must be &lt;private&gt;\n",
+		"- one\n- two \\| three\n",
+		"_Reviewed with gke-review " + version.String() + "._\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(report, e) {
+			t.Errorf("report = %q; want it to contain %q", report, e)
+		}
+	}
+	if strings.Contains(report, "gke.policy.other)") {
+		t.Errorf("report = %q; want no section for valid policy", report)
+	}
+}
+
+func TestNewMarkdownReport_truncated(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	violations := make([]string, MarkdownMaxViolations+5)
+	for i := range violations {
+		violations[i] = strings.Repeat("x", 1000)
+	}
+	for i := 0; i < 10; i++ {
+		result.AddPolicy(&policy.Policy{Name: fmt.Sprintf("gke.policy.p%d", i), Group: "Security", Violations: violations})
+	}
+	report := NewMarkdownReport([]*policy.PolicyEvaluationResult{result})
+	if len(report) > MarkdownMaxSize {
+		t.Errorf("len(report) = %v; want at most %v", len(report), MarkdownMaxSize)
+	}
+	if !strings.Contains(report, "- _5 more violations omitted_\n") {
+		t.Errorf("report does not contain omitted violations note")
+	}
+	if !strings.Contains(report, "_7 more violated policies omitted to fit the comment size._") {
+		t.Errorf("report does not contain omitted policies note")
+	}
+	if !strings.Contains(report, "| **Total** | 0 | 10 | 0 |") {
+		t.Errorf("report does not contain total counts")
+	}
+}
+
+func TestMarkdownResultWriter(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.ClusterError = errors.New("not found")
+	buff := new(bytes.Buffer)
+	if err := NewMarkdownResultWriter(buff).Write([]*policy.PolicyEvaluationResult{result}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !strings.Contains(buff.String(), "Cluster could not be reviewed: not found") {
+		t.Errorf("output = %q; want cluster error", buff.String())
+	}
+}
+
+func TestNewMarkdownReport_truncatedViolations(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.noisy", Title: "Noisy", Group: "Security", Violations: []string{"one", "two", "three"}})
+	result.TruncateViolations(1)
+	report := NewMarkdownReport([]*policy.PolicyEvaluationResult{result})
+	if !strings.Contains(report, "- one\n- _2 more violations omitted_\n") {
+		t.Errorf("report = %q; want one violation and note of 2 more", report)
+	}
+}
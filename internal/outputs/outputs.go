@@ -0,0 +1,36 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+const (
+	ToolName           = "gke-review"
+	ToolInformationURI = "https://github.com/mikouaj/gke-review"
+)
+
+// ResultWriter writes policy evaluation results in a given format.
+type ResultWriter interface {
+	Write(results []*policy.PolicyEvaluationResult) error
+}
+
+// StreamResultWriter writes the result of each cluster as soon as the cluster is reviewed,
+// instead of all results at the end of the review.
+type StreamResultWriter interface {
+	ResultWriter
+	WriteResult(result *policy.PolicyEvaluationResult) error
+}
@@ -0,0 +1,145 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+// prometheusMetrics are metrics with policy counts, in the order they are written.
+var prometheusMetrics = []struct {
+	name string
+	help string
+}{
+	{"gke_review_valid_total", "Number of valid policies."},
+	{"gke_review_violations_total", "Number of violated enforced policies."},
+	{"gke_review_warnings_total", "Number of violated policies with warn or audit enforcement."},
+	{"gke_review_waived_total", "Number of policies with waived violations."},
+	{"gke_review_errors_total", "Number of policies that could not be evaluated."},
+}
+
+// prometheusLabels are labels of policy count metrics. Values are limited by the number of
+// reviewed clusters, policy groups and severities.
+type prometheusLabels struct {
+	cluster  string
+	group    string
+	severity string
+}
+
+type prometheusResultWriter struct {
+	w   io.Writer
+	now func() time.Time
+}
+
+func NewPrometheusResultWriter(w io.Writer) ResultWriter {
+	return &prometheusResultWriter{w: w, now: time.Now}
+}
+
+func (p *prometheusResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	_, err := io.WriteString(p.w, NewPrometheusReport(results, p.now()))
+	return err
+}
+
+// NewPrometheusReport renders policy counts of results in the Prometheus text exposition format,
+// i.e. for the node exporter textfile collector. Counts are labeled with the cluster, policy group
+// and severity, all combinations found in a cluster are reported, including zero counts.
+// Clusters that could not be reviewed are reported with gke_review_cluster_reviewed set to 0.
+// Versions of gke-review and the embedded OPA are reported as labels of gke_review_build_info.
+func NewPrometheusReport(results []*policy.PolicyEvaluationResult, now time.Time) string {
+	counts := make(map[prometheusLabels][]int)
+	reviewed := make(map[string]int)
+	add := func(cluster string, policies []*policy.Policy, metric int) {
+		for _, p := range policies {
+			severity := p.Severity
+			if severity == "" {
+				severity = policy.DefaultSeverity
+			}
+			labels := prometheusLabels{cluster: cluster, group: p.Group, severity: severity}
+			if _, ok := counts[labels]; !ok {
+				counts[labels] = make([]int, len(prometheusMetrics))
+			}
+			counts[labels][metric]++
+		}
+	}
+	for _, result := range results {
+		if result.ClusterError != nil {
+			reviewed[result.ClusterName] = 0
+			continue
+		}
+		reviewed[result.ClusterName] = 1
+		for _, group := range result.Groups() {
+			add(result.ClusterName, result.Valid[group], 0)
+			add(result.ClusterName, result.Violated[group], 1)
+			add(result.ClusterName, result.Warned[group], 2)
+			add(result.ClusterName, result.Audited[group], 2)
+			add(result.ClusterName, result.Waived[group], 3)
+		}
+		add(result.ClusterName, result.Errored, 4)
+	}
+	labels := make([]prometheusLabels, 0, len(counts))
+	for l := range counts {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].cluster != labels[j].cluster {
+			return labels[i].cluster < labels[j].cluster
+		}
+		if labels[i].group != labels[j].group {
+			return labels[i].group < labels[j].group
+		}
+		return policy.SeverityLevel(labels[i].severity) > policy.SeverityLevel(labels[j].severity)
+	})
+
+	var sb strings.Builder
+	for i, metric := range prometheusMetrics {
+		writePrometheusHeader(&sb, metric.name, metric.help)
+		for _, l := range labels {
+			sb.WriteString(fmt.Sprintf("%s{cluster=\"%s\",group=\"%s\",severity=\"%s\"} %d\n", metric.name,
+				prometheusEscape(l.cluster), prometheusEscape(l.group), prometheusEscape(l.severity), counts[l][i]))
+		}
+	}
+	clusters := make([]string, 0, len(reviewed))
+	for cluster := range reviewed {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	writePrometheusHeader(&sb, "gke_review_cluster_reviewed", "Whether the cluster was reviewed (1) or could not be reviewed (0).")
+	for _, cluster := range clusters {
+		sb.WriteString(fmt.Sprintf("gke_review_cluster_reviewed{cluster=\"%s\"} %d\n", prometheusEscape(cluster), reviewed[cluster]))
+	}
+	writePrometheusHeader(&sb, "gke_review_last_run_timestamp", "Unix time of the last review in seconds.")
+	sb.WriteString(fmt.Sprintf("gke_review_last_run_timestamp %d\n", now.Unix()))
+	writePrometheusHeader(&sb, "gke_review_build_info", "Versions of gke-review and the embedded OPA that reviewed the clusters.")
+	sb.WriteString(fmt.Sprintf("gke_review_build_info{version=\"%s\",opa_version=\"%s\"} 1\n",
+		prometheusEscape(version.Version), prometheusEscape(version.OPAVersion())))
+	return sb.String()
+}
+
+func writePrometheusHeader(sb *strings.Builder, name string, help string) {
+	sb.WriteString(fmt.Sprintf("# HELP %s %s\n", name, help))
+	sb.WriteString(fmt.Sprintf("# TYPE %s gauge\n", name))
+}
+
+// prometheusEscape escapes a label value as required by the text exposition format.
+func prometheusEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
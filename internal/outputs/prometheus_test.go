@@ -0,0 +1,90 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+func TestNewPrometheusReport(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.one", Group: "Security", Severity: policy.SeverityHigh, Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.two", Group: "Security", Severity: policy.SeverityHigh, Violations: []string{"one", "two"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.three", Group: "Security", Severity: policy.SeverityLow, Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.four", Group: "Net \"ops\"", Enforcement: policy.EnforcementWarn, Violations: []string{"three"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.five", Group: "Security", Severity: policy.SeverityLow, ProcessingErrors: []error{errors.New("error")}})
+	clusterErr := policy.NewPolicyEvaluationResult()
+	clusterErr.ClusterName = "clusterTwo"
+	clusterErr.ClusterError = errors.New("not found")
+
+	report := NewPrometheusReport([]*policy.PolicyEvaluationResult{result, clusterErr}, time.Unix(1650000000, 0))
+	expected := "# HELP gke_review_valid_total Number of valid policies.\n" +
+		"# TYPE gke_review_valid_total gauge\n" +
+		"gke_review_valid_total{cluster=\"clusterOne\",group=\"Net \\\"ops\\\"\",severity=\"MEDIUM\"} 0\n" +
+		"gke_review_valid_total{cluster=\"clusterOne\",group=\"Security\",severity=\"HIGH\"} 1\n" +
+		"gke_review_valid_total{cluster=\"clusterOne\",group=\"Security\",severity=\"LOW\"} 1\n" +
+		"# HELP gke_review_violations_total Number of violated enforced policies.\n" +
+		"# TYPE gke_review_violations_total gauge\n" +
+		"gke_review_violations_total{cluster=\"clusterOne\",group=\"Net \\\"ops\\\"\",severity=\"MEDIUM\"} 0\n" +
+		"gke_review_violations_total{cluster=\"clusterOne\",group=\"Security\",severity=\"HIGH\"} 1\n" +
+		"gke_review_violations_total{cluster=\"clusterOne\",group=\"Security\",severity=\"LOW\"} 0\n" +
+		"# HELP gke_review_warnings_total Number of violated policies with warn or audit enforcement.\n" +
+		"# TYPE gke_review_warnings_total gauge\n" +
+		"gke_review_warnings_total{cluster=\"clusterOne\",group=\"Net \\\"ops\\\"\",severity=\"MEDIUM\"} 1\n" +
+		"gke_review_warnings_total{cluster=\"clusterOne\",group=\"Security\",severity=\"HIGH\"} 0\n" +
+		"gke_review_warnings_total{cluster=\"clusterOne\",group=\"Security\",severity=\"LOW\"} 0\n" +
+		"# HELP gke_review_waived_total Number of policies with waived violations.\n" +
+		"# TYPE gke_review_waived_total gauge\n" +
+		"gke_review_waived_total{cluster=\"clusterOne\",group=\"Net \\\"ops\\\"\",severity=\"MEDIUM\"} 0\n" +
+		"gke_review_waived_total{cluster=\"clusterOne\",group=\"Security\",severity=\"HIGH\"} 0\n" +
+		"gke_review_waived_total{cluster=\"clusterOne\",group=\"Security\",severity=\"LOW\"} 0\n" +
+		"# HELP gke_review_errors_total Number of policies that could not be evaluated.\n" +
+		"# TYPE gke_review_errors_total gauge\n" +
+		"gke_review_errors_total{cluster=\"clusterOne\",group=\"Net \\\"ops\\\"\",severity=\"MEDIUM\"} 0\n" +
+		"gke_review_errors_total{cluster=\"clusterOne\",group=\"Security\",severity=\"HIGH\"} 0\n" +
+		"gke_review_errors_total{cluster=\"clusterOne\",group=\"Security\",severity=\"LOW\"} 1\n" +
+		"# HELP gke_review_cluster_reviewed Whether the cluster was reviewed (1) or could not be reviewed (0).\n" +
+		"# TYPE gke_review_cluster_reviewed gauge\n" +
+		"gke_review_cluster_reviewed{cluster=\"clusterOne\"} 1\n" +
+		"gke_review_cluster_reviewed{cluster=\"clusterTwo\"} 0\n" +
+		"# HELP gke_review_last_run_timestamp Unix time of the last review in seconds.\n" +
+		"# TYPE gke_review_last_run_timestamp gauge\n" +
+		"gke_review_last_run_timestamp 1650000000\n" +
+		"# HELP gke_review_build_info Versions of gke-review and the embedded OPA that reviewed the clusters.\n" +
+		"# TYPE gke_review_build_info gauge\n" +
+		"gke_review_build_info{version=\"" + version.Version + "\",opa_version=\"" + version.OPAVersion() + "\"} 1\n"
+	if report != expected {
+		t.Errorf("report = %s; want %s", report, expected)
+	}
+}
+
+func TestPrometheusResultWriter(t *testing.T) {
+	var buff bytes.Buffer
+	writer := NewPrometheusResultWriter(&buff)
+	if err := writer.Write([]*policy.PolicyEvaluationResult{policy.NewPolicyEvaluationResult()}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !strings.Contains(buff.String(), "gke_review_last_run_timestamp ") {
+		t.Errorf("output = %q; want last run timestamp", buff.String())
+	}
+}
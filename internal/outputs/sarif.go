@@ -0,0 +1,281 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// opaInformationURI describes the embedded OPA, listed as the tool extension
+	opaInformationURI = "https://www.openpolicyagent.org"
+)
+
+type SarifReport struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool        SarifTool          `json:"tool"`
+	Results     []*SarifResult     `json:"results"`
+	Invocations []*SarifInvocation `json:"invocations"`
+}
+
+// SarifTool describes gke-review as the driver and the embedded OPA as its extension.
+type SarifTool struct {
+	Driver     SarifDriver           `json:"driver"`
+	Extensions []*SarifToolComponent `json:"extensions,omitempty"`
+}
+
+type SarifDriver struct {
+	Name           string       `json:"name"`
+	Version        string       `json:"version"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*SarifRule `json:"rules"`
+}
+
+type SarifToolComponent struct {
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	InformationURI string `json:"informationUri,omitempty"`
+}
+
+type SarifRule struct {
+	ID               string               `json:"id"`
+	Name             string               `json:"name,omitempty"`
+	ShortDescription SarifMessage         `json:"shortDescription"`
+	FullDescription  SarifMessage         `json:"fullDescription"`
+	Help             *SarifMessage        `json:"help,omitempty"`
+	HelpURI          string               `json:"helpUri,omitempty"`
+	Properties       *SarifRuleProperties `json:"properties,omitempty"`
+}
+
+// SarifRuleProperties is the property bag of a rule with policy references.
+type SarifRuleProperties struct {
+	References []string `json:"references"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifResult struct {
+	RuleID       string                 `json:"ruleId"`
+	RuleIndex    int                    `json:"ruleIndex"`
+	Level        string                 `json:"level"`
+	Message      SarifMessage           `json:"message"`
+	Locations    []*SarifLocation       `json:"locations"`
+	Suppressions []*SarifSuppression    `json:"suppressions,omitempty"`
+	Properties   *SarifResultProperties `json:"properties,omitempty"`
+}
+
+// SarifResultProperties is the property bag of a result of a policy with violations over the
+// limit, with the number of violations that are not reported as results.
+type SarifResultProperties struct {
+	TruncatedViolations int `json:"truncatedViolations"`
+}
+
+type SarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation *SarifPhysicalLocation  `json:"physicalLocation,omitempty"`
+	LogicalLocations []*SarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+}
+
+type SarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type SarifInvocation struct {
+	ExecutionSuccessful        bool                 `json:"executionSuccessful"`
+	ToolExecutionNotifications []*SarifNotification `json:"toolExecutionNotifications"`
+}
+
+type SarifNotification struct {
+	Level      string                    `json:"level"`
+	Message    SarifMessage              `json:"message"`
+	Descriptor *SarifReportingDescriptor `json:"descriptor,omitempty"`
+}
+
+type SarifReportingDescriptor struct {
+	ID string `json:"id"`
+}
+
+type sarifResultWriter struct {
+	w io.Writer
+}
+
+func NewSarifResultWriter(w io.Writer) ResultWriter {
+	return &sarifResultWriter{w: w}
+}
+
+func (s *sarifResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	encoder := json.NewEncoder(s.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewSarifReport(results))
+}
+
+// NewSarifReport creates SARIF report with one result per violation of violated policies.
+// Each violated policy is described by a single rule, errored policies and clusters that
+// could not be reviewed are reported as tool execution notifications. Policies with all
+// violations suppressed are reported with a single suppressed result.
+func NewSarifReport(results []*policy.PolicyEvaluationResult) *SarifReport {
+	run := &SarifRun{
+		Tool: SarifTool{
+			Driver: SarifDriver{
+				Name:           ToolName,
+				Version:        version.Version,
+				InformationURI: ToolInformationURI,
+				Rules:          make([]*SarifRule, 0),
+			},
+			Extensions: []*SarifToolComponent{
+				{Name: "opa", Version: version.OPAVersion(), InformationURI: opaInformationURI},
+			},
+		},
+		Results: make([]*SarifResult, 0),
+	}
+	invocation := &SarifInvocation{
+		ExecutionSuccessful:        true,
+		ToolExecutionNotifications: make([]*SarifNotification, 0),
+	}
+	ruleIndexes := make(map[string]int)
+	for _, result := range results {
+		if result.ClusterError != nil {
+			invocation.ExecutionSuccessful = false
+			invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, &SarifNotification{
+				Level:   "error",
+				Message: SarifMessage{Text: fmt.Sprintf("cluster %s could not be reviewed: %s", result.ClusterName, result.ClusterError)},
+			})
+			continue
+		}
+		for _, group := range result.Groups() {
+			run.addResults(ruleIndexes, result.ClusterName, "error", result.Violated[group])
+			run.addResults(ruleIndexes, result.ClusterName, "warning", result.Warned[group])
+			run.addResults(ruleIndexes, result.ClusterName, "note", result.Audited[group])
+			run.addResults(ruleIndexes, result.ClusterName, "error", result.Waived[group])
+			for _, p := range result.Suppressed[group] {
+				run.addResults(ruleIndexes, result.ClusterName, sarifLevel(p.Enforcement), []*policy.Policy{p})
+			}
+		}
+		for _, policy := range result.Errored {
+			errs := make([]string, len(policy.ProcessingErrors))
+			for i := range policy.ProcessingErrors {
+				errs[i] = policy.ProcessingErrors[i].Error()
+			}
+			invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, &SarifNotification{
+				Level:      "error",
+				Message:    SarifMessage{Text: strings.Join(errs, ", ")},
+				Descriptor: &SarifReportingDescriptor{ID: policy.Name},
+			})
+		}
+	}
+	run.Invocations = []*SarifInvocation{invocation}
+	return &SarifReport{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []*SarifRun{run},
+	}
+}
+
+func (r *SarifRun) addResults(ruleIndexes map[string]int, clusterName string, level string, policies []*policy.Policy) {
+	for _, policy := range policies {
+		ruleIndex, ok := ruleIndexes[policy.Name]
+		if !ok {
+			ruleIndex = len(r.Tool.Driver.Rules)
+			ruleIndexes[policy.Name] = ruleIndex
+			rule := &SarifRule{
+				ID:               policy.Name,
+				Name:             policy.Title,
+				ShortDescription: SarifMessage{Text: policy.Title},
+				FullDescription:  SarifMessage{Text: policy.Description},
+			}
+			if policy.Remediation != "" {
+				rule.Help = &SarifMessage{Text: policy.Remediation}
+			}
+			if len(policy.References) > 0 {
+				rule.HelpURI = policy.References[0]
+				rule.Properties = &SarifRuleProperties{References: policy.References}
+			}
+			r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, rule)
+		}
+		var properties *SarifResultProperties
+		if policy.TruncatedViolations > 0 {
+			properties = &SarifResultProperties{TruncatedViolations: policy.TruncatedViolations}
+		}
+		violations := policy.Violations
+		var suppressions []*SarifSuppression
+		if policy.Waiver != nil {
+			suppressions = []*SarifSuppression{{Kind: "external", Justification: policy.Waiver.Reason}}
+		}
+		if len(violations) == 0 && policy.SuppressedViolations > 0 {
+			violations = []string{fmt.Sprintf("all %d violations suppressed", policy.SuppressedViolations)}
+			suppressions = []*SarifSuppression{{Kind: "external", Justification: "violations suppressed"}}
+		}
+		for _, violation := range violations {
+			r.Results = append(r.Results, &SarifResult{
+				RuleID:    policy.Name,
+				RuleIndex: ruleIndex,
+				Level:     level,
+				Message:   SarifMessage{Text: violation},
+				Locations: []*SarifLocation{
+					{
+						PhysicalLocation: &SarifPhysicalLocation{
+							ArtifactLocation: SarifArtifactLocation{URI: policy.File},
+						},
+						LogicalLocations: []*SarifLogicalLocation{
+							{FullyQualifiedName: clusterName, Kind: "resource"},
+						},
+					},
+				},
+				Suppressions: suppressions,
+				Properties:   properties,
+			})
+		}
+	}
+}
+
+// sarifLevel returns the level of results of a policy with a given enforcement.
+func sarifLevel(enforcement string) string {
+	switch enforcement {
+	case policy.EnforcementWarn:
+		return "warning"
+	case policy.EnforcementAudit:
+		return "note"
+	default:
+		return "error"
+	}
+}
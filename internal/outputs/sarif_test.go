@@ -0,0 +1,209 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+func TestNewSarifReport(t *testing.T) {
+	violatedPolicy := &policy.Policy{
+		Name:        "gke.policy.private_cluster",
+		File:        "gke-policies/policy/private_cluster.rego",
+		Title:       "GKE private cluster",
+		Description: "GKE cluster should be private",
+		Group:       "Security",
+		Remediation: "Enable private nodes",
+		References:  []string{"https://cloud.google.com/kubernetes-engine/docs/private-clusters", "https://example.com"},
+		Violations:  []string{"violation one", "violation two"},
+	}
+	erroredPolicy := &policy.Policy{
+		Name:             "gke.policy.errored",
+		Group:            "Security",
+		ProcessingErrors: []error{errors.New("error one"), errors.New("error two")},
+	}
+	resultOne := policy.NewPolicyEvaluationResult()
+	resultOne.ClusterName = "clusterOne"
+	resultOne.AddPolicy(violatedPolicy)
+	resultOne.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	resultOne.AddPolicy(erroredPolicy)
+	resultTwo := policy.NewPolicyEvaluationResult()
+	resultTwo.ClusterName = "clusterTwo"
+	resultTwo.AddPolicy(violatedPolicy)
+
+	report := NewSarifReport([]*policy.PolicyEvaluationResult{resultOne, resultTwo})
+	if report.Version != sarifVersion {
+		t.Errorf("version = %v; want %v", report.Version, sarifVersion)
+	}
+	if len(report.Runs) != 1 {
+		t.Fatalf("len(runs) = %v; want %v", len(report.Runs), 1)
+	}
+	run := report.Runs[0]
+	if run.Tool.Driver.Version != version.Version {
+		t.Errorf("driver version = %v; want %v", run.Tool.Driver.Version, version.Version)
+	}
+	if len(run.Tool.Extensions) != 1 || run.Tool.Extensions[0].Version != version.OPAVersion() {
+		t.Errorf("extensions = %+v; want opa %v", run.Tool.Extensions, version.OPAVersion())
+	}
+	if len(run.Tool.Driver.Rules) != 1 {
+		t.Fatalf("len(rules) = %v; want %v", len(run.Tool.Driver.Rules), 1)
+	}
+	rule := run.Tool.Driver.Rules[0]
+	if rule.ID != violatedPolicy.Name {
+		t.Errorf("rule id = %v; want %v", rule.ID, violatedPolicy.Name)
+	}
+	if rule.FullDescription.Text != violatedPolicy.Description {
+		t.Errorf("rule fullDescription = %v; want %v", rule.FullDescription.Text, violatedPolicy.Description)
+	}
+	if rule.Help == nil || rule.Help.Text != violatedPolicy.Remediation {
+		t.Errorf("rule help = %v; want %v", rule.Help, violatedPolicy.Remediation)
+	}
+	if rule.HelpURI != violatedPolicy.References[0] {
+		t.Errorf("rule helpUri = %v; want %v", rule.HelpURI, violatedPolicy.References[0])
+	}
+	if rule.Properties == nil || !reflect.DeepEqual(rule.Properties.References, violatedPolicy.References) {
+		t.Errorf("rule properties = %v; want references %v", rule.Properties, violatedPolicy.References)
+	}
+	if len(run.Results) != 4 {
+		t.Fatalf("len(results) = %v; want %v", len(run.Results), 4)
+	}
+	for _, result := range run.Results {
+		if result.RuleID != violatedPolicy.Name {
+			t.Errorf("result ruleId = %v; want %v", result.RuleID, violatedPolicy.Name)
+		}
+		if result.Level != "error" {
+			t.Errorf("result level = %v; want %v", result.Level, "error")
+		}
+	}
+	if run.Results[0].Message.Text != "violation one" {
+		t.Errorf("result[0] message = %v; want %v", run.Results[0].Message.Text, "violation one")
+	}
+	notifications := run.Invocations[0].ToolExecutionNotifications
+	if len(notifications) != 1 {
+		t.Fatalf("len(notifications) = %v; want %v", len(notifications), 1)
+	}
+	if notifications[0].Descriptor.ID != erroredPolicy.Name {
+		t.Errorf("notification descriptor id = %v; want %v", notifications[0].Descriptor.ID, erroredPolicy.Name)
+	}
+	if notifications[0].Message.Text != "error one, error two" {
+		t.Errorf("notification message = %v; want %v", notifications[0].Message.Text, "error one, error two")
+	}
+}
+
+func TestSarifResultWriter(t *testing.T) {
+	var buff bytes.Buffer
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.test", Group: "Test", Violations: []string{"violation"}})
+	if err := NewSarifResultWriter(&buff).Write([]*policy.PolicyEvaluationResult{result}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	report := &SarifReport{}
+	if err := json.Unmarshal(buff.Bytes(), report); err != nil {
+		t.Fatalf("unmarshal err = %v; want nil", err)
+	}
+	if report.Schema != sarifSchema {
+		t.Errorf("schema = %v; want %v", report.Schema, sarifSchema)
+	}
+	if len(report.Runs[0].Results) != 1 {
+		t.Errorf("len(results) = %v; want %v", len(report.Runs[0].Results), 1)
+	}
+}
+
+func TestNewSarifReport_clusterError(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.ClusterError = errors.New("not found")
+	report := NewSarifReport([]*policy.PolicyEvaluationResult{result})
+	invocation := report.Runs[0].Invocations[0]
+	if invocation.ExecutionSuccessful {
+		t.Errorf("executionSuccessful = %v; want %v", true, false)
+	}
+	if len(invocation.ToolExecutionNotifications) != 1 {
+		t.Fatalf("len(notifications) = %v; want %v", len(invocation.ToolExecutionNotifications), 1)
+	}
+	expected := "cluster clusterOne could not be reviewed: not found"
+	if text := invocation.ToolExecutionNotifications[0].Message.Text; text != expected {
+		t.Errorf("notification message = %v; want %v", text, expected)
+	}
+}
+
+func TestNewSarifReport_waived(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.Waived["Security"] = []*policy.Policy{{
+		Name:       "gke.policy.private_cluster",
+		Group:      "Security",
+		Violations: []string{"violation one"},
+		Waiver:     &policy.PolicyWaiver{Policy: "private_cluster", Reason: "accepted risk"},
+	}}
+	report := NewSarifReport([]*policy.PolicyEvaluationResult{result})
+	results := report.Runs[0].Results
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %v; want %v", len(results), 1)
+	}
+	if len(results[0].Suppressions) != 1 || results[0].Suppressions[0].Justification != "accepted risk" {
+		t.Errorf("suppressions = %v; want single suppression with justification", results[0].Suppressions)
+	}
+}
+
+func TestNewSarifReport_suppressed(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.Suppressed["Security"] = []*policy.Policy{{
+		Name:                 "gke.policy.private_cluster",
+		Group:                "Security",
+		Enforcement:          policy.EnforcementWarn,
+		SuppressedViolations: 2,
+	}}
+	results := NewSarifReport([]*policy.PolicyEvaluationResult{result}).Runs[0].Results
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %v; want %v", len(results), 1)
+	}
+	if results[0].Level != "warning" {
+		t.Errorf("level = %v; want %v", results[0].Level, "warning")
+	}
+	if results[0].Message.Text != "all 2 violations suppressed" {
+		t.Errorf("message = %v; want %v", results[0].Message.Text, "all 2 violations suppressed")
+	}
+	if len(results[0].Suppressions) != 1 || results[0].Suppressions[0].Kind != "external" {
+		t.Errorf("suppressions = %v; want single external suppression", results[0].Suppressions)
+	}
+}
+
+func TestNewSarifReport_truncated(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.noisy", Group: "Security", Violations: []string{"one", "two", "three"}})
+	result.TruncateViolations(2)
+	run := NewSarifReport([]*policy.PolicyEvaluationResult{result}).Runs[0]
+	if len(run.Results) != 2 {
+		t.Fatalf("len(results) = %v; want %v", len(run.Results), 2)
+	}
+	for i, sarifResult := range run.Results {
+		if sarifResult.Message.Text != []string{"one", "two"}[i] {
+			t.Errorf("result [%d] message = %v; want %v", i, sarifResult.Message.Text, []string{"one", "two"}[i])
+		}
+		if sarifResult.Properties == nil || sarifResult.Properties.TruncatedViolations != 1 {
+			t.Errorf("result [%d] properties = %+v; want 1 truncated violation", i, sarifResult.Properties)
+		}
+	}
+}
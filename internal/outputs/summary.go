@@ -0,0 +1,145 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"gopkg.in/yaml.v2"
+)
+
+// JSONSummaryReport is the top level document of the summary output, that carries
+// policy counts only.
+type JSONSummaryReport struct {
+	Version string                `json:"version" yaml:"version"`
+	Tool    JSONTool              `json:"tool" yaml:"tool"`
+	Results []*JSONClusterSummary `json:"results" yaml:"results"`
+	// ViolatedBySeverity is the number of violated policies of each severity on all clusters
+	ViolatedBySeverity JSONSeverityCounts `json:"violatedBySeverity" yaml:"violatedBySeverity"`
+}
+
+// JSONClusterSummary holds total and per group policy counts of a single cluster.
+type JSONClusterSummary struct {
+	Cluster string             `json:"cluster" yaml:"cluster"`
+	Error   string             `json:"error,omitempty" yaml:"error,omitempty"`
+	Counts  JSONCounts         `json:"counts" yaml:"counts"`
+	Groups  []*JSONGroupCounts `json:"groups" yaml:"groups"`
+	// ViolatedBySeverity is the number of violated policies of each severity on the cluster
+	ViolatedBySeverity JSONSeverityCounts `json:"violatedBySeverity" yaml:"violatedBySeverity"`
+}
+
+// JSONGroupCounts holds number of valid, violated and errored policies of a group.
+type JSONGroupCounts struct {
+	Group    string `json:"group" yaml:"group"`
+	Valid    int    `json:"valid" yaml:"valid"`
+	Violated int    `json:"violated" yaml:"violated"`
+	Errored  int    `json:"errored" yaml:"errored"`
+}
+
+type jsonSummaryResultWriter struct {
+	w io.Writer
+}
+
+// NewJSONSummaryResultWriter creates writer of the summary report as compact JSON.
+func NewJSONSummaryResultWriter(w io.Writer) ResultWriter {
+	return &jsonSummaryResultWriter{w: w}
+}
+
+func (j *jsonSummaryResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	return json.NewEncoder(j.w).Encode(NewJSONSummaryReport(results))
+}
+
+type yamlSummaryResultWriter struct {
+	w io.Writer
+}
+
+// NewYAMLSummaryResultWriter creates writer of the summary report as YAML.
+func NewYAMLSummaryResultWriter(w io.Writer) ResultWriter {
+	return &yamlSummaryResultWriter{w: w}
+}
+
+func (y *yamlSummaryResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	encoder := yaml.NewEncoder(y.w)
+	if err := encoder.Encode(NewJSONSummaryReport(results)); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// NewJSONSummaryReport creates summary report view of policy evaluation results.
+func NewJSONSummaryReport(results []*policy.PolicyEvaluationResult) *JSONSummaryReport {
+	report := &JSONSummaryReport{
+		Version:            JSONReportVersion,
+		Tool:               NewJSONTool(),
+		Results:            make([]*JSONClusterSummary, len(results)),
+		ViolatedBySeverity: NewSeverityCounts(results),
+	}
+	for i, result := range results {
+		report.Results[i] = &JSONClusterSummary{
+			Cluster: result.ClusterName,
+			Counts: JSONCounts{
+				Valid:    result.ValidCount(),
+				Violated: result.ViolatedCount(),
+				Warned:   result.WarnedCount(),
+				Audited:  result.AuditedCount(),
+				Filtered: result.FilteredCount(),
+				Waived:   result.WaivedCount(),
+				Skipped:  result.SkippedCount(),
+				Errored:  result.ErroredCount(),
+			},
+			Groups: NewGroupCounts(result),
+		}
+		report.Results[i].ViolatedBySeverity = NewSeverityCounts([]*policy.PolicyEvaluationResult{result})
+		if result.ClusterError != nil {
+			report.Results[i].Error = result.ClusterError.Error()
+		}
+	}
+	return report
+}
+
+// NewGroupCounts returns policy counts of each group, including groups of errored policies,
+// sorted by the group name or from the most severe when grouped by severity.
+func NewGroupCounts(result *policy.PolicyEvaluationResult) []*JSONGroupCounts {
+	errored := make(map[string]int)
+	for _, p := range result.Errored {
+		errored[result.GroupOf(p)]++
+	}
+	groupSet := make(map[string]bool)
+	for _, group := range result.Groups() {
+		groupSet[group] = true
+	}
+	for group := range errored {
+		groupSet[group] = true
+	}
+	counts := make([]*JSONGroupCounts, 0, len(groupSet))
+	for group := range groupSet {
+		counts = append(counts, &JSONGroupCounts{
+			Group:    group,
+			Valid:    len(result.Valid[group]),
+			Violated: len(result.Violated[group]),
+			Errored:  errored[group],
+		})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if result.IsGroupedBySeverity() {
+			return policy.SeverityLevel(counts[i].Group) > policy.SeverityLevel(counts[j].Group)
+		}
+		return counts[i].Group < counts[j].Group
+	})
+	return counts
+}
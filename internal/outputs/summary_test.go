@@ -0,0 +1,82 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+func TestNewJSONSummaryReport(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Group: "Security", Violations: []string{"one"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.other", Group: "Management", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.errored", Group: "Availability",
+		ProcessingErrors: []error{errors.New("error one")}})
+	failed := policy.NewPolicyEvaluationResult()
+	failed.ClusterName = "clusterTwo"
+	failed.ClusterError = errors.New("not found")
+
+	report := NewJSONSummaryReport([]*policy.PolicyEvaluationResult{result, failed})
+	expectedGroups := []*JSONGroupCounts{
+		{Group: "Availability", Errored: 1},
+		{Group: "Management", Valid: 1},
+		{Group: "Security", Valid: 1, Violated: 1},
+	}
+	if !reflect.DeepEqual(report.Results[0].Groups, expectedGroups) {
+		t.Errorf("groups = %+v; want %+v", report.Results[0].Groups, expectedGroups)
+	}
+	expectedCounts := JSONCounts{Valid: 2, Violated: 1, Errored: 1}
+	if report.Results[0].Counts != expectedCounts {
+		t.Errorf("counts = %+v; want %+v", report.Results[0].Counts, expectedCounts)
+	}
+	if report.ViolatedBySeverity != (JSONSeverityCounts{Medium: 1}) {
+		t.Errorf("violatedBySeverity = %+v; want %+v", report.ViolatedBySeverity, JSONSeverityCounts{Medium: 1})
+	}
+	if report.Results[1].Error != "not found" {
+		t.Errorf("error = %v; want %v", report.Results[1].Error, "not found")
+	}
+}
+
+func TestJSONSummaryResultWriter(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Group: "Security", Violations: []string{"one"}})
+	buff := new(bytes.Buffer)
+	if err := NewJSONSummaryResultWriter(buff).Write([]*policy.PolicyEvaluationResult{result}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if lines := strings.Count(buff.String(), "\n"); lines != 1 {
+		t.Errorf("output has %d lines; want compact output in 1 line", lines)
+	}
+	report := &JSONSummaryReport{}
+	if err := json.Unmarshal(buff.Bytes(), report); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if strings.Contains(buff.String(), "violations") {
+		t.Errorf("output = %s; want no policy details", buff.String())
+	}
+	if report.Results[0].Counts.Violated != 1 {
+		t.Errorf("violated = %v; want %v", report.Results[0].Counts.Violated, 1)
+	}
+}
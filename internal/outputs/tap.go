@@ -0,0 +1,165 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+	"gopkg.in/yaml.v2"
+)
+
+const tapVersion = "TAP version 13"
+
+// tapDiagnostic is the YAML diagnostic block of a test line.
+type tapDiagnostic struct {
+	Message    string   `yaml:"message,omitempty"`
+	Severity   string   `yaml:"severity,omitempty"`
+	Group      string   `yaml:"group,omitempty"`
+	Violations []string `yaml:"violations,omitempty"`
+	Errors     []string `yaml:"errors,omitempty"`
+}
+
+type tapResultWriter struct {
+	w io.Writer
+}
+
+func NewTAPResultWriter(w io.Writer) ResultWriter {
+	return &tapResultWriter{w: w}
+}
+
+func (t *tapResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	report, err := NewTAPReport(results)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(t.w, report)
+	return err
+}
+
+// NewTAPReport renders results as TAP version 13 stream with one test line per evaluated policy
+// and a trailing plan. Violated policies fail, errored policies fail with ERROR directive and
+// violations of warn and audit policies and waived violations are skipped, like in the JUnit report.
+// Clusters that could not be reviewed are reported as comments, as no policies were evaluated.
+// The first comment holds versions of gke-review and the embedded OPA.
+func NewTAPReport(results []*policy.PolicyEvaluationResult) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(tapVersion + "\n")
+	sb.WriteString(fmt.Sprintf("# %s %s\n", ToolName, tapEscape(version.String())))
+	n := 0
+	line := func(ok bool, p *policy.Policy, directive string, diag *tapDiagnostic) error {
+		n++
+		status := "ok"
+		if !ok {
+			status = "not ok"
+		}
+		sb.WriteString(fmt.Sprintf("%s %d - %s", status, n, p.Name))
+		if directive != "" {
+			sb.WriteString(" # " + tapEscape(directive))
+		}
+		sb.WriteString("\n")
+		if diag == nil {
+			return nil
+		}
+		data, err := yaml.Marshal(diag)
+		if err != nil {
+			return err
+		}
+		sb.WriteString("  ---\n")
+		for _, l := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			sb.WriteString("  " + l + "\n")
+		}
+		sb.WriteString("  ...\n")
+		return nil
+	}
+	for _, result := range results {
+		sb.WriteString(fmt.Sprintf("# cluster: %s\n", tapEscape(result.ClusterName)))
+		if result.ClusterError != nil {
+			sb.WriteString(fmt.Sprintf("# cluster could not be reviewed: %s\n", tapEscape(result.ClusterError.Error())))
+			continue
+		}
+		for _, group := range result.Groups() {
+			for _, p := range result.Valid[group] {
+				if err := line(true, p, "", nil); err != nil {
+					return "", err
+				}
+			}
+			for _, p := range result.Violated[group] {
+				if err := line(false, p, "", newTAPViolationsDiagnostic(p)); err != nil {
+					return "", err
+				}
+			}
+			for _, p := range append(result.Warned[group], result.Audited[group]...) {
+				if err := line(true, p, "SKIP "+p.Enforcement, newTAPViolationsDiagnostic(p)); err != nil {
+					return "", err
+				}
+			}
+			for _, p := range result.Waived[group] {
+				directive := fmt.Sprintf("SKIP waived until %s: %s", p.Waiver.Expires.Format(policy.WaiverDateFormat), p.Waiver.Reason)
+				if err := line(true, p, directive, newTAPViolationsDiagnostic(p)); err != nil {
+					return "", err
+				}
+			}
+			for _, p := range result.Suppressed[group] {
+				directive := fmt.Sprintf("SKIP all %d violations suppressed", p.SuppressedViolations)
+				if err := line(true, p, directive, nil); err != nil {
+					return "", err
+				}
+			}
+		}
+		filteredGroups := make([]string, 0, len(result.Filtered))
+		for group := range result.Filtered {
+			filteredGroups = append(filteredGroups, group)
+		}
+		sort.Strings(filteredGroups)
+		for _, group := range filteredGroups {
+			for _, p := range result.Filtered[group] {
+				directive := fmt.Sprintf("SKIP filtered out due to %s severity", p.Severity)
+				if err := line(true, p, directive, newTAPViolationsDiagnostic(p)); err != nil {
+					return "", err
+				}
+			}
+		}
+		for _, p := range result.Errored {
+			diag := &tapDiagnostic{Group: p.Group, Errors: make([]string, len(p.ProcessingErrors))}
+			for i := range p.ProcessingErrors {
+				diag.Errors[i] = p.ProcessingErrors[i].Error()
+			}
+			if err := line(false, p, "ERROR", diag); err != nil {
+				return "", err
+			}
+		}
+	}
+	sb.WriteString(fmt.Sprintf("1..%d\n", n))
+	return sb.String(), nil
+}
+
+func newTAPViolationsDiagnostic(p *policy.Policy) *tapDiagnostic {
+	return &tapDiagnostic{
+		Message:    p.Description,
+		Severity:   p.Severity,
+		Group:      p.Group,
+		Violations: p.Violations,
+	}
+}
+
+// tapEscape joins lines, so that a value does not break the line based protocol.
+func tapEscape(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
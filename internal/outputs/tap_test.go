@@ -0,0 +1,83 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"github.com/mikouaj/gke-review/internal/version"
+)
+
+func TestNewTAPReport(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Description: "Must be private", Group: "Security",
+		Severity: policy.SeverityHigh, Violations: []string{"one", "two"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.warned", Group: "Security", Enforcement: policy.EnforcementWarn,
+		Violations: []string{"three"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.errored", ProcessingErrors: []error{errors.New("error one")}})
+	result.Suppressed["Security"] = []*policy.Policy{{Name: "gke.policy.suppressed", Group: "Security", SuppressedViolations: 2}}
+	result.Filtered["Security"] = []*policy.Policy{{Name: "gke.policy.filtered", Group: "Security", Severity: policy.SeverityLow,
+		Violations: []string{"four"}}}
+	clusterErr := policy.NewPolicyEvaluationResult()
+	clusterErr.ClusterName = "clusterTwo"
+	clusterErr.ClusterError = errors.New("not found")
+
+	report, err := NewTAPReport([]*policy.PolicyEvaluationResult{result, clusterErr})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := "TAP version 13\n" +
+		"# gke-review " + version.String() + "\n" +
+		"# cluster: clusterOne\n" +
+		"ok 1 - gke.policy.valid\n" +
+		"not ok 2 - gke.policy.violated\n" +
+		"  ---\n" +
+		"  message: Must be private\n" +
+		"  severity: HIGH\n" +
+		"  group: Security\n" +
+		"  violations:\n" +
+		"  - one\n" +
+		"  - two\n" +
+		"  ...\n" +
+		"ok 3 - gke.policy.warned # SKIP warn\n" +
+		"  ---\n" +
+		"  group: Security\n" +
+		"  violations:\n" +
+		"  - three\n" +
+		"  ...\n" +
+		"ok 4 - gke.policy.suppressed # SKIP all 2 violations suppressed\n" +
+		"ok 5 - gke.policy.filtered # SKIP filtered out due to LOW severity\n" +
+		"  ---\n" +
+		"  severity: LOW\n" +
+		"  group: Security\n" +
+		"  violations:\n" +
+		"  - four\n" +
+		"  ...\n" +
+		"not ok 6 - gke.policy.errored # ERROR\n" +
+		"  ---\n" +
+		"  errors:\n" +
+		"  - error one\n" +
+		"  ...\n" +
+		"# cluster: clusterTwo\n" +
+		"# cluster could not be reviewed: not found\n" +
+		"1..6\n"
+	if report != expected {
+		t.Errorf("report = %q; want %q", report, expected)
+	}
+}
@@ -0,0 +1,102 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+// TrendDateFormat is the format of dates of the trend series points.
+const TrendDateFormat = "2006-01-02"
+
+// TrendReport is the top level document of the trend series, with points ordered by time.
+type TrendReport struct {
+	Version string        `json:"version"`
+	Series  []*TrendPoint `json:"series"`
+}
+
+// TrendPoint holds policy counts of a single cluster snapshot. Violations is the number
+// of violations of violated policies, that can report more than one violation each.
+type TrendPoint struct {
+	Date       string    `json:"date"`
+	Time       time.Time `json:"time"`
+	Snapshot   string    `json:"snapshot"`
+	Valid      int       `json:"valid"`
+	Violated   int       `json:"violated"`
+	Warned     int       `json:"warned"`
+	Waived     int       `json:"waived"`
+	Errored    int       `json:"errored"`
+	Violations int       `json:"violations"`
+}
+
+// NewTrendPoint creates the series point of a snapshot taken at a given time.
+func NewTrendPoint(t time.Time, snapshot string, result *policy.PolicyEvaluationResult) *TrendPoint {
+	violations := 0
+	for _, policies := range result.Violated {
+		for _, p := range policies {
+			violations += len(p.Violations)
+		}
+	}
+	return &TrendPoint{
+		Date:       t.UTC().Format(TrendDateFormat),
+		Time:       t.UTC(),
+		Snapshot:   snapshot,
+		Valid:      result.ValidCount(),
+		Violated:   result.ViolatedCount(),
+		Warned:     result.WarnedCount(),
+		Waived:     result.WaivedCount(),
+		Errored:    result.ErroredCount(),
+		Violations: violations,
+	}
+}
+
+// WriteTrendJSON writes the trend series as indented JSON.
+func WriteTrendJSON(w io.Writer, points []*TrendPoint) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&TrendReport{Version: JSONReportVersion, Series: points})
+}
+
+// WriteTrendCSV writes the trend series as CSV with a header line and one line per point.
+func WriteTrendCSV(w io.Writer, points []*TrendPoint) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"date", "time", "snapshot", "valid", "violated", "warned", "waived", "errored", "violations"}); err != nil {
+		return err
+	}
+	for _, point := range points {
+		record := []string{
+			point.Date,
+			point.Time.Format(time.RFC3339),
+			point.Snapshot,
+			strconv.Itoa(point.Valid),
+			strconv.Itoa(point.Violated),
+			strconv.Itoa(point.Warned),
+			strconv.Itoa(point.Waived),
+			strconv.Itoa(point.Errored),
+			strconv.Itoa(point.Violations),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,68 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+func testTrendPoints() []*TrendPoint {
+	result := policy.NewPolicyEvaluationResult()
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Security", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Group: "Security", Violations: []string{"one", "two"}})
+	t := time.Date(2022, 3, 15, 6, 30, 0, 0, time.UTC)
+	return []*TrendPoint{NewTrendPoint(t, "snapshots/2022-03-15.json", result)}
+}
+
+func TestNewTrendPoint(t *testing.T) {
+	point := testTrendPoints()[0]
+	if point.Date != "2022-03-15" {
+		t.Errorf("date = %v; want %v", point.Date, "2022-03-15")
+	}
+	if point.Valid != 1 || point.Violated != 1 || point.Violations != 2 {
+		t.Errorf("counts = %+v; want 1 valid, 1 violated with 2 violations", point)
+	}
+}
+
+func TestWriteTrendJSON(t *testing.T) {
+	var buff bytes.Buffer
+	if err := WriteTrendJSON(&buff, testTrendPoints()); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	report := TrendReport{}
+	if err := json.Unmarshal(buff.Bytes(), &report); err != nil {
+		t.Fatalf("could not decode trend report: %s", err)
+	}
+	if len(report.Series) != 1 || report.Series[0].Snapshot != "snapshots/2022-03-15.json" {
+		t.Errorf("series = %+v; want single point of snapshots/2022-03-15.json", report.Series)
+	}
+}
+
+func TestWriteTrendCSV(t *testing.T) {
+	var buff bytes.Buffer
+	if err := WriteTrendCSV(&buff, testTrendPoints()); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := "date,time,snapshot,valid,violated,warned,waived,errored,violations\n" +
+		"2022-03-15,2022-03-15T06:30:00Z,snapshots/2022-03-15.json,1,1,0,0,0,2\n"
+	if buff.String() != expected {
+		t.Errorf("csv = %q; want %q", buff.String(), expected)
+	}
+}
@@ -0,0 +1,39 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"io"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"gopkg.in/yaml.v2"
+)
+
+type yamlResultWriter struct {
+	w io.Writer
+}
+
+// NewYAMLResultWriter creates writer of the JSON report view serialized as YAML.
+func NewYAMLResultWriter(w io.Writer) ResultWriter {
+	return &yamlResultWriter{w: w}
+}
+
+func (y *yamlResultWriter) Write(results []*policy.PolicyEvaluationResult) error {
+	encoder := yaml.NewEncoder(y.w)
+	if err := encoder.Encode(NewJSONReport(results)); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,58 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mikouaj/gke-review/internal/policy"
+	"gopkg.in/yaml.v2"
+)
+
+func TestYAMLResultWriter(t *testing.T) {
+	result := policy.NewPolicyEvaluationResult()
+	result.ClusterName = "clusterOne"
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.violated", Group: "Security", Violations: []string{"violation one"}})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.valid", Group: "Management", Valid: true})
+	result.AddPolicy(&policy.Policy{Name: "gke.policy.errored", Group: "Security",
+		ProcessingErrors: []error{errors.New("error one")}})
+	results := []*policy.PolicyEvaluationResult{result}
+
+	buff := new(bytes.Buffer)
+	if err := NewYAMLResultWriter(buff).Write(results); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	output := buff.String()
+	if strings.Index(output, "counts:") > strings.Index(output, "groups:") {
+		t.Errorf("output = %q; want counts before groups", output)
+	}
+	report := &JSONReport{}
+	if err := yaml.Unmarshal(buff.Bytes(), report); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	fromYAML, _ := json.Marshal(report)
+	expected, _ := json.Marshal(NewJSONReport(results))
+	if !bytes.Equal(fromYAML, expected) {
+		t.Errorf("report = %s; want %s", fromYAML, expected)
+	}
+	if errs := report.Results[0].Errored[0].Errors; !reflect.DeepEqual(errs, []string{"error one"}) {
+		t.Errorf("errors = %v; want %v", errs, []string{"error one"})
+	}
+}
@@ -0,0 +1,140 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// benchmarkPolicyCounts are numbers of synthetic policies used by sub-benchmarks
+var benchmarkPolicyCounts = []int{10, 100, 500}
+
+const benchmarkPolicyTemplate = "# METADATA\n" +
+	"# title: Synthetic policy %[1]d\n" +
+	"# description: Synthetic policy for benchmarks\n" +
+	"# custom:\n" +
+	"#   group: Group %[2]d\n" +
+	"#   severity: HIGH\n" +
+	"package gke.policy.synthetic_%[1]d\n" +
+	"default valid = false\n" +
+	"valid { count(violation) == 0 }\n" +
+	"violation[msg] { not input.private_cluster_config.enable_private_nodes; msg := \"private nodes are disabled\" }\n" +
+	"violation[msg] { pool := input.node_pools[_]; not pool.management.auto_upgrade; msg := sprintf(\"node pool %%q has no auto upgrade\", [pool.name]) }\n" +
+	"violation[msg] { input.node_pools[_].config.machine_type == \"e2-micro-%[1]d\"; msg := \"machine type is not allowed\" }\n"
+
+// syntheticPolicyFiles returns n policy files with metadata and rules that are
+// similar to the default policies.
+func syntheticPolicyFiles(n int) []*PolicyFile {
+	files := make([]*PolicyFile, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("synthetic_%d.rego", i)
+		files[i] = &PolicyFile{name, name, fmt.Sprintf(benchmarkPolicyTemplate, i, i%10)}
+	}
+	return files
+}
+
+// syntheticClusterInput returns a cluster input resembling the one of a standard cluster
+// with a few node pools.
+func syntheticClusterInput() map[string]interface{} {
+	nodePools := make([]interface{}, 5)
+	for i := range nodePools {
+		nodePools[i] = map[string]interface{}{
+			"name": fmt.Sprintf("pool-%d", i),
+			"config": map[string]interface{}{
+				"machine_type": "e2-standard-4",
+				"disk_size_gb": 100,
+				"oauth_scopes": []interface{}{"https://www.googleapis.com/auth/cloud-platform"},
+				"labels":       map[string]interface{}{"env": "prod"},
+			},
+			"management": map[string]interface{}{
+				"auto_upgrade": i%2 == 0,
+				"auto_repair":  true,
+			},
+			"initial_node_count": 3,
+		}
+	}
+	return map[string]interface{}{
+		"name":                   "cluster-benchmark",
+		"location":               "europe-central2",
+		"current_master_version": "1.22.8-gke.200",
+		"private_cluster_config": map[string]interface{}{
+			"enable_private_nodes":    false,
+			"enable_private_endpoint": false,
+		},
+		"master_auth":       map[string]interface{}{"cluster_ca_certificate": "LS0tLS1CRUdJTi"},
+		"network_policy":    map[string]interface{}{"enabled": true, "provider": "CALICO"},
+		"resource_labels":   map[string]interface{}{"owner": "platform"},
+		"node_pools":        nodePools,
+		"release_channel":   map[string]interface{}{"channel": "REGULAR"},
+		"workload_identity": map[string]interface{}{"workload_pool": "project.svc.id.goog"},
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	for _, n := range benchmarkPolicyCounts {
+		files := syntheticPolicyFiles(n)
+		b.Run(fmt.Sprintf("policies=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pa := NewPolicyAgent(context.Background())
+				if err := pa.WithFiles(files); err != nil {
+					b.Fatalf("error = %v; want nil", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEvaluatePolicies(b *testing.B) {
+	input := syntheticClusterInput()
+	for _, n := range benchmarkPolicyCounts {
+		pa := NewPolicyAgent(context.Background())
+		if err := pa.WithFiles(syntheticPolicyFiles(n)); err != nil {
+			b.Fatalf("error = %v; want nil", err)
+		}
+		b.Run(fmt.Sprintf("policies=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				result, err := pa.Evaluate(input)
+				if err != nil {
+					b.Fatalf("error = %v; want nil", err)
+				}
+				if result.ViolatedCount() != n {
+					b.Fatalf("violatedCount = %v; want %v", result.ViolatedCount(), n)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseRegoPolicyData(b *testing.B) {
+	for _, n := range []int{1, 10, 100} {
+		violations := make([]interface{}, n)
+		for i := range violations {
+			violations[i] = fmt.Sprintf("node pool %q has no auto upgrade", fmt.Sprintf("pool-%d", i))
+		}
+		data := map[string]interface{}{"valid": false, "violation": violations}
+		b.Run(fmt.Sprintf("violations=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := parseRegoPolicyData(data); err != nil {
+					b.Fatalf("error = %v; want nil", err)
+				}
+			}
+		})
+	}
+}
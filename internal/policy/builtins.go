@@ -0,0 +1,172 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+)
+
+// Builtin is a custom function, implemented in Go, that policies can call in addition
+// to OPA built-in functions. Errors returned by the implementation make the calling
+// expression undefined.
+type Builtin struct {
+	Name        string
+	Description string
+	Decl        *types.Function
+	Impl        rego.BuiltinDyn
+}
+
+var (
+	builtinsMutex sync.RWMutex
+	builtins      = make(map[string]*Builtin)
+)
+
+func init() {
+	mustRegisterBuiltin(&Builtin{
+		Name:        "net.cidr_contains_any",
+		Description: "checks if any of CIDRs contains an IP address or a CIDR, i.e. net.cidr_contains_any([\"10.0.0.0/8\"], \"10.1.0.0/16\")",
+		Decl: types.NewFunction(
+			types.Args(types.NewAny(types.NewArray(nil, types.S), types.NewSet(types.S)), types.S),
+			types.B),
+		Impl: cidrContainsAny,
+	})
+}
+
+// RegisterBuiltin makes a custom function available to policies compiled afterwards.
+// Names of OPA built-in functions and of already registered functions are rejected.
+func RegisterBuiltin(builtin *Builtin) error {
+	if builtin == nil || builtin.Name == "" {
+		return errors.New("builtin name is not set")
+	}
+	if builtin.Decl == nil || builtin.Impl == nil {
+		return fmt.Errorf("builtin %s has no declaration or implementation", builtin.Name)
+	}
+	if _, ok := ast.BuiltinMap[builtin.Name]; ok {
+		return fmt.Errorf("builtin %s is an OPA built-in function", builtin.Name)
+	}
+	builtinsMutex.Lock()
+	defer builtinsMutex.Unlock()
+	if _, ok := builtins[builtin.Name]; ok {
+		return fmt.Errorf("builtin %s is already registered", builtin.Name)
+	}
+	builtins[builtin.Name] = builtin
+	return nil
+}
+
+func mustRegisterBuiltin(builtin *Builtin) {
+	if err := RegisterBuiltin(builtin); err != nil {
+		panic(err)
+	}
+}
+
+// Builtins returns registered custom functions, ordered by name.
+func Builtins() []*Builtin {
+	builtinsMutex.RLock()
+	defer builtinsMutex.RUnlock()
+	result := make([]*Builtin, 0, len(builtins))
+	for _, builtin := range builtins {
+		result = append(result, builtin)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// builtinDecls returns declarations of registered custom functions for the compiler.
+func builtinDecls() map[string]*ast.Builtin {
+	decls := make(map[string]*ast.Builtin)
+	for _, builtin := range Builtins() {
+		decls[builtin.Name] = &ast.Builtin{Name: builtin.Name, Decl: builtin.Decl}
+	}
+	return decls
+}
+
+// regoBuiltins returns option with implementations of registered custom functions.
+func regoBuiltins() func(r *rego.Rego) {
+	registered := Builtins()
+	return func(r *rego.Rego) {
+		for _, builtin := range registered {
+			rego.FunctionDyn(&rego.Function{Name: builtin.Name, Decl: builtin.Decl}, builtin.Impl)(r)
+		}
+	}
+}
+
+// cidrContainsAny implements net.cidr_contains_any. The second argument is an IP address
+// or a CIDR, that is contained when all of its addresses are.
+func cidrContainsAny(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+	var cidrs []*ast.Term
+	switch value := terms[0].Value.(type) {
+	case *ast.Array:
+		value.Foreach(func(term *ast.Term) {
+			cidrs = append(cidrs, term)
+		})
+	case ast.Set:
+		cidrs = value.Slice()
+	default:
+		return nil, fmt.Errorf("operand 1 must be array or set of strings")
+	}
+	addr, ok := terms[1].Value.(ast.String)
+	if !ok {
+		return nil, fmt.Errorf("operand 2 must be string")
+	}
+	first, ones, err := parseIPOrCIDR(string(addr))
+	if err != nil {
+		return nil, fmt.Errorf("operand 2: %s", err)
+	}
+	for _, term := range cidrs {
+		cidr, ok := term.Value.(ast.String)
+		if !ok {
+			return nil, fmt.Errorf("operand 1 must be array or set of strings")
+		}
+		_, network, err := net.ParseCIDR(string(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("operand 1: %s", err)
+		}
+		networkOnes, bits := network.Mask.Size()
+		if len(first)*8 != bits || ones < networkOnes {
+			continue
+		}
+		if network.Contains(first) {
+			return ast.BooleanTerm(true), nil
+		}
+	}
+	return ast.BooleanTerm(false), nil
+}
+
+// parseIPOrCIDR returns the first address and the prefix length of a CIDR, or an IP address
+// with the full prefix length. IPv4 addresses are returned in their 4 bytes form.
+func parseIPOrCIDR(value string) (net.IP, int, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4, 32, nil
+		}
+		return ip, 128, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, 0, err
+	}
+	ones, _ := network.Mask.Size()
+	return network.IP, ones, nil
+}
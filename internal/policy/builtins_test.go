@@ -0,0 +1,130 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+)
+
+func TestCidrContainsAny(t *testing.T) {
+	inputs := []struct {
+		cidrs    []string
+		addr     string
+		expected bool
+	}{
+		{[]string{"10.0.0.0/8"}, "10.1.2.3", true},
+		{[]string{"192.168.0.0/16", "10.0.0.0/8"}, "10.1.0.0/16", true},
+		{[]string{"10.0.0.0/16"}, "10.0.0.0/8", false},
+		{[]string{"10.0.0.0/8"}, "172.16.0.1", false},
+		{[]string{"fd00::/8"}, "fd00::1", true},
+		{[]string{"fd00::/8"}, "10.0.0.1", false},
+		{[]string{"0.0.0.0/0"}, "fd00::1", false},
+		{[]string{}, "10.0.0.1", false},
+	}
+	for _, input := range inputs {
+		terms := make([]*ast.Term, 0, len(input.cidrs))
+		for _, cidr := range input.cidrs {
+			terms = append(terms, ast.StringTerm(cidr))
+		}
+		for _, cidrs := range []*ast.Term{ast.ArrayTerm(terms...), ast.SetTerm(terms...)} {
+			result, err := cidrContainsAny(rego.BuiltinContext{}, []*ast.Term{cidrs, ast.StringTerm(input.addr)})
+			if err != nil {
+				t.Fatalf("%v, %s: err = %v; want nil", cidrs, input.addr, err)
+			}
+			if result.Value.Compare(ast.Boolean(input.expected)) != 0 {
+				t.Errorf("%v, %s: result = %v; want %v", cidrs, input.addr, result, input.expected)
+			}
+		}
+	}
+	invalid := [][]*ast.Term{
+		{ast.ArrayTerm(ast.StringTerm("10.0.0.0/33")), ast.StringTerm("10.0.0.1")},
+		{ast.ArrayTerm(ast.StringTerm("10.0.0.0/8")), ast.StringTerm("not an address")},
+		{ast.ArrayTerm(ast.IntNumberTerm(10)), ast.StringTerm("10.0.0.1")},
+	}
+	for _, terms := range invalid {
+		if _, err := cidrContainsAny(rego.BuiltinContext{}, terms); err == nil {
+			t.Errorf("%v: err is nil; want error", terms)
+		}
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	impl := func(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+		n, ok := terms[0].Value.(ast.Number)
+		if !ok {
+			return nil, errors.New("operand 1 must be number")
+		}
+		i, _ := n.Int()
+		return ast.IntNumberTerm(i * 2), nil
+	}
+	decl := types.NewFunction(types.Args(types.N), types.N)
+	invalid := []*Builtin{
+		{Name: "net.cidr_contains", Decl: decl, Impl: impl},
+		{Name: "net.cidr_contains_any", Decl: decl, Impl: impl},
+		{Name: "test.no_impl", Decl: decl},
+		{Decl: decl, Impl: impl},
+	}
+	for _, builtin := range invalid {
+		if err := RegisterBuiltin(builtin); err == nil {
+			t.Errorf("%q: err is nil; want error", builtin.Name)
+		}
+	}
+	if err := RegisterBuiltin(&Builtin{Name: "test.double", Decl: decl, Impl: impl}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	found := false
+	for _, builtin := range Builtins() {
+		found = found || builtin.Name == "test.double"
+	}
+	if !found {
+		t.Errorf("builtins do not include test.double")
+	}
+}
+
+func TestEvaluate_builtins(t *testing.T) {
+	content := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.authorized_networks\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] {\n" +
+		"  cidr := input.authorized_networks[_]\n" +
+		"  not net.cidr_contains_any([\"10.0.0.0/8\", \"192.168.0.0/16\"], cidr)\n" +
+		"  msg := sprintf(\"network %s is not private\", [cidr])\n" +
+		"}\n"
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles([]*PolicyFile{{"networks.rego", "networks.rego", content}}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{"authorized_networks": []string{"10.1.0.0/16", "8.8.8.0/24"}})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if result.ViolatedCount() != 1 {
+		t.Fatalf("violatedCount = %v; want %v", result.ViolatedCount(), 1)
+	}
+	if violations := result.Violated["Test"][0].Violations; len(violations) != 1 || violations[0] != "network 8.8.8.0/24 is not private" {
+		t.Errorf("violations = %v; want %v", violations, "network 8.8.8.0/24 is not private")
+	}
+}
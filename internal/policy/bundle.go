@@ -0,0 +1,155 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const bundleManifestFile = ".manifest"
+
+// bundleDownloadTimeout limits the time of downloading a bundle, including reading its body.
+const bundleDownloadTimeout = 5 * time.Minute
+
+// bundleClient downloads bundles, so a stalled download does not block the review forever.
+var bundleClient = &http.Client{Timeout: bundleDownloadTimeout}
+
+type OpenFn func(location string) (io.ReadCloser, error)
+
+// BundleManifest holds the fields of OPA bundle manifest that are used by the tool.
+type BundleManifest struct {
+	Revision string `json:"revision"`
+}
+
+// BundlePolicySource reads policies from OPA bundle, a gzipped tar archive
+// stored in a local file or available under HTTP(S) URL.
+type BundlePolicySource struct {
+	location string
+	manifest *BundleManifest
+	openFn   OpenFn
+}
+
+// NewBundlePolicySource creates policy source of a bundle. Download of a bundle under HTTP(S)
+// URL is cancelled with the context.
+func NewBundlePolicySource(ctx context.Context, location string) *BundlePolicySource {
+	return &BundlePolicySource{
+		location: location,
+		openFn: func(location string) (io.ReadCloser, error) {
+			return openBundle(ctx, location)
+		},
+	}
+}
+
+func (src BundlePolicySource) String() string {
+	return fmt.Sprintf("OPA bundle: %s", src.location)
+}
+
+// Revision returns revision from the bundle manifest. It is empty until
+// policy files are read or when the bundle has no revision.
+func (src *BundlePolicySource) Revision() string {
+	if src.manifest == nil {
+		return ""
+	}
+	return src.manifest.Revision
+}
+
+func (src *BundlePolicySource) GetPolicyFiles() ([]*PolicyFile, error) {
+	r, err := src.openFn(src.location)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	files, manifest, err := PolicyFilesFromBundle(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %q: %s", src.location, err)
+	}
+	src.manifest = manifest
+	return files, nil
+}
+
+// PolicyFilesFromBundle reads REGO files from gzipped tar OPA bundle. The in-bundle paths
+// are kept as files full names. Data documents are ignored, the manifest is returned
+// if the bundle has one.
+func PolicyFilesFromBundle(r io.Reader) ([]*PolicyFile, *BundleManifest, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gzr.Close()
+	files := make([]*PolicyFile, 0)
+	var manifest *BundleManifest
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		switch {
+		case name == bundleManifestFile:
+			manifest = &BundleManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %s", err)
+			}
+		case strings.HasSuffix(name, ".rego"):
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, nil, err
+			}
+			files = append(files, &PolicyFile{
+				Name:     path.Base(name),
+				FullName: name,
+				Content:  string(data),
+			})
+		}
+	}
+	return files, manifest, nil
+}
+
+func openBundle(ctx context.Context, location string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(location, "https://") && !strings.HasPrefix(location, "http://") {
+		return os.Open(location)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := bundleClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download bundle %q: %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,167 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestBundle(t *testing.T, files map[string]string) []byte {
+	buff := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buff)
+	tw := tar.NewWriter(gzw)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("could not write tar header: %s", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("could not write tar content: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("could not close tar writer: %s", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("could not close gzip writer: %s", err)
+	}
+	return buff.Bytes()
+}
+
+func TestPolicyFilesFromBundle(t *testing.T) {
+	bundle := newTestBundle(t, map[string]string{
+		"/policy/a.rego":       "package gke.policy.a",
+		"policy/b/b.rego":      "package gke.policy.b",
+		"data.json":            "{}",
+		"/.manifest":           `{"revision": "v1.2.3", "roots": ["gke"]}`,
+		"policy/README.md":     "readme",
+		"policy/a_test.rego":   "package gke.policy.a",
+		"./policy/c/../c.rego": "package gke.policy.c",
+	})
+	files, manifest, err := PolicyFilesFromBundle(bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if manifest == nil || manifest.Revision != "v1.2.3" {
+		t.Errorf("manifest = %+v; want revision %v", manifest, "v1.2.3")
+	}
+	fullNames := make(map[string]string)
+	for _, file := range files {
+		fullNames[file.FullName] = file.Name
+	}
+	expected := map[string]string{
+		"policy/a.rego":      "a.rego",
+		"policy/b/b.rego":    "b.rego",
+		"policy/a_test.rego": "a_test.rego",
+		"policy/c.rego":      "c.rego",
+	}
+	if !reflect.DeepEqual(fullNames, expected) {
+		t.Errorf("files = %v; want %v", fullNames, expected)
+	}
+}
+
+func TestPolicyFilesFromBundle_invalid(t *testing.T) {
+	inputs := [][]byte{
+		[]byte("not a gzip"),
+		newTestBundle(t, map[string]string{".manifest": "{"}),
+	}
+	for i, input := range inputs {
+		if _, _, err := PolicyFilesFromBundle(bytes.NewReader(input)); err == nil {
+			t.Errorf("input %d: err is nil; want error", i)
+		}
+	}
+}
+
+func TestBundlePolicySource_GetPolicyFiles(t *testing.T) {
+	bundle := newTestBundle(t, map[string]string{
+		"policy/a.rego": "package gke.policy.a",
+		".manifest":     `{"revision": "abc"}`,
+	})
+	src := NewBundlePolicySource(context.Background(), "bundle.tar.gz")
+	src.openFn = func(location string) (io.ReadCloser, error) {
+		if location != "bundle.tar.gz" {
+			t.Errorf("location = %v; want %v", location, "bundle.tar.gz")
+		}
+		return io.NopCloser(bytes.NewReader(bundle)), nil
+	}
+	if src.Revision() != "" {
+		t.Errorf("revision = %v; want empty", src.Revision())
+	}
+	files, err := src.GetPolicyFiles()
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("len(files) = %v; want %v", len(files), 1)
+	}
+	if src.Revision() != "abc" {
+		t.Errorf("revision = %v; want %v", src.Revision(), "abc")
+	}
+}
+
+func TestOpenBundle_http(t *testing.T) {
+	bundle := newTestBundle(t, map[string]string{"policy/a.rego": "package gke.policy.a"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bundle.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(bundle)
+	}))
+	defer server.Close()
+
+	r, err := openBundle(context.Background(), server.URL+"/bundle.tar.gz")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !bytes.Equal(data, bundle) {
+		t.Errorf("downloaded bundle does not match")
+	}
+	if _, err := openBundle(context.Background(), server.URL+"/missing.tar.gz"); err == nil {
+		t.Errorf("err is nil; want error for missing bundle")
+	}
+}
+
+func TestOpenBundle_cancelled(t *testing.T) {
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-stalled:
+		}
+	}))
+	defer server.Close()
+	defer close(stalled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := openBundle(ctx, server.URL+"/bundle.tar.gz"); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v; want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,100 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/cover"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// PolicyCoverage is the share of rego lines exercised by evaluation of policies against
+// a single input, in percent, along with the coverage of each policy file.
+type PolicyCoverage struct {
+	Coverage float64
+	Files    []*PolicyFileCoverage
+}
+
+// PolicyFileCoverage is the coverage of a single policy file.
+type PolicyFileCoverage struct {
+	File            string
+	Coverage        float64
+	CoveredLines    int
+	NotCoveredLines int
+}
+
+// WithCoverage enables tracing of evaluated rego lines, so that evaluation results
+// have coverage set. Coverage is collected only for policies loaded with WithFiles.
+func (pa *PolicyAgent) WithCoverage() {
+	pa.coverage = true
+}
+
+// coverageTracer guards OPA coverage tracer, as policies are evaluated concurrently.
+type coverageTracer struct {
+	mu    sync.Mutex
+	cover *cover.Cover
+}
+
+func newCoverageTracer() *coverageTracer {
+	return &coverageTracer{cover: cover.New()}
+}
+
+func (t *coverageTracer) Enabled() bool {
+	return true
+}
+
+func (t *coverageTracer) Config() topdown.TraceConfig {
+	return t.cover.Config()
+}
+
+func (t *coverageTracer) TraceEvent(event topdown.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cover.TraceEvent(event)
+}
+
+// report returns coverage of given modules, with files sorted by name.
+func (t *coverageTracer) report(modules map[string]*ast.Module) *PolicyCoverage {
+	t.mu.Lock()
+	report := t.cover.Report(modules)
+	t.mu.Unlock()
+	coverage := &PolicyCoverage{
+		Coverage: report.Coverage,
+		Files:    make([]*PolicyFileCoverage, 0, len(report.Files)),
+	}
+	for file, fileReport := range report.Files {
+		coverage.Files = append(coverage.Files, &PolicyFileCoverage{
+			File:            file,
+			Coverage:        fileReport.Coverage,
+			CoveredLines:    coverageRangeLines(fileReport.Covered),
+			NotCoveredLines: coverageRangeLines(fileReport.NotCovered),
+		})
+	}
+	sort.Slice(coverage.Files, func(i, j int) bool {
+		return coverage.Files[i].File < coverage.Files[j].File
+	})
+	return coverage
+}
+
+func coverageRangeLines(ranges []cover.Range) int {
+	lines := 0
+	for _, r := range ranges {
+		lines += r.End.Row - r.Start.Row + 1
+	}
+	return lines
+}
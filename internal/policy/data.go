@@ -0,0 +1,98 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// DataFile is a JSON document with data used by policies, i.e. lookup tables.
+type DataFile struct {
+	Name    string
+	Content []byte
+}
+
+// WithDataFiles merges JSON documents under the dot separated base path and makes them
+// available to policies, i.e. base path "config" exposes the documents as data.config.
+// Keys defined in more than one document are reported as error.
+func (pa *PolicyAgent) WithDataFiles(files []*DataFile, basePath string) error {
+	merged := make(map[string]interface{})
+	owners := make(map[string]string)
+	for _, file := range files {
+		var document map[string]interface{}
+		if err := util.UnmarshalJSON(file.Content, &document); err != nil {
+			return fmt.Errorf("failed to parse data file %q: %s", file.Name, err)
+		}
+		if err := mergeData(merged, document, "", file.Name, owners); err != nil {
+			return err
+		}
+	}
+	data := merged
+	if basePath != "" {
+		parts := strings.Split(basePath, ".")
+		for i := len(parts) - 1; i >= 0; i-- {
+			if parts[i] == "" {
+				return fmt.Errorf("invalid data base path %q", basePath)
+			}
+			data = map[string]interface{}{parts[i]: data}
+		}
+	}
+	pa.store = inmem.NewFromObject(data)
+	return nil
+}
+
+// mergeData merges src object into dst. Nested objects are merged recursively, any other
+// value defined in both objects is a conflict. Owners map keeps files defining each path.
+func mergeData(dst map[string]interface{}, src map[string]interface{}, path string, file string, owners map[string]string) error {
+	for key, value := range src {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		existing, ok := dst[key]
+		if !ok {
+			dst[key] = value
+			owners[keyPath] = file
+			continue
+		}
+		existingObj, okExisting := existing.(map[string]interface{})
+		valueObj, okValue := value.(map[string]interface{})
+		if !okExisting || !okValue {
+			return fmt.Errorf("data key %q is defined in both %q and %q", keyPath, dataOwner(owners, keyPath), file)
+		}
+		if err := mergeData(existingObj, valueObj, keyPath, file, owners); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataOwner returns the file that defined the path or the closest of its parents.
+func dataOwner(owners map[string]string, path string) string {
+	for {
+		if owner, ok := owners[path]; ok {
+			return owner
+		}
+		idx := strings.LastIndex(path, ".")
+		if idx < 0 {
+			return ""
+		}
+		path = path[:idx]
+	}
+}
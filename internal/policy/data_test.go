@@ -0,0 +1,85 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWithDataFiles(t *testing.T) {
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.location\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { not data.config.allowed.regions[input.location]; msg := \"region not allowed\" }\n" +
+		"violation[msg] { input.machine_type != data.config.allowed.machine_type; msg := \"machine type not allowed\" }\n"
+	files := []*DataFile{
+		{Name: "regions.json", Content: []byte(`{"allowed": {"regions": {"europe-central2": true}}}`)},
+		{Name: "machines.json", Content: []byte(`{"allowed": {"machine_type": "e2-medium"}}`)},
+	}
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithDataFiles(files, "config"); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.WithFiles([]*PolicyFile{{"location.rego", "location.rego", policyContent}}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	inputs := map[string]int{
+		"europe-central2": 0,
+		"us-central1":     1,
+	}
+	for location, violated := range inputs {
+		result, err := pa.Evaluate(map[string]interface{}{"location": location, "machine_type": "e2-medium"})
+		if err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if result.ViolatedCount() != violated {
+			t.Errorf("location %s: violatedCount = %v; want %v", location, result.ViolatedCount(), violated)
+		}
+	}
+}
+
+func TestWithDataFiles_errors(t *testing.T) {
+	inputs := []struct {
+		files    []*DataFile
+		basePath string
+		expected string
+	}{
+		{[]*DataFile{
+			{Name: "one.json", Content: []byte(`{"allowed": {"regions": ["europe-central2"]}}`)},
+			{Name: "two.json", Content: []byte(`{"allowed": {"regions": ["us-central1"]}}`)},
+		}, "config", `data key "allowed.regions" is defined in both "one.json" and "two.json"`},
+		{[]*DataFile{
+			{Name: "one.json", Content: []byte(`{"allowed": true}`)},
+			{Name: "two.json", Content: []byte(`{"allowed": {"regions": []}}`)},
+		}, "", `data key "allowed" is defined in both "one.json" and "two.json"`},
+		{[]*DataFile{{Name: "broken.json", Content: []byte(`{"allowed": `)}}, "", "broken.json"},
+		{[]*DataFile{{Name: "array.json", Content: []byte(`[1, 2]`)}}, "", "array.json"},
+		{[]*DataFile{{Name: "one.json", Content: []byte(`{}`)}}, "config..tables", "invalid data base path"},
+	}
+	for i, input := range inputs {
+		pa := NewPolicyAgent(context.Background())
+		err := pa.WithDataFiles(input.files, input.basePath)
+		if err == nil || !strings.Contains(err.Error(), input.expected) {
+			t.Errorf("input [%d]: err = %v; want error containing %q", i, err, input.expected)
+		}
+	}
+}
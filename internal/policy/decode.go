@@ -0,0 +1,141 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// regoPolicyResult is the expected shape of an evaluated policy package.
+type regoPolicyResult struct {
+	Valid     *bool             `json:"valid"`
+	Violation []json.RawMessage `json:"violation"`
+}
+
+// regoViolation is the expected shape of a violation reported as an object.
+type regoViolation struct {
+	Message  *string                `json:"message"`
+	Resource string                 `json:"resource"`
+	Details  map[string]interface{} `json:"details"`
+}
+
+// decodeRegoPolicyResult decodes the evaluated policy package into the typed result, so that
+// a policy emitting the wrong shape is reported with the offending field.
+func decodeRegoPolicyResult(value interface{}) (bool, []*Violation, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, nil, fmt.Errorf("could not encode policy result: %s", err)
+	}
+	if kind := jsonValueKind(data); kind != "object" {
+		return false, nil, fmt.Errorf("invalid policy result: result is %s (expected object)", kind)
+	}
+	result := regoPolicyResult{}
+	if err := decodeJSON(data, &result); err != nil {
+		return false, nil, fmt.Errorf("invalid policy result: %s", err)
+	}
+	if result.Valid == nil {
+		return false, nil, fmt.Errorf("invalid policy result: field %q is not set", "valid")
+	}
+	if result.Violation == nil {
+		return false, nil, fmt.Errorf("invalid policy result: field %q is not set", "violation")
+	}
+	violations := make([]*Violation, len(result.Violation))
+	for i, raw := range result.Violation {
+		violation, err := decodeViolation(raw)
+		if err != nil {
+			return false, nil, fmt.Errorf("invalid policy result: violation[%d]: %s", i, err)
+		}
+		violations[i] = violation
+	}
+	return *result.Valid, violations, nil
+}
+
+// decodeViolation decodes a violation reported either as a string or as an object.
+func decodeViolation(data json.RawMessage) (*Violation, error) {
+	switch kind := jsonValueKind(data); kind {
+	case "string":
+		var message string
+		if err := json.Unmarshal(data, &message); err != nil {
+			return nil, err
+		}
+		return &Violation{Message: message}, nil
+	case "object":
+		violation := regoViolation{}
+		if err := decodeJSON(data, &violation); err != nil {
+			return nil, err
+		}
+		if violation.Message == nil {
+			return nil, fmt.Errorf("field %q is not set", "message")
+		}
+		return &Violation{Message: *violation.Message, Resource: violation.Resource, Details: violation.Details}, nil
+	default:
+		return nil, fmt.Errorf("violation is %s (expected string or object)", kind)
+	}
+}
+
+// decodeJSON decodes data keeping numbers as json.Number, like rego values, and reports
+// type mismatches with the field name and JSON types.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(v)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("field %q is %s (expected %s)", typeErr.Field, typeErr.Value, jsonTypeName(typeErr.Type))
+	}
+	return err
+}
+
+// jsonValueKind returns the JSON type of encoded value.
+func jsonValueKind(data []byte) string {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "empty"
+	}
+	switch data[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// jsonTypeName returns the JSON type name of a Go type.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonTypeName(t.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return "number"
+}
@@ -0,0 +1,72 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeRegoPolicyResult(t *testing.T) {
+	input := map[string]interface{}{
+		"valid": false,
+		"violation": []interface{}{
+			"legacy",
+			map[string]interface{}{"message": "structured", "resource": "r", "details": map[string]interface{}{"actual": json.Number("1")}},
+		},
+		"other": "ignored",
+	}
+	expected := []*Violation{
+		{Message: "legacy"},
+		{Message: "structured", Resource: "r", Details: map[string]interface{}{"actual": json.Number("1")}},
+	}
+	valid, violations, err := decodeRegoPolicyResult(input)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if valid {
+		t.Errorf("valid = %v; want false", valid)
+	}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("violations = %v; want %v", violations, expected)
+	}
+}
+
+func TestDecodeRegoPolicyResult_errors(t *testing.T) {
+	inputs := []struct {
+		value interface{}
+		err   string
+	}{
+		{"text", `invalid policy result: result is string (expected object)`},
+		{map[string]interface{}{"violation": []interface{}{}}, `invalid policy result: field "valid" is not set`},
+		{map[string]interface{}{"valid": true}, `invalid policy result: field "violation" is not set`},
+		{map[string]interface{}{"valid": "yes", "violation": []interface{}{}}, `invalid policy result: field "valid" is string (expected boolean)`},
+		{map[string]interface{}{"valid": true, "violation": "text"}, `invalid policy result: field "violation" is string (expected array)`},
+		{map[string]interface{}{"valid": true, "violation": []interface{}{1}}, `invalid policy result: violation[0]: violation is number (expected string or object)`},
+		{map[string]interface{}{"valid": true, "violation": []interface{}{map[string]interface{}{"resource": "r"}}},
+			`invalid policy result: violation[0]: field "message" is not set`},
+		{map[string]interface{}{"valid": true, "violation": []interface{}{map[string]interface{}{"message": "m", "resource": 1}}},
+			`invalid policy result: violation[0]: field "resource" is number (expected string)`},
+		{map[string]interface{}{"valid": true, "violation": []interface{}{"ok", map[string]interface{}{"message": "m", "details": "d"}}},
+			`invalid policy result: violation[1]: field "details" is string (expected object)`},
+	}
+	for _, input := range inputs {
+		_, _, err := decodeRegoPolicyResult(input.value)
+		if err == nil || err.Error() != input.err {
+			t.Errorf("value %v: err = %v; want %s", input.value, err, input.err)
+		}
+	}
+}
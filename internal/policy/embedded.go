@@ -0,0 +1,74 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const embeddedPolicyPrefix = "embedded"
+
+// EmbeddedPolicySource reads policies from a file system embedded in the binary.
+type EmbeddedPolicySource struct {
+	fsys    fs.FS
+	version string
+}
+
+func NewEmbeddedPolicySource(fsys fs.FS) *EmbeddedPolicySource {
+	return &EmbeddedPolicySource{fsys: fsys}
+}
+
+func (src EmbeddedPolicySource) String() string {
+	return "embedded default policies"
+}
+
+// Version returns short digest of embedded policy files. It is empty until policy files are read.
+func (src *EmbeddedPolicySource) Version() string {
+	return src.version
+}
+
+func (src *EmbeddedPolicySource) GetPolicyFiles() ([]*PolicyFile, error) {
+	files := make([]*PolicyFile, 0)
+	digest := sha256.New()
+	err := fs.WalkDir(src.fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(filePath, ".rego") {
+			return nil
+		}
+		data, err := fs.ReadFile(src.fsys, filePath)
+		if err != nil {
+			return err
+		}
+		digest.Write([]byte(filePath))
+		digest.Write(data)
+		files = append(files, &PolicyFile{
+			Name:     path.Base(filePath),
+			FullName: path.Join(embeddedPolicyPrefix, filePath),
+			Content:  string(data),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	src.version = hex.EncodeToString(digest.Sum(nil))[:12]
+	return files, nil
+}
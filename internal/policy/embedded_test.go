@@ -0,0 +1,53 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedPolicySource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"policy/one.rego":  {Data: []byte("package gke.policy.one")},
+		"policy/README.md": {Data: []byte("# Policies")},
+		"rule/two.rego":    {Data: []byte("package gke.rule.two")},
+	}
+	src := NewEmbeddedPolicySource(fsys)
+	files, err := src.GetPolicyFiles()
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	expected := []*PolicyFile{
+		{Name: "one.rego", FullName: "embedded/policy/one.rego", Content: "package gke.policy.one"},
+		{Name: "two.rego", FullName: "embedded/rule/two.rego", Content: "package gke.rule.two"},
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("files = %v; want %v", files, expected)
+	}
+	version := src.Version()
+	if len(version) != 12 {
+		t.Errorf("version = %q; want 12 characters", version)
+	}
+
+	fsys["rule/two.rego"] = &fstest.MapFile{Data: []byte("package gke.rule.three")}
+	if _, err := src.GetPolicyFiles(); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if src.Version() == version {
+		t.Errorf("version = %q; want changed version", src.Version())
+	}
+}
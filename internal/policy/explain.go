@@ -0,0 +1,67 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// PolicyExplanation is the evaluated policy along with the trace of its evaluation.
+type PolicyExplanation struct {
+	Policy *Policy
+	// Trace is the evaluation trace, one step per line, indented by the depth of the query
+	// and prefixed with the location of the evaluated expression
+	Trace []string
+}
+
+// Explain evaluates a single policy, loaded with WithFiles, against a given input with tracing
+// enabled. The policy name can be a full name or a name without the policy package prefix.
+// Only the requested policy is evaluated, so the trace does not include other policies.
+func (pa *PolicyAgent) Explain(name string, input interface{}) (*PolicyExplanation, error) {
+	compiled, ok := pa.compiled[name]
+	if !ok {
+		compiled, ok = pa.compiled[pa.packageName()+"."+name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("policy %s not found", name)
+	}
+	parsedInput, err := ast.InterfaceToValue(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %s", err)
+	}
+	job := pa.newPolicyEvaluationJob(compiled, parsedInput)
+	job.trace = topdown.NewBufferTracer()
+	policy := pa.evaluatePolicy(pa.ctx, job, parsedInput)
+	if err := pa.ctx.Err(); err != nil {
+		return nil, fmt.Errorf("policy evaluation cancelled: %w", err)
+	}
+	return &PolicyExplanation{Policy: policy, Trace: formatTrace(*job.trace)}, nil
+}
+
+// formatTrace formats trace events into indented lines with locations of evaluated expressions.
+func formatTrace(events []*topdown.Event) []string {
+	var buf bytes.Buffer
+	topdown.PrettyTraceWithLocation(&buf, events)
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return []string{}
+	}
+	return lines
+}
@@ -0,0 +1,71 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.explained\n" +
+		"default valid = false\n" +
+		"valid {\n" +
+		"  input.value < 10\n" +
+		"}\n"
+	otherContent := "package gke.policy.other\n" +
+		"default valid = true\n"
+	pa := NewPolicyAgent(context.Background())
+	files := []*PolicyFile{
+		{"explained.rego", "explained.rego", policyContent},
+		{"other.rego", "other.rego", otherContent},
+	}
+	if err := pa.WithFiles(files); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	explanation, err := pa.Explain("explained", map[string]interface{}{"value": 15})
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if explanation.Policy.Name != regoPolicyPackage+".explained" {
+		t.Errorf("policy name = %v; want %v", explanation.Policy.Name, regoPolicyPackage+".explained")
+	}
+	if explanation.Policy.Valid {
+		t.Errorf("policy valid = %v; want %v", explanation.Policy.Valid, false)
+	}
+	if len(explanation.Trace) == 0 {
+		t.Fatalf("trace is empty; want trace lines")
+	}
+	trace := strings.Join(explanation.Trace, "\n")
+	if !strings.Contains(trace, "explained.rego") {
+		t.Errorf("trace does not contain policy file location")
+	}
+	if strings.Contains(trace, "other.rego") {
+		t.Errorf("trace contains other policy; want only explained policy")
+	}
+}
+
+func TestExplain_notFound(t *testing.T) {
+	pa := NewPolicyAgent(context.Background())
+	if _, err := pa.Explain("missing", map[string]interface{}{}); err == nil {
+		t.Errorf("error is nil; want error")
+	}
+}
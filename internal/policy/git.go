@@ -15,8 +15,11 @@
 package policy
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"github.com/go-git/go-billy/v5"
@@ -24,17 +27,23 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/storage"
 	"github.com/go-git/go-git/v5/storage/memory"
 )
 
-type CloneFn func(s storage.Storer, worktree billy.Filesystem, o *git.CloneOptions) (*git.Repository, error)
+const gitTokenVarName = "GKE_POLICY_GIT_TOKEN"
+
+type CloneFn func(ctx context.Context, s storage.Storer, worktree billy.Filesystem, o *git.CloneOptions) (*git.Repository, error)
 
 type GitPolicySource struct {
+	ctx           context.Context
 	repoUrl       string
-	repoBranch    string
+	repoRef       string
 	policyDir     string
 	policyFileExt string
+	auth          transport.AuthMethod
 	cloneFn       CloneFn
 }
 
@@ -61,20 +70,35 @@ type GitPolicyFile struct {
 	*PolicyFile
 }
 
-func NewGitPolicySource(repoURL string, repoBrach string, policyDir string) PolicySource {
+// NewGitPolicySource creates policy source for a given directory in a GIT repository.
+// The repoRef can be a branch name, a tag name or a commit SHA.
+func NewGitPolicySource(repoURL string, repoRef string, policyDir string) PolicySource {
+	return newGitPolicySource(context.Background(), repoURL, repoRef, policyDir)
+}
+
+func newGitPolicySource(ctx context.Context, repoURL string, repoRef string, policyDir string) *GitPolicySource {
 	return &GitPolicySource{
+		ctx:           ctx,
 		repoUrl:       repoURL,
-		repoBranch:    repoBrach,
+		repoRef:       repoRef,
 		policyDir:     policyDir,
 		policyFileExt: "rego",
-		cloneFn:       git.Clone,
+		auth:          getGitAuth(repoURL, os.Getenv),
+		cloneFn:       git.CloneContext,
 	}
 }
 
+// PolicyFilesFromGit reads all policy files from a GIT repository at a given ref (branch, tag
+// or commit SHA). The repository is cloned to the memory storage, so there is nothing
+// left behind when the context is cancelled.
+func PolicyFilesFromGit(ctx context.Context, url string, ref string) ([]*PolicyFile, error) {
+	return newGitPolicySource(ctx, url, ref, "").GetPolicyFiles()
+}
+
 func (src GitPolicySource) String() string {
-	return fmt.Sprintf("GIT repository: %s, branch: %s, directory: %s",
+	return fmt.Sprintf("GIT repository: %s, ref: %s, directory: %s",
 		src.repoUrl,
-		src.repoBranch,
+		src.repoRef,
 		src.policyDir)
 }
 
@@ -83,13 +107,13 @@ func (src GitPolicySource) GetPolicyFiles() ([]*PolicyFile, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone GIT repository: %s", err)
 	}
-	tree, err := src.getHeadTree(repo)
+	tree, err := src.getRefTree(repo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get GIT HEAD ref tree: %s", err)
+		return nil, fmt.Errorf("failed to get GIT ref tree: %s", err)
 	}
 	entries, err := src.getGitPolicyEntries(object.NewTreeWalker(tree, true, nil))
 	if err != nil {
-		return nil, fmt.Errorf("failed to list policy files in GIT HEAD ref tree: %s", err)
+		return nil, fmt.Errorf("failed to list policy files in GIT ref tree: %s", err)
 	}
 	files := make([]*PolicyFile, len(entries))
 	for i := range entries {
@@ -103,25 +127,36 @@ func (src GitPolicySource) GetPolicyFiles() ([]*PolicyFile, error) {
 }
 
 func (src GitPolicySource) clone() (*git.Repository, error) {
-	repo, err := src.cloneFn(memory.NewStorage(), nil, &git.CloneOptions{
-		URL:           src.repoUrl,
-		Depth:         1,
-		ReferenceName: plumbing.ReferenceName("refs/heads/" + src.repoBranch),
-		SingleBranch:  true,
-	})
-	if err != nil {
-		return nil, err
-
+	opts := &git.CloneOptions{
+		URL:  src.repoUrl,
+		Auth: src.auth,
+	}
+	if plumbing.IsHash(src.repoRef) {
+		return src.cloneFn(src.ctx, memory.NewStorage(), nil, opts)
+	}
+	opts.Depth = 1
+	opts.SingleBranch = true
+	opts.ReferenceName = plumbing.NewBranchReferenceName(src.repoRef)
+	repo, err := src.cloneFn(src.ctx, memory.NewStorage(), nil, opts)
+	if err == nil || !errors.Is(err, git.NoMatchingRefSpecError{}) {
+		return repo, err
 	}
-	return repo, nil
+	opts.ReferenceName = plumbing.NewTagReferenceName(src.repoRef)
+	return src.cloneFn(src.ctx, memory.NewStorage(), nil, opts)
 }
 
-func (src GitPolicySource) getHeadTree(repo *git.Repository) (*object.Tree, error) {
-	head, err := repo.Head()
-	if err != nil {
-		return nil, err
+func (src GitPolicySource) getRefTree(repo *git.Repository) (*object.Tree, error) {
+	var hash plumbing.Hash
+	if plumbing.IsHash(src.repoRef) {
+		hash = plumbing.NewHash(src.repoRef)
+	} else {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		hash = head.Hash()
 	}
-	commit, err := repo.CommitObject(head.Hash())
+	commit, err := repo.CommitObject(hash)
 	if err != nil {
 		return nil, err
 	}
@@ -142,7 +177,7 @@ func (src GitPolicySource) getGitPolicyEntries(wkr GitTreeWalker) ([]*gitPolicyE
 		if entry.Mode == filemode.Dir || entry.Mode == filemode.Submodule {
 			continue
 		}
-		if strings.HasPrefix(name, src.policyDir+"/") && strings.HasSuffix(name, "."+src.policyFileExt) {
+		if (src.policyDir == "" || strings.HasPrefix(name, src.policyDir+"/")) && strings.HasSuffix(name, "."+src.policyFileExt) {
 			entries = append(entries, &gitPolicyEntry{
 				name:  name,
 				entry: &entry,
@@ -170,3 +205,16 @@ func (e gitPolicyEntry) readPolicyFile(t GitTree) (*GitPolicyFile, error) {
 		},
 	}, nil
 }
+
+func getGitAuth(repoURL string, getenv func(string) string) transport.AuthMethod {
+	if !strings.HasPrefix(repoURL, "https://") && !strings.HasPrefix(repoURL, "http://") {
+		return nil
+	}
+	if token := getenv(gitTokenVarName); token != "" {
+		return &http.BasicAuth{
+			Username: "git",
+			Password: token,
+		}
+	}
+	return nil
+}
@@ -15,6 +15,7 @@
 package policy
 
 import (
+	"context"
 	"io"
 	"strings"
 	"testing"
@@ -24,6 +25,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/storage"
 )
 
@@ -99,10 +101,10 @@ func (o encObjectMock) Writer() (io.WriteCloser, error) {
 
 func TestNewGitPolicySource(t *testing.T) {
 	repoURL := "https://test.com/repo"
-	repoBranch := "main"
+	repoRef := "main"
 	policyDir := "dir"
 
-	src := NewGitPolicySource(repoURL, repoBranch, policyDir)
+	src := NewGitPolicySource(repoURL, repoRef, policyDir)
 	gitSrc, ok := src.(*GitPolicySource)
 	if !ok {
 		t.Errorf("Result of NewGitPolicySource is not *GitPolicySource")
@@ -110,8 +112,11 @@ func TestNewGitPolicySource(t *testing.T) {
 	if gitSrc.repoUrl != repoURL {
 		t.Errorf("repoUrl = %s; want %s", gitSrc.repoUrl, repoURL)
 	}
-	if gitSrc.repoBranch != repoBranch {
-		t.Errorf("repoBranch = %s; want %s", gitSrc.repoBranch, repoBranch)
+	if gitSrc.repoRef != repoRef {
+		t.Errorf("repoRef = %s; want %s", gitSrc.repoRef, repoRef)
+	}
+	if gitSrc.ctx == nil {
+		t.Errorf("ctx is nil; want context")
 	}
 	if gitSrc.policyDir != policyDir {
 		t.Errorf("policyDir = %s; want %s", gitSrc.policyDir, policyDir)
@@ -123,15 +128,15 @@ func TestNewGitPolicySource(t *testing.T) {
 
 func TestClone(t *testing.T) {
 	var opts git.CloneOptions
-	cloneFn := func(s storage.Storer, worktree billy.Filesystem, o *git.CloneOptions) (*git.Repository, error) {
+	cloneFn := func(ctx context.Context, s storage.Storer, worktree billy.Filesystem, o *git.CloneOptions) (*git.Repository, error) {
 		opts = *o
 		return &git.Repository{}, nil
 	}
 
 	policySrc := &GitPolicySource{
-		repoUrl:    "https://test.com/repository",
-		repoBranch: "main",
-		cloneFn:    cloneFn,
+		repoUrl: "https://test.com/repository",
+		repoRef: "main",
+		cloneFn: cloneFn,
 	}
 
 	_, err := policySrc.clone()
@@ -141,7 +146,7 @@ func TestClone(t *testing.T) {
 	if opts.URL != policySrc.repoUrl {
 		t.Errorf("URL = %s; want %s", opts.URL, policySrc.repoUrl)
 	}
-	refName := plumbing.ReferenceName("refs/heads/" + policySrc.repoBranch)
+	refName := plumbing.ReferenceName("refs/heads/" + policySrc.repoRef)
 	if opts.ReferenceName != refName {
 		t.Errorf("referenceName = %s; want %s", opts.ReferenceName, refName)
 	}
@@ -153,6 +158,106 @@ func TestClone(t *testing.T) {
 	}
 }
 
+func TestClone_tag(t *testing.T) {
+	opts := make([]git.CloneOptions, 0)
+	cloneFn := func(ctx context.Context, s storage.Storer, worktree billy.Filesystem, o *git.CloneOptions) (*git.Repository, error) {
+		opts = append(opts, *o)
+		if o.ReferenceName.IsBranch() {
+			return nil, git.NoMatchingRefSpecError{}
+		}
+		return &git.Repository{}, nil
+	}
+	policySrc := &GitPolicySource{
+		repoUrl: "https://test.com/repository",
+		repoRef: "v1.0.0",
+		cloneFn: cloneFn,
+	}
+	if _, err := policySrc.clone(); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(opts) != 2 {
+		t.Fatalf("number of clone calls = %d; want %d", len(opts), 2)
+	}
+	refName := plumbing.NewTagReferenceName(policySrc.repoRef)
+	if opts[1].ReferenceName != refName {
+		t.Errorf("referenceName = %s; want %s", opts[1].ReferenceName, refName)
+	}
+}
+
+func TestClone_commit(t *testing.T) {
+	var opts git.CloneOptions
+	cloneFn := func(ctx context.Context, s storage.Storer, worktree billy.Filesystem, o *git.CloneOptions) (*git.Repository, error) {
+		opts = *o
+		return &git.Repository{}, nil
+	}
+	policySrc := &GitPolicySource{
+		repoUrl: "https://test.com/repository",
+		repoRef: "0d25de62c8d1e282b4d07ea74e6ca0912aa401fd",
+		cloneFn: cloneFn,
+	}
+	if _, err := policySrc.clone(); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if opts.ReferenceName != "" {
+		t.Errorf("referenceName = %s; want empty", opts.ReferenceName)
+	}
+	if opts.Depth != 0 {
+		t.Errorf("depth = %d; want %d", opts.Depth, 0)
+	}
+}
+
+func TestGetGitAuth(t *testing.T) {
+	token := "secret"
+	getenv := func(key string) string {
+		if key == gitTokenVarName {
+			return token
+		}
+		return ""
+	}
+	auth := getGitAuth("https://test.com/repository", getenv)
+	basicAuth, ok := auth.(*http.BasicAuth)
+	if !ok {
+		t.Fatalf("auth is not *http.BasicAuth")
+	}
+	if basicAuth.Password != token {
+		t.Errorf("password = %s; want %s", basicAuth.Password, token)
+	}
+	if auth := getGitAuth("git@test.com:repository.git", getenv); auth != nil {
+		t.Errorf("auth for ssh URL is not nil; want nil")
+	}
+	if auth := getGitAuth("https://test.com/repository", func(string) string { return "" }); auth != nil {
+		t.Errorf("auth without token is not nil; want nil")
+	}
+}
+
+func TestGetRegoFileEntries_noPolicyDir(t *testing.T) {
+	policySrc := &GitPolicySource{
+		policyFileExt: "rego",
+	}
+	mock := &gitTreeWalkerMock{}
+	mock.wkrResults = []*gitTreeWalkerResult{
+		{
+			name:  "policies/policy_one.rego",
+			entry: &object.TreeEntry{Name: "policy_one.rego", Mode: filemode.Regular},
+		},
+		{
+			name:  "policy_two.rego",
+			entry: &object.TreeEntry{Name: "policy_two.rego", Mode: filemode.Regular},
+		},
+		{
+			name:  "README.md",
+			entry: &object.TreeEntry{Name: "README.md", Mode: filemode.Regular},
+		},
+	}
+	gitPolicyEntries, err := policySrc.getGitPolicyEntries(mock)
+	if err != nil {
+		t.Errorf("err is not nil; want nil")
+	}
+	if len(gitPolicyEntries) != 2 {
+		t.Errorf("len(gitPolicyEntries) = %d; want %d", len(gitPolicyEntries), 2)
+	}
+}
+
 func TestGetRegoFileEntries(t *testing.T) {
 	policySrc := &GitPolicySource{
 		policyDir:     "policies",
@@ -58,7 +58,7 @@ func (src LocalPolicySource) getPolicyFiles(walkFn WalkFn) ([]*PolicyFile, error
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && strings.HasSuffix(path, "."+src.policyFileExt) {
+		if !info.IsDir() && (strings.HasSuffix(path, "."+src.policyFileExt) || info.Name() == PolicySidecarFileName) {
 			files = append(files, &PolicyFile{
 				Name:     info.Name(),
 				FullName: path})
@@ -169,3 +169,28 @@ func TestReadPolicyFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestGetPolicyFiles_sidecar(t *testing.T) {
+	mockWalkFn := func(root string, fn filepath.WalkFunc) error {
+		for _, name := range []string{"policy.rego", PolicySidecarFileName, "other.yaml"} {
+			info := fileInfoMock{
+				nameFn:  func() string { return name },
+				isDirFn: func() bool { return false }}
+			if err := fn("rego/"+name, info, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	src := LocalPolicySource{directory: "dir", policyFileExt: "rego"}
+	files, err := src.getPolicyFiles(mockWalkFn)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("len(files) = %d; want %d", len(files), 2)
+	}
+	if files[1].Name != PolicySidecarFileName {
+		t.Errorf("files[1].Name = %s; want %s", files[1].Name, PolicySidecarFileName)
+	}
+}
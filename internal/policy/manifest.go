@@ -0,0 +1,57 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// PolicyManifest lists compiled policy files with content hashes, to record which policies ran.
+type PolicyManifest struct {
+	Files []*PolicyManifestFile `json:"files"`
+}
+
+// PolicyManifestFile is a compiled policy file. Name and title are empty for files
+// without a policy, i.e. shared rules.
+type PolicyManifestFile struct {
+	File   string `json:"file"`
+	Name   string `json:"name,omitempty"`
+	Title  string `json:"title,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest returns the manifest of given policy files, as compiled with WithFiles.
+func (pa *PolicyAgent) Manifest(files []*PolicyFile) *PolicyManifest {
+	policies := make(map[string]*Policy)
+	for _, policy := range pa.compiled {
+		policies[policy.File] = policy
+	}
+	manifest := &PolicyManifest{Files: make([]*PolicyManifestFile, 0, len(files))}
+	for _, file := range files {
+		sum := sha256.Sum256([]byte(file.Content))
+		entry := &PolicyManifestFile{File: file.FullName, SHA256: hex.EncodeToString(sum[:])}
+		if policy, ok := policies[file.FullName]; ok {
+			entry.Name = policy.Name
+			entry.Title = policy.Title
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].File < manifest.Files[j].File
+	})
+	return manifest
+}
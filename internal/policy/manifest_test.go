@@ -0,0 +1,61 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	policyContent := `# METADATA
+# title: Test policy
+# description: Test description
+# custom:
+#   group: Test
+package gke.policy.test
+valid { true }`
+	ruleContent := `package gke.rule.test
+r = 1`
+	files := []*PolicyFile{
+		{"policy.rego", "dir/policy.rego", policyContent},
+		{"rule.rego", "dir/a/rule.rego", ruleContent},
+	}
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles(files); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	manifest := pa.Manifest(files)
+	if len(manifest.Files) != 2 {
+		t.Fatalf("len(files) = %d; want 2", len(manifest.Files))
+	}
+	rule, policy := manifest.Files[0], manifest.Files[1]
+	if rule.File != "dir/a/rule.rego" || rule.Name != "" || rule.SHA256 != sha256Hex(ruleContent) {
+		t.Errorf("rule file = %+v; want rule file without policy", rule)
+	}
+	if policy.File != "dir/policy.rego" || policy.Name != "gke.policy.test" || policy.Title != "Test policy" {
+		t.Errorf("policy file = %+v; want gke.policy.test", policy)
+	}
+	if policy.SHA256 != sha256Hex(policyContent) {
+		t.Errorf("policy sha256 = %s; want %s", policy.SHA256, sha256Hex(policyContent))
+	}
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mikouaj/gke-review/internal/log"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// MergePolicyFiles merges policy files of consecutive sources into a single list. Files of
+// a later source override files of earlier sources that declare the same package, so policy
+// sets can be layered. The same file given by more than one source is reported as error.
+func MergePolicyFiles(sources [][]*PolicyFile) ([]*PolicyFile, error) {
+	merged := make([]*PolicyFile, 0)
+	for _, files := range sources {
+		fullNames := make(map[string]bool, len(merged))
+		for _, file := range merged {
+			fullNames[file.FullName] = true
+		}
+		packages := make(map[string]*PolicyFile)
+		for _, file := range files {
+			if fullNames[file.FullName] {
+				return nil, fmt.Errorf("policy file %q is given more than once", file.FullName)
+			}
+			if pkg := policyFilePackage(file); pkg != "" {
+				packages[pkg] = file
+			}
+		}
+		kept := make([]*PolicyFile, 0, len(merged)+len(files))
+		for _, file := range merged {
+			pkg := policyFilePackage(file)
+			if override, ok := packages[pkg]; ok {
+				log.Debugf("policy package %s from %s is overridden by %s", pkg, file.FullName, override.FullName)
+				continue
+			}
+			kept = append(kept, file)
+		}
+		merged = append(kept, files...)
+	}
+	return merged, nil
+}
+
+// policyFilePackage returns package of the policy file or empty string if the file
+// does not parse. Such files are kept, so that compilation reports the error.
+func policyFilePackage(file *PolicyFile) string {
+	module, err := ast.ParseModule(file.FullName, file.Content)
+	if err != nil || module == nil {
+		return ""
+	}
+	return strings.TrimPrefix(module.Package.Path.String(), "data.")
+}
@@ -0,0 +1,52 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergePolicyFiles(t *testing.T) {
+	baseline := []*PolicyFile{
+		{"one.rego", "baseline/one.rego", "package gke.policy.one\n"},
+		{"one_test.rego", "baseline/one_test.rego", "package gke.policy.one\n"},
+		{"two.rego", "baseline/two.rego", "package gke.policy.two\n"},
+		{"broken.rego", "baseline/broken.rego", "package"},
+	}
+	overrides := []*PolicyFile{
+		{"one.rego", "overrides/one.rego", "package gke.policy.one\n"},
+		{"three.rego", "overrides/three.rego", "package gke.policy.three\n"},
+	}
+	merged, err := MergePolicyFiles([][]*PolicyFile{baseline, overrides})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	fullNames := make([]string, len(merged))
+	for i, file := range merged {
+		fullNames[i] = file.FullName
+	}
+	expected := []string{"baseline/two.rego", "baseline/broken.rego", "overrides/one.rego", "overrides/three.rego"}
+	if !reflect.DeepEqual(fullNames, expected) {
+		t.Errorf("merged = %v; want %v", fullNames, expected)
+	}
+}
+
+func TestMergePolicyFiles_conflict(t *testing.T) {
+	files := []*PolicyFile{{"one.rego", "policies/one.rego", "package gke.policy.one\n"}}
+	if _, err := MergePolicyFiles([][]*PolicyFile{files, files}); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
@@ -16,23 +16,129 @@ package policy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mikouaj/gke-review/internal/log"
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/topdown"
 )
 
 const regoPolicyPackage = "gke.policy"
-const regoQuery = "data." + regoPolicyPackage + "[name]"
+
+// DefaultQuery is the rego query that resolves to the object with all policy packages.
+const DefaultQuery = "data." + regoPolicyPackage
 const regoTestFileSuffix = "_test.rego"
 
+// envVarRef matches ${VAR} references expanded in policy title and group.
+var envVarRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+const (
+	ExceptionStatusWarned     = "warned"
+	ExceptionStatusAudited    = "audited"
+	ExceptionStatusErrored    = "errored"
+	ExceptionStatusFiltered   = "filtered"
+	ExceptionStatusWaived     = "waived"
+	ExceptionStatusSkipped    = "skipped"
+	ExceptionStatusSuppressed = "suppressed"
+)
+
+const (
+	SeverityLow      = "LOW"
+	SeverityMedium   = "MEDIUM"
+	SeverityHigh     = "HIGH"
+	SeverityCritical = "CRITICAL"
+	DefaultSeverity  = SeverityMedium
+)
+
+var severityLevels = map[string]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+const (
+	EnforcementEnforce = "enforce"
+	EnforcementWarn    = "warn"
+	EnforcementAudit   = "audit"
+)
+
+// Cluster types a policy applies to, set with the custom.applies_to metadata.
+const (
+	AppliesToStandard  = "standard"
+	AppliesToAutopilot = "autopilot"
+	AppliesToBoth      = "both"
+)
+
+// inputAutopilotKey is the input key with the autopilot mode of the cluster.
+const inputAutopilotKey = "autopilot"
+
 type PolicyAgent struct {
-	ctx      context.Context
-	compiler *ast.Compiler
-	compiled map[string]*Policy
+	ctx           context.Context
+	compiler      *ast.Compiler
+	compiled      map[string]*Policy
+	policyPackage string
+	store         storage.Store
+	coverage      bool
+	// strictMetadata makes WithFiles fail on policies with metadata errors instead of
+	// evaluating them with fallback metadata
+	strictMetadata   bool
+	metadataWarnings []error
+	// strictCompile rejects deprecated built-ins, unused variables and imports and
+	// other constructs reported by the OPA strict mode
+	strictCompile bool
+	// failFast stops evaluation on the first policy that fails, as told by failsFast
+	failFast  bool
+	failsFast func(*Policy) bool
+	// sidecars are policy metadata files, keyed by their directory
+	sidecars map[string]*PolicySidecar
+	// ruleNames enable the rule mode, where rules of each policy package are evaluated
+	// instead of the whole package
+	ruleNames *RuleNames
+	// allowEmpty makes WithFiles accept files without any policy
+	allowEmpty bool
+}
+
+// ErrNoPolicies is returned by WithFiles when files have no policies in the policy package,
+// unless empty policy sets are allowed with WithAllowEmpty.
+var ErrNoPolicies = errors.New("no policies loaded")
+
+// WithContext returns a copy of the agent that evaluates policies with a given context.
+// Compiled policies are shared with the original agent.
+func (pa *PolicyAgent) WithContext(ctx context.Context) *PolicyAgent {
+	agent := *pa
+	agent.ctx = ctx
+	return &agent
+}
+
+// regoStore returns option with data documents store, set with WithDataFiles.
+func (pa *PolicyAgent) regoStore() func(r *rego.Rego) {
+	if pa.store == nil {
+		return func(r *rego.Rego) {}
+	}
+	return rego.Store(pa.store)
+}
+
+// packageName returns package path of policies, the default one unless set with WithQuery.
+func (pa *PolicyAgent) packageName() string {
+	if pa.policyPackage == "" {
+		return regoPolicyPackage
+	}
+	return pa.policyPackage
 }
 
 type Policy struct {
@@ -41,22 +147,82 @@ type Policy struct {
 	Title            string
 	Description      string
 	Group            string
+	Enforcement      string
+	Severity         string
+	AppliesTo        string
+	InputSelector    string
+	Remediation      string
+	CisControls      []string
+	References       []string
+	Tags             []string
+	Waiver           *PolicyWaiver
 	Valid            bool
 	Violations       []string
-	ProcessingErrors []error
+	ViolationDetails []*Violation
+	// SuppressedViolations is the number of violations removed by suppressions, see ApplySuppressions
+	SuppressedViolations int
+	ProcessingErrors     []error
+	EvaluationTime       time.Duration
+	// Prints is output of rego print() calls made while evaluating the policy
+	Prints []string
+	// TruncatedViolations is the number of violations over the limit, see TruncateViolations
+	TruncatedViolations int
+
+	// Deprecated policies are still evaluated, DeprecatedMessage optionally explains the deprecation
+	Deprecated        bool
+	DeprecatedMessage string
 }
 
 type PolicyEvaluationResult struct {
 	ClusterName string
-	Valid       map[string][]*Policy
-	Violated    map[string][]*Policy
-	Errored     []*Policy
+	// ClusterLabels are resource labels of the cluster
+	ClusterLabels map[string]string
+	// ClusterError is set when the cluster could not be reviewed, i.e. its details could not be fetched
+	ClusterError error
+	Valid        map[string][]*Policy
+	Violated     map[string][]*Policy
+	Warned       map[string][]*Policy
+	Audited      map[string][]*Policy
+	Filtered     map[string][]*Policy
+	Waived       map[string][]*Policy
+	// Suppressed holds violated policies with all violations suppressed, see ApplySuppressions
+	Suppressed map[string][]*Policy
+	// Skipped holds policies not applicable to the cluster type, that were not evaluated
+	Skipped map[string][]*Policy
+	Errored []*Policy
+	// Coverage is set when the agent collects coverage, see WithCoverage
+	Coverage *PolicyCoverage
+	// bySeverity is set when policies are grouped by severity instead of the policy group
+	bySeverity bool
+	// mu synchronizes merges into and from the result, see Merge
+	mu sync.Mutex
+}
+
+// PolicyException describes a policy evaluation that did not result in a plain
+// pass or fail, along with the reason for it.
+type PolicyException struct {
+	Policy *Policy
+	Status string
+	Reason string
+}
+
+// PolicyMetadataError describes metadata errors of a single policy along with
+// the location of its package declaration.
+type PolicyMetadataError struct {
+	Policy string
+	File   string
+	Line   int
+	Errors []string
+}
+
+func (e *PolicyMetadataError) Error() string {
+	return fmt.Sprintf("%s:%d: policy %s has metadata errors: %s", e.File, e.Line, e.Policy, strings.Join(e.Errors, ", "))
 }
 
 type RegoEvaluationResult struct {
 	Name       string
 	Valid      bool
-	Violations []string
+	Violations []*Violation
 }
 
 func NewPolicyAgent(ctx context.Context) *PolicyAgent {
@@ -68,9 +234,15 @@ func NewPolicyAgent(ctx context.Context) *PolicyAgent {
 
 func NewPolicyEvaluationResult() *PolicyEvaluationResult {
 	return &PolicyEvaluationResult{
-		Valid:    make(map[string][]*Policy),
-		Violated: make(map[string][]*Policy),
-		Errored:  make([]*Policy, 0),
+		Valid:      make(map[string][]*Policy),
+		Violated:   make(map[string][]*Policy),
+		Warned:     make(map[string][]*Policy),
+		Audited:    make(map[string][]*Policy),
+		Filtered:   make(map[string][]*Policy),
+		Waived:     make(map[string][]*Policy),
+		Suppressed: make(map[string][]*Policy),
+		Skipped:    make(map[string][]*Policy),
+		Errored:    make([]*Policy, 0),
 	}
 }
 
@@ -82,15 +254,179 @@ func (r *PolicyEvaluationResult) Groups() []string {
 	for k := range r.Violated {
 		groupMap[k] = true
 	}
+	for k := range r.Warned {
+		groupMap[k] = true
+	}
+	for k := range r.Audited {
+		groupMap[k] = true
+	}
+	for k := range r.Waived {
+		groupMap[k] = true
+	}
+	for k := range r.Suppressed {
+		groupMap[k] = true
+	}
 	groups := make([]string, len(groupMap))
 	i := 0
 	for k := range groupMap {
 		groups[i] = k
 		i++
 	}
+	r.sortGroups(groups)
 	return groups
 }
 
+// sortGroups orders groups by name or, when grouped by severity, from the most severe.
+func (r *PolicyEvaluationResult) sortGroups(groups []string) {
+	if r.bySeverity {
+		sort.Slice(groups, func(i, j int) bool {
+			return SeverityLevel(groups[i]) > SeverityLevel(groups[j])
+		})
+		return
+	}
+	sort.Strings(groups)
+}
+
+// GroupBySeverity returns a copy of the result with policies grouped by severity instead of
+// the policy group, most severe first. Policies themselves are shared, so counts are unchanged.
+func (r *PolicyEvaluationResult) GroupBySeverity() *PolicyEvaluationResult {
+	regrouped := NewPolicyEvaluationResult()
+	regrouped.ClusterName = r.ClusterName
+	regrouped.ClusterLabels = r.ClusterLabels
+	regrouped.ClusterError = r.ClusterError
+	regrouped.Errored = append(regrouped.Errored, r.Errored...)
+	regrouped.Coverage = r.Coverage
+	regrouped.bySeverity = true
+	pairs := []struct{ from, to map[string][]*Policy }{
+		{r.Valid, regrouped.Valid},
+		{r.Violated, regrouped.Violated},
+		{r.Warned, regrouped.Warned},
+		{r.Audited, regrouped.Audited},
+		{r.Filtered, regrouped.Filtered},
+		{r.Waived, regrouped.Waived},
+		{r.Suppressed, regrouped.Suppressed},
+		{r.Skipped, regrouped.Skipped},
+	}
+	for _, pair := range pairs {
+		for _, group := range sortedKeys(pair.from) {
+			for _, p := range pair.from[group] {
+				severity := regrouped.GroupOf(p)
+				pair.to[severity] = append(pair.to[severity], p)
+			}
+		}
+	}
+	regrouped.SortPolicies()
+	return regrouped
+}
+
+// Merge adds policies of the other result to this one, so counts of both results are summed.
+// Groups are united and policies present in both results are kept twice, as results may
+// come from different clusters. Cluster name, labels, cluster error and coverage of the other result
+// are taken only when not set. Nil maps of either result are handled. The other result is not
+// modified and its slices are not shared, only policies are. Merge is safe to call concurrently,
+// both into the same result and from the same other result.
+func (r *PolicyEvaluationResult) Merge(other *PolicyEvaluationResult) {
+	if other == nil {
+		return
+	}
+	merged := other.copyPolicies()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ClusterName == "" {
+		r.ClusterName = merged.ClusterName
+	}
+	if r.ClusterLabels == nil {
+		r.ClusterLabels = merged.ClusterLabels
+	}
+	if r.ClusterError == nil {
+		r.ClusterError = merged.ClusterError
+	}
+	if r.Coverage == nil {
+		r.Coverage = merged.Coverage
+	}
+	from := merged.policyMaps()
+	for i, to := range r.policyMaps() {
+		if *to == nil {
+			*to = make(map[string][]*Policy)
+		}
+		for group, policies := range *from[i] {
+			(*to)[group] = append((*to)[group], policies...)
+		}
+	}
+	r.Errored = append(r.Errored, merged.Errored...)
+	r.SortPolicies()
+}
+
+// copyPolicies returns a copy of the result with its own maps and slices, taken under the result
+// lock, so the copy can be read while the result is merged into. Policies are shared.
+func (r *PolicyEvaluationResult) copyPolicies() *PolicyEvaluationResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := &PolicyEvaluationResult{
+		ClusterName:   r.ClusterName,
+		ClusterLabels: r.ClusterLabels,
+		ClusterError:  r.ClusterError,
+		Coverage:      r.Coverage,
+		Errored:       append([]*Policy(nil), r.Errored...),
+	}
+	to := copied.policyMaps()
+	for i, from := range r.policyMaps() {
+		*to[i] = make(map[string][]*Policy, len(*from))
+		for group, policies := range *from {
+			(*to[i])[group] = append([]*Policy(nil), policies...)
+		}
+	}
+	return copied
+}
+
+// policyMaps returns pointers to the maps of policies of the result, always in the same order.
+func (r *PolicyEvaluationResult) policyMaps() []*map[string][]*Policy {
+	return []*map[string][]*Policy{&r.Valid, &r.Violated, &r.Warned, &r.Audited, &r.Filtered, &r.Waived, &r.Suppressed, &r.Skipped}
+}
+
+// IsGroupedBySeverity tells whether policies are grouped by severity.
+func (r *PolicyEvaluationResult) IsGroupedBySeverity() bool {
+	return r.bySeverity
+}
+
+// GroupOf returns the group the policy is reported in, that is its severity when
+// the result is grouped by severity.
+func (r *PolicyEvaluationResult) GroupOf(p *Policy) string {
+	if !r.bySeverity {
+		return p.Group
+	}
+	if p.Severity == "" {
+		return DefaultSeverity
+	}
+	return p.Severity
+}
+
+func sortedKeys(m map[string][]*Policy) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SortPolicies orders policies in every group, and errored policies, by name
+// so the result does not depend on the order of evaluation.
+func (r *PolicyEvaluationResult) SortPolicies() {
+	for _, m := range []map[string][]*Policy{r.Valid, r.Violated, r.Warned, r.Audited, r.Filtered, r.Waived, r.Suppressed, r.Skipped} {
+		for _, policies := range m {
+			sortPoliciesByName(policies)
+		}
+	}
+	sortPoliciesByName(r.Errored)
+}
+
+func sortPoliciesByName(policies []*Policy) {
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+}
+
 func (r *PolicyEvaluationResult) AddPolicy(policy *Policy) {
 	if len(policy.ProcessingErrors) > 0 {
 		r.Errored = append(r.Errored, policy)
@@ -98,7 +434,14 @@ func (r *PolicyEvaluationResult) AddPolicy(policy *Policy) {
 	}
 	if policy.Valid {
 		r.Valid[policy.Group] = append(r.Valid[policy.Group], policy)
-	} else {
+		return
+	}
+	switch policy.Enforcement {
+	case EnforcementWarn:
+		r.Warned[policy.Group] = append(r.Warned[policy.Group], policy)
+	case EnforcementAudit:
+		r.Audited[policy.Group] = append(r.Audited[policy.Group], policy)
+	default:
 		r.Violated[policy.Group] = append(r.Violated[policy.Group], policy)
 	}
 }
@@ -119,78 +462,863 @@ func (r *PolicyEvaluationResult) ViolatedCount() int {
 	return cnt
 }
 
+// ViolatedCountWithSeverity returns number of violated policies with a severity
+// equal or higher than a given minimal severity.
+func (r *PolicyEvaluationResult) ViolatedCountWithSeverity(minSeverity string) int {
+	cnt := 0
+	for _, v := range r.Violated {
+		for _, policy := range v {
+			if SeverityLevel(policy.Severity) >= SeverityLevel(minSeverity) {
+				cnt++
+			}
+		}
+	}
+	return cnt
+}
+
+// ViolatedCountBySeverity returns number of violated policies of each severity, with all
+// severities present. Policies without a valid severity count under the default severity.
+func (r *PolicyEvaluationResult) ViolatedCountBySeverity() map[string]int {
+	counts := make(map[string]int, len(severityLevels))
+	for severity := range severityLevels {
+		counts[severity] = 0
+	}
+	for _, v := range r.Violated {
+		for _, policy := range v {
+			severity := policy.Severity
+			if !IsValidSeverity(severity) {
+				severity = DefaultSeverity
+			}
+			counts[severity]++
+		}
+	}
+	return counts
+}
+
+func (r *PolicyEvaluationResult) WarnedCount() int {
+	cnt := 0
+	for _, v := range r.Warned {
+		cnt += len(v)
+	}
+	return cnt
+}
+
+func (r *PolicyEvaluationResult) AuditedCount() int {
+	cnt := 0
+	for _, v := range r.Audited {
+		cnt += len(v)
+	}
+	return cnt
+}
+
+func (r *PolicyEvaluationResult) WaivedCount() int {
+	cnt := 0
+	for _, v := range r.Waived {
+		cnt += len(v)
+	}
+	return cnt
+}
+
+func (r *PolicyEvaluationResult) FilteredCount() int {
+	cnt := 0
+	for _, v := range r.Filtered {
+		cnt += len(v)
+	}
+	return cnt
+}
+
+// FilterBySeverity moves violated, warned and audited policies with severity lower
+// than a given minimum severity to the filtered policies. Policies stay sorted by name.
+func (r *PolicyEvaluationResult) FilterBySeverity(minSeverity string) {
+	minLevel := severityLevels[minSeverity]
+	for _, policies := range []map[string][]*Policy{r.Violated, r.Warned, r.Audited} {
+		for group := range policies {
+			kept := make([]*Policy, 0, len(policies[group]))
+			for _, policy := range policies[group] {
+				if SeverityLevel(policy.Severity) < minLevel {
+					r.Filtered[group] = append(r.Filtered[group], policy)
+				} else {
+					kept = append(kept, policy)
+				}
+			}
+			if len(kept) > 0 {
+				policies[group] = kept
+			} else {
+				delete(policies, group)
+			}
+		}
+	}
+	r.SortPolicies()
+}
+
+func (r *PolicyEvaluationResult) SkippedCount() int {
+	cnt := 0
+	for _, v := range r.Skipped {
+		cnt += len(v)
+	}
+	return cnt
+}
+
 func (r *PolicyEvaluationResult) ErroredCount() int {
 	return len(r.Errored)
 }
 
+// ErrorsAsViolations reports errored policies as violated, with their processing errors
+// as violations. Policies with warn or audit enforcement are reported as warned or audited.
+func (r *PolicyEvaluationResult) ErrorsAsViolations() {
+	errored := r.Errored
+	r.Errored = make([]*Policy, 0)
+	for _, policy := range errored {
+		for _, err := range policy.ProcessingErrors {
+			policy.Violations = append(policy.Violations, fmt.Sprintf("policy could not be evaluated: %s", err))
+		}
+		policy.ProcessingErrors = nil
+		policy.Valid = false
+		r.AddPolicy(policy)
+	}
+	r.SortPolicies()
+}
+
+func (r *PolicyEvaluationResult) Exceptions() []*PolicyException {
+	exceptions := make([]*PolicyException, 0)
+	for _, group := range r.Groups() {
+		for _, policy := range r.Warned[group] {
+			exceptions = append(exceptions, &PolicyException{
+				Policy: policy,
+				Status: ExceptionStatusWarned,
+				Reason: fmt.Sprintf("violation tolerated due to %q enforcement level", policy.Enforcement),
+			})
+		}
+		for _, policy := range r.Audited[group] {
+			exceptions = append(exceptions, &PolicyException{
+				Policy: policy,
+				Status: ExceptionStatusAudited,
+				Reason: fmt.Sprintf("violation tolerated due to %q enforcement level", policy.Enforcement),
+			})
+		}
+		for _, policy := range r.Waived[group] {
+			exceptions = append(exceptions, &PolicyException{
+				Policy: policy,
+				Status: ExceptionStatusWaived,
+				Reason: fmt.Sprintf("violation waived until %s: %s",
+					policy.Waiver.Expires.Format(WaiverDateFormat), policy.Waiver.Reason),
+			})
+		}
+		for _, policy := range r.Suppressed[group] {
+			exceptions = append(exceptions, &PolicyException{
+				Policy: policy,
+				Status: ExceptionStatusSuppressed,
+				Reason: fmt.Sprintf("all %d violations suppressed", policy.SuppressedViolations),
+			})
+		}
+	}
+	for _, group := range sortedKeys(r.Filtered) {
+		for _, policy := range r.Filtered[group] {
+			exceptions = append(exceptions, &PolicyException{
+				Policy: policy,
+				Status: ExceptionStatusFiltered,
+				Reason: fmt.Sprintf("violation filtered out due to %q severity", policy.Severity),
+			})
+		}
+	}
+	for _, group := range sortedKeys(r.Skipped) {
+		for _, policy := range r.Skipped[group] {
+			exceptions = append(exceptions, &PolicyException{
+				Policy: policy,
+				Status: ExceptionStatusSkipped,
+				Reason: fmt.Sprintf("policy applies to %s clusters only", policy.AppliesTo),
+			})
+		}
+	}
+	for _, policy := range r.Errored {
+		errs := make([]string, len(policy.ProcessingErrors))
+		for i := range policy.ProcessingErrors {
+			errs[i] = policy.ProcessingErrors[i].Error()
+		}
+		exceptions = append(exceptions, &PolicyException{
+			Policy: policy,
+			Status: ExceptionStatusErrored,
+			Reason: fmt.Sprintf("policy could not be evaluated: %s", strings.Join(errs, ", ")),
+		})
+	}
+	return exceptions
+}
+
+// PolicyTiming is the evaluation time of a single policy.
+type PolicyTiming struct {
+	Policy   string
+	Group    string
+	Duration time.Duration
+}
+
+// Timings returns evaluation times of all evaluated policies, slowest first.
+// Policies evaluated with a single query have no timings.
+func (r *PolicyEvaluationResult) Timings() []*PolicyTiming {
+	timings := make([]*PolicyTiming, 0)
+	add := func(policies []*Policy) {
+		for _, policy := range policies {
+			if policy.EvaluationTime > 0 {
+				timings = append(timings, &PolicyTiming{
+					Policy:   policy.Name,
+					Group:    policy.Group,
+					Duration: policy.EvaluationTime,
+				})
+			}
+		}
+	}
+	for _, m := range []map[string][]*Policy{r.Valid, r.Violated, r.Warned, r.Audited, r.Filtered, r.Waived, r.Suppressed} {
+		for _, policies := range m {
+			add(policies)
+		}
+	}
+	add(r.Errored)
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Duration != timings[j].Duration {
+			return timings[i].Duration > timings[j].Duration
+		}
+		return timings[i].Policy < timings[j].Policy
+	})
+	return timings
+}
+
+// WithStrictCompile makes Compile use the OPA strict mode, so deprecated and unsafe
+// constructs are compilation errors.
+func (pa *PolicyAgent) WithStrictCompile() {
+	pa.strictCompile = true
+}
+
+// WithAllowEmpty makes WithFiles accept files without any policy, instead of returning ErrNoPolicies.
+func (pa *PolicyAgent) WithAllowEmpty() {
+	pa.allowEmpty = true
+}
+
+// WithFailFast makes Evaluate stop on the first policy that fails. The fails function tells
+// whether an evaluated policy fails, nil stands for errored or violated enforced policies.
+// Remaining evaluations are cancelled and the result holds that policy only. Evaluation of
+// policies that were not loaded with WithFiles can not be cancelled, the result is reduced
+// to the first failing policy in the group and name order once all policies are evaluated.
+func (pa *PolicyAgent) WithFailFast(fails func(*Policy) bool) {
+	pa.failFast = true
+	pa.failsFast = fails
+}
+
 func (pa *PolicyAgent) Compile(files []*PolicyFile) error {
-	modules := make(map[string]string)
+	modules := make(map[string]*ast.Module)
+	sidecars := make(map[string]*PolicySidecar)
 	for _, file := range files {
-		modules[file.FullName] = file.Content
+		if file.Name == PolicySidecarFileName {
+			sidecar, err := ParsePolicySidecar(file.FullName, []byte(file.Content))
+			if err != nil {
+				return err
+			}
+			sidecars[filepath.Dir(file.FullName)] = sidecar
+			continue
+		}
+		module, err := ast.ParseModuleWithOpts(file.FullName, file.Content, ast.ParserOptions{ProcessAnnotation: true})
+		if err != nil {
+			return err
+		}
+		modules[file.FullName] = module
 	}
-	compiler, err := ast.CompileModulesWithOpt(modules,
-		ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
-	if err != nil {
-		return err
+	compiler := ast.NewCompiler().WithStrict(pa.strictCompile).WithEnablePrintStatements(true).WithBuiltins(builtinDecls())
+	compiler.Compile(modules)
+	if compiler.Failed() {
+		return compiler.Errors
 	}
 	pa.compiler = compiler
+	pa.sidecars = sidecars
 	return nil
 }
 
+// CompileDiagnostics splits the error returned by Compile into messages of individual
+// errors, each prefixed with the file and line it refers to.
+func CompileDiagnostics(err error) []string {
+	var astErrs ast.Errors
+	if !errors.As(err, &astErrs) {
+		return []string{err.Error()}
+	}
+	diagnostics := make([]string, 0, len(astErrs))
+	for _, astErr := range astErrs {
+		diagnostics = append(diagnostics, astErr.Error())
+	}
+	return diagnostics
+}
+
 func (pa *PolicyAgent) ParseCompiled() ([]*Policy, []error) {
+	return pa.parseCompiled(false)
+}
+
+// parseCompiled maps compiled modules to policies. Policies with metadata errors are
+// reported as errors and, with keepInvalid, returned with fallback metadata too.
+func (pa *PolicyAgent) parseCompiled(keepInvalid bool) ([]*Policy, []error) {
 	if pa.compiler == nil {
 		return nil, []error{fmt.Errorf("compiler is nil")}
 	}
 	policies := make([]*Policy, 0)
 	errors := make([]error, 0)
+	appliedSidecars := make(map[*PolicySidecarMetadata]bool)
 	for _, m := range pa.compiler.Modules {
 		policy := Policy{}
 		policy.MapModule(m)
-		if !strings.HasPrefix(policy.Name, regoPolicyPackage) || strings.HasSuffix(policy.File, regoTestFileSuffix) {
+		if !strings.HasPrefix(policy.Name, pa.packageName()+".") || strings.HasSuffix(policy.File, regoTestFileSuffix) {
 			continue
 		}
+		if sidecar, ok := pa.sidecars[filepath.Dir(policy.File)]; ok {
+			if metadata, ok := sidecar.lookup(policy.Name, pa.packageName()); ok && metadata != nil {
+				log.Debugf("policy %s metadata is set from file %s", policy.Name, sidecar.File)
+				policy.applySidecar(metadata)
+				appliedSidecars[metadata] = true
+			}
+		}
 		metaErrs := policy.MetadataErrors()
 		if len(metaErrs) > 0 {
-			errors = append(errors, fmt.Errorf("policy %s has metadata errors: %s", policy.Name, strings.Join(metaErrs, ", ")))
+			metaErr := &PolicyMetadataError{Policy: policy.Name, File: policy.File, Errors: metaErrs}
+			if m.Package.Location != nil {
+				metaErr.Line = m.Package.Location.Row
+			}
+			errors = append(errors, metaErr)
+			if keepInvalid {
+				policy.setFallbackMetadata(pa.packageName())
+				policies = append(policies, &policy)
+			}
 		} else {
+			log.Debugf("compiled policy %s from file %s", policy.Name, policy.File)
 			policies = append(policies, &policy)
 		}
 	}
+	pa.warnUnusedSidecars(appliedSidecars)
 	return policies, errors
 }
 
+// warnUnusedSidecars warns about sidecar metadata of packages without policies next to the sidecar,
+// as it is most likely a misspelled package name.
+func (pa *PolicyAgent) warnUnusedSidecars(applied map[*PolicySidecarMetadata]bool) {
+	warned := make(map[*PolicySidecar]bool)
+	for _, sidecar := range pa.sidecars {
+		if warned[sidecar] {
+			continue
+		}
+		warned[sidecar] = true
+		for name, metadata := range sidecar.Metadata {
+			if !applied[metadata] {
+				log.Warnf("policy metadata file %s: no policy for package %s next to the file", sidecar.File, name)
+			}
+		}
+	}
+}
+
+// ParseQuery validates the query that resolves to the object with policy packages,
+// i.e. data.gke.policy, and returns the package path of policies.
+func ParseQuery(query string) (string, error) {
+	ref, err := ast.ParseRef(query)
+	if err != nil {
+		return "", fmt.Errorf("invalid query %q: %s", query, err)
+	}
+	if !ref.HasPrefix(ast.DefaultRootRef) || len(ref) < 2 {
+		return "", fmt.Errorf("invalid query %q: query has to start with data and refer to a package", query)
+	}
+	parts := make([]string, len(ref)-1)
+	for i, term := range ref[1:] {
+		str, ok := term.Value.(ast.String)
+		if !ok {
+			return "", fmt.Errorf("invalid query %q: %s is not a package name", query, term)
+		}
+		parts[i] = string(str)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// WithQuery sets the query that resolves to the object with policy packages, instead of
+// the DefaultQuery. Policies are packages directly under the query, i.e. data.gke.policy.private_cluster.
+func (pa *PolicyAgent) WithQuery(query string) error {
+	policyPackage, err := ParseQuery(query)
+	if err != nil {
+		return err
+	}
+	pa.policyPackage = policyPackage
+	return nil
+}
+
+// validateQuery checks that the query resolves to the object with policy packages.
+// Runtime errors of policies that fail without input are not taken into account, they
+// are reported as processing errors of the policies once evaluated. Other errors, i.e.
+// of the store, are returned.
+func (pa *PolicyAgent) validateQuery() error {
+	ctx := pa.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	query := "data." + pa.packageName()
+	results, err := rego.New(
+		rego.Compiler(pa.compiler),
+		pa.regoStore(),
+		regoBuiltins(),
+		rego.Query(query)).Eval(ctx)
+	if err != nil {
+		var evalErr *topdown.Error
+		if errors.As(err, &evalErr) && !topdown.IsCancel(err) {
+			log.Debugf("could not validate query %q: %s", query, err)
+			return nil
+		}
+		return fmt.Errorf("could not validate query %q: %w", query, err)
+	}
+	if len(results) < 1 || len(results[0].Expressions) < 1 {
+		return fmt.Errorf("query %q is undefined, %w", query, ErrNoPolicies)
+	}
+	if value := results[0].Expressions[0].Value; reflect.TypeOf(value) != reflect.TypeOf(map[string]interface{}{}) {
+		return fmt.Errorf("query %q resolves to %s (expected object with policy packages)", query, regoTypeName(value))
+	}
+	return nil
+}
+
+// regoTypeName returns the rego type name of the evaluated value.
+func regoTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return "number"
+}
+
+// WithStrictMetadata makes WithFiles fail on policies with metadata errors. By default, such
+// policies are evaluated with fallback metadata and their errors are returned by MetadataWarnings.
+func (pa *PolicyAgent) WithStrictMetadata() {
+	pa.strictMetadata = true
+}
+
+// MetadataWarnings returns metadata errors of policies loaded with WithFiles.
+func (pa *PolicyAgent) MetadataWarnings() []error {
+	return pa.metadataWarnings
+}
+
 func (pa *PolicyAgent) WithFiles(files []*PolicyFile) error {
 	if err := pa.Compile(files); err != nil {
 		return err
 	}
-	policies, errors := pa.ParseCompiled()
-	if len(errors) > 0 {
+	if err := pa.validateQuery(); err != nil && !(pa.allowEmpty && errors.Is(err, ErrNoPolicies)) {
+		return err
+	}
+	policies, errors := pa.parseCompiled(!pa.strictMetadata)
+	if len(errors) > 0 && pa.strictMetadata {
 		return errors[0]
 	}
+	pa.metadataWarnings = errors
 	pa.compiled = make(map[string]*Policy)
 	for _, policy := range policies {
 		pa.compiled[policy.Name] = policy
 	}
+	if len(pa.compiled) == 0 && !pa.allowEmpty {
+		return ErrNoPolicies
+	}
 	return nil
 }
 
+// FilterPolicies keeps only compiled policies with names matching the include expression
+// and not matching the exclude expression, so the others are not evaluated. Nil expressions
+// are not applied. Returns number of policies left.
+func (pa *PolicyAgent) FilterPolicies(include *regexp.Regexp, exclude *regexp.Regexp) int {
+	for name := range pa.compiled {
+		if !MatchesNameFilters(name, include, exclude) {
+			delete(pa.compiled, name)
+		}
+	}
+	return len(pa.compiled)
+}
+
+// FilterPoliciesByTags keeps only compiled policies carrying all, or any when matchAll is false,
+// of the given tags. Returns number of policies left.
+func (pa *PolicyAgent) FilterPoliciesByTags(tags []string, matchAll bool) int {
+	for name, policy := range pa.compiled {
+		if !MatchesTags(policy.Tags, tags, matchAll) {
+			delete(pa.compiled, name)
+		}
+	}
+	return len(pa.compiled)
+}
+
+// FilterDeprecated removes deprecated policies from compiled ones, so they are not evaluated.
+// Returns number of policies left.
+func (pa *PolicyAgent) FilterDeprecated() int {
+	for name, policy := range pa.compiled {
+		if policy.Deprecated {
+			delete(pa.compiled, name)
+		}
+	}
+	return len(pa.compiled)
+}
+
+// DeprecatedPolicies returns compiled policies marked as deprecated, sorted by name.
+func (pa *PolicyAgent) DeprecatedPolicies() []*Policy {
+	deprecated := make([]*Policy, 0)
+	for _, policy := range pa.compiled {
+		if policy.Deprecated {
+			deprecated = append(deprecated, policy)
+		}
+	}
+	sortPoliciesByName(deprecated)
+	return deprecated
+}
+
+// Policies returns policies loaded with WithFiles and left by filters, sorted by name.
+func (pa *PolicyAgent) Policies() []*Policy {
+	policies := make([]*Policy, 0, len(pa.compiled))
+	for _, policy := range pa.compiled {
+		policies = append(policies, policy)
+	}
+	sortPoliciesByName(policies)
+	return policies
+}
+
+// OverrideSeverities sets severities of compiled policies, keyed by the full policy name or
+// the name without the policy package prefix. Returns names that match no compiled policy, sorted.
+func (pa *PolicyAgent) OverrideSeverities(severities map[string]string) []string {
+	unknown := make([]string, 0)
+	for name, severity := range severities {
+		compiled, ok := pa.compiled[name]
+		if !ok {
+			compiled, ok = pa.compiled[pa.packageName()+"."+name]
+		}
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		log.Debugf("policy %s severity is overridden from %s to %s", compiled.Name, compiled.Severity, severity)
+		compiled.Severity = severity
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// MatchesTags returns true if policy tags include all, or any when matchAll is false, of the
+// given tags. Policy without tags never matches, empty list of tags matches any policy.
+func MatchesTags(policyTags []string, tags []string, matchAll bool) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	tagSet := make(map[string]bool, len(policyTags))
+	for _, tag := range policyTags {
+		tagSet[tag] = true
+	}
+	for _, tag := range tags {
+		found := tagSet[tag]
+		if matchAll && !found {
+			return false
+		}
+		if !matchAll && found {
+			return true
+		}
+	}
+	return matchAll
+}
+
+// MatchesNameFilters returns true if the policy name matches the include expression and does not
+// match the exclude expression. Nil expressions are not applied.
+func MatchesNameFilters(name string, include *regexp.Regexp, exclude *regexp.Regexp) bool {
+	return (include == nil || include.MatchString(name)) && (exclude == nil || !exclude.MatchString(name))
+}
+
+// Evaluate evaluates compiled policies against a given input. When policies were loaded with
+// WithFiles, each policy is evaluated separately by a pool of workers sized to the number of CPUs.
+// Runtime errors of a policy are reported as its processing errors, error is returned only
+// when the evaluation is cancelled or none of the policies could be evaluated.
 func (pa *PolicyAgent) Evaluate(input interface{}) (*PolicyEvaluationResult, error) {
+	if pa.compiler == nil || len(pa.compiled) == 0 {
+		return pa.evaluateAll(input)
+	}
+	return pa.evaluateParallel(input, runtime.NumCPU())
+}
+
+func (pa *PolicyAgent) evaluateAll(input interface{}) (*PolicyEvaluationResult, error) {
+	query := "data." + pa.packageName() + "[name]"
+	log.Debugf("evaluating rego query %q", query)
 	var rgo *rego.Rego
 	if pa.compiler == nil {
 		rgo = rego.New(
 			rego.Input(input),
-			rego.Query(regoQuery))
+			pa.regoStore(),
+			regoBuiltins(),
+			rego.Query(query))
 	} else {
 		rgo = rego.New(
 			rego.Compiler(pa.compiler),
 			rego.Input(input),
-			rego.Query(regoQuery))
+			pa.regoStore(),
+			regoBuiltins(),
+			rego.PrintHook(newPrintHook(pa.packageName())),
+			rego.Query(query))
 	}
 	results, err := rgo.Eval(pa.ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate rego: %s", err)
 	}
-	return pa.processRegoResultSet(results)
+	evalResults, err := pa.processRegoResultSet(results)
+	if err != nil {
+		return nil, err
+	}
+	evalResults.SortPolicies()
+	if pa.failFast {
+		evalResults = pa.firstFailingResult(evalResults)
+	}
+	return evalResults, nil
+}
+
+// firstFailingResult reduces the result to its first failing policy, errored policies first,
+// then the other policies in the group and name order. Result without failing policies is kept.
+func (pa *PolicyAgent) firstFailingResult(evalResults *PolicyEvaluationResult) *PolicyEvaluationResult {
+	policies := append([]*Policy{}, evalResults.Errored...)
+	for _, group := range evalResults.Groups() {
+		policies = append(policies, evalResults.Violated[group]...)
+		policies = append(policies, evalResults.Warned[group]...)
+		policies = append(policies, evalResults.Audited[group]...)
+	}
+	for _, policy := range policies {
+		if pa.failsEvaluation(policy) {
+			failed := NewPolicyEvaluationResult()
+			failed.AddPolicy(policy)
+			return failed
+		}
+	}
+	return evalResults
+}
+
+// policyEvaluationJob is evaluation of a single policy. When the query could not be prepared
+// or the input could not be selected, the error is kept and reported as the policy processing error.
+// Input is set when the policy has input selector. In the rule mode, the query is of the violation
+// rule and validQuery, if set, of the valid rule.
+type policyEvaluationJob struct {
+	policy     *Policy
+	query      rego.PreparedEvalQuery
+	validQuery *rego.PreparedEvalQuery
+	input      ast.Value
+	tracer     *coverageTracer
+	prints     *printHook
+	// trace collects evaluation trace of the policy, see Explain
+	trace *topdown.BufferTracer
+	err   error
+}
+
+// newPolicyEvaluationJob prepares queries of a policy and selects its input.
+func (pa *PolicyAgent) newPolicyEvaluationJob(policy *Policy, input ast.Value) *policyEvaluationJob {
+	job := &policyEvaluationJob{policy: policy, prints: newPrintHook(policy.Name)}
+	if pa.ruleNames != nil {
+		job.query, job.validQuery, job.err = pa.prepareRuleQueries(policy, job.prints)
+	} else {
+		job.query, job.err = pa.prepareQuery("data."+policy.Name, policy, job.prints)
+	}
+	if job.err == nil && policy.InputSelector != "" {
+		job.input, job.err = selectPolicyInput(input, policy.InputSelector)
+	}
+	return job
+}
+
+func (pa *PolicyAgent) prepareQuery(query string, policy *Policy, prints *printHook) (rego.PreparedEvalQuery, error) {
+	log.Debugf("preparing rego query %q for policy %s", query, policy.Name)
+	prepared, err := rego.New(
+		rego.Compiler(pa.compiler),
+		pa.regoStore(),
+		regoBuiltins(),
+		rego.PrintHook(prints),
+		rego.Query(query)).PrepareForEval(pa.ctx)
+	if err != nil {
+		log.Warnf("failed to prepare rego query for policy %s: %s", policy.Name, err)
+		return prepared, fmt.Errorf("failed to prepare rego query: %s", err)
+	}
+	return prepared, nil
+}
+
+func (pa *PolicyAgent) evaluateParallel(input interface{}, workers int) (*PolicyEvaluationResult, error) {
+	parsedInput, err := ast.InterfaceToValue(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %s", err)
+	}
+	evalResults := NewPolicyEvaluationResult()
+	var tracer *coverageTracer
+	if pa.coverage {
+		tracer = newCoverageTracer()
+	}
+	autopilot := isAutopilotInput(parsedInput)
+	jobs := make([]*policyEvaluationJob, 0, len(pa.compiled))
+	for _, policy := range pa.compiled {
+		if !policy.AppliesToCluster(autopilot) {
+			log.Debugf("skipping policy %s that applies to %s clusters only", policy.Name, policy.AppliesTo)
+			skipped := *policy
+			evalResults.Skipped[skipped.Group] = append(evalResults.Skipped[skipped.Group], &skipped)
+			continue
+		}
+		job := pa.newPolicyEvaluationJob(policy, parsedInput)
+		job.tracer = tracer
+		jobs = append(jobs, job)
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(pa.ctx)
+	defer cancel()
+	jobsChan := make(chan *policyEvaluationJob)
+	resultsChan := make(chan *Policy, len(jobs))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobsChan {
+				resultsChan <- pa.evaluatePolicy(ctx, job, parsedInput)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobsChan)
+		for _, job := range jobs {
+			select {
+			case jobsChan <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(resultsChan)
+	}()
+	var failed *Policy
+	for policy := range resultsChan {
+		if failed != nil {
+			continue
+		}
+		if pa.failFast && pa.failsEvaluation(policy) {
+			log.Debugf("policy %s failed, cancelling remaining evaluations", policy.Name)
+			failed = policy
+			cancel()
+			continue
+		}
+		evalResults.AddPolicy(policy)
+	}
+	if err := pa.ctx.Err(); err != nil {
+		return nil, fmt.Errorf("policy evaluation cancelled: %w", err)
+	}
+	if len(jobs) > 0 && evalResults.ErroredCount() == len(jobs) {
+		return nil, fmt.Errorf("failed to evaluate rego: all %d policies have errors, first: %v",
+			len(jobs), evalResults.Errored[0].ProcessingErrors[0])
+	}
+	if failed != nil {
+		evalResults = NewPolicyEvaluationResult()
+		evalResults.AddPolicy(failed)
+	}
+	if tracer != nil {
+		evalResults.Coverage = tracer.report(pa.compiler.Modules)
+	}
+	evalResults.SortPolicies()
+	return evalResults, nil
+}
+
+// failsEvaluation returns true if the policy fails, as told by the fail fast function or,
+// without one, if the policy is errored or is violated and enforced.
+func (pa *PolicyAgent) failsEvaluation(p *Policy) bool {
+	if pa.failsFast != nil {
+		return pa.failsFast(p)
+	}
+	if len(p.ProcessingErrors) > 0 {
+		return true
+	}
+	return !p.Valid && p.Enforcement != EnforcementWarn && p.Enforcement != EnforcementAudit
+}
+
+// isAutopilotInput returns true if the input describes Autopilot cluster.
+func isAutopilotInput(input ast.Value) bool {
+	obj, ok := input.(ast.Object)
+	if !ok {
+		return false
+	}
+	term := obj.Get(ast.StringTerm(inputAutopilotKey))
+	if term == nil {
+		return false
+	}
+	autopilot, ok := term.Value.(ast.Boolean)
+	return ok && bool(autopilot)
+}
+
+// AppliesToCluster returns true if the policy should be evaluated for the cluster of a given type.
+func (p *Policy) AppliesToCluster(autopilot bool) bool {
+	switch p.AppliesTo {
+	case AppliesToStandard:
+		return !autopilot
+	case AppliesToAutopilot:
+		return autopilot
+	}
+	return true
+}
+
+func (pa *PolicyAgent) evaluatePolicy(ctx context.Context, job *policyEvaluationJob, input ast.Value) *Policy {
+	policy := *job.policy
+	policy.Valid = false
+	policy.Violations = nil
+	policy.ViolationDetails = nil
+	policy.ProcessingErrors = nil
+	policy.Prints = nil
+	if job.err != nil {
+		policy.ProcessingErrors = []error{job.err}
+		return &policy
+	}
+	if job.input != nil {
+		input = job.input
+	}
+	options := []rego.EvalOption{rego.EvalParsedInput(input)}
+	if job.tracer != nil {
+		options = append(options, rego.EvalQueryTracer(job.tracer))
+	}
+	if job.trace != nil {
+		options = append(options, rego.EvalQueryTracer(job.trace))
+	}
+	start := time.Now()
+	if pa.ruleNames != nil {
+		valid, violations, err := pa.evaluateRules(ctx, job, options)
+		policy.EvaluationTime = time.Since(start)
+		policy.Prints = job.prints.messages()
+		if err != nil {
+			log.Warnf("failed to evaluate policy %s: %s", policy.Name, err)
+			policy.ProcessingErrors = []error{err}
+			return &policy
+		}
+		policy.Valid = valid
+		policy.Violations = violationStrings(violations)
+		policy.ViolationDetails = violations
+		return &policy
+	}
+	results, err := job.query.Eval(ctx, options...)
+	policy.EvaluationTime = time.Since(start)
+	if job.prints != nil {
+		policy.Prints = job.prints.messages()
+	}
+	if err != nil {
+		log.Warnf("failed to evaluate policy %s: %s", policy.Name, err)
+		policy.ProcessingErrors = []error{fmt.Errorf("failed to evaluate rego: %s", err)}
+		return &policy
+	}
+	if len(results) < 1 || len(results[0].Expressions) < 1 {
+		policy.ProcessingErrors = []error{fmt.Errorf("result has no expressions")}
+		return &policy
+	}
+	log.Debugf("policy %s evaluated to %v", policy.Name, results[0].Expressions[0].Value)
+	regoEvalResult := RegoEvaluationResult{}
+	if err := regoEvalResult.mapExpressionValue(results[0].Expressions[0].Value); err != nil {
+		policy.ProcessingErrors = []error{err}
+		return &policy
+	}
+	policy.Valid = regoEvalResult.Valid
+	policy.Violations = violationStrings(regoEvalResult.Violations)
+	policy.ViolationDetails = regoEvalResult.Violations
+	return &policy
 }
 
 func (pa *PolicyAgent) processRegoResultSet(results rego.ResultSet) (*PolicyEvaluationResult, error) {
@@ -206,18 +1334,24 @@ func (pa *PolicyAgent) processRegoResultSet(results rego.ResultSet) (*PolicyEval
 		if err := regoEvalResult.mapExpressionBindings(bindings); err != nil {
 			regoEvalResultErrors = append(regoEvalResultErrors, err)
 		}
+		log.Debugf("rego result evaluated to %v", value)
 		if err := regoEvalResult.mapExpressionValue(value); err != nil {
 			regoEvalResultErrors = append(regoEvalResultErrors, err)
 		}
 		policy := NewPolicyFromEvalResult(&regoEvalResult, regoEvalResultErrors)
-		policyName := regoPolicyPackage + "." + regoEvalResult.Name
+		policyName := pa.packageName() + "." + regoEvalResult.Name
 		if compiledPolicy, ok := pa.compiled[policyName]; ok {
-			compiledPolicy.Valid = policy.Valid
-			compiledPolicy.Violations = policy.Violations
-			compiledPolicy.ProcessingErrors = policy.ProcessingErrors
-			policy = compiledPolicy
+			evaluatedPolicy := *compiledPolicy
+			evaluatedPolicy.Valid = policy.Valid
+			evaluatedPolicy.Violations = policy.Violations
+			evaluatedPolicy.ViolationDetails = policy.ViolationDetails
+			evaluatedPolicy.ProcessingErrors = policy.ProcessingErrors
+			policy = &evaluatedPolicy
 		} else {
 			log.Warnf("rego policy %q has no match with any compiled policy", policyName)
+			if regoEvalResult.Name != "" {
+				policy.Name = policyName
+			}
 		}
 		evalResults.AddPolicy(policy)
 	}
@@ -252,11 +1386,7 @@ func (r *RegoEvaluationResult) mapExpressionBindings(bindings map[string]interfa
 }
 
 func (r *RegoEvaluationResult) mapExpressionValue(value interface{}) error {
-	valueMap, ok := value.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("rego expression value type is %q (expected map[string]interface{})", reflect.TypeOf(value))
-	}
-	valid, violations, err := parseRegoPolicyData(valueMap)
+	valid, violations, err := parseRegoPolicyData(value)
 	if err != nil {
 		return err
 	}
@@ -265,26 +1395,16 @@ func (r *RegoEvaluationResult) mapExpressionValue(value interface{}) error {
 	return nil
 }
 
-func parseRegoPolicyData(data interface{}) (valid bool, violations []string, err error) {
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		err = fmt.Errorf("failed to convert value of type %q to map[string]interface{}", reflect.TypeOf(data))
-		return
-	}
-	if valid, err = getBoolFromInterfaceMap("valid", dataMap); err != nil {
-		return
-	}
-	if violations, err = getStringListFromInterfaceMap("violation", dataMap); err != nil {
-		return
-	}
-	return
+func parseRegoPolicyData(data interface{}) (valid bool, violations []*Violation, err error) {
+	return decodeRegoPolicyResult(data)
 }
 
 func NewPolicyFromEvalResult(result *RegoEvaluationResult, errors []error) *Policy {
 	policy := &Policy{
-		Name:       result.Name,
-		Valid:      result.Valid,
-		Violations: result.Violations,
+		Name:             result.Name,
+		Valid:            result.Valid,
+		Violations:       violationStrings(result.Violations),
+		ViolationDetails: result.Violations,
 	}
 	if len(errors) > 0 {
 		policy.ProcessingErrors = errors
@@ -292,6 +1412,14 @@ func NewPolicyFromEvalResult(result *RegoEvaluationResult, errors []error) *Poli
 	return policy
 }
 
+// Label returns the policy title used to present the policy, or its name when the title is not set.
+func (p *Policy) Label() string {
+	if p.Title == "" {
+		return p.Name
+	}
+	return p.Title
+}
+
 func (p *Policy) MapModule(module *ast.Module) {
 	p.Name = module.Package.String()[8:]
 	p.File = module.Package.Location.File
@@ -306,6 +1434,150 @@ func (p *Policy) MapModule(module *ast.Module) {
 				p.Group = groupS
 			}
 		}
+		if enforcement, ok := annot.Custom["enforcement"]; ok {
+			if enforcementS, okS := enforcement.(string); okS {
+				p.Enforcement = strings.ToLower(enforcementS)
+			}
+		}
+		if remediation, ok := annot.Custom["remediation"]; ok {
+			if remediationS, okS := remediation.(string); okS {
+				p.Remediation = remediationS
+			}
+		}
+		if cis, ok := annot.Custom["cis"]; ok {
+			p.CisControls = parseCisControls(cis)
+		}
+		if references, ok := annot.Custom["references"]; ok {
+			p.References = parseReferences(p.Name, references)
+		}
+		if tags, ok := annot.Custom["tags"]; ok {
+			p.Tags = parseTags(tags)
+		}
+		if severity, ok := annot.Custom["severity"]; ok {
+			if severityS, okS := severity.(string); okS {
+				p.Severity = strings.ToUpper(severityS)
+			}
+		}
+		if appliesTo, ok := annot.Custom["applies_to"]; ok {
+			if appliesToS, okS := appliesTo.(string); okS {
+				p.AppliesTo = strings.ToLower(appliesToS)
+			}
+		}
+		if deprecated, ok := annot.Custom["deprecated"]; ok {
+			if deprecatedB, okB := deprecated.(bool); okB {
+				p.Deprecated = deprecatedB
+			}
+		}
+		if message, ok := annot.Custom["deprecated_message"]; ok {
+			if messageS, okS := message.(string); okS {
+				p.DeprecatedMessage = messageS
+			}
+		}
+		if selector, ok := annot.Custom["input_selector"]; ok {
+			if selectorS, okS := selector.(string); okS {
+				p.InputSelector = strings.TrimSpace(selectorS)
+			}
+		}
+	}
+	if p.Enforcement == "" {
+		p.Enforcement = EnforcementEnforce
+	}
+	if p.Severity == "" {
+		p.Severity = DefaultSeverity
+	}
+	if p.AppliesTo == "" {
+		p.AppliesTo = AppliesToBoth
+	}
+	p.Title = expandEnvVars(p.Name, p.Title)
+	p.Group = expandEnvVars(p.Name, p.Group)
+}
+
+// expandEnvVars replaces ${VAR} references with values of environment variables.
+// Unset variables are left as-is with a warning; other uses of $ are not changed.
+func expandEnvVars(policy string, value string) string {
+	return envVarRef.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envVarRef.FindStringSubmatch(ref)[1]
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+		log.Warnf("policy %s: environment variable %s is not set", policy, name)
+		return ref
+	})
+}
+
+// parseCisControls parses CIS benchmark control IDs from a single value
+// or a list of values. Control IDs parsed by YAML as numbers, i.e. "5.6", are converted back to strings.
+func parseCisControls(value interface{}) []string {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	controls := make([]string, 0, len(values))
+	for _, v := range values {
+		var control string
+		switch vT := v.(type) {
+		case string:
+			control = strings.TrimSpace(vT)
+		case float64:
+			control = strconv.FormatFloat(vT, 'f', -1, 64)
+		case int:
+			control = strconv.Itoa(vT)
+		}
+		if control != "" {
+			controls = append(controls, control)
+		}
+	}
+	return controls
+}
+
+// parseTags parses tags from a single value or a list of values. Tags are lowercased,
+// so that matching is case insensitive.
+func parseTags(value interface{}) []string {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	tags := make([]string, 0, len(values))
+	for _, v := range values {
+		if tag, ok := v.(string); ok && strings.TrimSpace(tag) != "" {
+			tags = append(tags, strings.ToLower(strings.TrimSpace(tag)))
+		}
+	}
+	return tags
+}
+
+// parseReferences parses reference URLs from a single value or a list of values.
+// Malformed URLs are skipped with a warning, as they do not prevent policy evaluation.
+func parseReferences(policyName string, value interface{}) []string {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	references := make([]string, 0, len(values))
+	for _, v := range values {
+		reference, ok := v.(string)
+		if !ok {
+			log.Warnf("policy %s has reference %v that is not a string, skipping", policyName, v)
+			continue
+		}
+		reference = strings.TrimSpace(reference)
+		if u, err := url.ParseRequestURI(reference); err != nil || u.Host == "" {
+			log.Warnf("policy %s has malformed reference URL %q, skipping", policyName, reference)
+			continue
+		}
+		references = append(references, reference)
+	}
+	return references
+}
+
+// setFallbackMetadata sets the policy name as the title and the policies package as the group,
+// when they are not set.
+func (p *Policy) setFallbackMetadata(packageName string) {
+	if p.Title == "" {
+		p.Title = p.Name
+	}
+	if p.Group == "" {
+		p.Group = packageName
 	}
 }
 
@@ -320,37 +1592,40 @@ func (p Policy) MetadataErrors() []string {
 	if p.Group == "" {
 		errs = append(errs, "group is not set")
 	}
+	switch p.Enforcement {
+	case "", EnforcementEnforce, EnforcementWarn, EnforcementAudit:
+	default:
+		errs = append(errs, fmt.Sprintf("enforcement %q is not one of %s, %s, %s",
+			p.Enforcement, EnforcementEnforce, EnforcementWarn, EnforcementAudit))
+	}
+	switch p.AppliesTo {
+	case "", AppliesToStandard, AppliesToAutopilot, AppliesToBoth:
+	default:
+		errs = append(errs, fmt.Sprintf("applies_to %q is not one of %s, %s, %s",
+			p.AppliesTo, AppliesToStandard, AppliesToAutopilot, AppliesToBoth))
+	}
+	if p.Severity != "" && !IsValidSeverity(p.Severity) {
+		errs = append(errs, fmt.Sprintf("severity %q is not one of %s, %s, %s, %s",
+			p.Severity, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical))
+	}
+	if p.InputSelector != "" {
+		if _, err := ParseInputSelector(p.InputSelector); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
 	return errs
 }
 
-func getBoolFromInterfaceMap(name string, m map[string]interface{}) (bool, error) {
-	v, ok := m[name]
-	if !ok {
-		return false, fmt.Errorf("map does not contain key: %q", name)
-	}
-	vBool, ok := v.(bool)
-	if !ok {
-		return false, fmt.Errorf("key %q type is %q (not a string)", name, reflect.ValueOf(v))
-	}
-	return vBool, nil
+func IsValidSeverity(severity string) bool {
+	_, ok := severityLevels[severity]
+	return ok
 }
 
-func getStringListFromInterfaceMap(name string, m map[string]interface{}) ([]string, error) {
-	v, ok := m[name]
-	if !ok {
-		return nil, fmt.Errorf("map does not contain key: %q", name)
-	}
-	vList, ok := v.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("key %q type is %q (not a []interface{})", name, reflect.ValueOf(v))
-	}
-	vStringList := make([]string, len(vList))
-	for i := range vList {
-		vStringListItem, ok := vList[i].(string)
-		if !ok {
-			return nil, fmt.Errorf("key's %q list element %d is not a string", name, i)
-		}
-		vStringList[i] = vStringListItem
+// SeverityLevel returns numeric level of a given severity, so severities can be compared.
+// Empty severity has the level of a default severity.
+func SeverityLevel(severity string) int {
+	if severity == "" {
+		severity = DefaultSeverity
 	}
-	return vStringList, nil
+	return severityLevels[severity]
 }
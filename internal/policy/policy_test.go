@@ -19,10 +19,17 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
 )
 
 func TestNewPolicyEvaluationResults(t *testing.T) {
@@ -77,6 +84,33 @@ func TestAddPolicy(t *testing.T) {
 	}
 }
 
+func TestAddPolicy_enforcement(t *testing.T) {
+	groupOneName := "groupOne"
+	inputs := []*Policy{
+		{Group: groupOneName, Valid: false, Enforcement: EnforcementEnforce, Violations: []string{"error"}},
+		{Group: groupOneName, Valid: false, Violations: []string{"error"}},
+		{Group: groupOneName, Valid: false, Enforcement: EnforcementWarn, Violations: []string{"error"}},
+		{Group: groupOneName, Valid: false, Enforcement: EnforcementAudit, Violations: []string{"error"}},
+		{Group: groupOneName, Valid: true, Enforcement: EnforcementWarn},
+	}
+	r := NewPolicyEvaluationResult()
+	for i := range inputs {
+		r.AddPolicy(inputs[i])
+	}
+	if r.ViolatedCount() != 2 {
+		t.Errorf("violatedCount = %v; want %v", r.ViolatedCount(), 2)
+	}
+	if r.WarnedCount() != 1 {
+		t.Errorf("warnedCount = %v; want %v", r.WarnedCount(), 1)
+	}
+	if r.AuditedCount() != 1 {
+		t.Errorf("auditedCount = %v; want %v", r.AuditedCount(), 1)
+	}
+	if r.ValidCount() != 1 {
+		t.Errorf("validCount = %v; want %v", r.ValidCount(), 1)
+	}
+}
+
 func TestViolatedCount(t *testing.T) {
 	inputs := []*Policy{
 		{Group: "groupOne", Valid: false, Violations: []string{"error"}},
@@ -128,6 +162,164 @@ func TestErroredCount(t *testing.T) {
 	}
 }
 
+func TestErrorsAsViolations(t *testing.T) {
+	inputs := []*Policy{
+		{Name: "one", Group: "groupOne", ProcessingErrors: []error{errors.New("undefined result")}},
+		{Name: "two", Group: "groupOne", Enforcement: EnforcementWarn, ProcessingErrors: []error{errors.New("undefined result")}},
+		{Name: "three", Group: "groupTwo", Valid: false, Violations: []string{"error"}},
+	}
+	r := NewPolicyEvaluationResult()
+	for i := range inputs {
+		r.AddPolicy(inputs[i])
+	}
+	r.ErrorsAsViolations()
+	if r.ErroredCount() != 0 {
+		t.Errorf("erroredCount = %v; want %v", r.ErroredCount(), 0)
+	}
+	if r.ViolatedCount() != 2 {
+		t.Errorf("violatedCount = %v; want %v", r.ViolatedCount(), 2)
+	}
+	if r.WarnedCount() != 1 {
+		t.Errorf("warnedCount = %v; want %v", r.WarnedCount(), 1)
+	}
+	expected := []string{"policy could not be evaluated: undefined result"}
+	if violations := r.Violated["groupOne"][0].Violations; !reflect.DeepEqual(violations, expected) {
+		t.Errorf("violations = %v; want %v", violations, expected)
+	}
+}
+
+func TestFilterBySeverity(t *testing.T) {
+	inputs := []*Policy{
+		{Group: "groupOne", Valid: true, Severity: SeverityLow},
+		{Group: "groupOne", Valid: false, Severity: SeverityLow, Violations: []string{"error"}},
+		{Group: "groupOne", Valid: false, Severity: SeverityCritical, Violations: []string{"error"}},
+		{Group: "groupTwo", Valid: false, Violations: []string{"error"}},
+		{Group: "groupTwo", Valid: false, Severity: SeverityHigh, Enforcement: EnforcementWarn, Violations: []string{"error"}},
+		{Group: "groupThree", Valid: false, Severity: SeverityMedium, Enforcement: EnforcementAudit, Violations: []string{"error"}},
+	}
+	r := NewPolicyEvaluationResult()
+	for i := range inputs {
+		r.AddPolicy(inputs[i])
+	}
+	r.FilterBySeverity(SeverityHigh)
+	if r.ViolatedCount() != 1 {
+		t.Errorf("violatedCount = %v; want %v", r.ViolatedCount(), 1)
+	}
+	if r.WarnedCount() != 1 {
+		t.Errorf("warnedCount = %v; want %v", r.WarnedCount(), 1)
+	}
+	if r.AuditedCount() != 0 {
+		t.Errorf("auditedCount = %v; want %v", r.AuditedCount(), 0)
+	}
+	if r.FilteredCount() != 3 {
+		t.Errorf("filteredCount = %v; want %v", r.FilteredCount(), 3)
+	}
+	if r.ValidCount() != 1 {
+		t.Errorf("validCount = %v; want %v", r.ValidCount(), 1)
+	}
+	if _, ok := r.Audited["groupThree"]; ok {
+		t.Errorf("audited group %v is present; want removed", "groupThree")
+	}
+}
+
+func TestViolatedCountWithSeverity(t *testing.T) {
+	r := NewPolicyEvaluationResult()
+	r.AddPolicy(&Policy{Group: "groupOne", Severity: SeverityLow, Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Group: "groupOne", Severity: SeverityHigh, Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Group: "groupTwo", Severity: SeverityCritical, Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Group: "groupTwo", Severity: SeverityCritical, Enforcement: EnforcementWarn, Violations: []string{"error"}})
+	expected := map[string]int{
+		SeverityLow:      3,
+		SeverityMedium:   2,
+		SeverityHigh:     2,
+		SeverityCritical: 1,
+	}
+	for severity, cnt := range expected {
+		if r.ViolatedCountWithSeverity(severity) != cnt {
+			t.Errorf("violatedCountWithSeverity(%v) = %v; want %v", severity, r.ViolatedCountWithSeverity(severity), cnt)
+		}
+	}
+}
+
+func TestViolatedCountBySeverity(t *testing.T) {
+	r := NewPolicyEvaluationResult()
+	r.AddPolicy(&Policy{Group: "groupOne", Severity: SeverityHigh, Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Group: "groupOne", Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Group: "groupTwo", Severity: SeverityCritical, Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Group: "groupTwo", Severity: SeverityCritical, Enforcement: EnforcementWarn, Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Group: "groupTwo", Severity: SeverityLow, Valid: true})
+	expected := map[string]int{
+		SeverityLow:      0,
+		SeverityMedium:   1,
+		SeverityHigh:     1,
+		SeverityCritical: 1,
+	}
+	if counts := r.ViolatedCountBySeverity(); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("violatedCountBySeverity = %v; want %v", counts, expected)
+	}
+}
+
+func TestSeverityLevel(t *testing.T) {
+	if SeverityLevel("") != SeverityLevel(DefaultSeverity) {
+		t.Errorf("level of empty severity = %v; want %v", SeverityLevel(""), SeverityLevel(DefaultSeverity))
+	}
+	if SeverityLevel(SeverityLow) >= SeverityLevel(SeverityMedium) {
+		t.Errorf("level of %v is not lower than %v", SeverityLow, SeverityMedium)
+	}
+	if SeverityLevel(SeverityHigh) >= SeverityLevel(SeverityCritical) {
+		t.Errorf("level of %v is not lower than %v", SeverityHigh, SeverityCritical)
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	inputs := []*Policy{
+		{Group: "groupOne", Valid: true},
+		{Group: "groupOne", Valid: false, Violations: []string{"error"}},
+		{Group: "groupOne", Valid: false, Enforcement: EnforcementWarn, Violations: []string{"error"}},
+		{Group: "groupTwo", Valid: false, Enforcement: EnforcementAudit, Violations: []string{"error"}},
+		{Group: "groupTwo", ProcessingErrors: []error{errors.New("error")}},
+	}
+	r := NewPolicyEvaluationResult()
+	for i := range inputs {
+		r.AddPolicy(inputs[i])
+	}
+	exceptions := r.Exceptions()
+	if len(exceptions) != 3 {
+		t.Fatalf("len(exceptions) = %v; want %v", len(exceptions), 3)
+	}
+	statuses := map[string]bool{}
+	for _, exception := range exceptions {
+		if exception.Reason == "" {
+			t.Errorf("exception for policy %v has no reason", exception.Policy)
+		}
+		statuses[exception.Status] = true
+	}
+	for _, status := range []string{ExceptionStatusWarned, ExceptionStatusAudited, ExceptionStatusErrored} {
+		if !statuses[status] {
+			t.Errorf("no exception with status %v", status)
+		}
+	}
+}
+
+func TestExceptions_skippedOrder(t *testing.T) {
+	r := NewPolicyEvaluationResult()
+	for _, group := range []string{"groupThree", "groupOne", "groupTwo"} {
+		r.Skipped[group] = []*Policy{{Name: group, Group: group, AppliesTo: "autopilot"}}
+	}
+	expected := []string{"groupOne", "groupThree", "groupTwo"}
+	for i := 0; i < 10; i++ {
+		names := make([]string, 0)
+		for _, exception := range r.Exceptions() {
+			if exception.Status == ExceptionStatusSkipped {
+				names = append(names, exception.Policy.Name)
+			}
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Fatalf("skipped = %v; want %v", names, expected)
+		}
+	}
+}
+
 func TestCompile(t *testing.T) {
 	policyFiles := []*PolicyFile{
 		{"test_one.rego", "folder/test_one.rego", `
@@ -166,6 +358,34 @@ bla bla`}}
 	}
 }
 
+func TestCompile_strict(t *testing.T) {
+	policyFiles := []*PolicyFile{
+		{"test_one.rego", "folder/test_one.rego", `
+package test_one
+import data.a.x
+import data.b.x
+p = x`},
+		{"test_two.rego", "folder/test_two.rego", `
+package test_two
+q { x := 1; true }`}}
+	for _, file := range policyFiles {
+		if err := NewPolicyAgent(context.Background()).Compile([]*PolicyFile{file}); err != nil {
+			t.Fatalf("file %s: err = %v; want nil", file.FullName, err)
+		}
+		pa := NewPolicyAgent(context.Background())
+		pa.WithStrictCompile()
+		err := pa.Compile([]*PolicyFile{file})
+		if err == nil {
+			t.Fatalf("file %s: err is nil; want error", file.FullName)
+		}
+		for _, d := range CompileDiagnostics(err) {
+			if !strings.HasPrefix(d, file.FullName+":") {
+				t.Errorf("diagnostic %q does not start with the file %s", d, file.FullName)
+			}
+		}
+	}
+}
+
 func TestParseCompiled(t *testing.T) {
 	goodPackage := "gke.policy.testOk"
 	policyContentOk := fmt.Sprintf("# METADATA\n"+
@@ -204,6 +424,31 @@ p = 1`
 	if policies[0].Name != goodPackage {
 		t.Errorf("policy[0] name = %v; want %v", policies[0].Name, goodPackage)
 	}
+	for _, err := range errors {
+		metaErr, ok := err.(*PolicyMetadataError)
+		if !ok {
+			t.Fatalf("error is not *PolicyMetadataError")
+		}
+		if metaErr.Line != 3 && metaErr.Line != 4 {
+			t.Errorf("error line = %v; want package line", metaErr.Line)
+		}
+		if metaErr.File != "folder/test_two.rego" && metaErr.File != "folder/test_three.rego" {
+			t.Errorf("error file = %v; want file with bad metadata", metaErr.File)
+		}
+	}
+}
+
+func TestPolicyMetadataError(t *testing.T) {
+	err := &PolicyMetadataError{
+		Policy: "gke.policy.test",
+		File:   "folder/test.rego",
+		Line:   4,
+		Errors: []string{"title is not set", "group is not set"},
+	}
+	expected := "folder/test.rego:4: policy gke.policy.test has metadata errors: title is not set, group is not set"
+	if err.Error() != expected {
+		t.Errorf("error = %v; want %v", err.Error(), expected)
+	}
 }
 
 func TestParseCompiled_noCompiler(t *testing.T) {
@@ -213,6 +458,46 @@ func TestParseCompiled_noCompiler(t *testing.T) {
 	}
 }
 
+func TestWithFiles_metadataErrors(t *testing.T) {
+	policyFiles := []*PolicyFile{{"meta.rego", "folder/meta.rego", "# METADATA\n" +
+		"# title: Meta\n" +
+		"# custom:\n" +
+		"#   severity: HIGHEST\n" +
+		"package gke.policy.meta\n" +
+		"default valid = false\n" +
+		"violation[msg] { msg := \"violated\" }"}}
+
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles(policyFiles); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if warnings := pa.MetadataWarnings(); len(warnings) != 1 {
+		t.Errorf("metadata warnings = %v; want one warning", warnings)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	violated := result.Violated["gke.policy"]
+	if len(violated) != 1 || violated[0].Title != "Meta" {
+		t.Errorf("violated = %v; want policy with fallback group", result.Violated)
+	}
+
+	strict := NewPolicyAgent(context.Background())
+	strict.WithStrictMetadata()
+	if err := strict.WithFiles(policyFiles); err == nil {
+		t.Errorf("strict err = nil; want error")
+	}
+}
+
+func TestSetFallbackMetadata(t *testing.T) {
+	p := &Policy{Name: "gke.policy.test"}
+	p.setFallbackMetadata("gke.policy")
+	if p.Title != "gke.policy.test" || p.Group != "gke.policy" {
+		t.Errorf("title, group = %q, %q; want %q, %q", p.Title, p.Group, "gke.policy.test", "gke.policy")
+	}
+}
+
 func TestWithFiles(t *testing.T) {
 	packageOne := regoPolicyPackage + ".package_one"
 	titleOne := "TitleOne"
@@ -260,6 +545,497 @@ func TestWithFiles(t *testing.T) {
 	}
 }
 
+func TestWithFiles_noPolicies(t *testing.T) {
+	testOnly := "package gke.policy.one_test\n" +
+		"test_p { true }\n"
+	inputs := [][]*PolicyFile{
+		{},
+		{{"one_test.rego", "folder/one_test.rego", testOnly}},
+	}
+	for i, files := range inputs {
+		pa := NewPolicyAgent(context.Background())
+		if err := pa.WithFiles(files); !errors.Is(err, ErrNoPolicies) {
+			t.Errorf("input [%d]: err = %v; want %v", i, err, ErrNoPolicies)
+		}
+		pa = NewPolicyAgent(context.Background())
+		pa.WithAllowEmpty()
+		if err := pa.WithFiles(files); err != nil {
+			t.Errorf("input [%d]: allow empty: err = %v; want nil", i, err)
+		}
+	}
+}
+
+// failingStore is a store that can not open transactions.
+type failingStore struct {
+	storage.Store
+}
+
+func (failingStore) NewTransaction(ctx context.Context, params ...storage.TransactionParams) (storage.Transaction, error) {
+	return nil, errors.New("store is not available")
+}
+
+func TestWithFiles_queryError(t *testing.T) {
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.one\n" +
+		"default valid = true\n"
+	pa := NewPolicyAgent(context.Background())
+	pa.store = failingStore{inmem.New()}
+	err := pa.WithFiles([]*PolicyFile{{"one.rego", "one.rego", policyContent}})
+	if err == nil || !strings.Contains(err.Error(), "could not validate query") || !strings.Contains(err.Error(), "store is not available") {
+		t.Errorf("err = %v; want query validation error with the store error", err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	policyTemplate := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: %s\n" +
+		"package gke.policy.%s\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.value > %d; msg := \"value too high\" }"
+	policyFiles := []*PolicyFile{
+		{"d.rego", "d.rego", fmt.Sprintf(policyTemplate, "groupTwo", "policy_d", 10)},
+		{"c.rego", "c.rego", fmt.Sprintf(policyTemplate, "groupTwo", "policy_c", 1)},
+		{"b.rego", "b.rego", fmt.Sprintf(policyTemplate, "groupOne", "policy_b", 10)},
+		{"a.rego", "a.rego", fmt.Sprintf(policyTemplate, "groupOne", "policy_a", 1)},
+	}
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles(policyFiles); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	for _, workers := range []int{1, 3, 8} {
+		result, err := pa.evaluateParallel(map[string]interface{}{"value": 5}, workers)
+		if err != nil {
+			t.Fatalf("workers = %v; error = %v; want nil", workers, err)
+		}
+		if groups := result.Groups(); !reflect.DeepEqual(groups, []string{"groupOne", "groupTwo"}) {
+			t.Errorf("workers = %v; groups = %v; want %v", workers, groups, []string{"groupOne", "groupTwo"})
+		}
+		if result.ValidCount() != 2 {
+			t.Errorf("workers = %v; validCount = %v; want %v", workers, result.ValidCount(), 2)
+		}
+		if result.ViolatedCount() != 2 {
+			t.Errorf("workers = %v; violatedCount = %v; want %v", workers, result.ViolatedCount(), 2)
+		}
+		if name := result.Violated["groupOne"][0].Name; name != regoPolicyPackage+".policy_a" {
+			t.Errorf("workers = %v; violated policy = %v; want %v", workers, name, regoPolicyPackage+".policy_a")
+		}
+		if violations := result.Violated["groupTwo"][0].Violations; !reflect.DeepEqual(violations, []string{"value too high"}) {
+			t.Errorf("workers = %v; violations = %v; want %v", workers, violations, []string{"value too high"})
+		}
+		if timings := result.Timings(); len(timings) != 4 {
+			t.Errorf("workers = %v; len(timings) = %v; want %v", workers, len(timings), 4)
+		}
+	}
+	if pa.compiled[regoPolicyPackage+".policy_a"].Valid || pa.compiled[regoPolicyPackage+".policy_a"].Violations != nil {
+		t.Errorf("compiled policy was modified by evaluation")
+	}
+}
+
+func TestEvaluate_partialErrors(t *testing.T) {
+	validPolicy := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.valid\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.value > 10; msg := \"value too high\" }"
+	conflictPolicy := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.conflict\n" +
+		"valid = true { input.value > 0 }\n" +
+		"valid = false { input.value > 1 }\n" +
+		"violation[msg] { input.value > 10; msg := \"value too high\" }"
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles([]*PolicyFile{
+		{"valid.rego", "valid.rego", validPolicy},
+		{"conflict.rego", "conflict.rego", conflictPolicy},
+	}); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{"value": 5})
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if result.ValidCount() != 1 {
+		t.Errorf("validCount = %v; want %v", result.ValidCount(), 1)
+	}
+	if result.ErroredCount() != 1 {
+		t.Fatalf("erroredCount = %v; want %v", result.ErroredCount(), 1)
+	}
+	if name := result.Errored[0].Name; name != regoPolicyPackage+".conflict" {
+		t.Errorf("errored policy = %v; want %v", name, regoPolicyPackage+".conflict")
+	}
+	if len(result.Errored[0].ProcessingErrors) != 1 {
+		t.Errorf("len(processingErrors) = %v; want %v", len(result.Errored[0].ProcessingErrors), 1)
+	}
+
+	delete(pa.compiled, regoPolicyPackage+".valid")
+	if _, err := pa.Evaluate(map[string]interface{}{"value": 5}); err == nil {
+		t.Errorf("error is nil; want error when all policies fail")
+	}
+}
+
+func TestEvaluate_autopilot(t *testing.T) {
+	policyContent := func(pkg string, appliesTo string) string {
+		return "# METADATA\n" +
+			"# title: Test\n" +
+			"# description: Test\n" +
+			"# custom:\n" +
+			"#   group: Test\n" +
+			"#   applies_to: " + appliesTo + "\n" +
+			"package gke.policy." + pkg + "\n" +
+			"default valid = false\n" +
+			"valid { count(violation) == 0 }\n" +
+			"violation[msg] { pool := input.node_pools[_]; not pool.management.auto_repair; msg := \"no auto repair\" }"
+	}
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles([]*PolicyFile{
+		{"standard.rego", "standard.rego", policyContent("standard", AppliesToStandard)},
+		{"autopilot.rego", "autopilot.rego", policyContent("autopilot", AppliesToAutopilot)},
+		{"both.rego", "both.rego", policyContent("both", AppliesToBoth)},
+	}); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	inputs := map[bool]string{true: regoPolicyPackage + ".standard", false: regoPolicyPackage + ".autopilot"}
+	for autopilot, skippedName := range inputs {
+		result, err := pa.Evaluate(map[string]interface{}{"autopilot": autopilot, "node_pools": []interface{}{}})
+		if err != nil {
+			t.Fatalf("error = %v; want nil", err)
+		}
+		if result.ValidCount() != 2 {
+			t.Errorf("autopilot %v: validCount = %v; want %v", autopilot, result.ValidCount(), 2)
+		}
+		if result.SkippedCount() != 1 {
+			t.Fatalf("autopilot %v: skippedCount = %v; want %v", autopilot, result.SkippedCount(), 1)
+		}
+		if name := result.Skipped["Test"][0].Name; name != skippedName {
+			t.Errorf("autopilot %v: skipped policy = %v; want %v", autopilot, name, skippedName)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	inputs := map[string]string{
+		"data.gke.policy":             "gke.policy",
+		"data.company.gke.results":    "company.gke.results",
+		`data["company"].gke.results`: "company.gke.results",
+	}
+	for query, expected := range inputs {
+		pkg, err := ParseQuery(query)
+		if err != nil {
+			t.Errorf("query %q: err = %v; want nil", query, err)
+		}
+		if pkg != expected {
+			t.Errorf("query %q: package = %v; want %v", query, pkg, expected)
+		}
+	}
+	for _, query := range []string{"data", "input.gke", "data.gke[x]", "data.gke[1]", "data.gke."} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("query %q: err is nil; want error", query)
+		}
+	}
+}
+
+func TestWithQuery(t *testing.T) {
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package company.gke.results.policy_a\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.value > 1; msg := \"value too high\" }\n"
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithQuery("data.company.gke.results"); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.WithFiles([]*PolicyFile{{"a.rego", "a.rego", policyContent}}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{"value": 5})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if result.ViolatedCount() != 1 {
+		t.Errorf("violatedCount = %v; want %v", result.ViolatedCount(), 1)
+	}
+
+	inputs := map[string]string{
+		"data.company.gke.missing":                "is undefined",
+		"data.company.gke.results.policy_a.valid": "resolves to boolean",
+	}
+	for query, expected := range inputs {
+		pa := NewPolicyAgent(context.Background())
+		if err := pa.WithQuery(query); err != nil {
+			t.Fatalf("query %q: err = %v; want nil", query, err)
+		}
+		err := pa.WithFiles([]*PolicyFile{{"a.rego", "a.rego", policyContent}})
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("query %q: err = %v; want error containing %q", query, err, expected)
+		}
+	}
+}
+
+func TestEvaluate_cancelled(t *testing.T) {
+	policyFiles := []*PolicyFile{
+		{"a.rego", "a.rego", "# METADATA\n" +
+			"# title: Test\n" +
+			"# description: Test\n" +
+			"# custom:\n" +
+			"#   group: Test\n" +
+			"package gke.policy.policy_a\n" +
+			"valid = true\n"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pa := NewPolicyAgent(ctx)
+	if err := pa.WithFiles(policyFiles); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	cancel()
+	if _, err := pa.Evaluate(map[string]interface{}{}); err == nil {
+		t.Errorf("error is nil; want error")
+	}
+}
+
+func TestEvaluate_failFast(t *testing.T) {
+	policyTemplate := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"#   enforcement: %s\n" +
+		"package gke.policy.%s\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] { input.value > %d; msg := \"value too high\" }"
+	policyFiles := make([]*PolicyFile, 0)
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("valid_%d", i)
+		policyFiles = append(policyFiles, &PolicyFile{name + ".rego", name + ".rego", fmt.Sprintf(policyTemplate, "enforce", name, 10)})
+	}
+	policyFiles = append(policyFiles,
+		&PolicyFile{"warned.rego", "warned.rego", fmt.Sprintf(policyTemplate, "warn", "warned", 1)},
+		&PolicyFile{"violated.rego", "violated.rego", fmt.Sprintf(policyTemplate, "enforce", "violated", 1)})
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles(policyFiles); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	pa.WithFailFast(nil)
+	goroutines := runtime.NumGoroutine()
+	for _, workers := range []int{1, 4} {
+		result, err := pa.evaluateParallel(map[string]interface{}{"value": 5}, workers)
+		if err != nil {
+			t.Fatalf("workers = %v; error = %v; want nil", workers, err)
+		}
+		if result.ViolatedCount() != 1 || result.ValidCount() != 0 || result.WarnedCount() != 0 {
+			t.Errorf("workers = %v; valid, violated, warned = %v, %v, %v; want 0, 1, 0",
+				workers, result.ValidCount(), result.ViolatedCount(), result.WarnedCount())
+		}
+		if name := result.Violated["Test"][0].Name; name != regoPolicyPackage+".violated" {
+			t.Errorf("workers = %v; violated policy = %v; want %v", workers, name, regoPolicyPackage+".violated")
+		}
+	}
+	result, err := pa.evaluateParallel(map[string]interface{}{"value": 0}, 4)
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if result.ValidCount() != 22 {
+		t.Errorf("validCount = %v; want %v", result.ValidCount(), 22)
+	}
+	for i := 0; runtime.NumGoroutine() > goroutines && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runtime.NumGoroutine() > goroutines {
+		t.Errorf("goroutines = %v; want at most %v", runtime.NumGoroutine(), goroutines)
+	}
+	pa.WithFailFast(func(policy *Policy) bool { return false })
+	result, err = pa.evaluateParallel(map[string]interface{}{"value": 5}, 4)
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if result.ValidCount() != 20 || result.ViolatedCount() != 1 || result.WarnedCount() != 1 {
+		t.Errorf("fails never: valid, violated, warned = %v, %v, %v; want 20, 1, 1",
+			result.ValidCount(), result.ViolatedCount(), result.WarnedCount())
+	}
+	compiledPa := NewPolicyAgent(context.Background())
+	if err := compiledPa.Compile(policyFiles); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	compiledPa.WithFailFast(nil)
+	result, err = compiledPa.Evaluate(map[string]interface{}{"value": 5})
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if result.ViolatedCount() != 1 || result.ValidCount() != 0 || result.WarnedCount() != 0 {
+		t.Errorf("compiled: valid, violated, warned = %v, %v, %v; want 0, 1, 0",
+			result.ValidCount(), result.ViolatedCount(), result.WarnedCount())
+	}
+}
+
+func TestFilterPolicies(t *testing.T) {
+	names := []string{"gke.policy.control_plane_access", "gke.policy.control_plane_endpoint", "gke.policy.private_cluster"}
+	inputs := []struct {
+		include  string
+		exclude  string
+		expected int
+	}{
+		{"", "", 3},
+		{"control_plane", "", 2},
+		{"", "endpoint$", 2},
+		{"control_plane", "endpoint$", 1},
+		{"control_plane", "control_plane", 0},
+	}
+	for _, input := range inputs {
+		pa := NewPolicyAgent(context.Background())
+		for _, name := range names {
+			pa.compiled[name] = &Policy{Name: name}
+		}
+		var include, exclude *regexp.Regexp
+		if input.include != "" {
+			include = regexp.MustCompile(input.include)
+		}
+		if input.exclude != "" {
+			exclude = regexp.MustCompile(input.exclude)
+		}
+		if cnt := pa.FilterPolicies(include, exclude); cnt != input.expected {
+			t.Errorf("include %q exclude %q: cnt = %v; want %v", input.include, input.exclude, cnt, input.expected)
+		}
+	}
+}
+
+func TestMatchesTags(t *testing.T) {
+	inputs := []struct {
+		policyTags []string
+		tags       []string
+		matchAll   bool
+		expected   bool
+	}{
+		{[]string{"iam", "networking"}, []string{"networking", "iam"}, true, true},
+		{[]string{"iam"}, []string{"networking", "iam"}, true, false},
+		{[]string{"iam"}, []string{"networking", "iam"}, false, true},
+		{[]string{"encryption"}, []string{"networking", "iam"}, false, false},
+		{nil, []string{"iam"}, false, false},
+		{nil, nil, true, true},
+	}
+	for i, input := range inputs {
+		if result := MatchesTags(input.policyTags, input.tags, input.matchAll); result != input.expected {
+			t.Errorf("input [%d]: result = %v; want %v", i, result, input.expected)
+		}
+	}
+}
+
+func TestFilterPoliciesByTags(t *testing.T) {
+	pa := NewPolicyAgent(context.Background())
+	pa.compiled["gke.policy.one"] = &Policy{Name: "gke.policy.one", Tags: []string{"iam", "networking"}}
+	pa.compiled["gke.policy.two"] = &Policy{Name: "gke.policy.two", Tags: []string{"networking"}}
+	pa.compiled["gke.policy.three"] = &Policy{Name: "gke.policy.three"}
+	if cnt := pa.FilterPoliciesByTags([]string{"networking"}, true); cnt != 2 {
+		t.Errorf("cnt = %v; want %v", cnt, 2)
+	}
+	if cnt := pa.FilterPoliciesByTags([]string{"iam", "networking"}, true); cnt != 1 {
+		t.Errorf("cnt = %v; want %v", cnt, 1)
+	}
+}
+
+func TestFilterDeprecated(t *testing.T) {
+	pa := NewPolicyAgent(context.Background())
+	pa.compiled["gke.policy.one"] = &Policy{Name: "gke.policy.one", Deprecated: true}
+	pa.compiled["gke.policy.two"] = &Policy{Name: "gke.policy.two"}
+	pa.compiled["gke.policy.three"] = &Policy{Name: "gke.policy.three", Deprecated: true}
+	deprecated := pa.DeprecatedPolicies()
+	if len(deprecated) != 2 || deprecated[0].Name != "gke.policy.one" || deprecated[1].Name != "gke.policy.three" {
+		t.Errorf("deprecated policies = %v; want [gke.policy.one gke.policy.three]", deprecated)
+	}
+	if cnt := pa.FilterDeprecated(); cnt != 1 {
+		t.Errorf("cnt = %v; want %v", cnt, 1)
+	}
+	if _, ok := pa.compiled["gke.policy.two"]; !ok {
+		t.Errorf("policy gke.policy.two was filtered out")
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	pa := NewPolicyAgent(context.Background())
+	pa.compiled["gke.policy.two"] = &Policy{Name: "gke.policy.two"}
+	pa.compiled["gke.policy.one"] = &Policy{Name: "gke.policy.one"}
+	policies := pa.Policies()
+	if len(policies) != 2 || policies[0].Name != "gke.policy.one" || policies[1].Name != "gke.policy.two" {
+		t.Errorf("policies = %v; want [gke.policy.one gke.policy.two]", policies)
+	}
+}
+
+func TestOverrideSeverities(t *testing.T) {
+	pa := NewPolicyAgent(context.Background())
+	pa.compiled["gke.policy.one"] = &Policy{Name: "gke.policy.one", Severity: SeverityLow}
+	pa.compiled["gke.policy.two"] = &Policy{Name: "gke.policy.two", Severity: SeverityLow}
+	pa.compiled["gke.policy.three"] = &Policy{Name: "gke.policy.three", Severity: SeverityLow}
+	unknown := pa.OverrideSeverities(map[string]string{
+		"gke.policy.one": SeverityCritical,
+		"two":            SeverityHigh,
+		"missing":        SeverityHigh,
+		"gke.policy.bad": SeverityHigh,
+	})
+	if !reflect.DeepEqual(unknown, []string{"gke.policy.bad", "missing"}) {
+		t.Errorf("unknown = %v; want [gke.policy.bad missing]", unknown)
+	}
+	expected := map[string]string{
+		"gke.policy.one":   SeverityCritical,
+		"gke.policy.two":   SeverityHigh,
+		"gke.policy.three": SeverityLow,
+	}
+	for name, severity := range expected {
+		if pa.compiled[name].Severity != severity {
+			t.Errorf("policy %s severity = %v; want %v", name, pa.compiled[name].Severity, severity)
+		}
+	}
+}
+
+func TestTimings(t *testing.T) {
+	result := NewPolicyEvaluationResult()
+	result.AddPolicy(&Policy{Name: "valid", Group: "A", Valid: true, EvaluationTime: 2 * time.Millisecond})
+	result.AddPolicy(&Policy{Name: "violated", Group: "B", Violations: []string{"v"}, EvaluationTime: 5 * time.Millisecond})
+	result.AddPolicy(&Policy{Name: "errored", ProcessingErrors: []error{errors.New("e")}, EvaluationTime: 3 * time.Millisecond})
+	result.AddPolicy(&Policy{Name: "untimed", Group: "A", Valid: true})
+	expected := []*PolicyTiming{
+		{Policy: "violated", Group: "B", Duration: 5 * time.Millisecond},
+		{Policy: "errored", Duration: 3 * time.Millisecond},
+		{Policy: "valid", Group: "A", Duration: 2 * time.Millisecond},
+	}
+	if timings := result.Timings(); !reflect.DeepEqual(timings, expected) {
+		t.Errorf("timings = %v; want %v", timings, expected)
+	}
+}
+
+func TestSortPolicies(t *testing.T) {
+	r := NewPolicyEvaluationResult()
+	for _, name := range []string{"c", "a", "b"} {
+		r.AddPolicy(&Policy{Name: name, Group: "group", Valid: true})
+		r.AddPolicy(&Policy{Name: name, ProcessingErrors: []error{fmt.Errorf("error")}})
+	}
+	r.SortPolicies()
+	for i, name := range []string{"a", "b", "c"} {
+		if r.Valid["group"][i].Name != name {
+			t.Errorf("valid policy [%d] = %v; want %v", i, r.Valid["group"][i].Name, name)
+		}
+		if r.Errored[i].Name != name {
+			t.Errorf("errored policy [%d] = %v; want %v", i, r.Errored[i].Name, name)
+		}
+	}
+}
+
 func TestProcessRegoResultSet(t *testing.T) {
 	policyOneCompiled := &Policy{
 		Name:        regoPolicyPackage + ".policy_one",
@@ -334,6 +1110,34 @@ func TestProcessRegoResultSet(t *testing.T) {
 	if len(result.Errored) != 1 {
 		t.Fatalf("number of errored policies = %v; want %v", len(result.Errored), 1)
 	}
+	if violated := result.Violated["policy_two"]; len(violated) != 1 || violated[0].Title != policyTwoCompiled.Title {
+		t.Errorf("violated policies = %v; want one with title %q", violated, policyTwoCompiled.Title)
+	}
+}
+
+func TestProcessRegoResultSet_notCompiled(t *testing.T) {
+	resultSet := []rego.Result{{
+		Expressions: []*rego.ExpressionValue{{Value: map[string]interface{}{"valid": true, "violation": []interface{}{}}}},
+		Bindings:    map[string]interface{}{"name": "policy_one"},
+	}}
+	pa := PolicyAgent{}
+	result, err := pa.processRegoResultSet(resultSet)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	valid := result.Valid[""]
+	if len(valid) != 1 || valid[0].Label() != regoPolicyPackage+".policy_one" {
+		t.Errorf("valid policies = %v; want one labeled %q", valid, regoPolicyPackage+".policy_one")
+	}
+}
+
+func TestPolicyLabel(t *testing.T) {
+	if label := (&Policy{Name: "gke.policy.a", Title: "Policy A"}).Label(); label != "Policy A" {
+		t.Errorf("label = %q; want %q", label, "Policy A")
+	}
+	if label := (&Policy{Name: "gke.policy.a"}).Label(); label != "gke.policy.a" {
+		t.Errorf("label = %q; want %q", label, "gke.policy.a")
+	}
 }
 
 func TestGetResultDataForEval(t *testing.T) {
@@ -402,7 +1206,7 @@ func TestMapExpressionValue(t *testing.T) {
 		"violation": []interface{}{"violation"},
 	}
 	expectedValid := true
-	expectedViolations := []string{"violation"}
+	expectedViolations := []*Violation{{Message: "violation"}}
 
 	result := RegoEvaluationResult{}
 	if err := result.mapExpressionValue(input); err != nil {
@@ -422,7 +1226,7 @@ func TestParseRegoPolicyData(t *testing.T) {
 		"violation": []interface{}{"violation"},
 	}
 	expectedValid := true
-	expectedViolations := []string{"violation"}
+	expectedViolations := []*Violation{{Message: "violation"}}
 
 	valid, violations, err := parseRegoPolicyData(input)
 	if err != nil {
@@ -473,11 +1277,219 @@ func TestMapModule(t *testing.T) {
 	if policy.Group != group {
 		t.Errorf("group = %v; want %v", policy.Group, group)
 	}
+	if policy.Enforcement != EnforcementEnforce {
+		t.Errorf("enforcement = %v; want %v", policy.Enforcement, EnforcementEnforce)
+	}
+	if policy.Severity != DefaultSeverity {
+		t.Errorf("severity = %v; want %v", policy.Severity, DefaultSeverity)
+	}
+}
+
+func TestMapModule_envVars(t *testing.T) {
+	t.Setenv("GKE_REVIEW_TEST_ENV", "prod")
+	file := "folder/test_one.rego"
+	content := "# METADATA\n" +
+		"# title: Costs $5 in ${GKE_REVIEW_TEST_ENV}\n" +
+		"# custom:\n" +
+		"#   group: ${GKE_REVIEW_TEST_ENV}-networking-${GKE_REVIEW_TEST_UNSET}\n" +
+		"package gke.policy.test\n" +
+		"p = 1"
+	compiler := ast.MustCompileModulesWithOpts(map[string]string{file: content},
+		ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
+	policy := Policy{}
+	policy.MapModule(compiler.Modules[file])
+
+	if expected := "Costs $5 in prod"; policy.Title != expected {
+		t.Errorf("title = %v; want %v", policy.Title, expected)
+	}
+	if expected := "prod-networking-${GKE_REVIEW_TEST_UNSET}"; policy.Group != expected {
+		t.Errorf("group = %v; want %v", policy.Group, expected)
+	}
+}
+
+func TestMapModule_enforcement(t *testing.T) {
+	file := "folder/test_one.rego"
+	content := "# METADATA\n" +
+		"# title: Title\n" +
+		"# description: Description\n" +
+		"# custom:\n" +
+		"#   group: TestGroup\n" +
+		"#   enforcement: Warn\n" +
+		"#   severity: critical\n" +
+		"package gke.policy.test\n" +
+		"p = 1"
+	modules := map[string]string{file: content}
+	compiler := ast.MustCompileModulesWithOpts(modules,
+		ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
+	policy := Policy{}
+	policy.MapModule(compiler.Modules[file])
+	if policy.Enforcement != EnforcementWarn {
+		t.Errorf("enforcement = %v; want %v", policy.Enforcement, EnforcementWarn)
+	}
+	if policy.Severity != SeverityCritical {
+		t.Errorf("severity = %v; want %v", policy.Severity, SeverityCritical)
+	}
+	if policy.AppliesTo != AppliesToBoth {
+		t.Errorf("appliesTo = %v; want %v", policy.AppliesTo, AppliesToBoth)
+	}
+}
+
+func TestMapModule_tags(t *testing.T) {
+	file := "folder/test_one.rego"
+	content := "# METADATA\n" +
+		"# title: Title\n" +
+		"# description: Description\n" +
+		"# custom:\n" +
+		"#   group: TestGroup\n" +
+		"#   tags:\n" +
+		"#   - Networking\n" +
+		"#   - iam\n" +
+		"package gke.policy.test\n" +
+		"p = 1"
+	modules := map[string]string{file: content}
+	compiler := ast.MustCompileModulesWithOpts(modules,
+		ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
+	policy := Policy{}
+	policy.MapModule(compiler.Modules[file])
+	if expected := []string{"networking", "iam"}; !reflect.DeepEqual(policy.Tags, expected) {
+		t.Errorf("tags = %v; want %v", policy.Tags, expected)
+	}
+}
+
+func TestMapModule_appliesTo(t *testing.T) {
+	file := "folder/test_one.rego"
+	content := "# METADATA\n" +
+		"# title: Title\n" +
+		"# description: Description\n" +
+		"# custom:\n" +
+		"#   group: TestGroup\n" +
+		"#   applies_to: Standard\n" +
+		"package gke.policy.test\n" +
+		"p = 1"
+	modules := map[string]string{file: content}
+	compiler := ast.MustCompileModulesWithOpts(modules,
+		ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
+	policy := Policy{}
+	policy.MapModule(compiler.Modules[file])
+	if policy.AppliesTo != AppliesToStandard {
+		t.Errorf("appliesTo = %v; want %v", policy.AppliesTo, AppliesToStandard)
+	}
+	if !policy.AppliesToCluster(false) || policy.AppliesToCluster(true) {
+		t.Errorf("standard policy applies to autopilot cluster or does not apply to standard cluster")
+	}
+}
+
+func TestMapModule_remediation(t *testing.T) {
+	file := "folder/test_one.rego"
+	content := "# METADATA\n" +
+		"# title: Title\n" +
+		"# description: Description\n" +
+		"# custom:\n" +
+		"#   group: TestGroup\n" +
+		"#   remediation: Enable private nodes\n" +
+		"package gke.policy.test\n" +
+		"p = 1"
+	modules := map[string]string{file: content}
+	compiler := ast.MustCompileModulesWithOpts(modules,
+		ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
+	policy := Policy{}
+	policy.MapModule(compiler.Modules[file])
+	if policy.Remediation != "Enable private nodes" {
+		t.Errorf("remediation = %v; want %v", policy.Remediation, "Enable private nodes")
+	}
+	if errs := policy.MetadataErrors(); len(errs) != 0 {
+		t.Errorf("metadata errors = %v; want none", errs)
+	}
+}
+
+func TestMapModule_deprecated(t *testing.T) {
+	file := "folder/test_one.rego"
+	content := "# METADATA\n" +
+		"# title: Title\n" +
+		"# description: Description\n" +
+		"# custom:\n" +
+		"#   group: TestGroup\n" +
+		"#   deprecated: true\n" +
+		"#   deprecated_message: Replaced by gke.policy.other\n" +
+		"package gke.policy.test\n" +
+		"p = 1"
+	modules := map[string]string{file: content}
+	compiler := ast.MustCompileModulesWithOpts(modules,
+		ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
+	policy := Policy{}
+	policy.MapModule(compiler.Modules[file])
+	if !policy.Deprecated {
+		t.Errorf("deprecated = %v; want %v", policy.Deprecated, true)
+	}
+	if policy.DeprecatedMessage != "Replaced by gke.policy.other" {
+		t.Errorf("deprecated message = %v; want %v", policy.DeprecatedMessage, "Replaced by gke.policy.other")
+	}
+}
+
+func TestMapModule_cis(t *testing.T) {
+	inputs := map[string][]string{
+		"#   cis: \"5.6.3\"\n":                 {"5.6.3"},
+		"#   cis: 5.6\n":                       {"5.6"},
+		"#   cis:\n#   - 5.6.3\n#   - 5.6.4\n": {"5.6.3", "5.6.4"},
+	}
+	for cis, expected := range inputs {
+		file := "folder/test_one.rego"
+		content := "# METADATA\n" +
+			"# title: Title\n" +
+			"# description: Description\n" +
+			"# custom:\n" +
+			"#   group: TestGroup\n" +
+			cis +
+			"package gke.policy.test\n" +
+			"p = 1"
+		modules := map[string]string{file: content}
+		compiler := ast.MustCompileModulesWithOpts(modules,
+			ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
+		policy := Policy{}
+		policy.MapModule(compiler.Modules[file])
+		if !reflect.DeepEqual(policy.CisControls, expected) {
+			t.Errorf("cisControls = %v; want %v", policy.CisControls, expected)
+		}
+	}
+}
+
+func TestMapModule_references(t *testing.T) {
+	inputs := map[string][]string{
+		"":                                      nil,
+		"#   references: https://example.com\n": {"https://example.com"},
+		"#   references:\n#   - https://example.com/one\n#   - not a url\n#   - https://example.com/two\n": {
+			"https://example.com/one", "https://example.com/two"},
+	}
+	for references, expected := range inputs {
+		file := "folder/test_one.rego"
+		content := "# METADATA\n" +
+			"# title: Title\n" +
+			"# description: Description\n" +
+			"# custom:\n" +
+			"#   group: TestGroup\n" +
+			references +
+			"package gke.policy.test\n" +
+			"p = 1"
+		modules := map[string]string{file: content}
+		compiler := ast.MustCompileModulesWithOpts(modules,
+			ast.CompileOpts{ParserOptions: ast.ParserOptions{ProcessAnnotation: true}})
+		policy := Policy{}
+		policy.MapModule(compiler.Modules[file])
+		if !reflect.DeepEqual(policy.References, expected) {
+			t.Errorf("references = %v; want %v", policy.References, expected)
+		}
+		if errs := policy.MetadataErrors(); len(errs) > 0 {
+			t.Errorf("metadata errors = %v; want none", errs)
+		}
+	}
 }
 
 func TestMetadataErrors(t *testing.T) {
 	input := []Policy{
 		{Title: "title", Description: "description", Group: "group"},
+		{Title: "title", Description: "description", Group: "group", Enforcement: "block"},
+		{Title: "title", Description: "description", Group: "group", Severity: "URGENT"},
+		{Title: "title", Description: "description", Group: "group", AppliesTo: "gke"},
 		{Title: "title", Description: "description"},
 		{Title: "title"},
 		{},
@@ -485,6 +1497,9 @@ func TestMetadataErrors(t *testing.T) {
 	expErrCnt := []int{
 		0,
 		1,
+		1,
+		1,
+		1,
 		2,
 		3,
 	}
@@ -496,55 +1511,238 @@ func TestMetadataErrors(t *testing.T) {
 	}
 }
 
-func TestGetBoolFromInterfaceMap(t *testing.T) {
-	inputName := "test"
-	inputMap := map[string]interface{}{"test": true}
-	expected := true
+func TestEvaluate_stableOrder(t *testing.T) {
+	files := make([]*PolicyFile, 0)
+	for _, name := range []string{"e", "b", "d", "a", "c", "f"} {
+		group := "GroupTwo"
+		if name < "c" {
+			group = "GroupOne"
+		}
+		content := fmt.Sprintf("# METADATA\n"+
+			"# title: %s\n"+
+			"# description: Test\n"+
+			"# custom:\n"+
+			"#   group: %s\n"+
+			"package gke.policy.%s\n"+
+			"default valid = false\n"+
+			"violation[msg] { msg := \"violated\" }", name, group, name)
+		files = append(files, &PolicyFile{name + ".rego", name + ".rego", content})
+	}
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles(files); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	order := func(r *PolicyEvaluationResult) []string {
+		names := make([]string, 0)
+		for _, group := range r.Groups() {
+			names = append(names, group)
+			for _, p := range r.Violated[group] {
+				names = append(names, p.Name)
+			}
+		}
+		return names
+	}
+	expected := []string{"GroupOne", "gke.policy.a", "gke.policy.b",
+		"GroupTwo", "gke.policy.c", "gke.policy.d", "gke.policy.e", "gke.policy.f"}
+	for i := 0; i < 10; i++ {
+		result, err := pa.Evaluate(map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if names := order(result); !reflect.DeepEqual(names, expected) {
+			t.Fatalf("run %d: order = %v; want %v", i, names, expected)
+		}
+		result.FilterBySeverity(SeverityHigh)
+		filtered := result.Filtered["GroupTwo"]
+		for j := 1; j < len(filtered); j++ {
+			if filtered[j-1].Name > filtered[j].Name {
+				t.Fatalf("run %d: filtered policies are not sorted: %v", i, filtered)
+			}
+		}
+	}
+}
 
-	result, err := getBoolFromInterfaceMap(inputName, inputMap)
-	if err != nil {
-		t.Errorf("err = %q; want nil", err)
+func TestMerge(t *testing.T) {
+	one := NewPolicyEvaluationResult()
+	one.ClusterName = "one"
+	one.AddPolicy(&Policy{Name: "gke.policy.a", Group: "Security", Valid: true})
+	one.AddPolicy(&Policy{Name: "gke.policy.b", Group: "Security", Violations: []string{"v"}})
+	two := NewPolicyEvaluationResult()
+	two.ClusterName = "two"
+	two.AddPolicy(&Policy{Name: "gke.policy.a", Group: "Security", Valid: true})
+	two.AddPolicy(&Policy{Name: "gke.policy.c", Group: "Availability", Violations: []string{"v"}})
+	two.AddPolicy(&Policy{Name: "gke.policy.d", Group: "Availability", ProcessingErrors: []error{errors.New("error")}})
+
+	one.Merge(two)
+	if one.ClusterName != "one" {
+		t.Errorf("cluster name = %q; want %q", one.ClusterName, "one")
+	}
+	if one.ValidCount() != 2 || one.ViolatedCount() != 2 || one.ErroredCount() != 1 {
+		t.Errorf("valid, violated, errored = %d, %d, %d; want 2, 2, 1", one.ValidCount(), one.ViolatedCount(), one.ErroredCount())
+	}
+	if groups := one.Groups(); !reflect.DeepEqual(groups, []string{"Availability", "Security"}) {
+		t.Errorf("groups = %v; want %v", groups, []string{"Availability", "Security"})
 	}
-	if result != expected {
-		t.Errorf("result = %v; want %v", result, expected)
+	if valid := one.Valid["Security"]; len(valid) != 2 || valid[0].Name != "gke.policy.a" || valid[1].Name != "gke.policy.a" {
+		t.Errorf("valid security policies = %v; want gke.policy.a twice", valid)
+	}
+	if two.ValidCount() != 1 || len(two.Violated["Security"]) != 0 {
+		t.Errorf("other result was modified")
 	}
 }
 
-func TestGetBoolFromInterfaceMap_negative(t *testing.T) {
-	inputNames := []string{"testTwo", "missing"}
-	inputMaps := []map[string]interface{}{{"testTwo": 101}, nil}
-	for i := range inputNames {
-		_, err := getBoolFromInterfaceMap(inputNames[i], inputMaps[i])
-		if err == nil {
-			t.Errorf("err = nil; want error")
-		}
+func TestMerge_nilMaps(t *testing.T) {
+	result := &PolicyEvaluationResult{}
+	other := NewPolicyEvaluationResult()
+	other.ClusterName = "other"
+	other.AddPolicy(&Policy{Name: "gke.policy.a", Group: "Security", Violations: []string{"v"}})
+	result.Merge(other)
+	result.Merge(&PolicyEvaluationResult{})
+	result.Merge(nil)
+	if result.ClusterName != "other" {
+		t.Errorf("cluster name = %q; want %q", result.ClusterName, "other")
+	}
+	if result.ViolatedCount() != 1 || result.ValidCount() != 0 {
+		t.Errorf("violated, valid = %d, %d; want 1, 0", result.ViolatedCount(), result.ValidCount())
 	}
 }
 
-func TestGetStringListFromInterfaceMap(t *testing.T) {
-	inputName := "test"
-	inputMap := map[string]interface{}{"test": []interface{}{"str1", "str2"}}
-	expected := []string{"str1", "str2"}
+func TestMerge_concurrent(t *testing.T) {
+	shared := NewPolicyEvaluationResult()
+	shared.AddPolicy(&Policy{Name: "gke.policy.shared", Group: "Security", Valid: true})
+	result := NewPolicyEvaluationResult()
+	others := make([]*PolicyEvaluationResult, 20)
+	for i := range others {
+		others[i] = NewPolicyEvaluationResult()
+		others[i].AddPolicy(&Policy{Name: fmt.Sprintf("gke.policy.p%d", i), Group: "Security", Violations: []string{"v"}})
+	}
+	var wg sync.WaitGroup
+	for _, other := range others {
+		wg.Add(1)
+		go func(other *PolicyEvaluationResult) {
+			defer wg.Done()
+			other.Merge(shared)
+			result.Merge(other)
+		}(other)
+	}
+	wg.Wait()
+	if result.ViolatedCount() != len(others) || result.ValidCount() != len(others) {
+		t.Errorf("violated, valid = %d, %d; want %d, %d", result.ViolatedCount(), result.ValidCount(), len(others), len(others))
+	}
+	if shared.ValidCount() != 1 {
+		t.Errorf("shared valid = %d; want 1", shared.ValidCount())
+	}
+}
+
+func TestGroupBySeverity(t *testing.T) {
+	result := NewPolicyEvaluationResult()
+	result.ClusterName = "cluster"
+	result.AddPolicy(&Policy{Name: "gke.policy.b", Group: "Security", Severity: SeverityHigh, Violations: []string{"v"}})
+	result.AddPolicy(&Policy{Name: "gke.policy.a", Group: "Availability", Severity: SeverityHigh, Violations: []string{"v"}})
+	result.AddPolicy(&Policy{Name: "gke.policy.c", Group: "Security", Severity: SeverityCritical, Violations: []string{"v"}})
+	result.AddPolicy(&Policy{Name: "gke.policy.d", Group: "Security", Valid: true})
+	result.AddPolicy(&Policy{Name: "gke.policy.e", Group: "Security", Severity: SeverityLow, Valid: true})
+	result.AddPolicy(&Policy{Name: "gke.policy.f", Group: "Security", ProcessingErrors: []error{errors.New("err")}})
 
-	result, err := getStringListFromInterfaceMap(inputName, inputMap)
+	regrouped := result.GroupBySeverity()
+	if !regrouped.IsGroupedBySeverity() || result.IsGroupedBySeverity() {
+		t.Errorf("IsGroupedBySeverity = %v, %v; want true, false", regrouped.IsGroupedBySeverity(), result.IsGroupedBySeverity())
+	}
+	expectedGroups := []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow}
+	if groups := regrouped.Groups(); !reflect.DeepEqual(groups, expectedGroups) {
+		t.Errorf("groups = %v; want %v", groups, expectedGroups)
+	}
+	high := regrouped.Violated[SeverityHigh]
+	if len(high) != 2 || high[0].Name != "gke.policy.a" || high[1].Name != "gke.policy.b" {
+		t.Errorf("high severity policies = %v; want gke.policy.a and gke.policy.b", high)
+	}
+	if regrouped.Valid[SeverityMedium][0].Name != "gke.policy.d" {
+		t.Errorf("medium severity valid policy = %v; want gke.policy.d", regrouped.Valid[SeverityMedium][0].Name)
+	}
+	if regrouped.ValidCount() != result.ValidCount() || regrouped.ViolatedCount() != result.ViolatedCount() ||
+		regrouped.ErroredCount() != result.ErroredCount() {
+		t.Errorf("counts of regrouped result differ from the original result")
+	}
+	if group := regrouped.GroupOf(result.Errored[0]); group != SeverityMedium {
+		t.Errorf("group of errored policy = %v; want %v", group, SeverityMedium)
+	}
+	if regrouped.ClusterName != result.ClusterName {
+		t.Errorf("cluster name = %v; want %v", regrouped.ClusterName, result.ClusterName)
+	}
+}
+
+func TestEvaluate_coverage(t *testing.T) {
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.coverage\n" +
+		"default valid = false\n" +
+		"valid { count(violation) == 0 }\n" +
+		"violation[msg] {\n" +
+		"  input.value > 10\n" +
+		"  msg := \"value too high\"\n" +
+		"}\n"
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles([]*PolicyFile{{"coverage.rego", "coverage.rego", policyContent}}); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{"value": 5})
 	if err != nil {
-		t.Errorf("err = %q; want nil", err)
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if result.Coverage != nil {
+		t.Errorf("coverage = %v; want nil when not enabled", result.Coverage)
+	}
+
+	pa.WithCoverage()
+	low, err := pa.Evaluate(map[string]interface{}{"value": 5})
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	high, err := pa.Evaluate(map[string]interface{}{"value": 15})
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if low.Coverage == nil || len(low.Coverage.Files) != 1 || low.Coverage.Files[0].File != "coverage.rego" {
+		t.Fatalf("coverage = %+v; want coverage of coverage.rego", low.Coverage)
+	}
+	if low.Coverage.Files[0].NotCoveredLines == 0 {
+		t.Errorf("not covered lines = 0; want message line not covered")
 	}
-	if !reflect.DeepEqual(result, expected) {
-		t.Errorf("result = %v; want %v", result, expected)
+	if high.Coverage.Coverage <= low.Coverage.Coverage {
+		t.Errorf("coverage = %v; want more than %v", high.Coverage.Coverage, low.Coverage.Coverage)
 	}
 }
 
-func TestGetStringListFromInterfaceMap_negative(t *testing.T) {
-	inputNames := []string{"testTwo", "testThree", "missing"}
-	inputMaps := []map[string]interface{}{
-		{"testTwo": nil},
-		{"testThree": []interface{}{"str1", 100}},
-		nil}
-	for i := range inputNames {
-		_, err := getStringListFromInterfaceMap(inputNames[i], inputMaps[i])
-		if err == nil {
-			t.Errorf("err = nil; want error")
-		}
+func TestEvaluate_prints(t *testing.T) {
+	policyContent := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n" +
+		"package gke.policy.prints\n" +
+		"default valid = false\n" +
+		"valid {\n" +
+		"  print(\"value\", input.value)\n" +
+		"  input.value < 10\n" +
+		"}\n"
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles([]*PolicyFile{{"prints.rego", "prints.rego", policyContent}}); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{"value": 5})
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if result.ValidCount() != 1 {
+		t.Fatalf("validCount = %v; want %v", result.ValidCount(), 1)
+	}
+	if prints := result.Valid["Test"][0].Prints; !reflect.DeepEqual(prints, []string{"value 5"}) {
+		t.Errorf("prints = %v; want %v", prints, []string{"value 5"})
+	}
+	if pa.compiled[regoPolicyPackage+".prints"].Prints != nil {
+		t.Errorf("compiled policy was modified by evaluation")
 	}
 }
@@ -0,0 +1,49 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"sync"
+
+	"github.com/mikouaj/gke-review/internal/log"
+	"github.com/open-policy-agent/opa/topdown/print"
+)
+
+// printHook captures output of rego print() calls of a single policy. Output is logged at
+// the debug level, prefixed with the policy name, and kept so it can be reported with the policy.
+type printHook struct {
+	policy string
+	mu     sync.Mutex
+	prints []string
+}
+
+func newPrintHook(policy string) *printHook {
+	return &printHook{policy: policy}
+}
+
+func (h *printHook) Print(ctx print.Context, msg string) error {
+	log.Debugf("policy %s: print: %s", h.policy, msg)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prints = append(h.prints, msg)
+	return nil
+}
+
+// messages returns output of print() calls captured so far.
+func (h *printHook) messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.prints...)
+}
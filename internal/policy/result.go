@@ -0,0 +1,268 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ResultSchemaVersion is the version of the saved evaluation result format. It is increased
+// on incompatible changes, so results saved by older versions are rejected.
+const ResultSchemaVersion = 1
+
+type resultEnvelope struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Result        json.RawMessage `json:"result"`
+}
+
+type savedResult struct {
+	ClusterName  string                    `json:"clusterName"`
+	ClusterError string                    `json:"clusterError,omitempty"`
+	BySeverity   bool                      `json:"bySeverity,omitempty"`
+	Valid        map[string][]*savedPolicy `json:"valid"`
+	Violated     map[string][]*savedPolicy `json:"violated"`
+	Warned       map[string][]*savedPolicy `json:"warned"`
+	Audited      map[string][]*savedPolicy `json:"audited"`
+	Filtered     map[string][]*savedPolicy `json:"filtered"`
+	Waived       map[string][]*savedPolicy `json:"waived"`
+	Suppressed   map[string][]*savedPolicy `json:"suppressed"`
+	Skipped      map[string][]*savedPolicy `json:"skipped"`
+	Errored      []*savedPolicy            `json:"errored"`
+	Coverage     *PolicyCoverage           `json:"coverage,omitempty"`
+
+	ClusterLabels map[string]string `json:"clusterLabels,omitempty"`
+}
+
+type savedPolicy struct {
+	Name             string            `json:"name"`
+	File             string            `json:"file,omitempty"`
+	Title            string            `json:"title,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Group            string            `json:"group,omitempty"`
+	Enforcement      string            `json:"enforcement,omitempty"`
+	Severity         string            `json:"severity,omitempty"`
+	AppliesTo        string            `json:"appliesTo,omitempty"`
+	InputSelector    string            `json:"inputSelector,omitempty"`
+	Remediation      string            `json:"remediation,omitempty"`
+	CisControls      []string          `json:"cisControls,omitempty"`
+	References       []string          `json:"references,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	Waiver           *savedWaiver      `json:"waiver,omitempty"`
+	Valid            bool              `json:"valid"`
+	Violations       []string          `json:"violations,omitempty"`
+	ViolationDetails []*savedViolation `json:"violationDetails,omitempty"`
+	ProcessingErrors []string          `json:"processingErrors,omitempty"`
+	EvaluationTime   time.Duration     `json:"evaluationTime,omitempty"`
+
+	Deprecated        bool   `json:"deprecated,omitempty"`
+	DeprecatedMessage string `json:"deprecatedMessage,omitempty"`
+
+	SuppressedViolations int `json:"suppressedViolations,omitempty"`
+	TruncatedViolations  int `json:"truncatedViolations,omitempty"`
+}
+
+type savedWaiver struct {
+	Policy  string    `json:"policy"`
+	Cluster string    `json:"cluster,omitempty"`
+	Reason  string    `json:"reason"`
+	Expires time.Time `json:"expires"`
+}
+
+type savedViolation struct {
+	Message  string                 `json:"message"`
+	Resource string                 `json:"resource,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// Save writes the result as JSON document with the schema version, so it can be read back
+// with LoadResult, i.e. for caching and comparing results of subsequent reviews.
+func (r *PolicyEvaluationResult) Save(w io.Writer) error {
+	data, err := json.Marshal(newSavedResult(r))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&resultEnvelope{SchemaVersion: ResultSchemaVersion, Result: data})
+}
+
+// LoadResult reads the result written with Save. Results with a different schema version
+// are rejected. Errors are restored with their messages only.
+func LoadResult(r io.Reader) (*PolicyEvaluationResult, error) {
+	envelope := &resultEnvelope{}
+	if err := json.NewDecoder(r).Decode(envelope); err != nil {
+		return nil, fmt.Errorf("invalid saved result: %s", err)
+	}
+	if envelope.SchemaVersion == 0 {
+		return nil, fmt.Errorf("saved result has no schema version, it was not written by this tool version; save the result again")
+	}
+	if envelope.SchemaVersion != ResultSchemaVersion {
+		return nil, fmt.Errorf("saved result has schema version %d, only version %d is supported; save the result again",
+			envelope.SchemaVersion, ResultSchemaVersion)
+	}
+	saved := &savedResult{}
+	if err := decodeJSON(envelope.Result, saved); err != nil {
+		return nil, fmt.Errorf("invalid saved result: %s", err)
+	}
+	return saved.toResult(), nil
+}
+
+func newSavedResult(r *PolicyEvaluationResult) *savedResult {
+	saved := &savedResult{
+		ClusterName: r.ClusterName,
+		BySeverity:  r.bySeverity,
+		Valid:       newSavedPolicyMap(r.Valid),
+		Violated:    newSavedPolicyMap(r.Violated),
+		Warned:      newSavedPolicyMap(r.Warned),
+		Audited:     newSavedPolicyMap(r.Audited),
+		Filtered:    newSavedPolicyMap(r.Filtered),
+		Waived:      newSavedPolicyMap(r.Waived),
+		Suppressed:  newSavedPolicyMap(r.Suppressed),
+		Skipped:     newSavedPolicyMap(r.Skipped),
+		Errored:     newSavedPolicies(r.Errored),
+		Coverage:    r.Coverage,
+
+		ClusterLabels: r.ClusterLabels,
+	}
+	if r.ClusterError != nil {
+		saved.ClusterError = r.ClusterError.Error()
+	}
+	return saved
+}
+
+func (s *savedResult) toResult() *PolicyEvaluationResult {
+	r := NewPolicyEvaluationResult()
+	r.ClusterName = s.ClusterName
+	r.ClusterLabels = s.ClusterLabels
+	if s.ClusterError != "" {
+		r.ClusterError = errors.New(s.ClusterError)
+	}
+	r.bySeverity = s.BySeverity
+	pairs := []struct {
+		from map[string][]*savedPolicy
+		to   map[string][]*Policy
+	}{
+		{s.Valid, r.Valid},
+		{s.Violated, r.Violated},
+		{s.Warned, r.Warned},
+		{s.Audited, r.Audited},
+		{s.Filtered, r.Filtered},
+		{s.Waived, r.Waived},
+		{s.Suppressed, r.Suppressed},
+		{s.Skipped, r.Skipped},
+	}
+	for _, pair := range pairs {
+		for group, policies := range pair.from {
+			pair.to[group] = toPolicies(policies)
+		}
+	}
+	r.Errored = toPolicies(s.Errored)
+	r.Coverage = s.Coverage
+	return r
+}
+
+func newSavedPolicyMap(policies map[string][]*Policy) map[string][]*savedPolicy {
+	saved := make(map[string][]*savedPolicy, len(policies))
+	for group, groupPolicies := range policies {
+		saved[group] = newSavedPolicies(groupPolicies)
+	}
+	return saved
+}
+
+func newSavedPolicies(policies []*Policy) []*savedPolicy {
+	saved := make([]*savedPolicy, 0, len(policies))
+	for _, p := range policies {
+		s := &savedPolicy{
+			Name:           p.Name,
+			File:           p.File,
+			Title:          p.Title,
+			Description:    p.Description,
+			Group:          p.Group,
+			Enforcement:    p.Enforcement,
+			Severity:       p.Severity,
+			AppliesTo:      p.AppliesTo,
+			InputSelector:  p.InputSelector,
+			Remediation:    p.Remediation,
+			CisControls:    p.CisControls,
+			References:     p.References,
+			Tags:           p.Tags,
+			Valid:          p.Valid,
+			Violations:     p.Violations,
+			EvaluationTime: p.EvaluationTime,
+
+			Deprecated:        p.Deprecated,
+			DeprecatedMessage: p.DeprecatedMessage,
+
+			SuppressedViolations: p.SuppressedViolations,
+			TruncatedViolations:  p.TruncatedViolations,
+		}
+		if p.Waiver != nil {
+			s.Waiver = &savedWaiver{Policy: p.Waiver.Policy, Cluster: p.Waiver.Cluster, Reason: p.Waiver.Reason, Expires: p.Waiver.Expires}
+		}
+		for _, v := range p.ViolationDetails {
+			s.ViolationDetails = append(s.ViolationDetails, &savedViolation{Message: v.Message, Resource: v.Resource, Details: v.Details})
+		}
+		for _, err := range p.ProcessingErrors {
+			s.ProcessingErrors = append(s.ProcessingErrors, err.Error())
+		}
+		saved = append(saved, s)
+	}
+	return saved
+}
+
+func toPolicies(saved []*savedPolicy) []*Policy {
+	policies := make([]*Policy, 0, len(saved))
+	for _, s := range saved {
+		p := &Policy{
+			Name:           s.Name,
+			File:           s.File,
+			Title:          s.Title,
+			Description:    s.Description,
+			Group:          s.Group,
+			Enforcement:    s.Enforcement,
+			Severity:       s.Severity,
+			AppliesTo:      s.AppliesTo,
+			InputSelector:  s.InputSelector,
+			Remediation:    s.Remediation,
+			CisControls:    s.CisControls,
+			References:     s.References,
+			Tags:           s.Tags,
+			Valid:          s.Valid,
+			Violations:     s.Violations,
+			EvaluationTime: s.EvaluationTime,
+
+			Deprecated:        s.Deprecated,
+			DeprecatedMessage: s.DeprecatedMessage,
+
+			SuppressedViolations: s.SuppressedViolations,
+			TruncatedViolations:  s.TruncatedViolations,
+		}
+		if s.Waiver != nil {
+			p.Waiver = &PolicyWaiver{Policy: s.Waiver.Policy, Cluster: s.Waiver.Cluster, Reason: s.Waiver.Reason, Expires: s.Waiver.Expires}
+		}
+		for _, v := range s.ViolationDetails {
+			p.ViolationDetails = append(p.ViolationDetails, &Violation{Message: v.Message, Resource: v.Resource, Details: v.Details})
+		}
+		for _, err := range s.ProcessingErrors {
+			p.ProcessingErrors = append(p.ProcessingErrors, errors.New(err))
+		}
+		policies = append(policies, p)
+	}
+	return policies
+}
@@ -0,0 +1,98 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultSaveLoad(t *testing.T) {
+	result := NewPolicyEvaluationResult()
+	result.ClusterName = "projects/test/locations/europe-central2/clusters/one"
+	result.ClusterLabels = map[string]string{"env": "prod"}
+	result.AddPolicy(&Policy{Name: "gke.policy.valid", Group: "Security", Valid: true, Tags: []string{"iam"}})
+	result.AddPolicy(&Policy{Name: "gke.policy.violated", Group: "Security", Severity: SeverityHigh,
+		Violations:       []string{"violation one (resource: pool-1, count: 3)"},
+		ViolationDetails: []*Violation{{Message: "violation one", Resource: "pool-1", Details: map[string]interface{}{"count": json.Number("3")}}},
+		EvaluationTime:   3 * time.Millisecond})
+	result.Violated["Security"][0].TruncatedViolations = 2
+	result.AddPolicy(&Policy{Name: "gke.policy.warned", Group: "Availability", Enforcement: EnforcementWarn, Violations: []string{"warn"}})
+	result.AddPolicy(&Policy{Name: "gke.policy.errored", Group: "Security", ProcessingErrors: []error{errors.New("error one")}})
+	result.Waived["Security"] = []*Policy{{Name: "gke.policy.waived", Group: "Security", Violations: []string{"waived"},
+		Waiver: &PolicyWaiver{Policy: "gke.policy.waived", Reason: "accepted", Expires: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}}}
+	result.Suppressed["Availability"] = []*Policy{{Name: "gke.policy.suppressed", Group: "Availability", SuppressedViolations: 2}}
+	result.Coverage = &PolicyCoverage{Coverage: 50, Files: []*PolicyFileCoverage{{File: "a.rego", Coverage: 50, CoveredLines: 1, NotCoveredLines: 1}}}
+
+	var buf bytes.Buffer
+	if err := result.Save(&buf); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	loaded, err := LoadResult(&buf)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(loaded, result) {
+		t.Errorf("loaded result = %+v; want %+v", loaded, result)
+	}
+	if !reflect.DeepEqual(loaded.Groups(), result.Groups()) {
+		t.Errorf("groups = %v; want %v", loaded.Groups(), result.Groups())
+	}
+	if loaded.ErroredCount() != 1 || loaded.Errored[0].ProcessingErrors[0].Error() != "error one" {
+		t.Errorf("errored policies = %v; want one with error %q", loaded.Errored, "error one")
+	}
+}
+
+func TestResultSaveLoad_clusterError(t *testing.T) {
+	result := NewPolicyEvaluationResult()
+	result.ClusterName = "cluster"
+	result.ClusterError = errors.New("cluster not found")
+	bySeverity := result.GroupBySeverity()
+
+	var buf bytes.Buffer
+	if err := bySeverity.Save(&buf); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	loaded, err := LoadResult(&buf)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if loaded.ClusterError == nil || loaded.ClusterError.Error() != "cluster not found" {
+		t.Errorf("cluster error = %v; want %q", loaded.ClusterError, "cluster not found")
+	}
+	if !loaded.IsGroupedBySeverity() {
+		t.Errorf("loaded result is not grouped by severity")
+	}
+}
+
+func TestLoadResult_schemaVersion(t *testing.T) {
+	inputs := map[string]string{
+		`{"clusterName": "cluster"}`:                   "no schema version",
+		`{"schemaVersion": 2, "result": {}}`:           "schema version 2",
+		`{"schemaVersion": 1, "result": {"valid": 1}}`: "invalid saved result",
+		`not json`: "invalid saved result",
+	}
+	for input, expected := range inputs {
+		_, err := LoadResult(strings.NewReader(input))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("input %s: err = %v; want error with %q", input, err, expected)
+		}
+	}
+}
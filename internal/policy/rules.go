@@ -0,0 +1,121 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// ruleName matches names of rules that can be referenced directly in a query.
+var ruleName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// RuleNames are names of rules evaluated in each policy package in the rule mode. The violation
+// rule is a set or an array of violations, undefined rule means no violations. The valid rule
+// is optional; when it is not set or undefined, a policy is valid when it has no violations.
+type RuleNames struct {
+	Valid     string
+	Violation string
+}
+
+// WithRuleNames enables the rule mode, where rules with given names are evaluated individually
+// in each policy package, instead of reading valid and violation fields of the whole package.
+// It allows policies exposing their verdict with rules like deny, without the valid rule.
+func (pa *PolicyAgent) WithRuleNames(names RuleNames) error {
+	if !ruleName.MatchString(names.Violation) {
+		return fmt.Errorf("invalid violation rule name %q", names.Violation)
+	}
+	if names.Valid != "" && !ruleName.MatchString(names.Valid) {
+		return fmt.Errorf("invalid valid rule name %q", names.Valid)
+	}
+	pa.ruleNames = &names
+	return nil
+}
+
+// prepareRuleQueries prepares queries of the violation rule and, when set, the valid rule of the policy.
+func (pa *PolicyAgent) prepareRuleQueries(policy *Policy, prints *printHook) (rego.PreparedEvalQuery, *rego.PreparedEvalQuery, error) {
+	query, err := pa.prepareQuery("data."+policy.Name+"."+pa.ruleNames.Violation, policy, prints)
+	if err != nil || pa.ruleNames.Valid == "" {
+		return query, nil, err
+	}
+	validQuery, err := pa.prepareQuery("data."+policy.Name+"."+pa.ruleNames.Valid, policy, prints)
+	if err != nil {
+		return query, nil, err
+	}
+	return query, &validQuery, nil
+}
+
+// evaluateRules evaluates rules of the policy in the rule mode. When the valid rule is false and
+// there are no violations, a violation naming the rule is reported, so violated policies always
+// have violations. A policy with violations is not valid, even if the valid rule is true.
+func (pa *PolicyAgent) evaluateRules(ctx context.Context, job *policyEvaluationJob, options []rego.EvalOption) (bool, []*Violation, error) {
+	results, err := job.query.Eval(ctx, options...)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to evaluate rego: %s", err)
+	}
+	violations := make([]*Violation, 0)
+	if len(results) > 0 && len(results[0].Expressions) > 0 {
+		if violations, err = decodeRuleViolations(results[0].Expressions[0].Value); err != nil {
+			return false, nil, fmt.Errorf("invalid rule %q: %s", pa.ruleNames.Violation, err)
+		}
+	}
+	if job.validQuery == nil {
+		return len(violations) == 0, violations, nil
+	}
+	results, err = job.validQuery.Eval(ctx, options...)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to evaluate rego: %s", err)
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return len(violations) == 0, violations, nil
+	}
+	valid, ok := results[0].Expressions[0].Value.(bool)
+	if !ok {
+		return false, nil, fmt.Errorf("invalid rule %q: rule is %s (expected boolean)",
+			pa.ruleNames.Valid, regoTypeName(results[0].Expressions[0].Value))
+	}
+	if !valid && len(violations) == 0 {
+		violations = append(violations, &Violation{Message: fmt.Sprintf("rule %s is false", pa.ruleNames.Valid)})
+	}
+	return valid && len(violations) == 0, violations, nil
+}
+
+// decodeRuleViolations decodes the value of the violation rule, a set or an array of violations.
+func decodeRuleViolations(value interface{}) ([]*Violation, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode rule value: %s", err)
+	}
+	if kind := jsonValueKind(data); kind != "array" {
+		return nil, fmt.Errorf("rule is %s (expected set or array)", kind)
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, err
+	}
+	violations := make([]*Violation, len(raws))
+	for i, raw := range raws {
+		violation, err := decodeViolation(raw)
+		if err != nil {
+			return nil, fmt.Errorf("violation[%d]: %s", i, err)
+		}
+		violations[i] = violation
+	}
+	return violations, nil
+}
@@ -0,0 +1,102 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWithRuleNames(t *testing.T) {
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithRuleNames(RuleNames{Violation: "deny"}); err != nil {
+		t.Errorf("err = %v; want nil", err)
+	}
+	if err := pa.WithRuleNames(RuleNames{}); err == nil {
+		t.Errorf("err is nil; want error for empty violation rule")
+	}
+	if err := pa.WithRuleNames(RuleNames{Violation: "deny", Valid: "data.allow"}); err == nil {
+		t.Errorf("err is nil; want error for invalid valid rule")
+	}
+}
+
+func TestEvaluate_ruleNames(t *testing.T) {
+	metadata := "# METADATA\n" +
+		"# title: Test\n" +
+		"# description: Test\n" +
+		"# custom:\n" +
+		"#   group: Test\n"
+	denied := metadata +
+		"package gke.policy.denied\n" +
+		"deny[msg] { input.value > 10; msg := \"value is too big\" }\n" +
+		"deny[{\"message\": \"value is even\", \"resource\": \"value\"}] { input.value % 2 == 0 }\n"
+	allowed := metadata +
+		"package gke.policy.allowed\n" +
+		"deny[msg] { input.value < 0; msg := \"value is negative\" }\n"
+	notAllowed := metadata +
+		"package gke.policy.not_allowed\n" +
+		"default allow = false\n"
+	contradicted := metadata +
+		"package gke.policy.contradicted\n" +
+		"default allow = true\n" +
+		"deny[msg] { input.value > 10; msg := \"value is too big\" }\n"
+	invalid := metadata +
+		"package gke.policy.invalid\n" +
+		"deny = \"not a set\"\n"
+	files := []*PolicyFile{
+		{"denied.rego", "denied.rego", denied},
+		{"allowed.rego", "allowed.rego", allowed},
+		{"not_allowed.rego", "not_allowed.rego", notAllowed},
+		{"contradicted.rego", "contradicted.rego", contradicted},
+		{"invalid.rego", "invalid.rego", invalid},
+	}
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithRuleNames(RuleNames{Violation: "deny", Valid: "allow"}); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if err := pa.WithFiles(files); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{"value": 12})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if result.ValidCount() != 1 || result.Valid["Test"][0].Name != "gke.policy.allowed" {
+		t.Errorf("valid = %v; want %v", result.Valid["Test"], "gke.policy.allowed")
+	}
+	if result.ViolatedCount() != 3 {
+		t.Fatalf("violatedCount = %v; want %v", result.ViolatedCount(), 3)
+	}
+	violated := make(map[string]*Policy)
+	for _, p := range result.Violated["Test"] {
+		violated[p.Name] = p
+	}
+	expected := []string{"value is too big", "value is even (resource: value)"}
+	if violations := violated["gke.policy.denied"].Violations; !reflect.DeepEqual(violations, expected) {
+		t.Errorf("violations = %v; want %v", violations, expected)
+	}
+	expected = []string{"value is too big"}
+	if violations := violated["gke.policy.contradicted"].Violations; !reflect.DeepEqual(violations, expected) {
+		t.Errorf("violations = %v; want %v", violations, expected)
+	}
+	expected = []string{"rule allow is false"}
+	if violations := violated["gke.policy.not_allowed"].Violations; !reflect.DeepEqual(violations, expected) {
+		t.Errorf("violations = %v; want %v", violations, expected)
+	}
+	if result.ErroredCount() != 1 || result.Errored[0].Name != "gke.policy.invalid" {
+		t.Errorf("errored = %v; want %v", result.Errored, "gke.policy.invalid")
+	}
+}
@@ -0,0 +1,83 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// ParseInputSelector parses input selector of a policy into a reference rooted at the input.
+// The selector is a rego reference, i.e. input.master_auth or node_pools[0].config, or a simple
+// JSONPath expression, i.e. $.node_pools[0].config. Selectors must not contain variables.
+func ParseInputSelector(selector string) (ast.Ref, error) {
+	selector = strings.TrimSpace(selector)
+	if strings.HasPrefix(selector, "$") {
+		selector = ast.InputRootDocument.String() + strings.TrimPrefix(selector, "$")
+	}
+	root := ast.InputRootDocument.String()
+	if selector != root && !strings.HasPrefix(selector, root+".") && !strings.HasPrefix(selector, root+"[") {
+		selector = root + "." + selector
+	}
+	ref, err := ast.ParseRef(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input selector %q: %s", selector, err)
+	}
+	if !ref.HasPrefix(ast.InputRootRef) {
+		return nil, fmt.Errorf("input selector %q does not refer to the input", selector)
+	}
+	if !ref.IsGround() {
+		return nil, fmt.Errorf("input selector %q must not contain variables", selector)
+	}
+	return ref, nil
+}
+
+// selectPolicyInput returns part of the input selected by the policy input selector.
+func selectPolicyInput(input ast.Value, selector string) (ast.Value, error) {
+	ref, err := ParseInputSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return selectInput(input, ref)
+}
+
+// selectInput returns part of the input the reference points to. Error is returned when
+// the reference selects no value or null.
+func selectInput(input ast.Value, ref ast.Ref) (ast.Value, error) {
+	value := input
+	for _, term := range ref[1:] {
+		var next *ast.Term
+		switch v := value.(type) {
+		case ast.Object:
+			next = v.Get(term)
+		case *ast.Array:
+			if index, ok := term.Value.(ast.Number); ok {
+				if i, ok := index.Int(); ok && i >= 0 && i < v.Len() {
+					next = v.Elem(i)
+				}
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("input selector %s selected no value", ref)
+		}
+		value = next.Value
+	}
+	if _, ok := value.(ast.Null); ok {
+		return nil, fmt.Errorf("input selector %s selected null value", ref)
+	}
+	return value, nil
+}
@@ -0,0 +1,119 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestParseInputSelector(t *testing.T) {
+	inputs := map[string]string{
+		"input.master_auth":        "input.master_auth",
+		"master_auth":              "input.master_auth",
+		"inputs.value":             "input.inputs.value",
+		"node_pools[0].config":     "input.node_pools[0].config",
+		"$.node_pools[0].config":   "input.node_pools[0].config",
+		`input["private_cluster"]`: "input.private_cluster",
+		"input":                    "input",
+	}
+	for selector, expected := range inputs {
+		ref, err := ParseInputSelector(selector)
+		if err != nil {
+			t.Errorf("selector %q: err = %v; want nil", selector, err)
+			continue
+		}
+		if ref.String() != expected {
+			t.Errorf("selector %q: ref = %v; want %v", selector, ref, expected)
+		}
+	}
+}
+
+func TestParseInputSelector_negative(t *testing.T) {
+	for _, selector := range []string{"node_pools[_]", "input.a[x]", "input..a", "$[?(@.a)]"} {
+		if _, err := ParseInputSelector(selector); err == nil {
+			t.Errorf("selector %q: err = nil; want error", selector)
+		}
+	}
+}
+
+func TestSelectInput(t *testing.T) {
+	input, err := ast.InterfaceToValue(map[string]interface{}{
+		"name":       "cluster",
+		"empty":      nil,
+		"node_pools": []interface{}{map[string]interface{}{"name": "pool"}},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	value, err := selectPolicyInput(input, "node_pools[0].name")
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if value.Compare(ast.String("pool")) != 0 {
+		t.Errorf("value = %v; want %v", value, ast.String("pool"))
+	}
+	for _, selector := range []string{"missing", "empty", "node_pools[1]", "name.first"} {
+		if _, err := selectPolicyInput(input, selector); err == nil {
+			t.Errorf("selector %q: err = nil; want error", selector)
+		}
+	}
+}
+
+func TestEvaluate_inputSelector(t *testing.T) {
+	policyContent := func(pkg string, selector string) string {
+		return "# METADATA\n" +
+			"# title: Test\n" +
+			"# description: Test\n" +
+			"# custom:\n" +
+			"#   group: Test\n" +
+			"#   input_selector: " + selector + "\n" +
+			"package gke.policy." + pkg + "\n" +
+			"default valid = false\n" +
+			"valid { count(violation) == 0 }\n" +
+			"violation[msg] { not input.enabled; msg := \"not enabled\" }"
+	}
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles([]*PolicyFile{
+		{"selected.rego", "selected.rego", policyContent("selected", "$.private_cluster_config")},
+		{"missing.rego", "missing.rego", policyContent("missing", "input.missing")},
+	}); err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{
+		"private_cluster_config": map[string]interface{}{"enabled": true},
+	})
+	if err != nil {
+		t.Fatalf("error = %v; want nil", err)
+	}
+	if result.ValidCount() != 1 {
+		t.Errorf("validCount = %v; want %v", result.ValidCount(), 1)
+	}
+	if result.ErroredCount() != 1 {
+		t.Fatalf("erroredCount = %v; want %v", result.ErroredCount(), 1)
+	}
+	if name := result.Errored[0].Name; name != regoPolicyPackage+".missing" {
+		t.Errorf("errored policy = %v; want %v", name, regoPolicyPackage+".missing")
+	}
+}
+
+func TestMetadataErrors_inputSelector(t *testing.T) {
+	p := Policy{Title: "Test", Description: "Test", Group: "Test", InputSelector: "node_pools[_]"}
+	if errs := p.MetadataErrors(); len(errs) != 1 {
+		t.Errorf("metadata errors = %v; want one error", errs)
+	}
+}
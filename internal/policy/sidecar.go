@@ -0,0 +1,79 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PolicySidecarFileName is the name of the file with metadata of policies in its directory.
+// Policy sources return sidecar files along with policy files.
+const PolicySidecarFileName = "policy.yaml"
+
+// PolicySidecar is policy metadata given in a YAML file next to policy files, as an alternative
+// to metadata comments. Metadata is keyed by the package name, with or without the policy package
+// prefix, so a single sidecar describes all policies in its directory.
+type PolicySidecar struct {
+	File     string
+	Metadata map[string]*PolicySidecarMetadata
+}
+
+// PolicySidecarMetadata is metadata of a single policy given in a sidecar. Fields that are
+// set take precedence over metadata comments of the policy, empty fields are not applied.
+type PolicySidecarMetadata struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Group       string `yaml:"group"`
+	Severity    string `yaml:"severity"`
+}
+
+// ParsePolicySidecar parses content of a sidecar file.
+func ParsePolicySidecar(file string, data []byte) (*PolicySidecar, error) {
+	sidecar := &PolicySidecar{File: file}
+	if err := yaml.UnmarshalStrict(data, &sidecar.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse policy metadata file %q: %s", file, err)
+	}
+	return sidecar, nil
+}
+
+// lookup returns metadata of a policy with a given name, keyed by the full name or by
+// the name without a given package prefix. Full names take precedence.
+func (s *PolicySidecar) lookup(name string, packageName string) (*PolicySidecarMetadata, bool) {
+	if metadata, ok := s.Metadata[name]; ok {
+		return metadata, true
+	}
+	metadata, ok := s.Metadata[strings.TrimPrefix(name, packageName+".")]
+	return metadata, ok
+}
+
+// applySidecar sets metadata fields that are set in the sidecar, overriding metadata
+// comments of the policy.
+func (p *Policy) applySidecar(metadata *PolicySidecarMetadata) {
+	if metadata.Title != "" {
+		p.Title = expandEnvVars(p.Name, metadata.Title)
+	}
+	if metadata.Description != "" {
+		p.Description = metadata.Description
+	}
+	if metadata.Group != "" {
+		p.Group = expandEnvVars(p.Name, metadata.Group)
+	}
+	if metadata.Severity != "" {
+		p.Severity = strings.ToUpper(metadata.Severity)
+	}
+}
@@ -0,0 +1,76 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParsePolicySidecar(t *testing.T) {
+	data := "gke.policy.one:\n" +
+		"  title: One\n" +
+		"  severity: high\n" +
+		"two:\n" +
+		"  group: Security\n"
+	sidecar := "with_comments:\n" +
+		"  title: Sidecar title\n" +
+		"  severity: critical\n" +
+		"gke.policy.without_comments:\n" +
+		"  title: Sidecar only\n" +
+		"  description: Described in sidecar\n" +
+		"  group: Sidecar group\n"
+	pa := NewPolicyAgent(context.Background())
+	pa.WithStrictMetadata()
+	files := []*PolicyFile{
+		{"with_comments.rego", "policies/with_comments.rego", withComments},
+		{"without_comments.rego", "policies/without_comments.rego", withoutComments},
+		{PolicySidecarFileName, "policies/" + PolicySidecarFileName, sidecar},
+		{"without_sidecar.rego", "other/without_sidecar.rego", withoutSidecar},
+	}
+	if err := pa.WithFiles(files); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	withCommentsPolicy := pa.compiled["gke.policy.with_comments"]
+	if withCommentsPolicy.Title != "Sidecar title" {
+		t.Errorf("title = %v; want %v", withCommentsPolicy.Title, "Sidecar title")
+	}
+	if withCommentsPolicy.Severity != SeverityCritical {
+		t.Errorf("severity = %v; want %v", withCommentsPolicy.Severity, SeverityCritical)
+	}
+	if withCommentsPolicy.Description != "Comment description" {
+		t.Errorf("description = %v; want %v", withCommentsPolicy.Description, "Comment description")
+	}
+	if withCommentsPolicy.Group != "Comment group" {
+		t.Errorf("group = %v; want %v", withCommentsPolicy.Group, "Comment group")
+	}
+	if group := pa.compiled["gke.policy.without_comments"].Group; group != "Sidecar group" {
+		t.Errorf("group = %v; want %v", group, "Sidecar group")
+	}
+	if title := pa.compiled["gke.policy.without_sidecar"].Title; title != "Other title" {
+		t.Errorf("title = %v; want %v", title, "Other title")
+	}
+}
+
+func TestWithFiles_invalidSidecar(t *testing.T) {
+	pa := NewPolicyAgent(context.Background())
+	files := []*PolicyFile{
+		{"policy.rego", "policies/policy.rego", "package gke.policy.one\ndefault valid = true\n"},
+		{PolicySidecarFileName, "policies/" + PolicySidecarFileName, "one: [title]\n"},
+	}
+	if err := pa.WithFiles(files); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
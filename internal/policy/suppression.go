@@ -0,0 +1,127 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"regexp"
+)
+
+// ViolationSuppression hides individual violations of a policy with messages matching
+// the expression. Unlike a waiver, that accepts the whole policy, other violations of
+// the policy are still reported. The suppression applies to all clusters, unless the cluster is set.
+type ViolationSuppression struct {
+	Policy  string
+	Cluster string
+	Message *regexp.Regexp
+	Reason  string
+}
+
+// Matches returns true if the suppression applies to a given violation of a given policy on
+// a given cluster. The suppression policy can be a full policy name or a name without the
+// policy package prefix. The expression is matched against the violation string, so for
+// structured violations it covers the resource and details too.
+func (s *ViolationSuppression) Matches(policy *Policy, clusterName string, violation string) bool {
+	if s.Cluster != "" && s.Cluster != clusterName {
+		return false
+	}
+	if s.Policy != policy.Name && regoPolicyPackage+"."+s.Policy != policy.Name {
+		return false
+	}
+	return s.Message.MatchString(violation)
+}
+
+// ApplySuppressions removes suppressed violations from violated, warned and audited policies
+// and counts them in SuppressedViolations of the policy. Policies with all violations suppressed
+// are moved to the suppressed policies, instead of being reported as valid. Policies stay sorted by name.
+func (r *PolicyEvaluationResult) ApplySuppressions(suppressions []*ViolationSuppression) {
+	if len(suppressions) == 0 {
+		return
+	}
+	for _, policies := range []map[string][]*Policy{r.Violated, r.Warned, r.Audited} {
+		for group := range policies {
+			kept := make([]*Policy, 0, len(policies[group]))
+			for _, policy := range policies[group] {
+				policy.suppressViolations(suppressions, r.ClusterName)
+				if policy.SuppressedViolations > 0 && len(policy.Violations) == 0 {
+					r.Suppressed[group] = append(r.Suppressed[group], policy)
+					continue
+				}
+				kept = append(kept, policy)
+			}
+			if len(kept) > 0 {
+				policies[group] = kept
+			} else {
+				delete(policies, group)
+			}
+		}
+	}
+	r.SortPolicies()
+}
+
+// suppressViolations removes violations matching any of the suppressions. Violation details
+// are kept in line with violations.
+func (p *Policy) suppressViolations(suppressions []*ViolationSuppression, clusterName string) {
+	withDetails := len(p.ViolationDetails) == len(p.Violations)
+	violations := make([]string, 0, len(p.Violations))
+	var details []*Violation
+	if withDetails {
+		details = make([]*Violation, 0, len(p.ViolationDetails))
+	}
+	for i, violation := range p.Violations {
+		if findSuppression(suppressions, p, clusterName, violation) != nil {
+			p.SuppressedViolations++
+			continue
+		}
+		violations = append(violations, violation)
+		if withDetails {
+			details = append(details, p.ViolationDetails[i])
+		}
+	}
+	p.Violations = violations
+	if withDetails {
+		p.ViolationDetails = details
+	}
+}
+
+func findSuppression(suppressions []*ViolationSuppression, policy *Policy, clusterName string, violation string) *ViolationSuppression {
+	for _, suppression := range suppressions {
+		if suppression.Matches(policy, clusterName, violation) {
+			return suppression
+		}
+	}
+	return nil
+}
+
+// SuppressedCount returns number of policies with all violations suppressed.
+func (r *PolicyEvaluationResult) SuppressedCount() int {
+	cnt := 0
+	for _, v := range r.Suppressed {
+		cnt += len(v)
+	}
+	return cnt
+}
+
+// SuppressedViolationsCount returns number of suppressed violations of all policies.
+func (r *PolicyEvaluationResult) SuppressedViolationsCount() int {
+	cnt := 0
+	for _, m := range []map[string][]*Policy{r.Violated, r.Warned, r.Audited, r.Filtered, r.Waived, r.Suppressed} {
+		for _, policies := range m {
+			for _, policy := range policies {
+				cnt += policy.SuppressedViolations
+			}
+		}
+	}
+	return cnt
+}
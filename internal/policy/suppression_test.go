@@ -0,0 +1,77 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestViolationSuppressionMatches(t *testing.T) {
+	suppression := &ViolationSuppression{Policy: "noisy", Cluster: "clusterOne", Message: regexp.MustCompile("^pool batch-")}
+	policy := &Policy{Name: regoPolicyPackage + ".noisy"}
+	if !suppression.Matches(policy, "clusterOne", "pool batch-1 is not upgraded") {
+		t.Errorf("suppression does not match; want match")
+	}
+	if suppression.Matches(policy, "clusterTwo", "pool batch-1 is not upgraded") {
+		t.Errorf("suppression matches other cluster; want no match")
+	}
+	if suppression.Matches(policy, "clusterOne", "pool default is not upgraded") {
+		t.Errorf("suppression matches other message; want no match")
+	}
+	if suppression.Matches(&Policy{Name: regoPolicyPackage + ".other"}, "clusterOne", "pool batch-1 is not upgraded") {
+		t.Errorf("suppression matches other policy; want no match")
+	}
+}
+
+func TestApplySuppressions(t *testing.T) {
+	suppressions := []*ViolationSuppression{
+		{Policy: "noisy", Message: regexp.MustCompile("^pool batch-"), Reason: "batch pools"},
+	}
+	r := NewPolicyEvaluationResult()
+	r.ClusterName = "clusterOne"
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".noisy", Group: "groupOne", Violations: []string{"pool batch-1", "pool default"},
+		ViolationDetails: []*Violation{{Message: "pool batch-1"}, {Message: "pool default"}}})
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".noisy", Group: "groupTwo", Enforcement: EnforcementWarn, Violations: []string{"pool batch-2"}})
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".violated", Group: "groupTwo", Violations: []string{"pool batch-1"}})
+	r.ApplySuppressions(suppressions)
+	if r.ViolatedCount() != 2 {
+		t.Errorf("violatedCount = %v; want %v", r.ViolatedCount(), 2)
+	}
+	partial := r.Violated["groupOne"][0]
+	if !reflect.DeepEqual(partial.Violations, []string{"pool default"}) {
+		t.Errorf("violations = %v; want %v", partial.Violations, []string{"pool default"})
+	}
+	if len(partial.ViolationDetails) != 1 || partial.ViolationDetails[0].Message != "pool default" {
+		t.Errorf("violation details = %v; want pool default only", partial.ViolationDetails)
+	}
+	if r.WarnedCount() != 0 {
+		t.Errorf("warnedCount = %v; want %v", r.WarnedCount(), 0)
+	}
+	if r.SuppressedCount() != 1 {
+		t.Fatalf("suppressedCount = %v; want %v", r.SuppressedCount(), 1)
+	}
+	if r.SuppressedViolationsCount() != 2 {
+		t.Errorf("suppressedViolationsCount = %v; want %v", r.SuppressedViolationsCount(), 2)
+	}
+	if r.ValidCount() != 0 {
+		t.Errorf("validCount = %v; want %v", r.ValidCount(), 0)
+	}
+	exceptions := r.Exceptions()
+	if len(exceptions) != 1 || exceptions[0].Status != ExceptionStatusSuppressed {
+		t.Errorf("exceptions = %v; want single suppressed exception", exceptions)
+	}
+}
@@ -0,0 +1,145 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mikouaj/gke-review/internal/log"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/tester"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// PolicyTestResult is the result of a single rego unit test, a rule with the test_ prefix.
+type PolicyTestResult struct {
+	Package  string
+	Name     string
+	File     string
+	Line     int
+	Passed   bool
+	Skipped  bool
+	Error    error
+	Duration time.Duration
+	// FailedAt is the location and the expression the failed test stopped at
+	FailedAt string
+}
+
+// Failed returns true if the test failed or could not be run.
+func (r *PolicyTestResult) Failed() bool {
+	return !r.Passed && !r.Skipped
+}
+
+// Location returns the file and the line of the test rule.
+func (r *PolicyTestResult) Location() string {
+	if r.Line == 0 {
+		return r.File
+	}
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// registerTopdownBuiltins makes implementations of custom functions available to
+// evaluations not prepared with regoBuiltins, like the ones of the OPA test runner.
+func registerTopdownBuiltins() {
+	for _, builtin := range Builtins() {
+		impl := builtin.Impl
+		topdown.RegisterBuiltinFunc(builtin.Name, func(bctx topdown.BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+			result, err := impl(bctx, operands)
+			if err != nil {
+				return err
+			}
+			if result == nil {
+				return nil
+			}
+			return iter(result)
+		})
+	}
+}
+
+// RunTests compiles policy files together with their _test.rego counterparts and runs
+// rego unit tests with the OPA test runner. Results are ordered by file and line.
+// Data documents set with WithDataFiles are available to tests.
+func (pa *PolicyAgent) RunTests(files []*PolicyFile) ([]*PolicyTestResult, error) {
+	modules := make(map[string]*ast.Module)
+	for _, file := range files {
+		if file.Name == PolicySidecarFileName {
+			continue
+		}
+		module, err := ast.ParseModuleWithOpts(file.FullName, file.Content, ast.ParserOptions{ProcessAnnotation: true})
+		if err != nil {
+			return nil, err
+		}
+		modules[file.FullName] = module
+	}
+	registerTopdownBuiltins()
+	store := pa.store
+	if store == nil {
+		store = inmem.New()
+	}
+	txn, err := store.NewTransaction(pa.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Abort(pa.ctx, txn)
+
+	compiler := ast.NewCompiler().WithStrict(pa.strictCompile).WithBuiltins(builtinDecls())
+	runner := tester.NewRunner().
+		SetCompiler(compiler).
+		SetStore(store).
+		SetModules(modules).
+		EnableFailureLine(true)
+	ch, err := runner.RunTests(pa.ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*PolicyTestResult, 0)
+	for testResult := range ch {
+		result := newPolicyTestResult(testResult)
+		log.Debugf("test %s.%s passed: %t, skipped: %t", result.Package, result.Name, result.Passed, result.Skipped)
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].File != results[j].File {
+			return results[i].File < results[j].File
+		}
+		return results[i].Line < results[j].Line
+	})
+	return results, nil
+}
+
+func newPolicyTestResult(testResult *tester.Result) *PolicyTestResult {
+	result := &PolicyTestResult{
+		Package:  testResult.Package,
+		Name:     testResult.Name,
+		Passed:   testResult.Pass(),
+		Skipped:  testResult.Skip,
+		Error:    testResult.Error,
+		Duration: testResult.Duration,
+	}
+	if testResult.Location != nil {
+		result.File = testResult.Location.File
+		result.Line = testResult.Location.Row
+	}
+	if testResult.FailedAt != nil {
+		result.FailedAt = testResult.FailedAt.String()
+		if location := testResult.FailedAt.Location; location != nil {
+			result.FailedAt = fmt.Sprintf("%s:%d: %s", location.File, location.Row, location.Text)
+		}
+	}
+	return result
+}
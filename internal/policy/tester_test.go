@@ -0,0 +1,114 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunTests(t *testing.T) {
+	files := []*PolicyFile{
+		{"policy.rego", "folder/policy.rego", `
+package gke.policy.test_one
+
+default valid = false
+
+valid {
+	count(violation) == 0
+}
+
+violation[msg] {
+	net.cidr_contains_any(["0.0.0.0/0"], input.cidr)
+	msg := "public CIDR"
+}`},
+		{"policy_test.rego", "folder/policy_test.rego", `
+package gke.policy.test_one
+
+test_private {
+	valid with input as {"cidr": "fd00::1"}
+}
+
+test_public {
+	valid with input as {"cidr": "10.0.0.1"}
+}
+
+todo_test_later {
+	false
+}`},
+	}
+	pa := NewPolicyAgent(context.Background())
+	results, err := pa.RunTests(files)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d; want 3", len(results))
+	}
+	expected := []struct {
+		name    string
+		passed  bool
+		skipped bool
+		line    int
+	}{
+		{"test_private", true, false, 4},
+		{"test_public", false, false, 8},
+		{"todo_test_later", false, true, 12},
+	}
+	for i, e := range expected {
+		result := results[i]
+		if result.Name != e.name {
+			t.Errorf("results[%d].Name = %v; want %v", i, result.Name, e.name)
+		}
+		if result.Package != "data.gke.policy.test_one" {
+			t.Errorf("results[%d].Package = %v; want %v", i, result.Package, "data.gke.policy.test_one")
+		}
+		if result.Passed != e.passed {
+			t.Errorf("results[%d].Passed = %v; want %v", i, result.Passed, e.passed)
+		}
+		if result.Skipped != e.skipped {
+			t.Errorf("results[%d].Skipped = %v; want %v", i, result.Skipped, e.skipped)
+		}
+		if location := fmt.Sprintf("folder/policy_test.rego:%d", e.line); result.Location() != location {
+			t.Errorf("results[%d].Location() = %v; want %v", i, result.Location(), location)
+		}
+	}
+	if !results[1].Failed() {
+		t.Errorf("results[1].Failed() = false; want true")
+	}
+	if !strings.HasPrefix(results[1].FailedAt, "folder/policy_test.rego:9") {
+		t.Errorf("results[1].FailedAt = %v; want prefix %v", results[1].FailedAt, "folder/policy_test.rego:9")
+	}
+	if results[2].Failed() {
+		t.Errorf("results[2].Failed() = true; want false")
+	}
+}
+
+func TestRunTests_compileError(t *testing.T) {
+	files := []*PolicyFile{
+		{"policy_test.rego", "folder/policy_test.rego", `
+package gke.policy.test_one
+
+test_undefined {
+	undefined_function(1)
+}`},
+	}
+	pa := NewPolicyAgent(context.Background())
+	if _, err := pa.RunTests(files); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
@@ -0,0 +1,56 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+// TruncateViolations keeps at most max violations of each policy with violations. Number of
+// removed violations is counted in TruncatedViolations of the policy, so summaries stay accurate
+// and outputs can note them. Violation details are kept in line with violations. Non-positive
+// max keeps all violations.
+func (r *PolicyEvaluationResult) TruncateViolations(max int) {
+	if max < 1 {
+		return
+	}
+	for _, policies := range []map[string][]*Policy{r.Violated, r.Warned, r.Audited, r.Filtered, r.Waived} {
+		for _, group := range policies {
+			for _, policy := range group {
+				policy.truncateViolations(max)
+			}
+		}
+	}
+}
+
+func (p *Policy) truncateViolations(max int) {
+	if len(p.Violations) <= max {
+		return
+	}
+	if len(p.ViolationDetails) == len(p.Violations) {
+		p.ViolationDetails = p.ViolationDetails[:max:max]
+	}
+	p.TruncatedViolations += len(p.Violations) - max
+	p.Violations = p.Violations[:max:max]
+}
+
+// TruncatedViolationsCount returns number of violations of all policies removed by truncation.
+func (r *PolicyEvaluationResult) TruncatedViolationsCount() int {
+	cnt := 0
+	for _, m := range []map[string][]*Policy{r.Violated, r.Warned, r.Audited, r.Filtered, r.Waived} {
+		for _, policies := range m {
+			for _, policy := range policies {
+				cnt += policy.TruncatedViolations
+			}
+		}
+	}
+	return cnt
+}
@@ -0,0 +1,52 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTruncateViolations(t *testing.T) {
+	r := NewPolicyEvaluationResult()
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".noisy", Group: "groupOne", Violations: []string{"one", "two", "three", "four"},
+		ViolationDetails: []*Violation{{Message: "one"}, {Message: "two"}, {Message: "three"}, {Message: "four"}}})
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".quiet", Group: "groupOne", Violations: []string{"one", "two"}})
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".warned", Group: "groupTwo", Enforcement: EnforcementWarn, Violations: []string{"one", "two", "three"}})
+	r.TruncateViolations(2)
+
+	noisy := r.Violated["groupOne"][0]
+	expected := []string{"one", "two"}
+	if !reflect.DeepEqual(noisy.Violations, expected) {
+		t.Errorf("violations = %v; want %v", noisy.Violations, expected)
+	}
+	if len(noisy.ViolationDetails) != 2 || noisy.ViolationDetails[1].Message != "two" {
+		t.Errorf("violation details = %v; want details in line with violations", noisy.ViolationDetails)
+	}
+	if noisy.TruncatedViolations != 2 {
+		t.Errorf("truncated = %v; want %v", noisy.TruncatedViolations, 2)
+	}
+	if quiet := r.Violated["groupOne"][1]; len(quiet.Violations) != 2 || quiet.TruncatedViolations != 0 {
+		t.Errorf("violations = %v, truncated = %v; want 2 violations, 0 truncated", quiet.Violations, quiet.TruncatedViolations)
+	}
+	if r.TruncatedViolationsCount() != 3 {
+		t.Errorf("truncatedViolationsCount = %v; want %v", r.TruncatedViolationsCount(), 3)
+	}
+
+	r.TruncateViolations(0)
+	if r.TruncatedViolationsCount() != 3 {
+		t.Errorf("truncatedViolationsCount = %v; want %v", r.TruncatedViolationsCount(), 3)
+	}
+}
@@ -0,0 +1,142 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// URLPolicySource reads policies from HTTP(S) URL serving a single REGO file,
+// a gzipped tar archive or a zip archive.
+type URLPolicySource struct {
+	ctx context.Context
+	url string
+}
+
+func NewURLPolicySource(ctx context.Context, url string) *URLPolicySource {
+	return &URLPolicySource{ctx: ctx, url: url}
+}
+
+func (src URLPolicySource) String() string {
+	return fmt.Sprintf("URL: %s", src.url)
+}
+
+func (src *URLPolicySource) GetPolicyFiles() ([]*PolicyFile, error) {
+	return PolicyFilesFromURL(src.ctx, src.url)
+}
+
+// PolicyFilesFromURL downloads the resource under the URL and reads REGO files from it.
+// The resource kind is detected from its content: gzipped tar and zip archives are
+// extracted, other text is read as a single REGO file. HTML pages, i.e. error pages,
+// are rejected. Proxy is configured with HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables.
+func PolicyFilesFromURL(ctx context.Context, location string) ([]*PolicyFile, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported policy URL scheme %q", u.Scheme)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download policies %q: %s", location, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	files, err := policyFilesFromData(data, resp.Header.Get("Content-Type"), path.Base(u.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policies %q: %s", location, err)
+	}
+	return files, nil
+}
+
+// policyFilesFromData reads REGO files from the downloaded data, which kind is
+// detected by sniffing its bytes.
+func policyFilesFromData(data []byte, contentType string, name string) ([]*PolicyFile, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		files, _, err := PolicyFilesFromBundle(bytes.NewReader(data))
+		return files, err
+	case bytes.HasPrefix(data, zipMagic):
+		return policyFilesFromZip(data)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "text/html" ||
+		strings.HasPrefix(http.DetectContentType(data), "text/html") {
+		return nil, fmt.Errorf("got HTML page instead of policies")
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("unsupported content, expected REGO file, tar.gz or zip archive")
+	}
+	if !strings.HasSuffix(name, ".rego") {
+		name = "policy.rego"
+	}
+	return []*PolicyFile{{Name: name, FullName: name, Content: string(data)}}, nil
+}
+
+// policyFilesFromZip reads REGO files from zip archive, keeping in-archive paths
+// as files full names.
+func policyFilesFromZip(data []byte) ([]*PolicyFile, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*PolicyFile, 0)
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(path.Clean("/"+f.Name), "/")
+		if f.FileInfo().IsDir() || !strings.HasSuffix(name, ".rego") {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &PolicyFile{
+			Name:     path.Base(name),
+			FullName: name,
+			Content:  string(content),
+		})
+	}
+	return files, nil
+}
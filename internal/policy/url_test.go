@@ -0,0 +1,98 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func newTestZip(t *testing.T, files map[string]string) []byte {
+	buff := new(bytes.Buffer)
+	zw := zip.NewWriter(buff)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("could not create zip entry: %s", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("could not write zip content: %s", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("could not close zip writer: %s", err)
+	}
+	return buff.Bytes()
+}
+
+func TestPolicyFilesFromURL(t *testing.T) {
+	resources := map[string][]byte{
+		"/policy.rego":    []byte("package gke.policy.a"),
+		"/bundle.tar.gz":  newTestBundle(t, map[string]string{"policy/a.rego": "package gke.policy.a", "data.json": "{}"}),
+		"/policies.zip":   newTestZip(t, map[string]string{"policy/a.rego": "package gke.policy.a", "policy/b.rego": "package gke.policy.b", "README.md": "# Policies"}),
+		"/policies":       []byte("package gke.policy.a"),
+		"/not-found.rego": []byte("<!DOCTYPE html><html><body>Not found</body></html>"),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := resources[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	tests := map[string][]string{
+		"/policy.rego":   {"policy.rego"},
+		"/bundle.tar.gz": {"policy/a.rego"},
+		"/policies.zip":  {"policy/a.rego", "policy/b.rego"},
+		"/policies":      {"policy.rego"},
+	}
+	for resource, expected := range tests {
+		files, err := PolicyFilesFromURL(context.Background(), server.URL+resource)
+		if err != nil {
+			t.Fatalf("resource %s: err = %v; want nil", resource, err)
+		}
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, file.FullName)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("resource %s: files = %v; want %v", resource, names, expected)
+		}
+	}
+	for _, resource := range []string{"/not-found.rego", "/missing.rego"} {
+		if _, err := PolicyFilesFromURL(context.Background(), server.URL+resource); err == nil {
+			t.Errorf("resource %s: err = nil; want error", resource)
+		}
+	}
+	if _, err := PolicyFilesFromURL(context.Background(), "file:///tmp/policy.rego"); err == nil {
+		t.Errorf("file URL: err = nil; want error")
+	}
+}
+
+func TestPolicyFilesFromData_htmlContentType(t *testing.T) {
+	if _, err := policyFilesFromData([]byte("package gke.policy.a"), "text/html; charset=utf-8", "a.rego"); err == nil {
+		t.Errorf("err = nil; want error")
+	}
+}
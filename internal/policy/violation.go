@@ -0,0 +1,66 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Violation is a single violation reported by a policy. Policies report violations either
+// as strings or as objects with message, resource and details fields.
+type Violation struct {
+	Message  string
+	Resource string
+	Details  map[string]interface{}
+}
+
+// String returns the message followed by the resource and details, if set.
+func (v *Violation) String() string {
+	parts := make([]string, 0, len(v.Details)+1)
+	if v.Resource != "" {
+		parts = append(parts, "resource: "+v.Resource)
+	}
+	keys := make([]string, 0, len(v.Details))
+	for key := range v.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %v", key, v.Details[key]))
+	}
+	if len(parts) == 0 {
+		return v.Message
+	}
+	return fmt.Sprintf("%s (%s)", v.Message, strings.Join(parts, ", "))
+}
+
+// IsStructured returns true if the violation has more than a message.
+func (v *Violation) IsStructured() bool {
+	return v.Resource != "" || len(v.Details) > 0
+}
+
+// violationStrings returns string representations of violations.
+func violationStrings(violations []*Violation) []string {
+	if violations == nil {
+		return nil
+	}
+	strs := make([]string, len(violations))
+	for i, violation := range violations {
+		strs[i] = violation.String()
+	}
+	return strs
+}
@@ -0,0 +1,71 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestViolationString(t *testing.T) {
+	inputs := []struct {
+		violation *Violation
+		expected  string
+	}{
+		{&Violation{Message: "too few nodes"}, "too few nodes"},
+		{&Violation{Message: "too few nodes", Resource: "nodePools/default"}, "too few nodes (resource: nodePools/default)"},
+		{&Violation{Message: "too few nodes", Resource: "nodePools/default", Details: map[string]interface{}{"expected": 3, "actual": 1}},
+			"too few nodes (resource: nodePools/default, actual: 1, expected: 3)"},
+	}
+	for _, input := range inputs {
+		if str := input.violation.String(); str != input.expected {
+			t.Errorf("string = %q; want %q", str, input.expected)
+		}
+	}
+}
+
+func TestEvaluate_structuredViolations(t *testing.T) {
+	files := []*PolicyFile{{"policy.rego", "policy.rego", `# METADATA
+# title: Node count
+# description: Node count
+# custom:
+#   group: Test
+package gke.policy.node_count
+default valid = false
+valid { count(violation) == 0 }
+violation[{"message": "not enough nodes", "resource": "clusters/one", "details": {"actual": input.current_node_count, "expected": 3}}] {
+  input.current_node_count < 3
+}`}}
+	pa := NewPolicyAgent(context.Background())
+	if err := pa.WithFiles(files); err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	result, err := pa.Evaluate(map[string]interface{}{"current_node_count": 2})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(result.Violated["Test"]) != 1 {
+		t.Fatalf("violated = %v; want one policy", result.Violated)
+	}
+	p := result.Violated["Test"][0]
+	if len(p.ViolationDetails) != 1 || p.ViolationDetails[0].Resource != "clusters/one" {
+		t.Errorf("violation details = %v; want violation of clusters/one", p.ViolationDetails)
+	}
+	expected := []string{"not enough nodes (resource: clusters/one, actual: 2, expected: 3)"}
+	if !reflect.DeepEqual(p.Violations, expected) {
+		t.Errorf("violations = %v; want %v", p.Violations, expected)
+	}
+}
@@ -0,0 +1,89 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WaiverDateFormat is the format of waiver expiry date.
+const WaiverDateFormat = "2006-01-02"
+
+// PolicyWaiver accepts violation of a policy until a given expiry date. The waiver
+// applies to all clusters, unless the cluster is set.
+type PolicyWaiver struct {
+	Policy  string
+	Cluster string
+	Reason  string
+	Expires time.Time
+}
+
+// IsExpired returns true if the waiver is expired at a given time. The waiver
+// is valid through the whole day of its expiry date.
+func (w *PolicyWaiver) IsExpired(now time.Time) bool {
+	return !now.Before(w.Expires.AddDate(0, 0, 1))
+}
+
+// Matches returns true if the waiver applies to a given policy on a given cluster.
+// The waiver policy can be a full policy name or a name without the policy package prefix.
+func (w *PolicyWaiver) Matches(policy *Policy, clusterName string) bool {
+	if w.Cluster != "" && w.Cluster != clusterName {
+		return false
+	}
+	return w.Policy == policy.Name || regoPolicyPackage+"."+w.Policy == policy.Name
+}
+
+func (w *PolicyWaiver) String() string {
+	var sb strings.Builder
+	sb.WriteString(w.Policy)
+	if w.Cluster != "" {
+		sb.WriteString(fmt.Sprintf(" on cluster %s", w.Cluster))
+	}
+	sb.WriteString(fmt.Sprintf(" until %s", w.Expires.Format(WaiverDateFormat)))
+	return sb.String()
+}
+
+// ApplyWaivers moves violated policies that have a matching, non expired waiver
+// to the waived policies. Expired waivers are ignored. Policies stay sorted by name.
+func (r *PolicyEvaluationResult) ApplyWaivers(waivers []*PolicyWaiver, now time.Time) {
+	for group, policies := range r.Violated {
+		violated := make([]*Policy, 0, len(policies))
+		for _, policy := range policies {
+			if waiver := findWaiver(waivers, policy, r.ClusterName, now); waiver != nil {
+				policy.Waiver = waiver
+				r.Waived[group] = append(r.Waived[group], policy)
+				continue
+			}
+			violated = append(violated, policy)
+		}
+		if len(violated) > 0 {
+			r.Violated[group] = violated
+		} else {
+			delete(r.Violated, group)
+		}
+	}
+	r.SortPolicies()
+}
+
+func findWaiver(waivers []*PolicyWaiver, policy *Policy, clusterName string, now time.Time) *PolicyWaiver {
+	for _, waiver := range waivers {
+		if waiver.Matches(policy, clusterName) && !waiver.IsExpired(now) {
+			return waiver
+		}
+	}
+	return nil
+}
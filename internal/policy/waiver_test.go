@@ -0,0 +1,84 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyWaiverIsExpired(t *testing.T) {
+	waiver := &PolicyWaiver{Expires: time.Date(2022, 3, 10, 0, 0, 0, 0, time.UTC)}
+	inputs := map[time.Time]bool{
+		time.Date(2022, 3, 9, 12, 0, 0, 0, time.UTC):   false,
+		time.Date(2022, 3, 10, 23, 59, 0, 0, time.UTC): false,
+		time.Date(2022, 3, 11, 0, 0, 0, 0, time.UTC):   true,
+	}
+	for now, expected := range inputs {
+		if waiver.IsExpired(now) != expected {
+			t.Errorf("isExpired(%v) = %v; want %v", now, !expected, expected)
+		}
+	}
+}
+
+func TestPolicyWaiverMatches(t *testing.T) {
+	policy := &Policy{Name: regoPolicyPackage + ".private_cluster"}
+	inputs := []struct {
+		waiver   *PolicyWaiver
+		cluster  string
+		expected bool
+	}{
+		{&PolicyWaiver{Policy: regoPolicyPackage + ".private_cluster"}, "clusterOne", true},
+		{&PolicyWaiver{Policy: "private_cluster"}, "clusterOne", true},
+		{&PolicyWaiver{Policy: "private_cluster", Cluster: "clusterOne"}, "clusterOne", true},
+		{&PolicyWaiver{Policy: "private_cluster", Cluster: "clusterTwo"}, "clusterOne", false},
+		{&PolicyWaiver{Policy: "other"}, "clusterOne", false},
+	}
+	for i, input := range inputs {
+		if input.waiver.Matches(policy, input.cluster) != input.expected {
+			t.Errorf("input [%d]: matches = %v; want %v", i, !input.expected, input.expected)
+		}
+	}
+}
+
+func TestApplyWaivers(t *testing.T) {
+	now := time.Date(2022, 3, 10, 0, 0, 0, 0, time.UTC)
+	waivers := []*PolicyWaiver{
+		{Policy: "waived", Reason: "accepted", Expires: now.AddDate(0, 1, 0)},
+		{Policy: "expired", Reason: "accepted", Expires: now.AddDate(0, 0, -1)},
+	}
+	r := NewPolicyEvaluationResult()
+	r.ClusterName = "clusterOne"
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".waived", Group: "groupOne", Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".expired", Group: "groupTwo", Violations: []string{"error"}})
+	r.AddPolicy(&Policy{Name: regoPolicyPackage + ".violated", Group: "groupTwo", Violations: []string{"error"}})
+	r.ApplyWaivers(waivers, now)
+	if r.ViolatedCount() != 2 {
+		t.Errorf("violatedCount = %v; want %v", r.ViolatedCount(), 2)
+	}
+	if r.WaivedCount() != 1 {
+		t.Fatalf("waivedCount = %v; want %v", r.WaivedCount(), 1)
+	}
+	if _, ok := r.Violated["groupOne"]; ok {
+		t.Errorf("violated group %v is present; want removed", "groupOne")
+	}
+	if waived := r.Waived["groupOne"][0]; waived.Waiver != waivers[0] {
+		t.Errorf("waived policy waiver = %v; want %v", waived.Waiver, waivers[0])
+	}
+	exceptions := r.Exceptions()
+	if len(exceptions) != 1 || exceptions[0].Status != ExceptionStatusWaived {
+		t.Errorf("exceptions = %v; want single waived exception", exceptions)
+	}
+}
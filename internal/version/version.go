@@ -0,0 +1,35 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package version
+
+import (
+	"fmt"
+
+	opaversion "github.com/open-policy-agent/opa/version"
+)
+
+// Version is the gke-review version. It is set at build time, i.e. with
+// -ldflags "-X github.com/mikouaj/gke-review/internal/version.Version=v1.2.0".
+var Version = "dev"
+
+// OPAVersion returns version of the embedded Open Policy Agent, that evaluates rego policies.
+func OPAVersion() string {
+	return opaversion.Version
+}
+
+// String returns both versions, i.e. v1.2.0 (OPA 0.38.1).
+func String() string {
+	return fmt.Sprintf("%s (OPA %s)", Version, OPAVersion())
+}
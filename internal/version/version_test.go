@@ -0,0 +1,32 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package version
+
+import (
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	original := Version
+	defer func() { Version = original }()
+	Version = "v1.2.0"
+	expected := "v1.2.0 (OPA " + OPAVersion() + ")"
+	if String() != expected {
+		t.Errorf("String() = %v; want %v", String(), expected)
+	}
+	if OPAVersion() == "" {
+		t.Errorf("OPAVersion() is empty; want version")
+	}
+}
@@ -0,0 +1,104 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Package review evaluates GKE policies against cluster details in-process,
+// for embedding the tool in other Go programs.
+package review
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/mikouaj/gke-review/internal/gke"
+	"github.com/mikouaj/gke-review/internal/policy"
+)
+
+// PolicyFile is a REGO policy file with its content.
+type PolicyFile = policy.PolicyFile
+
+// Policy is a policy with its metadata and, once evaluated, its evaluation result.
+type Policy = policy.Policy
+
+// Violation is a violation reported by a policy, with optional resource and details.
+type Violation = policy.Violation
+
+// PolicyEvaluationResult holds evaluated policies of a cluster, grouped by the outcome
+// and the policy group.
+type PolicyEvaluationResult = policy.PolicyEvaluationResult
+
+// Builtin is a custom function, implemented in Go, that policies can call.
+type Builtin = policy.Builtin
+
+// Reviewer evaluates compiled policies against cluster details. It is safe for concurrent use.
+type Reviewer struct {
+	agent *policy.PolicyAgent
+}
+
+// New compiles policy files and validates their metadata.
+func New(policies []*PolicyFile) (*Reviewer, error) {
+	if len(policies) == 0 {
+		return nil, errors.New("no policy files given")
+	}
+	agent := policy.NewPolicyAgent(context.Background())
+	agent.WithStrictMetadata()
+	if err := agent.WithFiles(policies); err != nil {
+		return nil, err
+	}
+	return &Reviewer{agent: agent}, nil
+}
+
+// Review evaluates policies against cluster details. The clusterInput is any value that
+// serializes to the cluster JSON representation, i.e. a map decoded from JSON.
+func (r *Reviewer) Review(ctx context.Context, clusterInput interface{}) (*PolicyEvaluationResult, error) {
+	return r.agent.WithContext(ctx).Evaluate(clusterInput)
+}
+
+// RegisterBuiltin makes a custom function available to policies of reviewers created afterwards.
+func RegisterBuiltin(builtin *Builtin) error {
+	return policy.RegisterBuiltin(builtin)
+}
+
+// Builtins returns custom functions available to policies, ordered by name.
+func Builtins() []*Builtin {
+	return policy.Builtins()
+}
+
+// ClusterInputFromJSON decodes cluster details in the JSON format of GKE API.
+func ClusterInputFromJSON(data []byte) (map[string]interface{}, error) {
+	input, err := gke.NewClusterInputFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// ClusterInputsFromTerraformJSON decodes details of clusters defined in output of
+// terraform show -json for a plan or a state. Inputs are keyed by resource addresses.
+func ClusterInputsFromTerraformJSON(data []byte) (map[string]map[string]interface{}, error) {
+	clusters, err := gke.NewClusterInputsFromTerraformJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make(map[string]map[string]interface{}, len(clusters))
+	for _, cluster := range clusters {
+		inputs[cluster.Address] = cluster.Input
+	}
+	return inputs, nil
+}
+
+// LoadResult reads the evaluation result written with its Save method.
+func LoadResult(r io.Reader) (*PolicyEvaluationResult, error) {
+	return policy.LoadResult(r)
+}
@@ -0,0 +1,114 @@
+//Copyright 2022 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package review
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const testPolicy = "# METADATA\n" +
+	"# title: Node count\n" +
+	"# description: Cluster should have at least two nodes\n" +
+	"# custom:\n" +
+	"#   group: Availability\n" +
+	"package gke.policy.node_count\n" +
+	"default valid = false\n" +
+	"valid { count(violation) == 0 }\n" +
+	"violation[msg] { input.current_node_count < 2; msg := \"cluster has less than two nodes\" }\n"
+
+func TestReview(t *testing.T) {
+	reviewer, err := New([]*PolicyFile{{Name: "node_count.rego", FullName: "policies/node_count.rego", Content: testPolicy}})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	inputs := map[string]int{
+		`{"name": "one", "current_node_count": 3}`: 0,
+		`{"name": "two", "current_node_count": 1}`: 1,
+	}
+	for data, violated := range inputs {
+		input, err := ClusterInputFromJSON([]byte(data))
+		if err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		result, err := reviewer.Review(context.Background(), input)
+		if err != nil {
+			t.Fatalf("err = %v; want nil", err)
+		}
+		if result.ViolatedCount() != violated {
+			t.Errorf("input %s: violatedCount = %v; want %v", data, result.ViolatedCount(), violated)
+		}
+		if !reflect.DeepEqual(result.Groups(), []string{"Availability"}) {
+			t.Errorf("groups = %v; want %v", result.Groups(), []string{"Availability"})
+		}
+		for _, policy := range result.Violated["Availability"] {
+			if !reflect.DeepEqual(policy.Violations, []string{"cluster has less than two nodes"}) {
+				t.Errorf("violations = %v; want %v", policy.Violations, []string{"cluster has less than two nodes"})
+			}
+		}
+	}
+}
+
+func TestReview_cancelled(t *testing.T) {
+	reviewer, err := New([]*PolicyFile{{Name: "node_count.rego", FullName: "node_count.rego", Content: testPolicy}})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := reviewer.Review(ctx, map[string]interface{}{"current_node_count": 1}); err == nil {
+		t.Errorf("err is nil; want error for cancelled context")
+	}
+}
+
+func TestNew_invalid(t *testing.T) {
+	inputs := [][]*PolicyFile{
+		nil,
+		{{Name: "broken.rego", FullName: "broken.rego", Content: "package gke.policy.broken\np = "}},
+		{{Name: "meta.rego", FullName: "meta.rego", Content: "package gke.policy.meta\np = 1\n"}},
+	}
+	for i, input := range inputs {
+		if _, err := New(input); err == nil {
+			t.Errorf("input [%d]: err is nil; want error", i)
+		}
+	}
+}
+
+func TestReview_terraform(t *testing.T) {
+	reviewer, err := New([]*PolicyFile{{Name: "node_count.rego", FullName: "node_count.rego", Content: testPolicy}})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	plan := `{"format_version": "1.1", "planned_values": {"root_module": {"resources": [
+		{"address": "google_container_cluster.one", "mode": "managed", "type": "google_container_cluster",
+		 "values": {"name": "one", "current_node_count": 1}}
+	]}}}`
+	inputs, err := ClusterInputsFromTerraformJSON([]byte(plan))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	input, ok := inputs["google_container_cluster.one"]
+	if !ok {
+		t.Fatalf("inputs = %v; want google_container_cluster.one", inputs)
+	}
+	result, err := reviewer.Review(context.Background(), input)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if result.ViolatedCount() != 1 {
+		t.Errorf("violatedCount = %v; want %v", result.ViolatedCount(), 1)
+	}
+}